@@ -12,6 +12,7 @@ import (
 	"github.com/bilbilaki/ai2go/internal/chat"
 	"github.com/bilbilaki/ai2go/internal/commands"
 	"github.com/bilbilaki/ai2go/internal/config"
+	"github.com/bilbilaki/ai2go/internal/lsp"
 	"github.com/bilbilaki/ai2go/internal/tools"
 	"github.com/bilbilaki/ai2go/internal/ui"
 	"github.com/bilbilaki/ai2go/internal/utils" // Import the new utils package
@@ -34,20 +35,23 @@ func main() {
 	}
 	fmt.Println("\n" + ui.System(strings.Repeat("=", 50)))
 
-	store, history, err := chat.NewThreadStore(cfg.CurrentModel)
+	vault, err := commands.UnlockAtStartup(cfg)
 	if err != nil {
-		fmt.Println(ui.Error(fmt.Sprintf("Failed to load thread store: %v", err)))
+		fmt.Println(ui.Error(fmt.Sprintf("Failed to unlock encrypted storage: %v", err)))
 		return
 	}
-	fmt.Printf("Active thread: %s (%s)\n", ui.Thread(store.ActiveThreadTitle()), store.ActiveThreadID())
 
 	cliTool := tools.GetCLITool()
 	readTool := tools.GetReadFileTool()   // <--- New
 	patchTool := tools.GetPatchFileTool() // <--- New
+	editFileTool := tools.GetEditFileTool()
+	editTransactionTool := tools.GetEditTransactionTool()
 	applyUnifiedPatchTool := tools.GetApplyUnifiedDiffPatchTool()
 	createCheckpointTool := tools.GetCreateCheckpointTool()
 	undoCheckpointsTool := tools.GetUndoCheckpointsTool()
 	editorHistoryTool := tools.GetEditorHistoryTool()
+	restoreCheckpointFilesTool := tools.GetRestoreCheckpointFilesTool()
+	tagCheckpointTool := tools.GetTagCheckpointTool()
 	cpuUsageSampleTool := tools.GetCPUUsageSampleTool()
 	processSignalTool := tools.GetProcessSignalTool()
 	pageSizeTool := tools.GetPageSizeTool()
@@ -65,13 +69,44 @@ func main() {
 	fileComparisonTool := tools.GetFileComparisonTool()
 	createFileBackupTool := tools.GetCreateFileBackupTool()
 	restoreFileBackupTool := tools.GetRestoreFileBackupTool()
+	listFileBackupsTool := tools.GetListFileBackupsTool()
 	fileMergingTool := tools.GetFileMergingTool()
+	resolveConflictTool := tools.GetResolveMergeConflictTool()
 	fileTypeDetectionTool := tools.GetFileTypeDetectionTool()
 	miniFileHelperTool := tools.GetMiniFileHelperTool()
 	subagentFactoryTool := tools.GetSubagentFactoryTool()
 	subagentContextTool := tools.GetSubagentContextProviderTool()
 	projectArchitectTool := tools.GetProjectArchitectTool()
-	toolsList := []api.Tool{cliTool, readTool, patchTool, applyUnifiedPatchTool, createCheckpointTool, undoCheckpointsTool, editorHistoryTool, cpuUsageSampleTool, processSignalTool, pageSizeTool, askUserTool, organizeMediaTool, removeLinesTool, replaceLineRangeTool, batchLineOpsTool, deleteByPatternTool, extractLineRangeTool, reorderLineRangeTool, removeDuplicateLinesTool, miniEditorHelperTool, fileDiffViewerTool, fileComparisonTool, createFileBackupTool, restoreFileBackupTool, fileMergingTool, fileTypeDetectionTool, miniFileHelperTool, subagentFactoryTool, subagentContextTool, projectArchitectTool}
+	rememberTool := tools.GetRememberTool()
+	recallTool := tools.GetRecallTool()
+	forgetTool := tools.GetForgetTool()
+	findFilesTool := tools.GetFindFilesTool()
+	grepCodeTool := tools.GetGrepCodeTool()
+	goListSymbolsTool := tools.GetGoListSymbolsTool()
+	goShowFunctionTool := tools.GetGoShowFunctionTool()
+	goFindDefinitionTool := tools.GetGoFindDefinitionTool()
+	goFindReferencesTool := tools.GetGoFindReferencesTool()
+	goRenameSymbolTool := tools.GetGoRenameSymbolTool()
+	goReplaceFunctionBodyTool := tools.GetGoReplaceFunctionBodyTool()
+	goEditImportsTool := tools.GetGoEditImportsTool()
+	goAddStructFieldTool := tools.GetGoAddStructFieldTool()
+	goAddMethodTool := tools.GetGoAddMethodTool()
+	toolsList := []api.Tool{cliTool, readTool, patchTool, editFileTool, editTransactionTool, applyUnifiedPatchTool, createCheckpointTool, undoCheckpointsTool, editorHistoryTool, restoreCheckpointFilesTool, tagCheckpointTool, cpuUsageSampleTool, processSignalTool, pageSizeTool, askUserTool, organizeMediaTool, removeLinesTool, replaceLineRangeTool, batchLineOpsTool, deleteByPatternTool, extractLineRangeTool, reorderLineRangeTool, removeDuplicateLinesTool, miniEditorHelperTool, fileDiffViewerTool, fileComparisonTool, createFileBackupTool, restoreFileBackupTool, listFileBackupsTool, fileMergingTool, resolveConflictTool, fileTypeDetectionTool, miniFileHelperTool, subagentFactoryTool, subagentContextTool, projectArchitectTool, rememberTool, recallTool, forgetTool, findFilesTool, grepCodeTool, goListSymbolsTool, goShowFunctionTool, goFindDefinitionTool, goFindReferencesTool, goRenameSymbolTool, goReplaceFunctionBodyTool, goEditImportsTool, goAddStructFieldTool, goAddMethodTool}
+
+	store, history, err := chat.NewThreadStore(cfg.CurrentModel, vault, toolsList)
+	if err != nil {
+		fmt.Println(ui.Error(fmt.Sprintf("Failed to load thread store: %v", err)))
+		return
+	}
+	if err := commands.ConfigureRedaction(history, store, cfg); err != nil {
+		fmt.Println(ui.Warn(fmt.Sprintf("Invalid redaction settings, using defaults: %v", err)))
+	}
+	commands.ConfigureLSP(cfg)
+	commands.ConfigureCheckpoints(cfg)
+	commands.ConfigureBackups(cfg)
+	defer lsp.DefaultManager().Shutdown()
+	fmt.Printf("Project: %s\n", store.Project().Key())
+	fmt.Printf("Active thread: %s (%s)\n", ui.Thread(store.ActiveThreadTitle()), store.ActiveThreadID())
 	apiClient := api.NewClient(cfg)
 
 	homeDir, _ := os.UserHomeDir()
@@ -127,7 +162,7 @@ func main() {
 		for {
 			// If the current chunk has /file, resolve it immediately
 			if strings.Contains(finalMessage, "/file") {
-				finalMessage = utils.ResolveFileTokens(finalMessage)
+				finalMessage = utils.ResolveFileTokens(finalMessage, history.Redactor())
 
 				// Show status
 				fmt.Println("\n\033[36m[Draft Mode] File attached.\033[0m")
@@ -189,13 +224,48 @@ func main() {
 			}
 		}()
 
+		// The turn journal keeps the prompt on disk; keep secrets out of it.
+		turnPrompt, _ := history.Redactor().Redact(finalMessage)
+		turn := beginTurn(cfg, turnPrompt)
 		chat.ProcessConversation(runCtx, history, toolsList, cfg, apiClient, pauseCtrl)
 		chat.StopPauseSignal(pauseSig)
 		stop()
 		<-done
+		endTurn(turn)
 		if err := store.SyncActiveHistory(history); err != nil {
 			fmt.Println(ui.Error(fmt.Sprintf("Warning: failed to persist thread history: %v", err)))
 		}
 		commands.TryAutoSummarize(history, store, cfg, apiClient)
 	}
 }
+
+// beginTurn snapshots the working tree before an agent turn so /undo can
+// revert everything the turn changed, whichever tool made the change.
+func beginTurn(cfg *config.Config, prompt string) *tools.TurnRecorder {
+	if cfg.DisableAutoCheckpoint {
+		return nil
+	}
+	workTree, err := tools.DefaultWorkTree()
+	if err == nil {
+		var rec *tools.TurnRecorder
+		if rec, err = tools.BeginTurn(workTree, prompt); err == nil {
+			return rec
+		}
+	}
+	fmt.Println(ui.Warn(fmt.Sprintf("[Checkpoint] Skipped: %v", err)))
+	return nil
+}
+
+func endTurn(rec *tools.TurnRecorder) {
+	if rec == nil {
+		return
+	}
+	turn, err := rec.End()
+	if err != nil {
+		fmt.Println(ui.Warn(fmt.Sprintf("[Checkpoint] Failed to record turn: %v", err)))
+		return
+	}
+	if turn != nil {
+		fmt.Println(ui.System(fmt.Sprintf("[Checkpoint] Turn %d changed %d file(s). /changes to review, /undo to revert.", turn.ID, len(turn.Files))))
+	}
+}
@@ -0,0 +1,81 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+
+	"github.com/bilbilaki/ai2go/internal/lsp"
+	"github.com/bilbilaki/ai2go/internal/tools"
+)
+
+// editedFiles returns the files an edit tool call writes, or nil for tools
+// that do not edit files.
+func editedFiles(name, rawArgs string) []string {
+	var args map[string]any
+	if err := json.Unmarshal([]byte(rawArgs), &args); err != nil {
+		return nil
+	}
+	str := func(key string) string {
+		v, _ := args[key].(string)
+		return strings.TrimSpace(v)
+	}
+
+	switch {
+	case name == "apply_unified_diff_patch":
+		workTree := str("work_tree")
+		var files []string
+		patches, err := tools.ParseUnifiedDiff(str("patch"))
+		if err != nil {
+			return nil
+		}
+		for _, fp := range patches {
+			if fp.NewPath != "" {
+				files = append(files, filepath.Join(workTree, fp.NewPath))
+			}
+		}
+		return files
+	case name == "edit_transaction":
+		args, err := tools.ParseEditTransactionArgs(rawArgs)
+		if err != nil {
+			return nil
+		}
+		return args.TargetPaths()
+	case name == "resolve_merge_conflict":
+		// Without a conflict number the tool only lists conflicts.
+		if _, resolving := args["conflict"]; resolving {
+			return []string{str("path")}
+		}
+	case name == "merge_files":
+		if out := str("output_path"); out != "" {
+			return []string{out}
+		}
+	case strings.HasPrefix(name, "go_") && !strings.HasSuffix(str("path"), ".go"):
+		// Go edit tools also accept a package directory; the edited file
+		// is not known up front.
+		return nil
+	case fileEditTools[name]:
+		if path := str("path"); path != "" {
+			return []string{path}
+		}
+	}
+	return nil
+}
+
+// attachDiagnostics appends language server errors for the files touched by
+// a successful edit tool call to its response.
+func attachDiagnostics(ctx context.Context, name, rawArgs, response string) (string, string) {
+	if toolResponseFailed(response) {
+		return response, ""
+	}
+	files := editedFiles(name, rawArgs)
+	if len(files) == 0 {
+		return response, ""
+	}
+	report := lsp.FormatReports(lsp.DefaultManager().CheckFiles(ctx, files, lsp.DefaultDiagnosticsTimeout))
+	if report == "" {
+		return response, ""
+	}
+	return response + "\n\n" + report, report
+}
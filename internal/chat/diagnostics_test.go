@@ -0,0 +1,38 @@
+package chat
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestEditedFilesCoversEditTools(t *testing.T) {
+	patch := "--- a/pkg/a.go\n+++ b/pkg/a.go\n@@ -1 +1 @@\n-x\n+y\n--- a/old.go\n+++ /dev/null\n"
+	patchJSON, _ := json.Marshal(patch)
+	cases := []struct {
+		name string
+		args string
+		want []string
+	}{
+		{"patch_file", `{"path":"main.go","patch":"1++ x"}`, []string{"main.go"}},
+		{"replace_line_range", `{"path":"a.py"}`, []string{"a.py"}},
+		{"apply_unified_diff_patch", `{"work_tree":"/repo","patch":` + string(patchJSON) + `}`, []string{filepath.Join("/repo", "pkg/a.go")}},
+		{"merge_files", `{"output_path":"merged.go"}`, []string{"merged.go"}},
+		{"edit_transaction", `{"work_tree":"/repo","operations":[{"op":"rename","path":"a.go","to":"b.go"},{"op":"delete","path":"c.go"},{"op":"insert","path":"d.go","text":"x"}]}`,
+			[]string{filepath.Join("/repo", "b.go"), filepath.Join("/repo", "d.go")}},
+		{"go_add_method", `{"path":"pkg/a.go","type":"T"}`, []string{"pkg/a.go"}},
+		{"go_replace_function_body", `{"path":"pkg","name":"F"}`, nil},
+		{"read_file", `{"path":"main.go"}`, nil},
+		{"patch_file", `not json`, nil},
+	}
+	for _, tc := range cases {
+		if got := editedFiles(tc.name, tc.args); !reflect.DeepEqual(got, tc.want) {
+			t.Fatalf("%s: got %v, want %v", tc.name, got, tc.want)
+		}
+	}
+
+	if out, report := attachDiagnostics(t.Context(), "patch_file", `{"path":"x.go"}`, "Error: patch failed"); out != "Error: patch failed" || report != "" {
+		t.Fatalf("failed edits must not be checked, got %q / %q", out, report)
+	}
+}
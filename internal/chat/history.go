@@ -2,14 +2,23 @@ package chat
 
 import (
 	"fmt"
+	"os"
 	"runtime"
+	"strings"
+	"time"
 	"unicode/utf8"
 
 	"github.com/bilbilaki/ai2go/internal/api"
+	"github.com/bilbilaki/ai2go/internal/project"
+	"github.com/bilbilaki/ai2go/internal/prompt"
+	"github.com/bilbilaki/ai2go/internal/redact"
 	"github.com/pandodao/tokenizer-go"
 )
 
-const maxToolResponseChars = 6000
+const (
+	maxToolResponseChars = 6000
+	summaryMessagePrefix = "Conversation memory (compressed summary of earlier turns; authoritative prior context):\n"
+)
 
 // TokenCounter approximates session token usage (1 token ~4 chars).
 type TokenCounter struct {
@@ -37,6 +46,18 @@ func ApproximateTokens(text string) int64 {
 type History struct {
 	messages []api.Message
 	counter  *TokenCounter
+	redactor *redact.Redactor
+	memory   *MemoryStore
+
+	// workDir and userDir locate instruction files; they are re-read every
+	// time the system message is rebuilt so edits apply to new threads.
+	workDir          string
+	userDir          string
+	instructionFiles []project.InstructionFile
+
+	persona   string
+	toolNames []string
+	summary   *SummaryRecord
 }
 
 func NewHistory(currentModel string) *History {
@@ -44,68 +65,64 @@ func NewHistory(currentModel string) *History {
 		messages: []api.Message{},
 		counter:  &TokenCounter{},
 	}
+	h.redactor, _ = redact.New(nil)
 	h.SetSystemMessage(currentModel)
 	return h
 }
 
 func (h *History) SetSystemMessage(currentModel string) {
+	h.messages = []api.Message{h.buildSystemMessage(currentModel)}
+}
+
+// RefreshSystemMessage rebuilds the system message in place, keeping the rest
+// of the conversation.
+func (h *History) RefreshSystemMessage(currentModel string) {
+	sysMsg := h.buildSystemMessage(currentModel)
+	if len(h.messages) > 0 && h.messages[0].Role == "system" {
+		h.messages[0] = sysMsg
+		return
+	}
+	h.messages = append([]api.Message{sysMsg}, h.messages...)
+}
+
+func (h *History) buildSystemMessage(currentModel string) api.Message {
 	osName := "Linux/Mac"
 	if runtime.GOOS == "windows" {
 		osName = "Windows"
 	}
-	sysMsg := api.Message{
-		Role: "system",
-		Content: fmt.Sprintf(`You are an advanced terminal assistant. 
-Current OS: %s
- 
-RULES:
-1. You can use 'run_command' to execute shell commands.
-2. You can use 'read_file' to inspect files with line numbers.
-3. Use 'patch_file' as the default editor for file changes.
-4. Use 'apply_unified_diff_patch' only when multi-file atomic edits are required.
-   - Required args: 'work_tree', 'patch'
-   - Optional: 'verify_mode' in ['none', 'syntax', 'tests']
-   - Patch MUST be valid unified diff with headers/hunks.
-5. If 'apply_unified_diff_patch' fails due to parse/header/fragment errors, immediately switch to 'patch_file' and continue the task.
-6. After editing a file, re-run 'read_file' on the changed range to verify the result before claiming completion.
-7. If user scope says one file, stay on that file unless user expands scope.
-8. You can use 'create_checkpoint', 'editor_history', and 'undo_checkpoints' for manual checkpoint workflow.
-9. You can use process/system helpers when needed:
-   - 'get_process_cpu_usage_sample' for PID CPU sampling
-   - 'send_process_signal' for process tree signals
-   - 'get_page_size' for OS page size
-10. You can use 'subagent_factory' to split a mega task into concurrent subagent tasks and generate a report (requires experimental mode ON).
-11. You can use 'subagent_context_provider' with task_id to fetch summarized volatile context from a subagent run.
-12. You can use 'project_architect' to transform a rough project request into a detailed, implementation-ready step/task plan.
-13. If user asks to create a big project, or asks for long multi-step work with subagents, FIRST call 'project_architect' using the user request as prompt, then split/delegate tasks to subagents.
-14. For delegated execution, decide required subagent count from the generated plan and assign one concrete task per subagent.
-15. Subagents do not need 'project_architect'; planner is for main agent orchestration.
-16. When calling 'subagent_factory' for coding tasks, pass explicit 'timeout_sec' and 'max_concurrency'. Prefer lower concurrency for tasks that touch shared files.
-17. Do not run dependent file-overlapping tasks in parallel. Run them step-by-step if they modify the same modules.
-18. HANDLING LONG OUTPUT:
-   - If a command returns "[OUTPUT TRUNCATED]", DO NOT apologize. 
-   - IMMEDIATELY run a new command to filter the data (e.g., 'grep "error" file.log', 'tail -n 10 file.log').
-   - Never output huge chunks of text yourself.
-19. Use 'ask_user' when requirements are ambiguous or there are multiple valid solution paths.
-    - Pass a clear 'question'.
-    - Add 'options' only if useful; otherwise ask free text.
-    - You may ask follow-up questions via repeated 'ask_user' calls until requirements are clear.
-20. For large messy media folders, prefer 'organize_media_files' instead of long shell loops:
-    - First run with dry_run=true and show preview summary.
-    - Then ask for confirmation and run with dry_run=false.
-21. You can use line/text-edit tools for precise file operations:
-    - 'remove_lines', 'replace_line_range', 'batch_line_operations'
-    - 'delete_lines_by_pattern', 'extract_line_range'
-    - 'reorder_line_range', 'remove_duplicate_lines'
-22. You can use file-management tools when working with versions/compare/merge:
-    - 'show_file_diff', 'compare_files_side_by_side'
-    - 'create_file_backup', 'restore_file_backup'
-    - 'merge_files', 'detect_file_type'
-23. For delegated text-only work, use 'mini_editor_helper' with a focused prompt; it runs a minimal helper loop and returns a report.
-24. For delegated file-management work, use 'mini_file_helper' with a focused prompt.
-25. Always explain your plan briefly before executing commands.`, osName),
-	}
-	h.messages = []api.Message{sysMsg}
+	cwd := h.workDir
+	if cwd == "" {
+		cwd, _ = os.Getwd()
+	}
+	data := prompt.Data{
+		OS:    osName,
+		CWD:   cwd,
+		Model: currentModel,
+		Date:  time.Now().Format("2006-01-02"),
+		Tools: h.toolNames,
+	}
+	content, err := prompt.Render(h.persona, h.userDir, data)
+	if err != nil {
+		// A broken user template must not leave the thread without rules.
+		content, _ = prompt.Render(prompt.DefaultPersona, "", data)
+		content += fmt.Sprintf("\n\n(Persona %q could not be rendered: %v)", h.persona, err)
+	}
+	sysMsg := api.Message{Role: "system", Content: content}
+	// Instruction files and memories are user content; secrets in them are
+	// sent to the provider only as placeholders.
+	h.instructionFiles = nil
+	if h.workDir != "" || h.userDir != "" {
+		h.instructionFiles = project.LoadInstructions(h.workDir, h.userDir)
+		if section := project.FormatInstructions(h.instructionFiles); section != "" {
+			section, _ = h.redactor.Redact(section)
+			sysMsg.Content += "\n\n" + section
+		}
+	}
+	if section := h.memory.PromptSection(); section != "" {
+		section, _ = h.redactor.Redact(section)
+		sysMsg.Content += "\n\n" + section
+	}
+	return sysMsg
 }
 
 func (h *History) AddUserMessage(content string) {
@@ -121,7 +138,74 @@ func (h *History) AddAssistantMessage(msg api.Message) {
 	h.counter.Add(ApproximateTokens(msg.Content))
 }
 
+// SetTools records which tools are enabled so the system prompt only
+// describes those. A nil list keeps the rules for every tool.
+func (h *History) SetTools(toolsList []api.Tool) {
+	if toolsList == nil {
+		h.toolNames = nil
+		return
+	}
+	h.toolNames = make([]string, 0, len(toolsList))
+	for _, t := range toolsList {
+		h.toolNames = append(h.toolNames, t.Function.Name)
+	}
+}
+
+// SetPersona selects the persona template used when the system message is
+// rebuilt. "" means the default persona.
+func (h *History) SetPersona(persona string) {
+	h.persona = persona
+}
+
+// Persona returns the selected persona, "" for the default one.
+func (h *History) Persona() string {
+	return h.persona
+}
+
+// SetInstructionDirs sets where instruction files are looked up: workDir and
+// its parents, plus the user config dir.
+func (h *History) SetInstructionDirs(workDir, userDir string) {
+	h.workDir = workDir
+	h.userDir = userDir
+}
+
+// InstructionFiles returns the instruction files merged into the system
+// message the last time it was rebuilt in this session.
+func (h *History) InstructionFiles() []project.InstructionFile {
+	return h.instructionFiles
+}
+
+// InstructionDirs returns the directories set by SetInstructionDirs.
+func (h *History) InstructionDirs() (workDir, userDir string) {
+	return h.workDir, h.userDir
+}
+
+// SetMemory attaches the memory store whose most relevant entries are added
+// to the system prompt whenever a thread starts.
+func (h *History) SetMemory(m *MemoryStore) {
+	h.memory = m
+}
+
+// Memory returns the attached memory store, or nil.
+func (h *History) Memory() *MemoryStore {
+	return h.memory
+}
+
+// SetRedactor replaces the secret redactor; nil disables redaction.
+func (h *History) SetRedactor(r *redact.Redactor) {
+	h.redactor = r
+}
+
+// Redactor returns the active secret redactor, or nil when redaction is off.
+func (h *History) Redactor() *redact.Redactor {
+	return h.redactor
+}
+
 func (h *History) AddToolResponse(toolCallID, content string) {
+	// Redact before truncating so a secret split by the cut is still caught.
+	if redacted, n := h.redactor.Redact(content); n > 0 {
+		content = redacted + fmt.Sprintf("\n\n[%d secret value(s) redacted; use the placeholders as-is in tool arguments]", n)
+	}
 	content = truncateForHistory(content, maxToolResponseChars)
 
 	// FIX: The API requires a non-empty 'content' field for tool messages.
@@ -162,6 +246,7 @@ func truncateForHistory(s string, maxChars int) string {
 
 func (h *History) Clear(currentModel string) {
 	h.SetSystemMessage(currentModel)
+	h.summary = nil
 	h.counter.Reset()
 }
 
@@ -240,16 +325,127 @@ func (h *History) GetSanitizedMessages() []api.Message {
 	return sanitized
 }
 
-func (h *History) ReplaceWithSummary(currentModel, summary string) {
-	h.Clear(currentModel)
+// RollingSummary returns the chained conversation summary, or "" when the
+// thread has not been compacted yet.
+func (h *History) RollingSummary() string {
+	if n := h.promptLen(); len(h.messages) > n && IsSummaryMessage(h.messages[n]) {
+		return strings.TrimPrefix(h.messages[n].Content, summaryMessagePrefix)
+	}
+	return ""
+}
 
-	h.messages = append(h.messages, api.Message{
-		Role: "system",
-		Content: fmt.Sprintf(
-			"Conversation memory (compressed summary):\n%s\n\nUse this as authoritative prior context for follow-up turns.",
-			summary,
-		),
+// CompactionRange returns the [start, cut) range of messages that rolling
+// compaction would summarize. Messages from cut onward stay verbatim: at least
+// the last keepTurns user turns, plus older turns while the verbatim tail
+// stays under keepTokens. cut always falls on a user message so tool calls
+// and their responses are never split. ok is false when nothing is old enough
+// to compact.
+func (h *History) CompactionRange(keepTurns int, keepTokens int64) (start, cut int, ok bool) {
+	start = h.headLen()
+	cut = len(h.messages)
+	turns := 0
+	var tailTokens int64
+	for i := len(h.messages) - 1; i >= start; i-- {
+		tailTokens += ApproximateTokens(h.messages[i].Content)
+		if h.messages[i].Role != "user" {
+			continue
+		}
+		if turns >= keepTurns && tailTokens > keepTokens {
+			break
+		}
+		turns++
+		cut = i
+	}
+	if cut <= start || turns == 0 {
+		return start, cut, false
+	}
+	return start, cut, true
+}
+
+// FittingKeepTurns returns how many of the last keepTurns user turns fit
+// under limit tokens, but at least one. Keeping a verbatim tail larger than
+// the auto-summary threshold would leave the thread over it after every
+// compaction.
+func (h *History) FittingKeepTurns(keepTurns int, limit int64) int {
+	turns := 0
+	var tailTokens int64
+	for i := len(h.messages) - 1; i >= h.headLen() && turns < keepTurns; i-- {
+		tailTokens += ApproximateTokens(h.messages[i].Content)
+		if h.messages[i].Role != "user" {
+			continue
+		}
+		if tailTokens >= limit {
+			break
+		}
+		turns++
+	}
+	return max(turns, 1)
+}
+
+// ApplyRollingSummary replaces messages[start:cut] (as returned by
+// CompactionRange) with record, which should already include the previous
+// record. It returns the replaced messages so callers can keep them.
+func (h *History) ApplyRollingSummary(record SummaryRecord, cut int) []api.Message {
+	start := h.headLen()
+	if cut < start || cut > len(h.messages) {
+		return nil
+	}
+	compacted := cloneMessages(h.messages[start:cut])
+
+	rebuilt := make([]api.Message, 0, 2+len(h.messages)-cut)
+	rebuilt = append(rebuilt, h.messages[:h.promptLen()]...)
+	rebuilt = append(rebuilt, api.Message{
+		Role:    "system",
+		Content: summaryMessagePrefix + record.Render(),
 	})
+	rebuilt = append(rebuilt, h.messages[cut:]...)
+	h.messages = rebuilt
+	h.summary = &record
+
+	h.counter.Reset()
+	for _, msg := range h.messages {
+		if msg.Role != "system" {
+			h.counter.Add(ApproximateTokens(msg.Content))
+		}
+	}
+	return compacted
+}
+
+// SummaryRecord returns the structured rolling summary. Threads compacted
+// before records existed get their free-form summary back as a note.
+func (h *History) SummaryRecord() SummaryRecord {
+	if h.summary != nil {
+		return *h.summary
+	}
+	if text := h.RollingSummary(); text != "" {
+		return SummaryRecord{Notes: []string{text}}
+	}
+	return SummaryRecord{}
+}
+
+// SetSummaryRecord restores the structured summary of a loaded thread.
+func (h *History) SetSummaryRecord(record *SummaryRecord) {
+	h.summary = record
+}
+
+// promptLen is 1 when the thread starts with a system prompt, else 0.
+func (h *History) promptLen() int {
+	if len(h.messages) > 0 && h.messages[0].Role == "system" && !IsSummaryMessage(h.messages[0]) {
+		return 1
+	}
+	return 0
+}
+
+// headLen counts the leading system prompt and rolling summary messages.
+func (h *History) headLen() int {
+	n := h.promptLen()
+	if len(h.messages) > n && IsSummaryMessage(h.messages[n]) {
+		n++
+	}
+	return n
+}
 
-	fmt.Printf("\n\033[90m[Debug] Token count reset to: %d\033[0m\n", h.GetTotalTokens())
+// IsSummaryMessage reports whether msg holds the rolling conversation summary.
+func IsSummaryMessage(msg api.Message) bool {
+	return msg.Role == "system" && strings.HasPrefix(msg.Content, summaryMessagePrefix)
 }
@@ -0,0 +1,419 @@
+package chat
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bilbilaki/ai2go/internal/project"
+	"github.com/bilbilaki/ai2go/internal/redact"
+	"github.com/bilbilaki/ai2go/internal/secure"
+)
+
+const (
+	memoriesFile = "memories.json"
+
+	// maxPromptMemories and maxPromptMemoryChars bound how much of the memory
+	// store is injected into the system prompt when a thread starts.
+	maxPromptMemories    = 12
+	maxPromptMemoryChars = 2400
+	defaultRecallLimit   = 8
+)
+
+// Memory is a durable fact the assistant should know across threads. An empty
+// Project means the memory is global.
+type Memory struct {
+	ID        string    `json:"id"`
+	Content   string    `json:"content"`
+	Project   string    `json:"project,omitempty"`
+	Tags      []string  `json:"tags,omitempty"`
+	Uses      int       `json:"uses,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Global reports whether the memory applies to every project.
+func (m Memory) Global() bool {
+	return m.Project == ""
+}
+
+type memoryStoreData struct {
+	Memories []Memory `json:"memories"`
+}
+
+// MemoryStore persists memories next to the thread store and shares its
+// project scope and encryption settings.
+type MemoryStore struct {
+	path    string
+	project project.Info
+	vault   *secure.Vault
+	data    memoryStoreData
+}
+
+func loadMemoryStore(path string, info project.Info, vault *secure.Vault) (*MemoryStore, error) {
+	store := &MemoryStore{path: path, project: info, vault: vault}
+
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read memory store: %w", err)
+	}
+	if secure.IsSealed(content) {
+		if vault == nil {
+			return nil, fmt.Errorf("memory store is encrypted; enable encryption in config and unlock it")
+		}
+		content, err = vault.Open(content)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt memory store: %w", err)
+		}
+	}
+	if err := json.Unmarshal(content, &store.data); err != nil {
+		return nil, fmt.Errorf("failed to parse memory store: %w", err)
+	}
+	return store, nil
+}
+
+// Remember stores a new memory. Global memories apply to every project;
+// otherwise the memory is scoped to the current project.
+func (m *MemoryStore) Remember(content string, global bool, tags []string) (*Memory, error) {
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return nil, fmt.Errorf("memory content cannot be empty")
+	}
+	scope := m.project.Key()
+	if global {
+		scope = ""
+	}
+
+	for i := range m.data.Memories {
+		existing := &m.data.Memories[i]
+		if existing.Project == scope && strings.EqualFold(existing.Content, content) {
+			return existing, nil
+		}
+	}
+
+	now := time.Now().UTC()
+	mem := Memory{
+		ID:        fmt.Sprintf("mem_%d", now.UnixNano()),
+		Content:   content,
+		Project:   scope,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	for _, tag := range tags {
+		if tag = normalizeTag(tag); tag != "" {
+			mem.Tags = append(mem.Tags, tag)
+		}
+	}
+	m.data.Memories = append(m.data.Memories, mem)
+	if err := m.save(); err != nil {
+		return nil, err
+	}
+	return &m.data.Memories[len(m.data.Memories)-1], nil
+}
+
+// Recall returns the memories visible in the current project that best match
+// query, most relevant first. An empty query returns the prompt ranking.
+func (m *MemoryStore) Recall(query string, limit int) ([]Memory, error) {
+	if limit <= 0 {
+		limit = defaultRecallLimit
+	}
+	terms := memoryTerms(query)
+
+	type scored struct {
+		idx   int
+		score float64
+	}
+	var ranked []scored
+	for i, mem := range m.data.Memories {
+		if !m.visible(mem) {
+			continue
+		}
+		score := m.baseScore(mem)
+		if len(terms) > 0 {
+			matched := matchMemoryTerms(mem, terms)
+			if matched == 0 {
+				continue
+			}
+			score += 10 * float64(matched) / float64(len(terms))
+		}
+		ranked = append(ranked, scored{idx: i, score: score})
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return ranked[i].score > ranked[j].score
+	})
+	if len(ranked) > limit {
+		ranked = ranked[:limit]
+	}
+
+	out := make([]Memory, 0, len(ranked))
+	for _, r := range ranked {
+		if len(terms) > 0 {
+			m.data.Memories[r.idx].Uses++
+		}
+		out = append(out, m.data.Memories[r.idx])
+	}
+	if len(terms) > 0 && len(out) > 0 {
+		if err := m.save(); err != nil {
+			return out, err
+		}
+	}
+	return out, nil
+}
+
+// List returns memories visible in the current project, or every memory when
+// all is true, newest first.
+func (m *MemoryStore) List(all bool) []Memory {
+	out := []Memory{}
+	for _, mem := range m.data.Memories {
+		if all || m.visible(mem) {
+			out = append(out, mem)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		return out[i].UpdatedAt.After(out[j].UpdatedAt)
+	})
+	return out
+}
+
+// Edit replaces the content of a memory visible in the current project, or
+// of any memory when all is true.
+func (m *MemoryStore) Edit(id, content string, all bool) (*Memory, error) {
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return nil, fmt.Errorf("memory content cannot be empty")
+	}
+	idx, err := m.resolve(id, all)
+	if err != nil {
+		return nil, err
+	}
+	m.data.Memories[idx].Content = content
+	m.data.Memories[idx].UpdatedAt = time.Now().UTC()
+	if err := m.save(); err != nil {
+		return nil, err
+	}
+	return &m.data.Memories[idx], nil
+}
+
+// Forget deletes a memory visible in the current project, or any memory when
+// all is true, and returns it.
+func (m *MemoryStore) Forget(id string, all bool) (Memory, error) {
+	idx, err := m.resolve(id, all)
+	if err != nil {
+		return Memory{}, err
+	}
+	removed := m.data.Memories[idx]
+	m.data.Memories = append(m.data.Memories[:idx], m.data.Memories[idx+1:]...)
+	if err := m.save(); err != nil {
+		return Memory{}, err
+	}
+	return removed, nil
+}
+
+// PromptSection renders the most relevant memories for the system prompt, or
+// "" when there are none.
+func (m *MemoryStore) PromptSection() string {
+	if m == nil {
+		return ""
+	}
+	ranked, _ := m.Recall("", maxPromptMemories)
+	if len(ranked) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("LONG-TERM MEMORY (facts saved in earlier threads; prefer them over assumptions):\n")
+	used := 0
+	for _, mem := range ranked {
+		scope := "project"
+		if mem.Global() {
+			scope = "global"
+		}
+		line := fmt.Sprintf("- [%s %s] %s\n", mem.ID, scope, mem.Content)
+		if used+len(line) > maxPromptMemoryChars {
+			break
+		}
+		b.WriteString(line)
+		used += len(line)
+	}
+	return b.String()
+}
+
+func (m *MemoryStore) visible(mem Memory) bool {
+	return mem.Global() || mem.Project == m.project.Key()
+}
+
+// baseScore favours project memories, then frequently recalled and recently
+// updated ones.
+func (m *MemoryStore) baseScore(mem Memory) float64 {
+	score := 0.0
+	if !mem.Global() {
+		score += 2
+	}
+	score += float64(min(mem.Uses, 10)) * 0.2
+	age := time.Since(mem.UpdatedAt)
+	if age < 30*24*time.Hour {
+		score += 1 - age.Hours()/(30*24)
+	}
+	return score
+}
+
+// resolve finds a memory by id or unique id prefix. Memories of other
+// projects are only considered when all is true.
+func (m *MemoryStore) resolve(id string, all bool) (int, error) {
+	id = strings.TrimSpace(id)
+	if id == "" {
+		return -1, fmt.Errorf("memory id is required")
+	}
+	prefixMatches := []int{}
+	for i, mem := range m.data.Memories {
+		if !all && !m.visible(mem) {
+			continue
+		}
+		if mem.ID == id {
+			return i, nil
+		}
+		if strings.HasPrefix(mem.ID, id) {
+			prefixMatches = append(prefixMatches, i)
+		}
+	}
+	switch len(prefixMatches) {
+	case 0:
+		return -1, fmt.Errorf("memory not found: %s", id)
+	case 1:
+		return prefixMatches[0], nil
+	default:
+		return -1, fmt.Errorf("memory id prefix is ambiguous: %s", id)
+	}
+}
+
+func (m *MemoryStore) setVault(vault *secure.Vault) error {
+	m.vault = vault
+	return m.save()
+}
+
+func (m *MemoryStore) save() error {
+	if m.path == "" {
+		return nil
+	}
+	content, err := json.MarshalIndent(m.data, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal memory store: %w", err)
+	}
+	if m.vault != nil {
+		content, err = m.vault.Seal(content)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt memory store: %w", err)
+		}
+	}
+	if err := os.WriteFile(m.path, content, 0600); err != nil {
+		return fmt.Errorf("failed to write memory store: %w", err)
+	}
+	if err := os.Chmod(m.path, 0600); err != nil {
+		return fmt.Errorf("failed to restrict memory store permissions: %w", err)
+	}
+	return nil
+}
+
+func memoryTerms(query string) []string {
+	var terms []string
+	for _, f := range strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !(r == '_' || r == '-' || r == '.' || r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r > 127)
+	}) {
+		if len(f) > 1 {
+			terms = append(terms, f)
+		}
+	}
+	return terms
+}
+
+func matchMemoryTerms(mem Memory, terms []string) int {
+	haystack := strings.ToLower(mem.Content + " " + strings.Join(mem.Tags, " "))
+	matched := 0
+	for _, term := range terms {
+		if strings.Contains(haystack, term) {
+			matched++
+		}
+	}
+	return matched
+}
+
+type rememberArgs struct {
+	Content string   `json:"content"`
+	Scope   string   `json:"scope"`
+	Tags    []string `json:"tags"`
+}
+
+type recallArgs struct {
+	Query string `json:"query"`
+	Limit int    `json:"limit"`
+}
+
+type forgetArgs struct {
+	ID string `json:"id"`
+}
+
+// isMemoryTool reports whether name is one of the memory tools.
+func isMemoryTool(name string) bool {
+	return name == "remember" || name == "recall" || name == "forget"
+}
+
+// executeMemoryTool runs a memory tool. Secrets in remembered content are
+// replaced by redactor placeholders before they are stored.
+func executeMemoryTool(mem *MemoryStore, redactor *redact.Redactor, name, rawArgs string) string {
+	if mem == nil {
+		return "Error: memory store is not available"
+	}
+
+	switch name {
+	case "remember":
+		var args rememberArgs
+		if err := json.Unmarshal([]byte(rawArgs), &args); err != nil {
+			return fmt.Sprintf("Error parsing arguments: %v", err)
+		}
+		content, _ := redactor.Redact(args.Content)
+		saved, err := mem.Remember(content, strings.EqualFold(args.Scope, "global"), args.Tags)
+		if err != nil {
+			return fmt.Sprintf("Error: %v", err)
+		}
+		return fmt.Sprintf("Remembered %s: %s", saved.ID, saved.Content)
+	case "recall":
+		var args recallArgs
+		if err := json.Unmarshal([]byte(rawArgs), &args); err != nil {
+			return fmt.Sprintf("Error parsing arguments: %v", err)
+		}
+		found, err := mem.Recall(args.Query, args.Limit)
+		if err != nil {
+			return fmt.Sprintf("Error: %v", err)
+		}
+		if len(found) == 0 {
+			return "No matching memories."
+		}
+		var b strings.Builder
+		for _, m := range found {
+			scope := "project"
+			if m.Global() {
+				scope = "global"
+			}
+			fmt.Fprintf(&b, "%s [%s] %s\n", m.ID, scope, m.Content)
+		}
+		return strings.TrimRight(b.String(), "\n")
+	case "forget":
+		var args forgetArgs
+		if err := json.Unmarshal([]byte(rawArgs), &args); err != nil {
+			return fmt.Sprintf("Error parsing arguments: %v", err)
+		}
+		removed, err := mem.Forget(args.ID, false)
+		if err != nil {
+			return fmt.Sprintf("Error: %v", err)
+		}
+		return fmt.Sprintf("Forgot %s: %s", removed.ID, removed.Content)
+	default:
+		return fmt.Sprintf("Error: unsupported memory tool '%s'", name)
+	}
+}
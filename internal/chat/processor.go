@@ -12,6 +12,7 @@ import (
 
 	"github.com/bilbilaki/ai2go/internal/api"
 	"github.com/bilbilaki/ai2go/internal/config"
+	"github.com/bilbilaki/ai2go/internal/diff"
 	"github.com/bilbilaki/ai2go/internal/subagent"
 	"github.com/bilbilaki/ai2go/internal/tools"
 	"github.com/bilbilaki/ai2go/internal/ui"
@@ -71,14 +72,53 @@ func ProcessConversation(ctx context.Context, history *History, toolsList []api.
 				return
 			}
 
+			// Placeholders from redacted output are swapped back to real values
+			// locally; the assistant message in history keeps the placeholders.
+			// Memory tools are skipped: what they store ends up on disk and in
+			// the system prompt, so it must keep the placeholders.
+			if !isMemoryTool(tCall.Function.Name) {
+				tCall.Function.Arguments = history.Redactor().RestoreJSON(tCall.Function.Arguments)
+			}
+
 			toolResponse := ""
 			switch tCall.Function.Name {
 			case "remove_lines", "replace_line_range", "batch_line_operations", "delete_lines_by_pattern", "extract_line_range", "reorder_line_range", "remove_duplicate_lines":
-				_, toolResponse = tools.ExecuteLineTool(tCall.Function.Name, tCall.Function.Arguments)
+				_, toolResponse = tools.ExecuteVerifiedLineTool(ctx, tCall.Function.Name, tCall.Function.Arguments)
 				fmt.Printf("%s\n%s\n----------------\n", ui.Tool("[Output]"), toolResponse)
-			case "show_file_diff", "compare_files_side_by_side", "create_file_backup", "restore_file_backup", "merge_files", "detect_file_type":
+			case "show_file_diff", "compare_files_side_by_side", "create_file_backup", "restore_file_backup", "list_file_backups", "merge_files", "resolve_merge_conflict", "detect_file_type":
 				_, toolResponse = tools.ExecuteFileManagementTool(tCall.Function.Name, tCall.Function.Arguments)
+				fmt.Printf("%s\n%s\n----------------\n", ui.Tool("[Output]"), diff.Colorize(toolResponse))
+			case "restore_checkpoint_files", "tag_checkpoint":
+				_, toolResponse = tools.ExecuteCheckpointTool(tCall.Function.Name, tCall.Function.Arguments)
+				fmt.Printf("%s\n%s\n----------------\n", ui.Tool("[Output]"), toolResponse)
+			case "find_files", "grep_code":
+				_, toolResponse = tools.ExecuteSearchTool(ctx, tCall.Function.Name, tCall.Function.Arguments)
 				fmt.Printf("%s\n%s\n----------------\n", ui.Tool("[Output]"), toolResponse)
+			case "go_list_symbols", "go_show_function", "go_find_definition", "go_find_references":
+				_, toolResponse = tools.ExecuteGoSymbolTool(ctx, tCall.Function.Name, tCall.Function.Arguments)
+				fmt.Printf("%s\n%s\n----------------\n", ui.Tool("[Output]"), toolResponse)
+			case "go_rename_symbol", "go_replace_function_body", "go_edit_imports", "go_add_struct_field", "go_add_method":
+				var target struct {
+					Path string `json:"path"`
+				}
+				_ = json.Unmarshal([]byte(tCall.Function.Arguments), &target)
+
+				if !cfg.AutoAccept {
+					fmt.Printf("\n%s\n", ui.Tool(fmt.Sprintf("[Tool Request] %s: %s", tCall.Function.Name, target.Path)))
+					fmt.Print("Allow Go edit? (y/n): ")
+					confirmScanner := bufio.NewScanner(os.Stdin)
+					confirmScanner.Scan()
+					if strings.ToLower(strings.TrimSpace(confirmScanner.Text())) != "y" {
+						fmt.Println("Go edit denied.")
+						toolResponse = "User denied permission to edit Go code."
+						break
+					}
+				} else {
+					fmt.Printf("\n%s\n", ui.Tool(fmt.Sprintf("[Auto-Running] %s: %s", tCall.Function.Name, target.Path)))
+				}
+
+				_, toolResponse = tools.ExecuteGoEditTool(ctx, tCall.Function.Name, tCall.Function.Arguments)
+				fmt.Printf("%s\n%s\n----------------\n", ui.Tool("[Output]"), diff.Colorize(toolResponse))
 
 			case "run_command":
 				var args map[string]string
@@ -188,7 +228,13 @@ func ProcessConversation(ctx context.Context, history *History, toolsList []api.
 				}
 
 				fmt.Printf("\n%s\n", ui.Tool(fmt.Sprintf("[Tool] Patching file: %s", pathToPatch)))
-				output, err := tools.ApplyFilePatch(pathToPatch, patch)
+				output, err := tools.VerifyFileEdit(ctx, []string{pathToPatch}, args["verify"], func() (string, error) {
+					out, err := tools.ApplyFilePatch(pathToPatch, patch)
+					if err != nil {
+						return out, err
+					}
+					return tools.AppendFormatReport(ctx, []string{pathToPatch}, out), nil
+				})
 				if err != nil {
 					fmt.Printf("\033[31m[Error]\033[0m %v\n", err)
 					toolResponse = fmt.Sprintf("Error: %v", err)
@@ -197,6 +243,70 @@ func ProcessConversation(ctx context.Context, history *History, toolsList []api.
 				}
 				fmt.Printf("%s\n%s\n----------------\n", ui.Tool("[Output]"), output)
 
+			case "edit_file":
+				args, err := tools.ParseEditFileArgs(tCall.Function.Arguments)
+				if err != nil {
+					toolResponse = fmt.Sprintf("Error: %v", err)
+					break
+				}
+
+				if !cfg.AutoAccept {
+					fmt.Printf("\n%s\n", ui.Tool(fmt.Sprintf("[Tool Request] Edit File: %s", args.Path)))
+					fmt.Print("Allow Edit File? (y/n): ")
+					confirmScanner := bufio.NewScanner(os.Stdin)
+					confirmScanner.Scan()
+					if strings.ToLower(strings.TrimSpace(confirmScanner.Text())) != "y" {
+						fmt.Println("Edit File denied.")
+						toolResponse = "User denied permission to edit this file."
+						break
+					}
+				} else {
+					fmt.Printf("\n%s\n", ui.Tool(fmt.Sprintf("[Auto-Running] Edit File: %s", args.Path)))
+				}
+
+				output, err := tools.VerifyFileEdit(ctx, []string{args.Path}, args.Verify, func() (string, error) {
+					return tools.EditFile(ctx, args.Path, args.Edits)
+				})
+				if err != nil {
+					fmt.Printf("\033[31m[Error]\033[0m %v\n", err)
+					toolResponse = fmt.Sprintf("Error: %v", err)
+				} else {
+					toolResponse = output
+				}
+				fmt.Printf("%s\n%s\n----------------\n", ui.Tool("[Output]"), diff.Colorize(toolResponse))
+
+			case "edit_transaction":
+				args, err := tools.ParseEditTransactionArgs(tCall.Function.Arguments)
+				if err != nil {
+					toolResponse = fmt.Sprintf("Error: %v", err)
+					break
+				}
+
+				if !cfg.AutoAccept {
+					fmt.Printf("\n%s\n", ui.Tool(fmt.Sprintf("[Tool Request] Edit Transaction: %d operation(s) on %s", len(args.Operations), strings.Join(args.Paths(), ", "))))
+					fmt.Print("Allow Edit Transaction? (y/n): ")
+					confirmScanner := bufio.NewScanner(os.Stdin)
+					confirmScanner.Scan()
+					if strings.ToLower(strings.TrimSpace(confirmScanner.Text())) != "y" {
+						fmt.Println("Edit Transaction denied.")
+						toolResponse = "User denied permission to apply this edit transaction."
+						break
+					}
+				} else {
+					fmt.Printf("\n%s\n", ui.Tool(fmt.Sprintf("[Auto-Running] Edit Transaction: %d operation(s)", len(args.Operations))))
+				}
+
+				output, err := tools.VerifyFileEdit(ctx, args.Paths(), args.Verify, func() (string, error) {
+					return tools.EditTransaction(ctx, args)
+				})
+				if err != nil {
+					fmt.Printf("\033[31m[Error]\033[0m %v\n", err)
+					toolResponse = fmt.Sprintf("Error: %v", err)
+				} else {
+					toolResponse = output
+				}
+				fmt.Printf("%s\n%s\n----------------\n", ui.Tool("[Output]"), diff.Colorize(toolResponse))
+
 			case "apply_unified_diff_patch":
 				var args map[string]string
 				if err := json.Unmarshal([]byte(tCall.Function.Arguments), &args); err != nil {
@@ -217,13 +327,13 @@ func ProcessConversation(ctx context.Context, history *History, toolsList []api.
 					toolResponse = "Error: apply_unified_diff_patch requires a non-empty 'patch' argument."
 					break
 				}
-				output, err := tools.ApplyUnifiedDiffPatch(workTree, patch, verifyMode)
+				output, err := tools.ApplyUnifiedDiffPatch(ctx, workTree, patch, verifyMode)
 				if err != nil {
 					errMsg := err.Error()
-					if strings.Contains(errMsg, "patch fragment without header") ||
-						strings.Contains(errMsg, "failed to apply unified diff") ||
-						strings.Contains(errMsg, "No valid patches in input") {
+					if strings.Contains(errMsg, "failed to parse unified diff") {
 						toolResponse = fmt.Sprintf("Error: %v\nHint: unified diff parsing failed. Re-read target files and use patch_file for this edit.", err)
+					} else if strings.Contains(errMsg, "hunks rejected") {
+						toolResponse = fmt.Sprintf("Error: %v\nHint: nothing was written. Re-read the lines named above and resend the patch with corrected context.", err)
 					} else {
 						toolResponse = fmt.Sprintf("Error: %v", err)
 					}
@@ -305,13 +415,20 @@ func ProcessConversation(ctx context.Context, history *History, toolsList []api.
 						}
 					}
 				}
-				output, err := tools.EditorHistory(workTree, limit)
+				var output string
+				var err error
+				if checkpoint, _ := args["checkpoint"].(string); strings.TrimSpace(checkpoint) != "" {
+					compareTo, _ := args["compare_to"].(string)
+					output, err = tools.ShowCheckpointDiff(workTree, strings.TrimSpace(checkpoint), compareTo, diff.Options{})
+				} else {
+					output, err = tools.EditorHistory(workTree, limit)
+				}
 				if err != nil {
 					toolResponse = fmt.Sprintf("Error: %v", err)
 				} else {
 					toolResponse = output
 				}
-				fmt.Printf("%s\n%s\n----------------\n", ui.Tool("[Output]"), toolResponse)
+				fmt.Printf("%s\n%s\n----------------\n", ui.Tool("[Output]"), diff.Colorize(toolResponse))
 
 			case "get_process_cpu_usage_sample":
 				var args map[string]any
@@ -377,6 +494,10 @@ func ProcessConversation(ctx context.Context, history *History, toolsList []api.
 				}
 				fmt.Printf("%s\n%s\n----------------\n", ui.Tool("[Output]"), toolResponse)
 
+			case "remember", "recall", "forget":
+				toolResponse = executeMemoryTool(history.Memory(), history.Redactor(), tCall.Function.Name, tCall.Function.Arguments)
+				fmt.Printf("%s\n%s\n----------------\n", ui.Tool("[Output]"), toolResponse)
+
 			case "get_page_size":
 				toolResponse = fmt.Sprintf("%d", os.Getpagesize())
 				fmt.Printf("%s\n%s\n----------------\n", ui.Tool("[Output]"), toolResponse)
@@ -503,6 +624,12 @@ func ProcessConversation(ctx context.Context, history *History, toolsList []api.
 				toolResponse = fmt.Sprintf("Error: unsupported tool '%s'", tCall.Function.Name)
 			}
 
+			var diagnostics string
+			toolResponse, diagnostics = attachDiagnostics(ctx, tCall.Function.Name, tCall.Function.Arguments, toolResponse)
+			if diagnostics != "" {
+				fmt.Printf("%s\n%s\n----------------\n", ui.Tool("[Diagnostics]"), diagnostics)
+			}
+
 			history.AddToolResponse(tCall.ID, toolResponse)
 		}
 	}
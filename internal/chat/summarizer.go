@@ -11,16 +11,32 @@ import (
 const (
 	summaryChunkChars      = 12000
 	maxSummaryMessageChars = 4000
-	summaryMergeGroupSize  = 6
 )
 
 type SummaryResult struct {
-	Summary string
-	Chunks  int
-	Passes  int
+	Record SummaryRecord
+	Chunks int
+	// Cut is the index of the first message kept verbatim; pass it to
+	// History.ApplyRollingSummary.
+	Cut int
+	// Compacted is the number of messages folded into the summary.
+	Compacted int
+	// Unparsed counts chunks whose model output was not valid JSON and was
+	// kept as notes instead.
+	Unparsed int
 }
 
-func SummarizeHistoryMultiPass(ctx context.Context, history *History, client *api.Client, model string) (SummaryResult, error) {
+// RollingOptions controls how much of the recent conversation stays verbatim.
+type RollingOptions struct {
+	KeepTurns  int
+	KeepTokens int64
+}
+
+// SummarizeRolling summarizes only the messages older than the verbatim tail
+// described by opts. Each chunk is turned into a SummaryRecord by the model,
+// then merged deterministically onto the thread's existing record together
+// with facts taken straight from the tool calls.
+func SummarizeRolling(ctx context.Context, history *History, client *api.Client, model string, opts RollingOptions) (SummaryResult, error) {
 	if history == nil {
 		return SummaryResult{}, fmt.Errorf("history is required")
 	}
@@ -32,26 +48,34 @@ func SummarizeHistoryMultiPass(ctx context.Context, history *History, client *ap
 		return SummaryResult{}, fmt.Errorf("model is required")
 	}
 
-	transcript := buildSummarizableTranscript(history.GetMessages())
+	start, cut, ok := history.CompactionRange(opts.KeepTurns, opts.KeepTokens)
+	if !ok {
+		return SummaryResult{}, fmt.Errorf("nothing older than the last %d turns to compact", opts.KeepTurns)
+	}
+	older := history.GetMessages()[start:cut]
+
+	transcript := buildSummarizableTranscript(older)
 	chunks := chunkByChars(transcript, summaryChunkChars)
 	if len(chunks) == 0 {
 		return SummaryResult{}, fmt.Errorf("no content available to summarize")
 	}
 
-	partialSummaries := make([]string, 0, len(chunks))
+	record := history.SummaryRecord()
+	unparsed := 0
 	for i, chunk := range chunks {
 		msgs := []api.Message{
-			{Role: "system", Content: "You are a conversation compression assistant. Keep essential context, decisions, constraints, and unresolved tasks."},
+			{Role: "system", Content: "You are a conversation compression assistant. You output only JSON and never invent paths or commands."},
 			{
 				Role: "user",
 				Content: fmt.Sprintf(
-					"Chunk %d/%d. Summarize this section.\n"+
+					"Chunk %d/%d of a coding conversation. Extract a structured summary.\n"+
 						"Requirements:\n"+
-						"- Keep user goals, decisions, constraints, file paths, errors, and unfinished work.\n"+
-						"- Keep important numeric values and limits.\n"+
-						"- Remove filler and duplicated content.\n"+
-						"- Output compact bullet points.\n\n%s",
-					i+1, len(chunks), chunk,
+						"- Copy file paths, commands, error messages and numeric limits exactly.\n"+
+						"- Only include facts present in this chunk; leave lists empty otherwise.\n"+
+						"- Put items finished in this chunk that were open earlier into \"resolved\".\n"+
+						"- Output a single JSON object with this shape and nothing else:\n%s\n\n"+
+						"Open items from earlier chunks:\n%s\n\nChunk:\n%s",
+					i+1, len(chunks), summaryRecordSchema, openItems(record), chunk,
 				),
 			},
 		}
@@ -60,72 +84,36 @@ func SummarizeHistoryMultiPass(ctx context.Context, history *History, client *ap
 		if err != nil {
 			return SummaryResult{}, fmt.Errorf("chunk %d summarize failed: %w", i+1, err)
 		}
-		partial := strings.TrimSpace(resp.Content)
-		if partial == "" {
-			partial = fmt.Sprintf("- Chunk %d had no usable summary output.", i+1)
-		}
-		partialSummaries = append(partialSummaries, partial)
-	}
-
-	passes := 1
-	summaries := partialSummaries
-	for len(summaries) > 1 {
-		next := make([]string, 0, (len(summaries)+summaryMergeGroupSize-1)/summaryMergeGroupSize)
-		for i := 0; i < len(summaries); i += summaryMergeGroupSize {
-			end := i + summaryMergeGroupSize
-			if end > len(summaries) {
-				end = len(summaries)
-			}
-			merged, err := summarizeGroup(ctx, client, model, summaries[i:end])
-			if err != nil {
-				return SummaryResult{}, err
-			}
-			next = append(next, merged)
-		}
-		summaries = next
-		passes++
-	}
-
-	// Force a refinement pass so summarization always uses at least two model calls.
-	if len(chunks) == 1 {
-		refined, err := summarizeGroup(ctx, client, model, summaries)
+		chunkRecord, err := parseSummaryRecord(resp.Content)
 		if err != nil {
-			return SummaryResult{}, err
+			// Keep the model's text rather than dropping the chunk entirely.
+			unparsed++
+			chunkRecord = SummaryRecord{Notes: []string{compactWhitespace(resp.Content)}}
+			chunkRecord.normalize()
 		}
-		summaries[0] = refined
-		passes++
+		record = mergeSummaryRecords(record, chunkRecord)
 	}
-
-	finalSummary := strings.TrimSpace(summaries[0])
-	if finalSummary == "" {
+	record = mergeSummaryRecords(record, toolFacts(older))
+	if record.IsEmpty() {
 		return SummaryResult{}, fmt.Errorf("summary pipeline returned empty content")
 	}
 
 	return SummaryResult{
-		Summary: finalSummary,
-		Chunks:  len(chunks),
-		Passes:  passes,
+		Record:    record,
+		Chunks:    len(chunks),
+		Cut:       cut,
+		Compacted: cut - start,
+		Unparsed:  unparsed,
 	}, nil
 }
 
-func summarizeGroup(ctx context.Context, client *api.Client, model string, summaries []string) (string, error) {
-	finalPrompt := "Combine these summaries into one thread memory.\n" +
-		"Output format:\n" +
-		"1) Goals\n2) Decisions/Config\n3) Code Changes\n4) Open Issues\n5) Next Actions\n\n" +
-		strings.Join(summaries, "\n\n---\n\n")
-	finalMsgs := []api.Message{
-		{Role: "system", Content: "You produce compact, high-recall thread memory for future turns."},
-		{Role: "user", Content: finalPrompt},
-	}
-	finalResp, err := client.RunCompletionOnce(ctx, finalMsgs, nil, model)
-	if err != nil {
-		return "", fmt.Errorf("summary merge failed: %w", err)
-	}
-	finalSummary := strings.TrimSpace(finalResp.Content)
-	if finalSummary == "" {
-		return "", fmt.Errorf("summary merge returned empty content")
+// openItems lists the open errors and pending tasks the model may resolve.
+func openItems(rec SummaryRecord) string {
+	items := append(append([]string(nil), rec.OpenErrors...), rec.PendingTasks...)
+	if len(items) == 0 {
+		return "(none)"
 	}
-	return finalSummary, nil
+	return "- " + strings.Join(items, "\n- ")
 }
 
 func buildSummarizableTranscript(messages []api.Message) string {
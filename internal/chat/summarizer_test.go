@@ -41,3 +41,156 @@ func TestChunkByCharsSplitsLargeInput(t *testing.T) {
 		}
 	}
 }
+
+func TestCompactionRangeKeepsRecentTurnsVerbatim(t *testing.T) {
+	h := NewHistory("test-model")
+	for i := 0; i < 5; i++ {
+		h.AddUserMessage("question " + strings.Repeat("x", i))
+		h.AddAssistantMessage(api.Message{Role: "assistant", ToolCalls: []api.ToolCall{{ID: "call", Function: api.FunctionCall{Name: "read_file"}}}})
+		h.AddToolResponse("call", "file contents")
+		h.AddAssistantMessage(api.Message{Role: "assistant", Content: "answer"})
+	}
+
+	start, cut, ok := h.CompactionRange(2, 0)
+	if !ok || start != 1 {
+		t.Fatalf("unexpected range start=%d cut=%d ok=%v", start, cut, ok)
+	}
+	if msgs := h.GetMessages(); msgs[cut].Role != "user" || msgs[cut].Content != "question xxx" {
+		t.Fatalf("expected cut at the second-to-last user turn, got %#v", msgs[cut])
+	}
+
+	compacted := h.ApplyRollingSummary(SummaryRecord{Goals: []string{"first summary"}}, cut)
+	if len(compacted) != 12 {
+		t.Fatalf("expected 12 compacted messages, got %d", len(compacted))
+	}
+	if got := h.RollingSummary(); got != "Goals:\n- first summary" {
+		t.Fatalf("unexpected rolling summary %q", got)
+	}
+	if msgs := h.GetMessages(); len(msgs) != 10 || msgs[2].Content != "question xxx" {
+		t.Fatalf("unexpected history after compaction: %d messages", len(msgs))
+	}
+
+	// A second compaction starts after the existing summary message.
+	h.AddUserMessage("question 6")
+	start, cut, ok = h.CompactionRange(2, 0)
+	if !ok || start != 2 || h.GetMessages()[cut].Content != "question xxxx" {
+		t.Fatalf("unexpected second range start=%d cut=%d ok=%v", start, cut, ok)
+	}
+
+	if _, _, ok := h.CompactionRange(10, 0); ok {
+		t.Fatal("expected nothing to compact when keeping more turns than exist")
+	}
+}
+
+func TestFittingKeepTurnsShrinksOversizedTail(t *testing.T) {
+	h := NewHistory("test-model")
+	h.AddUserMessage("small question")
+	h.AddAssistantMessage(api.Message{Role: "assistant", Content: "small answer"})
+	h.AddUserMessage("big question")
+	h.AddAssistantMessage(api.Message{Role: "assistant", Content: strings.Repeat("word ", 2000)})
+	h.AddUserMessage("last question")
+
+	big := ApproximateTokens(strings.Repeat("word ", 2000))
+	if got := h.FittingKeepTurns(4, big*2); got != 3 {
+		t.Fatalf("expected every turn to fit, got %d", got)
+	}
+	if got := h.FittingKeepTurns(4, big/2); got != 1 {
+		t.Fatalf("expected the tail to shrink to the last turn, got %d", got)
+	}
+	if got := h.FittingKeepTurns(4, 1); got != 1 {
+		t.Fatalf("expected at least one turn, got %d", got)
+	}
+}
+
+func TestApplyRollingSummaryWithoutSystemPrompt(t *testing.T) {
+	h := NewHistory("test-model")
+	h.LoadMessages([]api.Message{
+		{Role: "user", Content: "first"},
+		{Role: "assistant", Content: "one"},
+		{Role: "user", Content: "second"},
+		{Role: "assistant", Content: "two"},
+	}, "test-model")
+
+	_, cut, ok := h.CompactionRange(1, 0)
+	if !ok || cut != 2 {
+		t.Fatalf("unexpected range cut=%d ok=%v", cut, ok)
+	}
+	if compacted := h.ApplyRollingSummary(SummaryRecord{Goals: []string{"g"}}, cut); len(compacted) != 2 {
+		t.Fatalf("expected 2 compacted messages, got %d", len(compacted))
+	}
+	msgs := h.GetMessages()
+	if len(msgs) != 3 || !IsSummaryMessage(msgs[0]) || msgs[1].Content != "second" {
+		t.Fatalf("unexpected history after compaction: %#v", msgs)
+	}
+	if got := h.RollingSummary(); got != "Goals:\n- g" {
+		t.Fatalf("unexpected rolling summary %q", got)
+	}
+
+	// The summary is replaced, not treated as a system prompt and kept.
+	h.AddUserMessage("third")
+	_, cut, _ = h.CompactionRange(1, 0)
+	h.ApplyRollingSummary(SummaryRecord{Goals: []string{"g2"}}, cut)
+	if msgs := h.GetMessages(); len(msgs) != 2 || !IsSummaryMessage(msgs[0]) || msgs[1].Content != "third" {
+		t.Fatalf("unexpected history after second compaction: %#v", msgs)
+	}
+}
+
+func TestMergeSummaryRecordsIsDeterministic(t *testing.T) {
+	base := SummaryRecord{
+		Goals:        []string{"Add retry logic"},
+		Files:        []FileState{{Path: "client.go", State: "added Fetch retry loop"}},
+		OpenErrors:   []string{"go test ./... fails: TestFetch timeout"},
+		PendingTasks: []string{"update README"},
+	}
+	next, err := parseSummaryRecord("Here you go:\n```json\n" + `{
+		"goals": ["add retry logic", ""],
+		"files": [{"path": "client.go", "state": "read"}, {"path": "README.md", "state": "documented retries"}],
+		"commands": ["go test ./..."],
+		"resolved": ["TestFetch timeout", "update README"]
+	}` + "\n```")
+	if err != nil {
+		t.Fatalf("parseSummaryRecord: %v", err)
+	}
+
+	merged := mergeSummaryRecords(base, next)
+	if len(merged.Goals) != 1 {
+		t.Fatalf("expected case-insensitive goal dedupe, got %#v", merged.Goals)
+	}
+	if len(merged.Files) != 2 || merged.Files[0].State != "added Fetch retry loop" {
+		t.Fatalf("expected 'read' not to overwrite an edit state, got %#v", merged.Files)
+	}
+	if len(merged.OpenErrors) != 0 || len(merged.PendingTasks) != 0 || merged.Resolved != nil {
+		t.Fatalf("expected resolved items removed, got %#v", merged)
+	}
+	if again := mergeSummaryRecords(base, next); again.Render() != merged.Render() {
+		t.Fatal("merge is not deterministic")
+	}
+
+	if _, err := parseSummaryRecord("no json here"); err == nil {
+		t.Fatal("expected error for output without JSON")
+	}
+}
+
+func TestToolFactsKeepsExactPathsAndCommands(t *testing.T) {
+	msgs := []api.Message{
+		{Role: "assistant", ToolCalls: []api.ToolCall{
+			{ID: "1", Function: api.FunctionCall{Name: "run_command", Arguments: `{"command":"make test"}`}},
+			{ID: "2", Function: api.FunctionCall{Name: "patch_file", Arguments: `{"path":"internal/x/y.go","patch":"..."}`}},
+			{ID: "3", Function: api.FunctionCall{Name: "run_command", Arguments: `{"command":"make lint"}`}},
+		}},
+		{Role: "tool", ToolCallID: "1", Content: "ok"},
+		{Role: "tool", ToolCallID: "2", Content: "Patched"},
+		{Role: "tool", ToolCallID: "3", Content: "lint output\n\nError: exit status 2"},
+	}
+
+	facts := toolFacts(msgs)
+	if len(facts.Commands) != 1 || facts.Commands[0] != "make test" {
+		t.Fatalf("unexpected commands %#v", facts.Commands)
+	}
+	if len(facts.Files) != 1 || facts.Files[0].Path != "internal/x/y.go" || !strings.Contains(facts.Files[0].State, "patch_file") {
+		t.Fatalf("unexpected files %#v", facts.Files)
+	}
+	if len(facts.OpenErrors) != 1 || !strings.Contains(facts.OpenErrors[0], "make lint") {
+		t.Fatalf("unexpected open errors %#v", facts.OpenErrors)
+	}
+}
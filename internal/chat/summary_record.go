@@ -0,0 +1,358 @@
+package chat
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/bilbilaki/ai2go/internal/api"
+	"github.com/bilbilaki/ai2go/internal/tools"
+)
+
+const (
+	maxSummaryItems     = 25
+	maxSummaryItemChars = 300
+
+	// fileStateRead never overwrites a more informative state for a file.
+	fileStateRead = "read"
+)
+
+// FileState is the last known state of a file touched in the conversation.
+type FileState struct {
+	Path  string `json:"path"`
+	State string `json:"state"`
+}
+
+// SummaryRecord is the structured rolling summary of compacted turns. Records
+// from each chunk are merged deterministically; Resolved only appears in
+// chunk output and removes matching open errors and pending tasks.
+type SummaryRecord struct {
+	Goals        []string    `json:"goals,omitempty"`
+	Decisions    []string    `json:"decisions,omitempty"`
+	Files        []FileState `json:"files,omitempty"`
+	Commands     []string    `json:"commands,omitempty"`
+	OpenErrors   []string    `json:"open_errors,omitempty"`
+	PendingTasks []string    `json:"pending_tasks,omitempty"`
+	Notes        []string    `json:"notes,omitempty"`
+	Resolved     []string    `json:"resolved,omitempty"`
+}
+
+// summaryRecordSchema is shown to the model when summarizing a chunk.
+const summaryRecordSchema = `{
+  "goals": ["user goals still relevant"],
+  "decisions": ["decisions, constraints and config values agreed on"],
+  "files": [{"path": "exact/path.go", "state": "last known state, e.g. 'added retry loop in Fetch; tests pass'"}],
+  "commands": ["exact shell commands that worked"],
+  "open_errors": ["errors not yet fixed, with the exact message"],
+  "pending_tasks": ["unfinished work"],
+  "resolved": ["open errors or pending tasks from earlier that this section finished"]
+}`
+
+// IsEmpty reports whether the record carries no information.
+func (r SummaryRecord) IsEmpty() bool {
+	return len(r.Goals)+len(r.Decisions)+len(r.Files)+len(r.Commands)+
+		len(r.OpenErrors)+len(r.PendingTasks)+len(r.Notes) == 0
+}
+
+// Render formats the record as the body of the memory system message.
+func (r SummaryRecord) Render() string {
+	var b strings.Builder
+	section := func(title string, items []string, format string) {
+		if len(items) == 0 {
+			return
+		}
+		fmt.Fprintf(&b, "%s:\n", title)
+		for _, item := range items {
+			fmt.Fprintf(&b, format+"\n", item)
+		}
+		b.WriteString("\n")
+	}
+
+	section("Goals", r.Goals, "- %s")
+	section("Decisions", r.Decisions, "- %s")
+	if len(r.Files) > 0 {
+		b.WriteString("Files touched (last known state):\n")
+		for _, f := range r.Files {
+			fmt.Fprintf(&b, "- %s: %s\n", f.Path, f.State)
+		}
+		b.WriteString("\n")
+	}
+	section("Commands that worked", r.Commands, "- `%s`")
+	section("Open errors", r.OpenErrors, "- %s")
+	section("Pending tasks", r.PendingTasks, "- %s")
+	section("Notes", r.Notes, "- %s")
+	return strings.TrimSpace(b.String())
+}
+
+// parseSummaryRecord extracts and validates a record from model output, which
+// may wrap the JSON in prose or a code fence.
+func parseSummaryRecord(text string) (SummaryRecord, error) {
+	start := strings.Index(text, "{")
+	end := strings.LastIndex(text, "}")
+	if start == -1 || end <= start {
+		return SummaryRecord{}, fmt.Errorf("no JSON object in summary output")
+	}
+	var rec SummaryRecord
+	if err := json.Unmarshal([]byte(text[start:end+1]), &rec); err != nil {
+		return SummaryRecord{}, fmt.Errorf("invalid summary JSON: %w", err)
+	}
+	rec.normalize()
+	if rec.IsEmpty() && len(rec.Resolved) == 0 {
+		return SummaryRecord{}, fmt.Errorf("summary JSON has no content")
+	}
+	return rec, nil
+}
+
+// mergeSummaryRecords folds next (newer) into base (older). Lists are unioned
+// in order, file states are overwritten by path, and next.Resolved removes
+// matching open errors and pending tasks.
+func mergeSummaryRecords(base, next SummaryRecord) SummaryRecord {
+	out := SummaryRecord{
+		Goals:        appendUnique(base.Goals, next.Goals),
+		Decisions:    appendUnique(base.Decisions, next.Decisions),
+		Commands:     appendUnique(base.Commands, next.Commands),
+		OpenErrors:   appendUnique(base.OpenErrors, next.OpenErrors),
+		PendingTasks: appendUnique(base.PendingTasks, next.PendingTasks),
+		Notes:        appendUnique(base.Notes, next.Notes),
+	}
+
+	out.Files = append([]FileState(nil), base.Files...)
+	for _, f := range next.Files {
+		replaced := false
+		for i := range out.Files {
+			if out.Files[i].Path == f.Path {
+				if f.State != fileStateRead {
+					out.Files[i].State = f.State
+				}
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			out.Files = append(out.Files, f)
+		}
+	}
+
+	if len(next.Resolved) > 0 {
+		out.OpenErrors = removeResolved(out.OpenErrors, next.Resolved)
+		out.PendingTasks = removeResolved(out.PendingTasks, next.Resolved)
+	}
+	out.normalize()
+	out.Resolved = nil
+	return out
+}
+
+func (r *SummaryRecord) normalize() {
+	r.Goals = cleanItems(r.Goals)
+	r.Decisions = cleanItems(r.Decisions)
+	r.Commands = cleanItems(r.Commands)
+	r.OpenErrors = cleanItems(r.OpenErrors)
+	r.PendingTasks = cleanItems(r.PendingTasks)
+	r.Notes = cleanItems(r.Notes)
+	r.Resolved = cleanItems(r.Resolved)
+
+	files := make([]FileState, 0, len(r.Files))
+	seen := map[string]int{}
+	for _, f := range r.Files {
+		f.Path = strings.TrimSpace(f.Path)
+		f.State = clipItem(compactWhitespace(f.State))
+		if f.Path == "" {
+			continue
+		}
+		if f.State == "" {
+			f.State = "touched"
+		}
+		if idx, ok := seen[f.Path]; ok {
+			if f.State != fileStateRead {
+				files[idx].State = f.State
+			}
+			continue
+		}
+		seen[f.Path] = len(files)
+		files = append(files, f)
+	}
+	if len(files) > maxSummaryItems {
+		files = files[len(files)-maxSummaryItems:]
+	}
+	r.Files = files
+}
+
+// cleanItems trims, clips and de-duplicates items, keeping the newest
+// maxSummaryItems.
+func cleanItems(items []string) []string {
+	out := appendUnique(nil, items)
+	if len(out) > maxSummaryItems {
+		out = out[len(out)-maxSummaryItems:]
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return out
+}
+
+func appendUnique(base, extra []string) []string {
+	out := make([]string, 0, len(base)+len(extra))
+	seen := map[string]bool{}
+	for _, list := range [][]string{base, extra} {
+		for _, item := range list {
+			item = clipItem(compactWhitespace(item))
+			key := strings.ToLower(item)
+			if item == "" || seen[key] {
+				continue
+			}
+			seen[key] = true
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+func removeResolved(items, resolved []string) []string {
+	out := items[:0:0]
+	for _, item := range items {
+		lower := strings.ToLower(item)
+		done := false
+		for _, r := range resolved {
+			r = strings.ToLower(r)
+			if lower == r || strings.Contains(lower, r) || strings.Contains(r, lower) {
+				done = true
+				break
+			}
+		}
+		if !done {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+func clipItem(s string) string {
+	runes := []rune(s)
+	if len(runes) <= maxSummaryItemChars {
+		return s
+	}
+	return string(runes[:maxSummaryItemChars]) + "..."
+}
+
+// fileEditTools modify the file named by their "path" argument.
+var fileEditTools = map[string]bool{
+	"patch_file":               true,
+	"edit_file":                true,
+	"remove_lines":             true,
+	"replace_line_range":       true,
+	"batch_line_operations":    true,
+	"delete_lines_by_pattern":  true,
+	"reorder_line_range":       true,
+	"remove_duplicate_lines":   true,
+	"restore_file_backup":      true,
+	"go_rename_symbol":         true,
+	"go_replace_function_body": true,
+	"go_edit_imports":          true,
+	"go_add_struct_field":      true,
+	"go_add_method":            true,
+}
+
+var diffTargetPattern = regexp.MustCompile(`(?m)^\+\+\+ (?:b/)?(\S+)`)
+
+// toolFacts derives file states and commands directly from tool calls and
+// their responses, so exact paths and commands survive regardless of how the
+// model paraphrases the chunk.
+func toolFacts(messages []api.Message) SummaryRecord {
+	responses := map[string]string{}
+	for _, msg := range messages {
+		if msg.Role == "tool" && msg.ToolCallID != "" {
+			responses[msg.ToolCallID] = msg.Content
+		}
+	}
+
+	var rec SummaryRecord
+	failedCommands := map[string]string{}
+	var failedOrder []string
+	for _, msg := range messages {
+		if msg.Role != "assistant" {
+			continue
+		}
+		for _, tc := range msg.ToolCalls {
+			var args map[string]any
+			if err := json.Unmarshal([]byte(tc.Function.Arguments), &args); err != nil {
+				continue
+			}
+			resp, answered := responses[tc.ID]
+			failed := !answered || toolResponseFailed(resp)
+			path, _ := args["path"].(string)
+
+			switch tc.Function.Name {
+			case "run_command":
+				cmd, _ := args["command"].(string)
+				cmd = strings.TrimSpace(cmd)
+				if cmd == "" || !answered {
+					continue
+				}
+				if failed {
+					if _, ok := failedCommands[cmd]; !ok {
+						failedOrder = append(failedOrder, cmd)
+					}
+					failedCommands[cmd] = firstErrorLine(resp)
+				} else {
+					rec.Commands = append(rec.Commands, cmd)
+					delete(failedCommands, cmd)
+				}
+			case "read_file":
+				if path != "" && !failed {
+					rec.Files = append(rec.Files, FileState{Path: path, State: fileStateRead})
+				}
+			case "apply_unified_diff_patch":
+				patch, _ := args["patch"].(string)
+				for _, m := range diffTargetPattern.FindAllStringSubmatch(patch, -1) {
+					if m[1] == "/dev/null" {
+						continue
+					}
+					rec.Files = append(rec.Files, FileState{Path: m[1], State: editState(tc.Function.Name, failed, resp)})
+				}
+			case "edit_transaction":
+				txArgs, err := tools.ParseEditTransactionArgs(tc.Function.Arguments)
+				if err != nil {
+					continue
+				}
+				for _, p := range txArgs.TargetPaths() {
+					rec.Files = append(rec.Files, FileState{Path: p, State: editState(tc.Function.Name, failed, resp)})
+				}
+			default:
+				if path != "" && fileEditTools[tc.Function.Name] {
+					rec.Files = append(rec.Files, FileState{Path: path, State: editState(tc.Function.Name, failed, resp)})
+				}
+			}
+		}
+	}
+	for _, cmd := range failedOrder {
+		if errLine, ok := failedCommands[cmd]; ok {
+			rec.OpenErrors = append(rec.OpenErrors, fmt.Sprintf("`%s` failed: %s", cmd, errLine))
+		}
+	}
+	rec.normalize()
+	return rec
+}
+
+func editState(tool string, failed bool, resp string) string {
+	if failed {
+		return fmt.Sprintf("%s failed: %s", tool, firstErrorLine(resp))
+	}
+	return fmt.Sprintf("modified via %s", tool)
+}
+
+func toolResponseFailed(resp string) bool {
+	trimmed := strings.TrimSpace(resp)
+	return strings.HasPrefix(trimmed, "Error") ||
+		strings.HasPrefix(trimmed, "User denied") ||
+		strings.Contains(trimmed, "\n\nError: ")
+}
+
+func firstErrorLine(resp string) string {
+	for _, line := range strings.Split(resp, "\n") {
+		if idx := strings.Index(line, "Error"); idx != -1 {
+			return clipItem(strings.TrimSpace(line[idx:]))
+		}
+	}
+	return clipItem(compactWhitespace(resp))
+}
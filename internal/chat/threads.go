@@ -12,20 +12,74 @@ import (
 	"unicode"
 
 	"github.com/bilbilaki/ai2go/internal/api"
+	"github.com/bilbilaki/ai2go/internal/project"
+	"github.com/bilbilaki/ai2go/internal/prompt"
+	"github.com/bilbilaki/ai2go/internal/redact"
+	"github.com/bilbilaki/ai2go/internal/secure"
 )
 
 const (
-	appDataDir  = ".config/ai2go"
-	threadsFile = "threads.json"
+	appDataDir     = ".config/ai2go"
+	threadsFile    = "threads.json"
+	redactionsFile = "redactions.json"
+
+	// TrashRetention is how long deleted threads stay restorable before they are purged.
+	TrashRetention = 30 * 24 * time.Hour
 )
 
 type Thread struct {
 	ID        string        `json:"id"`
 	Title     string        `json:"title"`
 	AutoTitle bool          `json:"auto_title"`
+	Tags      []string      `json:"tags,omitempty"`
+	Pinned    bool          `json:"pinned,omitempty"`
+	Archived  bool          `json:"archived,omitempty"`
+	DeletedAt *time.Time    `json:"deleted_at,omitempty"`
+	WorkDir   string        `json:"work_dir,omitempty"`
+	GitRoot   string        `json:"git_root,omitempty"`
+	Persona   string        `json:"persona,omitempty"`
 	CreatedAt time.Time     `json:"created_at"`
 	UpdatedAt time.Time     `json:"updated_at"`
 	Messages  []api.Message `json:"messages"`
+	// Compacted keeps the original messages folded into the rolling summary,
+	// oldest first, so they can still be shown after compaction.
+	Compacted []api.Message `json:"compacted_messages,omitempty"`
+	// SummaryRecord is the structured form of the rolling summary message.
+	SummaryRecord *SummaryRecord `json:"summary_record,omitempty"`
+}
+
+// ThreadFilter narrows ListThreads results. The zero value lists every live,
+// unarchived thread across all projects.
+type ThreadFilter struct {
+	Query    string
+	Project  string
+	Tags     []string
+	Pinned   bool
+	Archived bool
+	Trash    bool
+}
+
+// ProjectKey returns the git root the thread was started in, or its working
+// directory outside a repository. Threads created before project scoping
+// return "".
+func (t Thread) ProjectKey() string {
+	return project.Info{WorkDir: t.WorkDir, GitRoot: t.GitRoot}.Key()
+}
+
+// InTrash reports whether the thread was deleted and is waiting to be purged.
+func (t Thread) InTrash() bool {
+	return t.DeletedAt != nil
+}
+
+// HasTag reports whether the thread carries tag (case-insensitive).
+func (t Thread) HasTag(tag string) bool {
+	tag = normalizeTag(tag)
+	for _, existing := range t.Tags {
+		if existing == tag {
+			return true
+		}
+	}
+	return false
 }
 
 type SearchResult struct {
@@ -38,24 +92,45 @@ type SearchResult struct {
 }
 
 type threadStoreData struct {
-	ActiveThreadID string   `json:"active_thread_id"`
-	Threads        []Thread `json:"threads"`
+	ActiveThreadID string `json:"active_thread_id"`
+	// ProjectActive maps a project key to the thread last active in it.
+	ProjectActive map[string]string `json:"project_active,omitempty"`
+	Threads       []Thread          `json:"threads"`
 }
 
 type ThreadStore struct {
-	path string
-	data threadStoreData
+	path     string
+	project  project.Info
+	vault    *secure.Vault
+	memory   *MemoryStore
+	redactor *redact.Redactor
+	data     threadStoreData
+
+	// listed holds the thread ids of the last ListThreads result in order,
+	// so "/thread open 2" means row 2 of what /threads just printed.
+	listed []string
 }
 
-func NewThreadStore(currentModel string) (*ThreadStore, *History, error) {
+// NewThreadStore loads the thread store. When vault is non-nil the store is
+// written encrypted; an encrypted store cannot be opened without one.
+func NewThreadStore(currentModel string, vault *secure.Vault, toolsList []api.Tool) (*ThreadStore, *History, error) {
 	path, err := getThreadsPath()
 	if err != nil {
 		return nil, nil, err
 	}
 
-	store := &ThreadStore{path: path}
+	store := &ThreadStore{path: path, project: project.Detect(), vault: vault}
 
 	if content, readErr := os.ReadFile(path); readErr == nil {
+		if secure.IsSealed(content) {
+			if vault == nil {
+				return nil, nil, fmt.Errorf("thread store is encrypted; enable encryption in config and unlock it")
+			}
+			content, err = vault.Open(content)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to decrypt thread store: %w", err)
+			}
+		}
 		if unmarshalErr := json.Unmarshal(content, &store.data); unmarshalErr != nil {
 			return nil, nil, fmt.Errorf("failed to parse thread store: %w", unmarshalErr)
 		}
@@ -63,37 +138,62 @@ func NewThreadStore(currentModel string) (*ThreadStore, *History, error) {
 		return nil, nil, fmt.Errorf("failed to read thread store: %w", readErr)
 	}
 
-	if len(store.data.Threads) == 0 {
-		h := NewHistory(currentModel)
-		thread := Thread{
-			ID:        newThreadID(),
-			Title:     fmt.Sprintf("New Thread %s", time.Now().Format("2006-01-02 15:04")),
-			AutoTitle: true,
-			CreatedAt: time.Now().UTC(),
-			UpdatedAt: time.Now().UTC(),
-			Messages:  cloneMessages(h.GetMessages()),
-		}
-		store.data.Threads = []Thread{thread}
-		store.data.ActiveThreadID = thread.ID
-		if err := store.save(); err != nil {
-			return nil, nil, err
-		}
+	store.memory, err = loadMemoryStore(filepath.Join(filepath.Dir(path), memoriesFile), store.project, vault)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	if store.findThreadIndex(store.data.ActiveThreadID) == -1 {
-		store.data.ActiveThreadID = store.data.Threads[0].ID
-		if err := store.save(); err != nil {
-			return nil, nil, err
-		}
-	}
+	store.purgeExpiredTrash(time.Now().UTC())
 
 	history := NewHistory(currentModel)
-	active := store.GetActiveThread()
-	history.LoadMessages(active.Messages, currentModel)
+	history.SetTools(toolsList)
+	history.SetMemory(store.memory)
+	history.SetInstructionDirs(store.project.WorkDir, filepath.Dir(path))
+
+	// Reopen the thread last used in this project rather than the globally
+	// active one, so sessions in different repositories don't mix.
+	if idx := store.startupThreadIndex(); idx != -1 {
+		store.setActive(store.data.Threads[idx].ID)
+	} else {
+		history.Clear(currentModel)
+		thread := store.newThreadRecord(fmt.Sprintf("New Thread %s", time.Now().Format("2006-01-02 15:04")), true, history)
+		store.data.Threads = append(store.data.Threads, thread)
+		store.setActive(thread.ID)
+	}
+	if err := store.save(); err != nil {
+		return nil, nil, err
+	}
+
+	store.loadIntoHistory(store.findThreadIndex(store.data.ActiveThreadID), history, currentModel)
 
 	return store, history, nil
 }
 
+// Memory returns the long-term memory store kept alongside the threads.
+func (s *ThreadStore) Memory() *MemoryStore {
+	return s.memory
+}
+
+// Redactor returns the secret redactor whose placeholders persist beside the
+// threads, loading it on first use, with customPatterns applied. Threads
+// only hold placeholders, so this mapping is what restores them later.
+func (s *ThreadStore) Redactor(customPatterns []string) (*redact.Redactor, error) {
+	if s.redactor == nil {
+		r, err := redact.Load(filepath.Join(filepath.Dir(s.path), redactionsFile), s.vault, customPatterns)
+		if err != nil {
+			return nil, err
+		}
+		s.redactor = r
+		return r, nil
+	}
+	return s.redactor, s.redactor.SetCustomPatterns(customPatterns)
+}
+
+// Project returns the project the store was opened in.
+func (s *ThreadStore) Project() project.Info {
+	return s.project
+}
+
 func (s *ThreadStore) ActiveThreadID() string {
 	return s.data.ActiveThreadID
 }
@@ -120,6 +220,7 @@ func (s *ThreadStore) SyncActiveHistory(history *History) error {
 	}
 
 	thread.Messages = cloneMessages(history.GetMessages())
+	thread.SummaryRecord = history.summary
 	thread.UpdatedAt = time.Now().UTC()
 	if thread.AutoTitle {
 		if title := generateTitleFromMessages(thread.Messages); title != "" {
@@ -138,18 +239,10 @@ func (s *ThreadStore) NewThread(currentModel, explicitTitle string, history *His
 	}
 
 	history.Clear(currentModel)
-	now := time.Now().UTC()
-	thread := Thread{
-		ID:        newThreadID(),
-		Title:     title,
-		AutoTitle: auto,
-		CreatedAt: now,
-		UpdatedAt: now,
-		Messages:  cloneMessages(history.GetMessages()),
-	}
+	thread := s.newThreadRecord(title, auto, history)
 
 	s.data.Threads = append(s.data.Threads, thread)
-	s.data.ActiveThreadID = thread.ID
+	s.setActive(thread.ID)
 	if err := s.save(); err != nil {
 		return nil, err
 	}
@@ -163,8 +256,12 @@ func (s *ThreadStore) OpenThread(identifier string, history *History, currentMod
 		return nil, err
 	}
 
-	s.data.ActiveThreadID = s.data.Threads[idx].ID
-	history.LoadMessages(s.data.Threads[idx].Messages, currentModel)
+	if s.data.Threads[idx].InTrash() {
+		return nil, fmt.Errorf("thread is in trash; restore it first")
+	}
+
+	s.setActive(s.data.Threads[idx].ID)
+	s.loadIntoHistory(idx, history, currentModel)
 	if err := s.save(); err != nil {
 		return nil, err
 	}
@@ -192,10 +289,188 @@ func (s *ThreadStore) RenameThread(identifier, newTitle string) (*Thread, error)
 	return &s.data.Threads[idx], nil
 }
 
-func (s *ThreadStore) ListThreads(query, sortBy, order string) []Thread {
-	q := strings.ToLower(strings.TrimSpace(query))
+// KeepCompactedMessages appends messages removed by rolling compaction to the
+// active thread so they can still be shown later.
+func (s *ThreadStore) KeepCompactedMessages(msgs []api.Message) error {
+	if len(msgs) == 0 {
+		return nil
+	}
+	thread := s.GetActiveThread()
+	if thread == nil {
+		return fmt.Errorf("no active thread")
+	}
+	thread.Compacted = append(thread.Compacted, cloneMessages(msgs)...)
+	return s.save()
+}
+
+// SetActivePersona records persona on the active thread and rebuilds the
+// history's system message with it.
+func (s *ThreadStore) SetActivePersona(persona string, history *History, currentModel string) error {
+	thread := s.GetActiveThread()
+	if thread == nil {
+		return fmt.Errorf("no active thread")
+	}
+	if persona == prompt.DefaultPersona {
+		persona = ""
+	}
+	history.SetPersona(persona)
+	history.RefreshSystemMessage(currentModel)
+	thread.Persona = persona
+	thread.Messages = cloneMessages(history.GetMessages())
+	thread.UpdatedAt = time.Now().UTC()
+	return s.save()
+}
+
+// TagThread adds tags to a thread, ignoring ones it already has.
+func (s *ThreadStore) TagThread(identifier string, tags []string) (*Thread, error) {
+	idx, err := s.resolveThreadIdentifier(identifier)
+	if err != nil {
+		return nil, err
+	}
+
+	added := 0
+	for _, tag := range tags {
+		tag = normalizeTag(tag)
+		if tag == "" || s.data.Threads[idx].HasTag(tag) {
+			continue
+		}
+		s.data.Threads[idx].Tags = append(s.data.Threads[idx].Tags, tag)
+		added++
+	}
+	if added == 0 {
+		return &s.data.Threads[idx], nil
+	}
+	sort.Strings(s.data.Threads[idx].Tags)
+	if err := s.save(); err != nil {
+		return nil, err
+	}
+	return &s.data.Threads[idx], nil
+}
+
+// UntagThread removes tags from a thread.
+func (s *ThreadStore) UntagThread(identifier string, tags []string) (*Thread, error) {
+	idx, err := s.resolveThreadIdentifier(identifier)
+	if err != nil {
+		return nil, err
+	}
+
+	remove := make(map[string]struct{}, len(tags))
+	for _, tag := range tags {
+		remove[normalizeTag(tag)] = struct{}{}
+	}
+	kept := s.data.Threads[idx].Tags[:0]
+	for _, tag := range s.data.Threads[idx].Tags {
+		if _, ok := remove[tag]; !ok {
+			kept = append(kept, tag)
+		}
+	}
+	s.data.Threads[idx].Tags = kept
+	if len(kept) == 0 {
+		s.data.Threads[idx].Tags = nil
+	}
+	if err := s.save(); err != nil {
+		return nil, err
+	}
+	return &s.data.Threads[idx], nil
+}
+
+func (s *ThreadStore) SetPinned(identifier string, pinned bool) (*Thread, error) {
+	idx, err := s.resolveThreadIdentifier(identifier)
+	if err != nil {
+		return nil, err
+	}
+	s.data.Threads[idx].Pinned = pinned
+	if err := s.save(); err != nil {
+		return nil, err
+	}
+	return &s.data.Threads[idx], nil
+}
+
+func (s *ThreadStore) SetArchived(identifier string, archived bool) (*Thread, error) {
+	idx, err := s.resolveThreadIdentifier(identifier)
+	if err != nil {
+		return nil, err
+	}
+	if s.data.Threads[idx].InTrash() {
+		return nil, fmt.Errorf("thread is in trash; restore it first")
+	}
+	s.data.Threads[idx].Archived = archived
+	if err := s.save(); err != nil {
+		return nil, err
+	}
+	return &s.data.Threads[idx], nil
+}
+
+// DeleteThread moves a thread to the trash. Trashed threads are purged once
+// TrashRetention has passed. Deleting the active thread switches history to
+// the most recently updated remaining thread, or a fresh one if none is left.
+func (s *ThreadStore) DeleteThread(identifier string, history *History, currentModel string) (*Thread, error) {
+	idx, err := s.resolveThreadIdentifier(identifier)
+	if err != nil {
+		return nil, err
+	}
+	if s.data.Threads[idx].InTrash() {
+		return nil, fmt.Errorf("thread is already in trash")
+	}
+
+	now := time.Now().UTC()
+	s.data.Threads[idx].DeletedAt = &now
+	deleted := s.data.Threads[idx]
+
+	if deleted.ID == s.data.ActiveThreadID {
+		if s.liveThreadCount() == 0 {
+			if _, err := s.NewThread(currentModel, "", history); err != nil {
+				return nil, err
+			}
+			return &deleted, nil
+		}
+		next := s.fallbackThreadIndex()
+		s.setActive(s.data.Threads[next].ID)
+		s.loadIntoHistory(next, history, currentModel)
+	}
+
+	if err := s.save(); err != nil {
+		return nil, err
+	}
+	return &deleted, nil
+}
+
+// RestoreThread takes a thread out of the trash.
+func (s *ThreadStore) RestoreThread(identifier string) (*Thread, error) {
+	idx, err := s.resolveThreadIdentifier(identifier)
+	if err != nil {
+		return nil, err
+	}
+	if !s.data.Threads[idx].InTrash() {
+		return nil, fmt.Errorf("thread is not in trash")
+	}
+	s.data.Threads[idx].DeletedAt = nil
+	if err := s.save(); err != nil {
+		return nil, err
+	}
+	return &s.data.Threads[idx], nil
+}
+
+// ResolveThread returns a copy of the thread matching identifier.
+func (s *ThreadStore) ResolveThread(identifier string) (*Thread, error) {
+	idx, err := s.resolveThreadIdentifier(identifier)
+	if err != nil {
+		return nil, err
+	}
+	thread := s.data.Threads[idx]
+	return &thread, nil
+}
+
+// ListThreads returns the threads matching filter, pinned ones first, sorted
+// by sortBy. The order is remembered: numeric thread identifiers refer to
+// rows of the most recent listing.
+func (s *ThreadStore) ListThreads(filter ThreadFilter, sortBy, order string) []Thread {
+	q := strings.ToLower(strings.TrimSpace(filter.Query))
 	items := make([]Thread, 0, len(s.data.Threads))
 	for _, thread := range s.data.Threads {
+		if !filter.matches(thread) {
+			continue
+		}
 		if q == "" || strings.Contains(strings.ToLower(thread.Title), q) || strings.Contains(strings.ToLower(thread.ID), q) {
 			items = append(items, thread)
 		}
@@ -209,6 +484,9 @@ func (s *ThreadStore) ListThreads(query, sortBy, order string) []Thread {
 	desc := order == "" || order == "desc"
 
 	sort.SliceStable(items, func(i, j int) bool {
+		if items[i].Pinned != items[j].Pinned {
+			return items[i].Pinned
+		}
 		var cmp int
 		switch sortBy {
 		case "created":
@@ -233,9 +511,34 @@ func (s *ThreadStore) ListThreads(query, sortBy, order string) []Thread {
 		return cmp < 0
 	})
 
+	s.listed = make([]string, 0, len(items))
+	for _, thread := range items {
+		s.listed = append(s.listed, thread.ID)
+	}
 	return items
 }
 
+func (f ThreadFilter) matches(thread Thread) bool {
+	if thread.InTrash() != f.Trash {
+		return false
+	}
+	if f.Project != "" && thread.ProjectKey() != f.Project {
+		return false
+	}
+	if !f.Trash && thread.Archived != f.Archived {
+		return false
+	}
+	if f.Pinned && !thread.Pinned {
+		return false
+	}
+	for _, tag := range f.Tags {
+		if !thread.HasTag(tag) {
+			return false
+		}
+	}
+	return true
+}
+
 func (s *ThreadStore) Search(query, sortBy, order string) []SearchResult {
 	q := strings.ToLower(strings.TrimSpace(query))
 	if q == "" {
@@ -244,6 +547,9 @@ func (s *ThreadStore) Search(query, sortBy, order string) []SearchResult {
 
 	results := make([]SearchResult, 0)
 	for _, thread := range s.data.Threads {
+		if thread.InTrash() {
+			continue
+		}
 		if strings.Contains(strings.ToLower(thread.Title), q) {
 			results = append(results, SearchResult{
 				ThreadID:    thread.ID,
@@ -330,11 +636,19 @@ func (s *ThreadStore) resolveThreadIdentifier(identifier string) (int, error) {
 		return idx, nil
 	}
 
+	// Numbers are rows of the last /threads listing, or of the default
+	// listing (live threads of this project) when nothing was listed yet.
 	if n, err := strconv.Atoi(id); err == nil {
-		if n < 1 || n > len(s.data.Threads) {
-			return -1, fmt.Errorf("thread index out of range")
+		if s.listed == nil {
+			s.ListThreads(ThreadFilter{Project: s.project.Key()}, "", "")
 		}
-		return n - 1, nil
+		if n < 1 || n > len(s.listed) {
+			return -1, fmt.Errorf("thread index out of range (run /threads to see the numbered list)")
+		}
+		if idx := s.findThreadIndex(s.listed[n-1]); idx != -1 {
+			return idx, nil
+		}
+		return -1, fmt.Errorf("thread %d no longer exists; run /threads again", n)
 	}
 
 	exact := -1
@@ -361,14 +675,157 @@ func (s *ThreadStore) resolveThreadIdentifier(identifier string) (int, error) {
 	return -1, fmt.Errorf("thread not found")
 }
 
+func (s *ThreadStore) liveThreadCount() int {
+	n := 0
+	for _, thread := range s.data.Threads {
+		if !thread.InTrash() {
+			n++
+		}
+	}
+	return n
+}
+
+// fallbackThreadIndex picks the thread to activate when the current one goes
+// away, preferring threads from the current project, then unarchived ones,
+// then the most recently updated. Callers must ensure a live thread exists.
+func (s *ThreadStore) fallbackThreadIndex() int {
+	key := s.project.Key()
+	best := -1
+	for i, thread := range s.data.Threads {
+		if thread.InTrash() {
+			continue
+		}
+		if best == -1 {
+			best = i
+			continue
+		}
+		current := s.data.Threads[best]
+		if (current.ProjectKey() == key) != (thread.ProjectKey() == key) {
+			if thread.ProjectKey() == key {
+				best = i
+			}
+			continue
+		}
+		if current.Archived != thread.Archived {
+			if current.Archived {
+				best = i
+			}
+			continue
+		}
+		if thread.UpdatedAt.After(current.UpdatedAt) {
+			best = i
+		}
+	}
+	return best
+}
+
+// startupThreadIndex returns the thread to reopen for the current project:
+// the one last active here, else its most recently updated unarchived thread.
+// It returns -1 when the project has no threads yet.
+func (s *ThreadStore) startupThreadIndex() int {
+	key := s.project.Key()
+	if id, ok := s.data.ProjectActive[key]; ok {
+		if idx := s.findThreadIndex(id); idx != -1 && !s.data.Threads[idx].InTrash() {
+			return idx
+		}
+	}
+	best := -1
+	for i, thread := range s.data.Threads {
+		if thread.InTrash() || thread.Archived || thread.ProjectKey() != key {
+			continue
+		}
+		if best == -1 || thread.UpdatedAt.After(s.data.Threads[best].UpdatedAt) {
+			best = i
+		}
+	}
+	return best
+}
+
+func (s *ThreadStore) setActive(id string) {
+	s.data.ActiveThreadID = id
+	if key := s.project.Key(); key != "" {
+		if s.data.ProjectActive == nil {
+			s.data.ProjectActive = map[string]string{}
+		}
+		s.data.ProjectActive[key] = id
+	}
+}
+
+// loadIntoHistory replaces history with the thread at idx, including its
+// per-thread persona and structured summary.
+func (s *ThreadStore) loadIntoHistory(idx int, history *History, currentModel string) {
+	thread := s.data.Threads[idx]
+	history.SetPersona(thread.Persona)
+	history.SetSummaryRecord(thread.SummaryRecord)
+	history.LoadMessages(thread.Messages, currentModel)
+}
+
+func (s *ThreadStore) newThreadRecord(title string, auto bool, history *History) Thread {
+	now := time.Now().UTC()
+	return Thread{
+		ID:        newThreadID(),
+		Title:     title,
+		AutoTitle: auto,
+		WorkDir:   s.project.WorkDir,
+		GitRoot:   s.project.GitRoot,
+		Persona:   history.Persona(),
+		CreatedAt: now,
+		UpdatedAt: now,
+		Messages:  cloneMessages(history.GetMessages()),
+	}
+}
+
+func (s *ThreadStore) purgeExpiredTrash(now time.Time) int {
+	kept := make([]Thread, 0, len(s.data.Threads))
+	purged := 0
+	for _, thread := range s.data.Threads {
+		if thread.InTrash() && now.Sub(*thread.DeletedAt) > TrashRetention {
+			purged++
+			continue
+		}
+		kept = append(kept, thread)
+	}
+	s.data.Threads = kept
+	for key, id := range s.data.ProjectActive {
+		if s.findThreadIndex(id) == -1 {
+			delete(s.data.ProjectActive, key)
+		}
+	}
+	return purged
+}
+
+// SetVault switches encryption at rest on (non-nil) or off (nil) and rewrites
+// the store accordingly.
+func (s *ThreadStore) SetVault(vault *secure.Vault) error {
+	s.vault = vault
+	if s.memory != nil {
+		if err := s.memory.setVault(vault); err != nil {
+			return err
+		}
+	}
+	if err := s.redactor.SetVault(vault); err != nil {
+		return err
+	}
+	return s.save()
+}
+
 func (s *ThreadStore) save() error {
 	content, err := json.MarshalIndent(s.data, "", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to marshal thread store: %w", err)
 	}
-	if err := os.WriteFile(s.path, content, 0644); err != nil {
+	if s.vault != nil {
+		content, err = s.vault.Seal(content)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt thread store: %w", err)
+		}
+	}
+	if err := os.WriteFile(s.path, content, 0600); err != nil {
 		return fmt.Errorf("failed to write thread store: %w", err)
 	}
+	if err := os.Chmod(s.path, 0600); err != nil {
+		return fmt.Errorf("failed to restrict thread store permissions: %w", err)
+	}
 	return nil
 }
 
@@ -395,6 +852,10 @@ func cloneMessages(src []api.Message) []api.Message {
 	return out
 }
 
+func normalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(tag), "#"))
+}
+
 func newThreadID() string {
 	return fmt.Sprintf("th_%d", time.Now().UnixNano())
 }
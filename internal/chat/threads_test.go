@@ -0,0 +1,182 @@
+package chat
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bilbilaki/ai2go/internal/project"
+)
+
+func newTestThreadStore(t *testing.T, threads ...Thread) *ThreadStore {
+	t.Helper()
+	store := &ThreadStore{path: filepath.Join(t.TempDir(), threadsFile)}
+	store.data.Threads = threads
+	if len(threads) > 0 {
+		store.data.ActiveThreadID = threads[0].ID
+	}
+	return store
+}
+
+func TestListThreadsFiltersAndPinsFirst(t *testing.T) {
+	now := time.Now().UTC()
+	store := newTestThreadStore(t,
+		Thread{ID: "th_1", Title: "old pinned", Pinned: true, UpdatedAt: now.Add(-time.Hour)},
+		Thread{ID: "th_2", Title: "newest", Tags: []string{"infra"}, UpdatedAt: now},
+		Thread{ID: "th_3", Title: "archived", Archived: true, UpdatedAt: now},
+	)
+
+	got := store.ListThreads(ThreadFilter{}, "", "")
+	if len(got) != 2 || got[0].ID != "th_1" || got[1].ID != "th_2" {
+		t.Fatalf("expected pinned thread first and archived hidden, got %#v", got)
+	}
+
+	tagged := store.ListThreads(ThreadFilter{Tags: []string{"#Infra"}}, "", "")
+	if len(tagged) != 1 || tagged[0].ID != "th_2" {
+		t.Fatalf("unexpected tag filter result: %#v", tagged)
+	}
+
+	archived := store.ListThreads(ThreadFilter{Archived: true}, "", "")
+	if len(archived) != 1 || archived[0].ID != "th_3" {
+		t.Fatalf("unexpected archived filter result: %#v", archived)
+	}
+}
+
+func TestNumericIdentifiersFollowTheListing(t *testing.T) {
+	now := time.Now().UTC()
+	store := newTestThreadStore(t,
+		Thread{ID: "th_old", Title: "old", UpdatedAt: now.Add(-time.Hour)},
+		Thread{ID: "th_hidden", Title: "archived", Archived: true, UpdatedAt: now},
+		Thread{ID: "th_new", Title: "new", UpdatedAt: now.Add(-time.Minute)},
+	)
+
+	// Without a listing, numbers follow the default /threads view.
+	if got, err := store.ResolveThread("1"); err != nil || got.ID != "th_new" {
+		t.Fatalf("expected row 1 to be th_new, got %v %v", got, err)
+	}
+	if _, err := store.ResolveThread("3"); err == nil {
+		t.Fatal("archived thread must not be reachable by number from the default listing")
+	}
+
+	store.ListThreads(ThreadFilter{Archived: true}, "", "")
+	if got, err := store.ResolveThread("1"); err != nil || got.ID != "th_hidden" {
+		t.Fatalf("expected row 1 of the archived listing, got %v %v", got, err)
+	}
+	store.ListThreads(ThreadFilter{}, "title", "asc")
+	if got, err := store.ResolveThread("2"); err != nil || got.ID != "th_old" {
+		t.Fatalf("expected row 2 of the title listing, got %v %v", got, err)
+	}
+}
+
+func TestDeleteThreadMovesToTrashAndSwitchesActive(t *testing.T) {
+	now := time.Now().UTC()
+	store := newTestThreadStore(t,
+		Thread{ID: "th_1", Title: "active", UpdatedAt: now},
+		Thread{ID: "th_2", Title: "other", UpdatedAt: now.Add(-time.Minute)},
+	)
+	history := NewHistory("test-model")
+
+	if _, err := store.DeleteThread("current", history, "test-model"); err != nil {
+		t.Fatalf("DeleteThread: %v", err)
+	}
+	if store.ActiveThreadID() != "th_2" {
+		t.Fatalf("expected active thread to move to th_2, got %s", store.ActiveThreadID())
+	}
+	if trash := store.ListThreads(ThreadFilter{Trash: true}, "", ""); len(trash) != 1 || trash[0].ID != "th_1" {
+		t.Fatalf("expected th_1 in trash, got %#v", trash)
+	}
+	if _, err := store.OpenThread("th_1", history, "test-model"); err == nil {
+		t.Fatal("expected opening a trashed thread to fail")
+	}
+
+	if _, err := store.RestoreThread("th_1"); err != nil {
+		t.Fatalf("RestoreThread: %v", err)
+	}
+	if live := store.ListThreads(ThreadFilter{}, "", ""); len(live) != 2 {
+		t.Fatalf("expected both threads live after restore, got %#v", live)
+	}
+}
+
+func TestPurgeExpiredTrash(t *testing.T) {
+	now := time.Now().UTC()
+	expired := now.Add(-TrashRetention - time.Hour)
+	recent := now.Add(-time.Hour)
+	store := newTestThreadStore(t,
+		Thread{ID: "th_1", Title: "live"},
+		Thread{ID: "th_2", Title: "expired", DeletedAt: &expired},
+		Thread{ID: "th_3", Title: "recent", DeletedAt: &recent},
+	)
+
+	if n := store.purgeExpiredTrash(now); n != 1 {
+		t.Fatalf("expected 1 purged thread, got %d", n)
+	}
+	if store.findThreadIndex("th_2") != -1 {
+		t.Fatal("expected expired thread to be purged")
+	}
+	if store.findThreadIndex("th_3") == -1 {
+		t.Fatal("expected recently deleted thread to be kept")
+	}
+}
+
+func TestStartupThreadIndexPrefersProjectThread(t *testing.T) {
+	now := time.Now().UTC()
+	store := newTestThreadStore(t,
+		Thread{ID: "th_1", Title: "other repo", GitRoot: "/src/other", UpdatedAt: now},
+		Thread{ID: "th_2", Title: "this repo old", GitRoot: "/src/app", UpdatedAt: now.Add(-2 * time.Hour)},
+		Thread{ID: "th_3", Title: "this repo recent", GitRoot: "/src/app", WorkDir: "/src/app/cmd", UpdatedAt: now.Add(-time.Hour)},
+	)
+	store.project = project.Info{WorkDir: "/src/app", GitRoot: "/src/app"}
+
+	idx := store.startupThreadIndex()
+	if idx == -1 || store.data.Threads[idx].ID != "th_3" {
+		t.Fatalf("expected most recent project thread th_3, got index %d", idx)
+	}
+
+	store.setActive("th_2")
+	idx = store.startupThreadIndex()
+	if idx == -1 || store.data.Threads[idx].ID != "th_2" {
+		t.Fatalf("expected last active project thread th_2, got index %d", idx)
+	}
+
+	scoped := store.ListThreads(ThreadFilter{Project: store.Project().Key()}, "", "")
+	if len(scoped) != 2 {
+		t.Fatalf("expected 2 threads scoped to project, got %#v", scoped)
+	}
+
+	store.project = project.Info{WorkDir: "/src/new"}
+	if idx := store.startupThreadIndex(); idx != -1 {
+		t.Fatalf("expected no thread for a new project, got index %d", idx)
+	}
+}
+
+func TestSetActivePersonaPersistsPerThread(t *testing.T) {
+	store := newTestThreadStore(t,
+		Thread{ID: "th_1", Title: "review"},
+		Thread{ID: "th_2", Title: "plain"},
+	)
+	history := NewHistory("test-model")
+
+	if err := store.SetActivePersona("reviewer", history, "test-model"); err != nil {
+		t.Fatalf("SetActivePersona: %v", err)
+	}
+	if got := store.GetActiveThread().Persona; got != "reviewer" {
+		t.Fatalf("expected persona on thread, got %q", got)
+	}
+	if sys := history.GetMessages()[0].Content; !strings.Contains(sys, "code reviewer") {
+		t.Fatalf("expected reviewer prompt, got %q", sys)
+	}
+
+	if _, err := store.OpenThread("th_2", history, "test-model"); err != nil {
+		t.Fatalf("OpenThread: %v", err)
+	}
+	if history.Persona() != "" {
+		t.Fatalf("expected default persona for th_2, got %q", history.Persona())
+	}
+	if _, err := store.OpenThread("th_1", history, "test-model"); err != nil {
+		t.Fatalf("OpenThread: %v", err)
+	}
+	if history.Persona() != "reviewer" {
+		t.Fatalf("expected reviewer persona restored, got %q", history.Persona())
+	}
+}
@@ -16,13 +16,101 @@ func NewAutoCompleter() *readline.PrefixCompleter {
 		readline.PcItem("/change_url"),
 		readline.PcItem("/change_apikey"),
 		readline.PcItem("/proxy"),
+		readline.PcItem("/apikey_source",
+			readline.PcItem("status"),
+			readline.PcItem("env"),
+			readline.PcItem("command"),
+			readline.PcItem("config"),
+		),
+		readline.PcItem("/encryption",
+			readline.PcItem("status"),
+			readline.PcItem("enable"),
+			readline.PcItem("disable"),
+		),
+		readline.PcItem("/redaction",
+			readline.PcItem("status"),
+			readline.PcItem("on"),
+			readline.PcItem("off"),
+			readline.PcItem("add"),
+			readline.PcItem("remove"),
+		),
+		readline.PcItem("/lsp",
+			readline.PcItem("status"),
+			readline.PcItem("on"),
+			readline.PcItem("off"),
+			readline.PcItem("restart"),
+		),
+		readline.PcItem("/checkpoints",
+			readline.PcItem("status"),
+			readline.PcItem("on"),
+			readline.PcItem("off"),
+			readline.PcItem("list"),
+			readline.PcItem("show"),
+			readline.PcItem("restore"),
+			readline.PcItem("tag"),
+			readline.PcItem("usage"),
+			readline.PcItem("maxsize"),
+			readline.PcItem("retention"),
+			readline.PcItem("gc", readline.PcItem("--days"), readline.PcItem("--keep"), readline.PcItem("--all")),
+		),
+		readline.PcItem("/backups",
+			readline.PcItem("list"),
+			readline.PcItem("show"),
+			readline.PcItem("restore"),
+			readline.PcItem("status"),
+			readline.PcItem("verify"),
+			readline.PcItem("prune"),
+			readline.PcItem("retention"),
+		),
+		readline.PcItem("/format",
+			readline.PcItem("status"),
+			readline.PcItem("init"),
+			readline.PcItem("on"),
+			readline.PcItem("off"),
+			readline.PcItem("approve"),
+			readline.PcItem("set", readline.PcItem(".go", readline.PcItem("gofmt"), readline.PcItem("goimports"))),
+			readline.PcItem("unset"),
+		),
+		readline.PcItem("/undo", readline.PcItem("--force")),
+		readline.PcItem("/redo", readline.PcItem("--force")),
+		readline.PcItem("/changes", readline.PcItem("list")),
 		readline.PcItem("/search"),
-		readline.PcItem("/threads"),
+		readline.PcItem("/context"),
+		readline.PcItem("/persona",
+			readline.PcItem("list"),
+			readline.PcItem("default"),
+			readline.PcItem("reviewer"),
+			readline.PcItem("ops"),
+			readline.PcItem("writer"),
+		),
+		readline.PcItem("/memory",
+			readline.PcItem("list", readline.PcItem("--all")),
+			readline.PcItem("add", readline.PcItem("--global")),
+			readline.PcItem("edit"),
+			readline.PcItem("forget"),
+			readline.PcItem("search"),
+		),
+		readline.PcItem("/threads",
+			readline.PcItem("--all"),
+			readline.PcItem("--pinned"),
+			readline.PcItem("--archived"),
+			readline.PcItem("--trash"),
+			readline.PcItem("--tag="),
+		),
 		readline.PcItem("/thread",
 			readline.PcItem("new"),
 			readline.PcItem("open"),
 			readline.PcItem("rename"),
 			readline.PcItem("current"),
+			readline.PcItem("show", readline.PcItem("--full")),
+			readline.PcItem("tag"),
+			readline.PcItem("untag"),
+			readline.PcItem("pin"),
+			readline.PcItem("unpin"),
+			readline.PcItem("archive"),
+			readline.PcItem("unarchive"),
+			readline.PcItem("delete"),
+			readline.PcItem("restore"),
 		),
 	)
 }
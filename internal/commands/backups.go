@@ -0,0 +1,177 @@
+package commands
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/bilbilaki/ai2go/internal/config"
+	"github.com/bilbilaki/ai2go/internal/diff"
+	"github.com/bilbilaki/ai2go/internal/tools"
+)
+
+const backupsUsage = "Usage: /backups [list [path] [N]|show <id> [path]|restore <id> [path] [-y]|status|verify|prune|retention <per-file> <days> <MB>]"
+
+// ConfigureBackups applies the backup retention settings from cfg.
+func ConfigureBackups(cfg *config.Config) {
+	maxBytes := int64(0)
+	if cfg.BackupMaxMB > 0 {
+		maxBytes = int64(cfg.BackupMaxMB) << 20
+	}
+	tools.BackupRetentionPolicy = tools.BackupRetention{
+		KeepPerFile: cfg.BackupKeepPerFile,
+		KeepDays:    cfg.BackupKeepDays,
+		MaxBytes:    maxBytes,
+	}
+}
+
+func handleBackupsCommand(parts []string, cfg *config.Config) {
+	sub := "list"
+	if len(parts) > 1 {
+		sub = strings.ToLower(parts[1])
+	}
+	args := []string{}
+	if len(parts) > 2 {
+		args = parts[2:]
+	}
+
+	switch sub {
+	case "list":
+		path, limit := "", 20
+		for _, a := range args {
+			if n, err := strconv.Atoi(a); err == nil && n > 0 {
+				limit = n
+			} else if path == "" {
+				path = a
+			} else {
+				fmt.Println("Usage: /backups list [path] [N]")
+				return
+			}
+		}
+		out, err := tools.ListFileBackups(path, limit)
+		if err != nil {
+			fmt.Printf("\033[31mError: %v\033[0m\n", err)
+			return
+		}
+		fmt.Println(out)
+	case "show":
+		if len(args) < 1 || len(args) > 2 {
+			fmt.Println("Usage: /backups show <id> [path]")
+			return
+		}
+		showBackupPreview(args[0], optionalArg(args, 1))
+	case "restore":
+		yes := false
+		var rest []string
+		for _, a := range args {
+			if a == "-y" {
+				yes = true
+				continue
+			}
+			rest = append(rest, a)
+		}
+		if len(rest) < 1 || len(rest) > 2 {
+			fmt.Println("Usage: /backups restore <id> [path] [-y]")
+			return
+		}
+		if !showBackupPreview(rest[0], optionalArg(rest, 1)) {
+			return
+		}
+		if !yes {
+			fmt.Print("Restore this version? (y/n): ")
+			reader := bufio.NewReader(os.Stdin)
+			answer, _ := reader.ReadString('\n')
+			if strings.ToLower(strings.TrimSpace(answer)) != "y" {
+				fmt.Println("Restore cancelled.")
+				return
+			}
+		}
+		out, err := tools.RestoreFileBackup(optionalArg(rest, 1), rest[0])
+		if err != nil {
+			fmt.Printf("\033[31mRestore failed: %v\033[0m\n", err)
+			return
+		}
+		fmt.Printf("\033[32m%s\033[0m\n", out)
+	case "status":
+		backups, files, size, err := tools.BackupUsage()
+		if err != nil {
+			fmt.Printf("\033[31mError: %v\033[0m\n", err)
+			return
+		}
+		quota := "unlimited"
+		if cfg.BackupMaxMB > 0 {
+			quota = fmt.Sprintf("%d MB", cfg.BackupMaxMB)
+		}
+		fmt.Printf("Backups: %d of %d file(s), %s stored\n", backups, files, tools.FormatBytes(size))
+		fmt.Printf("Retention: %d version(s) per file, %d days, quota %s\n", cfg.BackupKeepPerFile, cfg.BackupKeepDays, quota)
+	case "verify":
+		report, err := tools.VerifyBackups()
+		if err != nil {
+			fmt.Printf("\033[31mError: %v\033[0m\n", err)
+			return
+		}
+		if report.OK() {
+			fmt.Printf("\033[32m%s\033[0m\n", report)
+		} else {
+			fmt.Printf("\033[31m%s\033[0m\n", report)
+		}
+	case "prune":
+		report, err := tools.PruneBackups(tools.BackupRetentionPolicy)
+		if err != nil {
+			fmt.Printf("\033[31mPrune failed: %v\033[0m\n", err)
+			return
+		}
+		fmt.Printf("\033[32m%s\033[0m\n", report)
+	case "retention":
+		if len(args) != 3 {
+			fmt.Println("Usage: /backups retention <per-file> <days> <MB> (MB -1 for no quota)")
+			return
+		}
+		perFile, errPerFile := strconv.Atoi(args[0])
+		days, errDays := strconv.Atoi(args[1])
+		mb, errMB := strconv.Atoi(args[2])
+		if errPerFile != nil || errDays != nil || errMB != nil {
+			fmt.Println("Usage: /backups retention <per-file> <days> <MB> (MB -1 for no quota)")
+			return
+		}
+		cfg.SetBackupRetention(perFile, days, mb)
+		ConfigureBackups(cfg)
+		fmt.Println("\033[32mBackup retention updated.\033[0m")
+	default:
+		fmt.Println(backupsUsage)
+	}
+}
+
+// showBackupPreview prints the diff from the current file to backup id and
+// reports whether the backup could be read.
+func showBackupPreview(id, path string) bool {
+	entry, err := tools.LookupBackup(id)
+	if err != nil {
+		fmt.Printf("\033[31mError: %v\033[0m\n", err)
+		return false
+	}
+	if path == "" {
+		path = entry.Path
+	}
+	fmt.Printf("Backup %s of %s, taken %s (%s)\n", entry.ID, entry.Path, entry.Created.Local().Format("2006-01-02 15:04:05"), tools.FormatBytes(entry.Size))
+	if _, err := os.Stat(path); err != nil {
+		fmt.Printf("%s does not exist; restoring recreates it.\n", path)
+		return true
+	}
+	out, err := tools.ShowFileDiff(path, "", entry.ID, diff.Options{})
+	if err != nil {
+		fmt.Printf("\033[31mError: %v\033[0m\n", err)
+		return false
+	}
+	fmt.Println(diff.Colorize(out))
+	return true
+}
+
+func optionalArg(args []string, i int) string {
+	if i < len(args) {
+		return args[i]
+	}
+	return ""
+}
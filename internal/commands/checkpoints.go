@@ -0,0 +1,330 @@
+package commands
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/bilbilaki/ai2go/internal/config"
+	"github.com/bilbilaki/ai2go/internal/diff"
+	"github.com/bilbilaki/ai2go/internal/tools"
+)
+
+const (
+	checkpointsUsage = "Usage: /checkpoints [status|on|off|list [N]|show <ref> [working|<ref>]|restore <ref> <path>...|tag <name> [ref]|usage|maxsize <MB>|retention <days> <count>|gc [--days N] [--keep N] [--all]]"
+	undoUsage        = "Usage: /undo [--force]"
+	redoUsage        = "Usage: /redo [--force]"
+	changesUsage     = "Usage: /changes [list|<turn>]"
+)
+
+// ConfigureCheckpoints applies the checkpoint size limit from cfg.
+func ConfigureCheckpoints(cfg *config.Config) {
+	tools.CheckpointMaxFileSize = int64(cfg.CheckpointMaxFileMB) << 20
+}
+
+func handleCheckpointsCommand(parts []string, cfg *config.Config) {
+	sub := "status"
+	if len(parts) > 1 {
+		sub = strings.ToLower(parts[1])
+	}
+
+	switch sub {
+	case "status":
+		status := "ON"
+		if cfg.DisableAutoCheckpoint {
+			status = "OFF"
+		}
+		fmt.Printf("Automatic turn checkpoints: %s\n", status)
+		maxSize := "unlimited"
+		if cfg.CheckpointMaxFileMB > 0 {
+			maxSize = fmt.Sprintf("%d MB", cfg.CheckpointMaxFileMB)
+		}
+		fmt.Printf("Max file size: %s\n", maxSize)
+		fmt.Printf("Retention: %d days, at least %d checkpoints\n", cfg.CheckpointKeepDays, cfg.CheckpointKeepCount)
+		if workTree, err := tools.DefaultWorkTree(); err == nil {
+			fmt.Printf("Work tree: %s\n", workTree)
+		}
+	case "list", "show", "restore", "tag":
+		handleCheckpointBrowse(sub, parts[2:])
+	case "usage":
+		showCheckpointUsage()
+	case "maxsize":
+		if len(parts) != 3 {
+			fmt.Println("Usage: /checkpoints maxsize <MB> (-1 for no limit)")
+			return
+		}
+		mb, err := strconv.Atoi(parts[2])
+		if err != nil {
+			fmt.Println("Usage: /checkpoints maxsize <MB> (-1 for no limit)")
+			return
+		}
+		cfg.SetCheckpointMaxFileMB(mb)
+		ConfigureCheckpoints(cfg)
+		fmt.Println("\033[32mCheckpoint size limit updated.\033[0m")
+	case "retention":
+		if len(parts) != 4 {
+			fmt.Println("Usage: /checkpoints retention <days> <count>")
+			return
+		}
+		days, errDays := strconv.Atoi(parts[2])
+		count, errCount := strconv.Atoi(parts[3])
+		if errDays != nil || errCount != nil {
+			fmt.Println("Usage: /checkpoints retention <days> <count>")
+			return
+		}
+		cfg.SetCheckpointRetention(days, count)
+		fmt.Println("\033[32mCheckpoint retention updated.\033[0m")
+	case "gc":
+		handleCheckpointsGC(parts[2:], cfg)
+	case "on", "off":
+		wantOff := sub == "off"
+		if cfg.DisableAutoCheckpoint != wantOff {
+			cfg.ToggleAutoCheckpoint()
+		}
+		fmt.Println("\033[32mCheckpoint settings updated.\033[0m")
+	default:
+		fmt.Println(checkpointsUsage)
+	}
+}
+
+// parseForce accepts an optional --force flag and nothing else.
+func parseForce(parts []string, usage string) (force, ok bool) {
+	for _, p := range parts[1:] {
+		if p != "--force" {
+			fmt.Println(usage)
+			return false, false
+		}
+		force = true
+	}
+	return force, true
+}
+
+func handleUndoCommand(parts []string) {
+	force, ok := parseForce(parts, undoUsage)
+	if !ok {
+		return
+	}
+	workTree, err := tools.DefaultWorkTree()
+	if err != nil {
+		fmt.Printf("\033[31mError: %v\033[0m\n", err)
+		return
+	}
+	turn, err := tools.UndoTurn(workTree, force)
+	if err != nil {
+		fmt.Printf("\033[31mUndo failed: %v\033[0m\n", err)
+		return
+	}
+	fmt.Printf("\033[32mUndid turn %d (%d file(s)): %s\033[0m\n", turn.ID, len(turn.Files), strings.Join(turn.Files, ", "))
+	fmt.Println("Use /redo to reapply it.")
+}
+
+func handleRedoCommand(parts []string) {
+	force, ok := parseForce(parts, redoUsage)
+	if !ok {
+		return
+	}
+	workTree, err := tools.DefaultWorkTree()
+	if err != nil {
+		fmt.Printf("\033[31mError: %v\033[0m\n", err)
+		return
+	}
+	turn, err := tools.RedoTurn(workTree, force)
+	if err != nil {
+		fmt.Printf("\033[31mRedo failed: %v\033[0m\n", err)
+		return
+	}
+	fmt.Printf("\033[32mReapplied turn %d (%d file(s)): %s\033[0m\n", turn.ID, len(turn.Files), strings.Join(turn.Files, ", "))
+}
+
+func handleChangesCommand(parts []string) {
+	workTree, err := tools.DefaultWorkTree()
+	if err != nil {
+		fmt.Printf("\033[31mError: %v\033[0m\n", err)
+		return
+	}
+
+	id := 0
+	if len(parts) > 1 {
+		if strings.ToLower(parts[1]) == "list" {
+			turns, err := tools.ListTurns(workTree)
+			if err != nil {
+				fmt.Printf("\033[31mError: %v\033[0m\n", err)
+				return
+			}
+			if len(turns) == 0 {
+				fmt.Println("No turns with file changes recorded yet.")
+				return
+			}
+			for i := len(turns) - 1; i >= 0; i-- {
+				fmt.Println(turns[i].Describe())
+			}
+			return
+		}
+		n, err := strconv.Atoi(parts[1])
+		if err != nil || n < 1 {
+			fmt.Println(changesUsage)
+			return
+		}
+		id = n
+	}
+
+	out, err := tools.TurnDiff(workTree, id, diff.Options{})
+	if err != nil {
+		fmt.Printf("\033[31mError: %v\033[0m\n", err)
+		return
+	}
+	fmt.Println(diff.Colorize(out))
+}
+
+func handleCheckpointBrowse(sub string, args []string) {
+	workTree, err := tools.DefaultWorkTree()
+	if err != nil {
+		fmt.Printf("\033[31mError: %v\033[0m\n", err)
+		return
+	}
+
+	var out string
+	switch sub {
+	case "list":
+		limit := 20
+		if len(args) > 0 {
+			if limit, err = strconv.Atoi(args[0]); err != nil || limit < 1 {
+				fmt.Println("Usage: /checkpoints list [N]")
+				return
+			}
+		}
+		out, err = tools.EditorHistory(workTree, limit)
+	case "show":
+		if len(args) < 1 || len(args) > 2 {
+			fmt.Println("Usage: /checkpoints show <ref> [working|<ref>]")
+			return
+		}
+		compareTo := ""
+		if len(args) == 2 {
+			compareTo = args[1]
+			if compareTo == "working" {
+				compareTo = tools.CompareWorkingTree
+			}
+		}
+		out, err = tools.ShowCheckpointDiff(workTree, args[0], compareTo, diff.Options{})
+		out = diff.Colorize(out)
+	case "restore":
+		if len(args) < 2 {
+			fmt.Println("Usage: /checkpoints restore <ref> <path>...")
+			return
+		}
+		out, err = tools.RestoreCheckpointFiles(workTree, args[0], args[1:])
+	case "tag":
+		if len(args) < 1 || len(args) > 2 {
+			fmt.Println("Usage: /checkpoints tag <name> [ref]")
+			return
+		}
+		ref := ""
+		if len(args) == 2 {
+			ref = args[1]
+		}
+		out, err = tools.TagCheckpoint(workTree, args[0], ref)
+	}
+	if err != nil {
+		fmt.Printf("\033[31mError: %v\033[0m\n", err)
+		return
+	}
+	fmt.Println(out)
+}
+
+func showCheckpointUsage() {
+	repos, err := tools.ListCheckpointRepos()
+	if err != nil {
+		fmt.Printf("\033[31mError: %v\033[0m\n", err)
+		return
+	}
+	if len(repos) == 0 {
+		fmt.Println("No checkpoint repos yet.")
+		return
+	}
+	var total int64
+	for _, r := range repos {
+		total += r.SizeBytes
+		workTree := r.WorkTree
+		switch {
+		case workTree == "":
+			workTree = "(unknown work tree)"
+		case r.Orphaned():
+			workTree += " (missing)"
+		}
+		fmt.Printf("%10s  %4d checkpoints  %3d turns  last used %s  %s\n",
+			tools.FormatBytes(r.SizeBytes), r.Checkpoints, r.Turns, r.LastUsed.Format("2006-01-02"), workTree)
+	}
+	fmt.Printf("Total: %s in %d repo(s)\n", tools.FormatBytes(total), len(repos))
+}
+
+func handleCheckpointsGC(args []string, cfg *config.Config) {
+	const gcUsage = "Usage: /checkpoints gc [--days N] [--keep N] [--all]"
+	keep := tools.CheckpointRetention{KeepDays: cfg.CheckpointKeepDays, KeepCheckpoints: cfg.CheckpointKeepCount}
+	all := false
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--all":
+			all = true
+		case "--days", "--keep":
+			if i+1 >= len(args) {
+				fmt.Println(gcUsage)
+				return
+			}
+			n, err := strconv.Atoi(args[i+1])
+			if err != nil || n < 0 {
+				fmt.Println(gcUsage)
+				return
+			}
+			if args[i] == "--days" {
+				keep.KeepDays = n
+			} else {
+				keep.KeepCheckpoints = n
+			}
+			i++
+		default:
+			fmt.Println(gcUsage)
+			return
+		}
+	}
+
+	var workTrees []string
+	if all {
+		repos, err := tools.ListCheckpointRepos()
+		if err != nil {
+			fmt.Printf("\033[31mError: %v\033[0m\n", err)
+			return
+		}
+		for _, r := range repos {
+			if r.WorkTree == "" {
+				fmt.Printf("Skipping %s: work tree unknown (it is recorded the next time the repo is used)\n", r.Dir)
+				continue
+			}
+			if r.Orphaned() {
+				if err := tools.RemoveCheckpointRepo(r); err != nil {
+					fmt.Printf("\033[31mError: %v\033[0m\n", err)
+					continue
+				}
+				fmt.Printf("Removed orphaned checkpoint repo %s (%s)\n", r.Dir, tools.FormatBytes(r.SizeBytes))
+				continue
+			}
+			workTrees = append(workTrees, r.WorkTree)
+		}
+	} else {
+		workTree, err := tools.DefaultWorkTree()
+		if err != nil {
+			fmt.Printf("\033[31mError: %v\033[0m\n", err)
+			return
+		}
+		workTrees = []string{workTree}
+	}
+
+	for _, wt := range workTrees {
+		report, err := tools.GCCheckpoints(wt, keep)
+		if err != nil {
+			fmt.Printf("\033[31mGC failed for %s: %v\033[0m\n", wt, err)
+			continue
+		}
+		fmt.Printf("\033[32m%s\033[0m\n", report)
+	}
+}
@@ -0,0 +1,43 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/bilbilaki/ai2go/internal/chat"
+	"github.com/bilbilaki/ai2go/internal/project"
+)
+
+func handleContextCommand(history *chat.History) {
+	workDir, userDir := history.InstructionDirs()
+	system := ""
+	if msgs := history.GetMessages(); len(msgs) > 0 && msgs[0].Role == "system" {
+		system = msgs[0].Content
+	}
+
+	paths := project.FindInstructionFiles(workDir, userDir)
+	fmt.Println("Instruction files (merged in this order when a thread starts):")
+	if len(paths) == 0 {
+		fmt.Printf("  none found. Create %s/%s in the project or %s in %s.\n",
+			project.InstructionsDir, project.InstructionsFile, project.InstructionsFile, userDir)
+	}
+	for i, path := range paths {
+		status := "not in current thread; start a new thread to load it"
+		if strings.Contains(system, "--- "+path+" ---") {
+			status = "loaded"
+		}
+		size := ""
+		if info, err := os.Stat(path); err == nil {
+			size = fmt.Sprintf(", %d bytes", info.Size())
+		}
+		fmt.Printf("  %d. %s (%s%s)\n", i+1, path, status, size)
+	}
+
+	for _, f := range history.InstructionFiles() {
+		if f.Truncated {
+			fmt.Printf("  note: %s was truncated when loaded\n", f.Path)
+		}
+	}
+	fmt.Printf("System prompt size: ~%d tokens\n", chat.ApproximateTokens(system))
+}
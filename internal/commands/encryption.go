@@ -0,0 +1,234 @@
+package commands
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bilbilaki/ai2go/internal/chat"
+	"github.com/bilbilaki/ai2go/internal/config"
+	"github.com/bilbilaki/ai2go/internal/secure"
+	"github.com/bilbilaki/ai2go/internal/ui"
+	"github.com/chzyer/readline"
+)
+
+const (
+	passphraseEnv         = "AI2GO_PASSPHRASE"
+	maxPassphraseAttempts = 3
+)
+
+// UnlockAtStartup unlocks the vault when encryption at rest is enabled.
+// The secret comes from the configured key file, the AI2GO_PASSPHRASE env
+// var, or an interactive passphrase prompt, in that order. It returns a nil
+// vault when encryption is off.
+func UnlockAtStartup(cfg *config.Config) (*secure.Vault, error) {
+	if !cfg.EncryptAtRest {
+		return nil, nil
+	}
+	dir, err := config.Dir()
+	if err != nil {
+		return nil, err
+	}
+	if !secure.Exists(dir) {
+		return nil, fmt.Errorf("encryption is enabled but no vault exists in %s", dir)
+	}
+
+	var vault *secure.Vault
+	switch {
+	case cfg.KeyFile != "":
+		secret, err := secure.ReadKeyFile(cfg.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		vault, err = secure.Unlock(dir, secret)
+		if err != nil {
+			return nil, err
+		}
+	case os.Getenv(passphraseEnv) != "":
+		vault, err = secure.Unlock(dir, []byte(os.Getenv(passphraseEnv)))
+		if err != nil {
+			return nil, err
+		}
+	default:
+		for attempt := 1; ; attempt++ {
+			secret, err := readline.Password("Passphrase to unlock ai2go storage: ")
+			if err != nil {
+				return nil, fmt.Errorf("failed to read passphrase: %w", err)
+			}
+			vault, err = secure.Unlock(dir, secret)
+			if err == nil {
+				break
+			}
+			if err != secure.ErrWrongSecret || attempt >= maxPassphraseAttempts {
+				return nil, err
+			}
+			fmt.Println(ui.Warn("Wrong passphrase, try again."))
+		}
+	}
+
+	if err := cfg.AttachVault(vault); err != nil {
+		return nil, err
+	}
+	return vault, nil
+}
+
+func handleEncryptionCommand(parts []string, store *chat.ThreadStore, cfg *config.Config) {
+	sub := "status"
+	if len(parts) > 1 {
+		sub = strings.ToLower(parts[1])
+	}
+
+	switch sub {
+	case "status":
+		status := "OFF"
+		if cfg.EncryptAtRest {
+			status = "ON"
+		}
+		fmt.Printf("Encryption at rest: %s\n", status)
+		if cfg.EncryptAtRest {
+			if cfg.KeyFile != "" {
+				fmt.Printf("Key source: key file %s\n", cfg.KeyFile)
+			} else {
+				fmt.Println("Key source: passphrase")
+			}
+		}
+		fmt.Printf("API key source: %s\n", cfg.APIKeySource())
+	case "enable":
+		if cfg.EncryptAtRest {
+			fmt.Println("Encryption at rest is already enabled.")
+			return
+		}
+		keyFile := ""
+		for _, arg := range parts[2:] {
+			if strings.HasPrefix(arg, "--key-file=") {
+				keyFile = strings.TrimSpace(strings.TrimPrefix(arg, "--key-file="))
+				if abs, err := filepath.Abs(keyFile); err == nil {
+					keyFile = abs
+				}
+			}
+		}
+		secret, err := readNewSecret(keyFile)
+		if err != nil {
+			fmt.Printf("\033[31mError: %v\033[0m\n", err)
+			return
+		}
+		dir, err := config.Dir()
+		if err != nil {
+			fmt.Printf("\033[31mError: %v\033[0m\n", err)
+			return
+		}
+		vault, err := secure.Init(dir, secret)
+		if err != nil {
+			fmt.Printf("\033[31mError creating vault: %v\033[0m\n", err)
+			return
+		}
+		if err := applyVault(vault, keyFile, store, cfg); err != nil {
+			// Nothing uses the new vault; drop its metadata so the next
+			// start does not expect a passphrase.
+			_ = secure.Remove(dir)
+			fmt.Printf("\033[31mError enabling encryption: %v\033[0m\n", err)
+			return
+		}
+		fmt.Println("\033[32mEncryption at rest enabled. Threads and the API key are now encrypted.\033[0m")
+		if keyFile == "" {
+			fmt.Printf("You will be asked for the passphrase at startup (or set %s).\n", passphraseEnv)
+		}
+	case "disable":
+		if !cfg.EncryptAtRest {
+			fmt.Println("Encryption at rest is already disabled.")
+			return
+		}
+		fmt.Print("Store threads and the API key in plaintext again? (y/n): ")
+		reader := bufio.NewReader(os.Stdin)
+		answer, _ := reader.ReadString('\n')
+		if strings.ToLower(strings.TrimSpace(answer)) != "y" {
+			fmt.Println("Cancelled.")
+			return
+		}
+		if err := applyVault(nil, "", store, cfg); err != nil {
+			fmt.Printf("\033[31mError disabling encryption: %v\033[0m\n", err)
+			return
+		}
+		if dir, err := config.Dir(); err == nil {
+			_ = secure.Remove(dir)
+		}
+		fmt.Println("\033[32mEncryption at rest disabled.\033[0m")
+	default:
+		fmt.Println("Usage: /encryption [status|enable [--key-file=PATH]|disable]")
+	}
+}
+
+func handleAPIKeySourceCommand(parts []string, cfg *config.Config) {
+	if len(parts) < 2 || strings.EqualFold(parts[1], "status") {
+		fmt.Printf("API key source: %s\n", cfg.APIKeySource())
+		return
+	}
+
+	kind := strings.ToLower(parts[1])
+	value := strings.Join(parts[2:], " ")
+	if kind == "config" {
+		// The key read from the old source is not carried over; ask for the
+		// one to store instead of echoing it in the command line.
+		fmt.Print("Enter API Key to store in the config: ")
+		key, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		value = strings.TrimSpace(key)
+	}
+	if err := cfg.SetAPIKeySource(kind, value); err != nil {
+		fmt.Printf("\033[31mError: %v\033[0m\n", err)
+		fmt.Println("Usage: /apikey_source [status|env <VAR>|command <shell command>|config]")
+		return
+	}
+	fmt.Printf("API key source set to: %s\n", cfg.APIKeySource())
+}
+
+// applyVault re-seals the stores with vault and saves the config last, so
+// the config never names an encryption the data on disk does not use. On
+// failure everything already switched goes back to the previous vault.
+func applyVault(vault *secure.Vault, keyFile string, store *chat.ThreadStore, cfg *config.Config) error {
+	prev, prevKeyFile, prevEncrypt := cfg.Vault(), cfg.KeyFile, cfg.EncryptAtRest
+	rollback := func(err error) error {
+		if rbErr := store.SetVault(prev); rbErr != nil {
+			return fmt.Errorf("%w; restoring the previous encryption also failed: %v", err, rbErr)
+		}
+		return err
+	}
+	if err := store.SetVault(vault); err != nil {
+		return rollback(err)
+	}
+
+	cfg.EncryptAtRest = vault != nil
+	cfg.KeyFile = keyFile
+	err := cfg.AttachVault(vault)
+	if err == nil {
+		err = cfg.Save()
+	}
+	if err != nil {
+		cfg.EncryptAtRest, cfg.KeyFile = prevEncrypt, prevKeyFile
+		_ = cfg.AttachVault(prev)
+		return rollback(err)
+	}
+	return nil
+}
+
+func readNewSecret(keyFile string) ([]byte, error) {
+	if keyFile != "" {
+		return secure.ReadKeyFile(keyFile)
+	}
+	first, err := readline.Password("New passphrase: ")
+	if err != nil {
+		return nil, err
+	}
+	if len(first) < 8 {
+		return nil, fmt.Errorf("passphrase must be at least 8 characters")
+	}
+	second, err := readline.Password("Repeat passphrase: ")
+	if err != nil {
+		return nil, err
+	}
+	if string(first) != string(second) {
+		return nil, fmt.Errorf("passphrases do not match")
+	}
+	return first, nil
+}
@@ -0,0 +1,134 @@
+package commands
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/bilbilaki/ai2go/internal/chat"
+	"github.com/bilbilaki/ai2go/internal/tools"
+)
+
+const formatUsage = "Usage: /format [status|init|on|off|approve|set <ext> <command>|unset <ext>]"
+
+func handleFormatCommand(parts []string, store *chat.ThreadStore) {
+	sub := "status"
+	if len(parts) > 1 {
+		sub = strings.ToLower(parts[1])
+	}
+	root := store.Project().Key()
+	cfg, found, err := tools.LoadFormatConfig(root)
+	if err != nil {
+		fmt.Printf("\033[31mError: %v\033[0m\n", err)
+		return
+	}
+
+	switch sub {
+	case "status":
+		if !found {
+			fmt.Printf("Formatting after edits: OFF (no %s; run /format init to opt in)\n", tools.FormatConfigPath(root))
+			return
+		}
+		status := "ON"
+		if !cfg.IsEnabled() {
+			status = "OFF"
+		}
+		fmt.Printf("Formatting after edits: %s (%s)\n", status, tools.FormatConfigPath(root))
+		exts := make([]string, 0, len(cfg.Formatters))
+		for ext := range cfg.Formatters {
+			exts = append(exts, ext)
+		}
+		sort.Strings(exts)
+		for _, ext := range exts {
+			command := cfg.Formatters[ext].Command
+			note := ""
+			if slices.Contains(cfg.ExternalCommands(), strings.TrimSpace(command)) && !tools.FormatCommandApproved(root, command) {
+				note = "  (not approved; /format approve)"
+			}
+			fmt.Printf("  %-6s %s%s\n", ext, command, note)
+		}
+		return
+	case "approve":
+		if !found {
+			fmt.Printf("No %s to approve.\n", tools.FormatConfigPath(root))
+			return
+		}
+		commands := cfg.ExternalCommands()
+		if len(commands) == 0 {
+			fmt.Println("Only built-in formatters are configured; nothing needs approval.")
+			return
+		}
+		if err := tools.ApproveFormatCommands(root, cfg); err != nil {
+			fmt.Printf("\033[31mError saving approval: %v\033[0m\n", err)
+			return
+		}
+		fmt.Println("\033[32mApproved these commands to run after edits in this project:\033[0m")
+		for _, c := range commands {
+			fmt.Printf("  %s\n", c)
+		}
+		return
+	case "init":
+		if found {
+			fmt.Printf("%s already exists; use /format set or /format on.\n", tools.FormatConfigPath(root))
+			return
+		}
+		cfg = tools.DefaultFormatConfig(root)
+	case "on", "off":
+		if !found {
+			cfg = tools.DefaultFormatConfig(root)
+		}
+		enabled := sub == "on"
+		cfg.Enabled = &enabled
+	case "set":
+		if len(parts) < 4 {
+			fmt.Println("Usage: /format set <ext> <command> (gofmt, goimports or a shell command with {file})")
+			return
+		}
+		if cfg.Formatters == nil {
+			cfg.Formatters = map[string]tools.FormatterSpec{}
+		}
+		cfg.Formatters[normalizeFormatExt(parts[2])] = tools.FormatterSpec{Command: strings.Join(parts[3:], " ")}
+	case "unset":
+		if len(parts) != 3 {
+			fmt.Println("Usage: /format unset <ext>")
+			return
+		}
+		ext := normalizeFormatExt(parts[2])
+		if _, ok := cfg.Formatters[ext]; !ok {
+			fmt.Printf("No formatter configured for %s.\n", ext)
+			return
+		}
+		delete(cfg.Formatters, ext)
+	default:
+		fmt.Println(formatUsage)
+		return
+	}
+
+	if err := tools.SaveFormatConfig(root, cfg); err != nil {
+		fmt.Printf("\033[31mError saving format config: %v\033[0m\n", err)
+		return
+	}
+	// Commands generated by init or typed into set need no separate
+	// approval; other commands keep whatever approval they had.
+	if sub == "init" || sub == "set" {
+		approved := tools.FormatConfig{Formatters: map[string]tools.FormatterSpec{}}
+		for ext, spec := range cfg.Formatters {
+			if sub == "init" || ext == normalizeFormatExt(parts[2]) || tools.FormatCommandApproved(root, spec.Command) {
+				approved.Formatters[ext] = spec
+			}
+		}
+		if err := tools.ApproveFormatCommands(root, approved); err != nil {
+			fmt.Printf("\033[31mError saving approval: %v\033[0m\n", err)
+		}
+	}
+	fmt.Printf("\033[32mFormat settings saved to %s.\033[0m\n", tools.FormatConfigPath(root))
+}
+
+func normalizeFormatExt(ext string) string {
+	ext = strings.ToLower(strings.TrimSpace(ext))
+	if !strings.HasPrefix(ext, ".") {
+		ext = "." + ext
+	}
+	return ext
+}
@@ -50,6 +50,12 @@ func HandleCommand(cmd string, history *chat.History, store *chat.ThreadStore, c
 		handleThreadCommand(parts, history, store, cfg)
 	case "/search":
 		handleSearch(parts, store)
+	case "/persona":
+		handlePersonaCommand(parts, history, store, cfg)
+	case "/context":
+		handleContextCommand(history)
+	case "/memory":
+		handleMemoryCommand(parts, store)
 
 	case "/change_url":
 		fmt.Print("Enter new Base URL: ")
@@ -58,6 +64,27 @@ func HandleCommand(cmd string, history *chat.History, store *chat.ThreadStore, c
 		cfg.SetBaseURL(strings.TrimSpace(newUrl))
 		fmt.Println("Base URL updated!")
 
+	case "/encryption":
+		handleEncryptionCommand(parts, store, cfg)
+	case "/apikey_source":
+		handleAPIKeySourceCommand(parts, cfg)
+	case "/redaction":
+		handleRedactionCommand(parts, history, store, cfg)
+	case "/lsp":
+		handleLSPCommand(parts, cfg)
+	case "/checkpoints":
+		handleCheckpointsCommand(parts, cfg)
+	case "/backups":
+		handleBackupsCommand(parts, cfg)
+	case "/format":
+		handleFormatCommand(parts, store)
+	case "/undo":
+		handleUndoCommand(parts)
+	case "/redo":
+		handleRedoCommand(parts)
+	case "/changes":
+		handleChangesCommand(parts)
+
 	case "/change_apikey":
 		fmt.Print("Enter new API Key: ")
 		reader := bufio.NewReader(os.Stdin)
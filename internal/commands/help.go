@@ -11,12 +11,25 @@ func ShowHelp() {
 	fmt.Println("  " + ui.HelpCommand("/models", "Show available models and switch"))
 	fmt.Println("  " + ui.HelpCommand("/current", "Show current model"))
 	fmt.Println("  " + ui.HelpCommand("/clear", "Clear conversation history"))
-	fmt.Println("  " + ui.HelpCommand("/threads", "List threads (supports query, --sort, --order)"))
-	fmt.Println("  " + ui.HelpCommand("/thread", "Thread ops: new/open/rename/current"))
+	fmt.Println("  " + ui.HelpCommand("/threads", "List project threads (query, --all, --sort, --order, --tag=, --pinned, --archived, --trash)"))
+	fmt.Println("  " + ui.HelpCommand("/thread", "Thread ops: new/open/rename/current/show/tag/untag/pin/unpin/archive/unarchive/delete/restore"))
 	fmt.Println("  " + ui.HelpCommand("/search", "Search across thread titles and messages"))
+	fmt.Println("  " + ui.HelpCommand("/memory", "Long-term memory: list [--all]/add [--global]/edit/forget/search"))
+	fmt.Println("  " + ui.HelpCommand("/context", "Show instruction files merged into the system prompt"))
+	fmt.Println("  " + ui.HelpCommand("/persona", "Show or switch the system prompt persona for this thread"))
 	fmt.Println("  " + ui.HelpCommand("/file", "add file content into chat"))
 	fmt.Println("  " + ui.HelpCommand("/change_url", "Change base URL"))
 	fmt.Println("  " + ui.HelpCommand("/change_apikey", "Change API key"))
+	fmt.Println("  " + ui.HelpCommand("/apikey_source", "Read API key from config, env <VAR> or command <cmd>"))
+	fmt.Println("  " + ui.HelpCommand("/encryption", "Encrypt threads and secrets at rest: status/enable/disable"))
+	fmt.Println("  " + ui.HelpCommand("/redaction", "Secret redaction: status/on/off/add <regex>/remove <regex>"))
+	fmt.Println("  " + ui.HelpCommand("/lsp", "Language server diagnostics after edits: status/on/off/restart"))
+	fmt.Println("  " + ui.HelpCommand("/undo", "Revert the file changes of the last agent turn (--force to overwrite later edits)"))
+	fmt.Println("  " + ui.HelpCommand("/redo", "Reapply the last undone turn"))
+	fmt.Println("  " + ui.HelpCommand("/changes", "Show the diff of the last turn, a turn number, or list turns"))
+	fmt.Println("  " + ui.HelpCommand("/checkpoints", "Checkpoints: status/on/off/list/show <ref> [working|ref]/restore <ref> <path>.../tag <name> [ref]/usage/maxsize/retention/gc"))
+	fmt.Println("  " + ui.HelpCommand("/backups", "File backups: list [path] [N]/show <id> [path]/restore <id> [path] [-y]/status/verify/prune/retention"))
+	fmt.Println("  " + ui.HelpCommand("/format", "Project formatters run after edits: status/init/on/off/set <ext> <command>/unset <ext>"))
 	fmt.Println("  " + ui.HelpCommand("/proxy", "Set proxy URL"))
 	fmt.Println("  " + ui.HelpCommand("/autoaccept", "Toggle auto-accept for commands"))
 	fmt.Println("  " + ui.HelpCommand("/subagent_experimental", "Toggle experimental subagent tool execution"))
@@ -0,0 +1,62 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bilbilaki/ai2go/internal/config"
+	"github.com/bilbilaki/ai2go/internal/lsp"
+)
+
+const lspUsage = "Usage: /lsp [status|on|off|restart]"
+
+// ConfigureLSP applies the language server settings from cfg to the shared
+// diagnostics manager.
+func ConfigureLSP(cfg *config.Config) {
+	servers := make([]lsp.ServerConfig, 0, len(cfg.LSPServers))
+	for _, s := range cfg.LSPServers {
+		servers = append(servers, lsp.ServerConfig{
+			Name:        s.Name,
+			Command:     s.Command,
+			Args:        s.Args,
+			Languages:   s.Languages,
+			RootMarkers: s.RootMarkers,
+		})
+	}
+	lsp.DefaultManager().Configure(!cfg.DisableLSP, servers)
+}
+
+func handleLSPCommand(parts []string, cfg *config.Config) {
+	sub := "status"
+	if len(parts) > 1 {
+		sub = strings.ToLower(parts[1])
+	}
+
+	switch sub {
+	case "status":
+		status := "ON"
+		if cfg.DisableLSP {
+			status = "OFF"
+		}
+		fmt.Printf("Diagnostics after edits: %s\n", status)
+		for _, line := range lsp.DefaultManager().Status() {
+			fmt.Printf("  %s\n", line)
+		}
+		return
+	case "on", "off":
+		wantOff := sub == "off"
+		if cfg.DisableLSP != wantOff {
+			cfg.ToggleLSP()
+		}
+	case "restart":
+		lsp.DefaultManager().Shutdown()
+		fmt.Println("\033[32mLanguage servers stopped; they restart on the next edit.\033[0m")
+		return
+	default:
+		fmt.Println(lspUsage)
+		return
+	}
+
+	ConfigureLSP(cfg)
+	fmt.Println("\033[32mLSP settings updated.\033[0m")
+}
@@ -0,0 +1,116 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bilbilaki/ai2go/internal/chat"
+)
+
+const memoryUsage = "Usage: /memory [list [--all]|add [--global] <text>|edit [--all] <id> <text>|forget [--all] <id>|search <query>]"
+
+func handleMemoryCommand(parts []string, store *chat.ThreadStore) {
+	mem := store.Memory()
+	if mem == nil {
+		fmt.Println("\033[31mMemory store is not available.\033[0m")
+		return
+	}
+
+	sub := "list"
+	if len(parts) > 1 {
+		sub = strings.ToLower(parts[1])
+	}
+
+	switch sub {
+	case "list":
+		all := len(parts) > 2 && parts[2] == "--all"
+		printMemories(mem.List(all), all)
+	case "add":
+		args := parts[2:]
+		global := len(args) > 0 && args[0] == "--global"
+		if global {
+			args = args[1:]
+		}
+		if len(args) == 0 {
+			fmt.Println(memoryUsage)
+			return
+		}
+		saved, err := mem.Remember(strings.Join(args, " "), global, nil)
+		if err != nil {
+			fmt.Printf("\033[31mError saving memory: %v\033[0m\n", err)
+			return
+		}
+		fmt.Printf("\033[32mRemembered:\033[0m %s %s\n", saved.ID, saved.Content)
+	case "edit":
+		args, all := memoryAllFlag(parts[2:])
+		if len(args) < 2 {
+			fmt.Println("Usage: /memory edit [--all] <id> <new text>")
+			return
+		}
+		edited, err := mem.Edit(args[0], strings.Join(args[1:], " "), all)
+		if err != nil {
+			fmt.Printf("\033[31mError editing memory: %v\033[0m\n", err)
+			return
+		}
+		fmt.Printf("\033[32mUpdated:\033[0m %s %s\n", edited.ID, edited.Content)
+	case "forget", "delete":
+		args, all := memoryAllFlag(parts[2:])
+		if len(args) < 1 {
+			fmt.Println("Usage: /memory forget [--all] <id>")
+			return
+		}
+		removed, err := mem.Forget(args[0], all)
+		if err != nil {
+			fmt.Printf("\033[31mError forgetting memory: %v\033[0m\n", err)
+			return
+		}
+		fmt.Printf("\033[32mForgot:\033[0m %s %s\n", removed.ID, removed.Content)
+	case "search":
+		if len(parts) < 3 {
+			fmt.Println("Usage: /memory search <query>")
+			return
+		}
+		found, err := mem.Recall(strings.Join(parts[2:], " "), 20)
+		if err != nil {
+			fmt.Printf("\033[31mError searching memory: %v\033[0m\n", err)
+		}
+		printMemories(found, false)
+	default:
+		fmt.Println(memoryUsage)
+		return
+	}
+
+	if sub != "list" && sub != "search" {
+		fmt.Println("Changes apply to the system prompt of new threads (/thread new or /clear).")
+	}
+}
+
+// memoryAllFlag strips a leading --all, which lets edit and forget reach
+// memories of other projects listed by /memory list --all.
+func memoryAllFlag(args []string) ([]string, bool) {
+	if len(args) > 0 && args[0] == "--all" {
+		return args[1:], true
+	}
+	return args, false
+}
+
+func printMemories(memories []chat.Memory, showProject bool) {
+	if len(memories) == 0 {
+		fmt.Println("No memories.")
+		return
+	}
+	for _, m := range memories {
+		scope := "project"
+		if m.Global() {
+			scope = "global"
+		}
+		line := fmt.Sprintf("%s [%s] %s", m.ID, scope, m.Content)
+		if len(m.Tags) > 0 {
+			line += " #" + strings.Join(m.Tags, " #")
+		}
+		if showProject && !m.Global() {
+			line += fmt.Sprintf(" project=%s", m.Project)
+		}
+		fmt.Println(line)
+	}
+}
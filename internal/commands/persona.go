@@ -0,0 +1,53 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bilbilaki/ai2go/internal/chat"
+	"github.com/bilbilaki/ai2go/internal/config"
+	"github.com/bilbilaki/ai2go/internal/prompt"
+	"github.com/bilbilaki/ai2go/internal/ui"
+)
+
+func handlePersonaCommand(parts []string, history *chat.History, store *chat.ThreadStore, cfg *config.Config) {
+	_, userDir := history.InstructionDirs()
+	current := history.Persona()
+	if current == "" {
+		current = prompt.DefaultPersona
+	}
+
+	if len(parts) < 2 || strings.EqualFold(parts[1], "list") {
+		fmt.Printf("Current persona: %s\n", ui.Name(current))
+		fmt.Println("Available personas:")
+		for _, p := range prompt.Personas(userDir) {
+			marker := "  "
+			if p.Name == current {
+				marker = "* "
+			}
+			source := "builtin"
+			if p.Path != "" {
+				source = p.Path
+			}
+			fmt.Printf("%s%s (%s)\n", marker, p.Name, source)
+		}
+		fmt.Printf("Add your own as %s/personas/<name>.tmpl (Go text/template).\n", userDir)
+		fmt.Println("Usage: /persona [list|<name>]")
+		return
+	}
+
+	name := strings.ToLower(parts[1])
+	if !prompt.Exists(name, userDir) {
+		fmt.Printf("\033[31mUnknown persona: %s\033[0m\n", name)
+		return
+	}
+	if _, err := prompt.Render(name, userDir, prompt.Data{}); err != nil {
+		fmt.Printf("\033[31mPersona template error: %v\033[0m\n", err)
+		return
+	}
+	if err := store.SetActivePersona(name, history, cfg.CurrentModel); err != nil {
+		fmt.Printf("\033[31mError saving persona: %v\033[0m\n", err)
+		return
+	}
+	fmt.Printf("\033[32mPersona for this thread set to:\033[0m %s\n", ui.Name(name))
+}
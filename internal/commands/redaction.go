@@ -0,0 +1,106 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bilbilaki/ai2go/internal/chat"
+	"github.com/bilbilaki/ai2go/internal/config"
+	"github.com/bilbilaki/ai2go/internal/redact"
+)
+
+const redactionUsage = "Usage: /redaction [status|on|off|add <regex>|remove <regex>]"
+
+// ConfigureRedaction applies the redaction settings from cfg to history,
+// using the store's persistent redactor so placeholders saved in threads
+// keep restoring across restarts.
+func ConfigureRedaction(history *chat.History, store *chat.ThreadStore, cfg *config.Config) error {
+	if cfg.DisableRedaction {
+		history.SetRedactor(nil)
+		return nil
+	}
+	r, err := store.Redactor(cfg.RedactPatterns)
+	if err != nil {
+		return err
+	}
+	history.SetRedactor(r)
+	return nil
+}
+
+func handleRedactionCommand(parts []string, history *chat.History, store *chat.ThreadStore, cfg *config.Config) {
+	sub := "status"
+	if len(parts) > 1 {
+		sub = strings.ToLower(parts[1])
+	}
+	pattern := strings.TrimSpace(strings.Join(parts[min(2, len(parts)):], " "))
+
+	switch sub {
+	case "status":
+		status := "ON"
+		if cfg.DisableRedaction {
+			status = "OFF"
+		}
+		fmt.Printf("Secret redaction: %s\n", status)
+		if r := history.Redactor(); r != nil {
+			fmt.Printf("Secrets known: %d", r.Count())
+			if kinds := r.SortedKinds(); len(kinds) > 0 {
+				fmt.Printf(" (%s)", strings.Join(kinds, ", "))
+			}
+			fmt.Println()
+			if err := r.Err(); err != nil {
+				fmt.Printf("\033[31mPlaceholders are not being saved: %v\033[0m\n", err)
+			}
+		}
+		if len(cfg.RedactPatterns) == 0 {
+			fmt.Println("Custom patterns: none")
+		} else {
+			fmt.Println("Custom patterns:")
+			for _, p := range cfg.RedactPatterns {
+				fmt.Printf("  %s\n", p)
+			}
+		}
+		return
+	case "on", "off":
+		wantOff := sub == "off"
+		if cfg.DisableRedaction != wantOff {
+			cfg.ToggleRedaction()
+		}
+	case "add":
+		if pattern == "" {
+			fmt.Println(redactionUsage)
+			return
+		}
+		for _, p := range cfg.RedactPatterns {
+			if p == pattern {
+				fmt.Println("Pattern already configured.")
+				return
+			}
+		}
+		if _, err := redact.New([]string{pattern}); err != nil {
+			fmt.Printf("\033[31mError: %v\033[0m\n", err)
+			return
+		}
+		cfg.SetRedactPatterns(append(cfg.RedactPatterns, pattern))
+	case "remove":
+		kept := make([]string, 0, len(cfg.RedactPatterns))
+		for _, p := range cfg.RedactPatterns {
+			if p != pattern {
+				kept = append(kept, p)
+			}
+		}
+		if len(kept) == len(cfg.RedactPatterns) {
+			fmt.Printf("Pattern not found: %s\n", pattern)
+			return
+		}
+		cfg.SetRedactPatterns(kept)
+	default:
+		fmt.Println(redactionUsage)
+		return
+	}
+
+	if err := ConfigureRedaction(history, store, cfg); err != nil {
+		fmt.Printf("\033[31mError applying redaction settings: %v\033[0m\n", err)
+		return
+	}
+	fmt.Println("\033[32mRedaction settings updated.\033[0m")
+}
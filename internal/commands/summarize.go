@@ -13,7 +13,7 @@ import (
 
 func HandleSummarizeCommand(parts []string, history *chat.History, store *chat.ThreadStore, cfg *config.Config, apiClient *api.Client) {
 	if len(parts) == 1 || strings.EqualFold(parts[1], "now") {
-		if err := runSummary(context.Background(), history, store, cfg, apiClient, "manual"); err != nil {
+		if err := runSummary(context.Background(), history, store, cfg, apiClient, "manual", cfg.SummaryKeepTurns); err != nil {
 			fmt.Printf("\033[31mError generating summary: %v\033[0m\n", err)
 		}
 		return
@@ -24,7 +24,7 @@ func HandleSummarizeCommand(parts []string, history *chat.History, store *chat.T
 		return
 	}
 
-	fmt.Println("Usage: /summarize [now|auto on|auto off|auto status|auto threshold <tokens>]")
+	fmt.Println("Usage: /summarize [now|auto on|auto off|auto status|auto threshold <tokens>|auto keep <turns>]")
 }
 
 func handleSummarizeAuto(args []string, cfg *config.Config) {
@@ -33,7 +33,7 @@ func handleSummarizeAuto(args []string, cfg *config.Config) {
 		if cfg.AutoSummarize {
 			status = "ON"
 		}
-		fmt.Printf("Auto summarize: %s (threshold=%d tokens)\n", status, cfg.AutoSummaryThreshold)
+		fmt.Printf("Auto summarize: %s (threshold=%d tokens, keep last %d turns verbatim)\n", status, cfg.AutoSummaryThreshold, cfg.SummaryKeepTurns)
 		return
 	}
 
@@ -60,8 +60,20 @@ func handleSummarizeAuto(args []string, cfg *config.Config) {
 		}
 		cfg.SetAutoSummaryThreshold(n)
 		fmt.Printf("Auto summarize threshold set to %d tokens.\n", cfg.AutoSummaryThreshold)
+	case "keep":
+		if len(args) < 2 {
+			fmt.Println("Usage: /summarize auto keep <turns>")
+			return
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(args[1]))
+		if err != nil || n < 1 {
+			fmt.Println("Invalid turn count. Use an integer >= 1.")
+			return
+		}
+		cfg.SetSummaryKeepTurns(n)
+		fmt.Printf("Rolling summary keeps the last %d turns verbatim.\n", cfg.SummaryKeepTurns)
 	default:
-		fmt.Println("Usage: /summarize auto [on|off|status|threshold <tokens>]")
+		fmt.Println("Usage: /summarize auto [on|off|status|threshold <tokens>|keep <turns>]")
 	}
 }
 
@@ -72,31 +84,54 @@ func TryAutoSummarize(history *chat.History, store *chat.ThreadStore, cfg *confi
 	if !cfg.AutoSummarize {
 		return
 	}
-	if history.GetTotalTokens() < int64(cfg.AutoSummaryThreshold) {
+	threshold := int64(cfg.AutoSummaryThreshold)
+	if history.GetTotalTokens() < threshold {
 		return
 	}
 
+	// Keep fewer turns verbatim when they alone exceed the threshold, and
+	// wait for the next turn when even the latest one does.
+	keep := history.FittingKeepTurns(cfg.SummaryKeepTurns, threshold)
+	if _, _, ok := history.CompactionRange(keep, 0); !ok {
+		fmt.Printf("\n\033[33m[Auto Summary] The latest turn alone exceeds the threshold (%d tokens); it will be compacted after the next turn.\033[0m\n", cfg.AutoSummaryThreshold)
+		return
+	}
 	fmt.Printf("\n\033[33m[Auto Summary] Token threshold reached (%d >= %d). Compressing thread...\033[0m\n", history.GetTotalTokens(), cfg.AutoSummaryThreshold)
-	if err := runSummary(context.Background(), history, store, cfg, apiClient, "auto"); err != nil {
+	if keep < cfg.SummaryKeepTurns {
+		fmt.Printf("\033[33m[Auto Summary] Keeping only the last %d turn(s) verbatim; the last %d exceed the threshold.\033[0m\n", keep, cfg.SummaryKeepTurns)
+	}
+	if err := runSummary(context.Background(), history, store, cfg, apiClient, "auto", keep); err != nil {
 		fmt.Printf("\033[31m[Auto Summary] Failed: %v\033[0m\n", err)
 	}
 }
 
-func runSummary(ctx context.Context, history *chat.History, store *chat.ThreadStore, cfg *config.Config, apiClient *api.Client, mode string) error {
+func runSummary(ctx context.Context, history *chat.History, store *chat.ThreadStore, cfg *config.Config, apiClient *api.Client, mode string, keepTurns int) error {
 	if ctx == nil {
 		ctx = context.Background()
 	}
-	fmt.Println("\n\033[33mGenerating session summary (multi-pass)...\033[0m")
-	result, err := chat.SummarizeHistoryMultiPass(ctx, history, apiClient, cfg.CurrentModel)
+	fmt.Println("\n\033[33mCompacting older turns into the rolling summary...\033[0m")
+	// The verbatim tail may use up to a quarter of the auto-summary budget
+	// beyond the guaranteed last N turns.
+	opts := chat.RollingOptions{
+		KeepTurns:  keepTurns,
+		KeepTokens: int64(cfg.AutoSummaryThreshold / 4),
+	}
+	result, err := chat.SummarizeRolling(ctx, history, apiClient, cfg.CurrentModel, opts)
 	if err != nil {
 		return err
 	}
 
-	history.ReplaceWithSummary(cfg.CurrentModel, result.Summary)
+	compacted := history.ApplyRollingSummary(result.Record, result.Cut)
+	if err := store.KeepCompactedMessages(compacted); err != nil {
+		return fmt.Errorf("summary created but failed to keep original messages: %w", err)
+	}
 	if err := store.SyncActiveHistory(history); err != nil {
 		return fmt.Errorf("summary created but failed to persist thread: %w", err)
 	}
 
-	fmt.Printf("\033[32mHistory summarized (%s mode). passes=%d chunks=%d\033[0m\n", mode, result.Passes, result.Chunks)
+	fmt.Printf("\033[32mHistory summarized (%s mode). compacted=%d messages, chunks=%d, tokens now=%d\033[0m\n", mode, result.Compacted, result.Chunks, history.GetTotalTokens())
+	if result.Unparsed > 0 {
+		fmt.Printf("\033[33m%d chunk(s) did not return valid JSON and were kept as notes.\033[0m\n", result.Unparsed)
+	}
 	return nil
 }
@@ -1,39 +1,81 @@
 package commands
 
 import (
+	"bufio"
 	"fmt"
+	"os"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/bilbilaki/ai2go/internal/api"
 	"github.com/bilbilaki/ai2go/internal/chat"
 	"github.com/bilbilaki/ai2go/internal/config"
 	"github.com/bilbilaki/ai2go/internal/ui"
 )
 
+const (
+	threadUsage = "Usage: /thread [new|open|rename|current|show|tag|untag|pin|unpin|archive|unarchive|delete|restore] ..."
+
+	showPreviewChars = 300
+)
+
 func handleThreadsList(parts []string, store *chat.ThreadStore) {
-	query, sortBy, order := parseQuerySortOrder(parts[1:])
-	threads := store.ListThreads(query, sortBy, order)
+	filter, all, sortBy, order := parseThreadListArgs(parts[1:])
+	if !all {
+		filter.Project = store.Project().Key()
+	}
+	threads := store.ListThreads(filter, sortBy, order)
 	if len(threads) == 0 {
 		fmt.Println("No threads matched.")
 		return
 	}
 
 	activeID := store.ActiveThreadID()
-	fmt.Printf("Threads (%d):\n", len(threads))
+	label := "Threads"
+	switch {
+	case filter.Trash:
+		label = "Trash"
+	case filter.Archived:
+		label = "Archived threads"
+	}
+	scope := "all projects"
+	if !all {
+		scope = store.Project().Key()
+	}
+	fmt.Printf("%s (%d) [%s]:\n", label, len(threads), scope)
 	for i, t := range threads {
 		marker := " "
 		if t.ID == activeID {
 			marker = "*"
 		}
-		fmt.Printf("%s %2d. %s\n", marker, i+1, ui.Thread(t.Title))
-		fmt.Printf("    id=%s messages=%d updated=%s\n", t.ID, len(t.Messages), t.UpdatedAt.Local().Format(time.RFC822))
+		pin := ""
+		if t.Pinned {
+			pin = "[pinned] "
+		}
+		fmt.Printf("%s %2d. %s%s\n", marker, i+1, pin, ui.Thread(t.Title))
+		details := fmt.Sprintf("    id=%s messages=%d updated=%s", t.ID, len(t.Messages), t.UpdatedAt.Local().Format(time.RFC822))
+		if len(t.Tags) > 0 {
+			details += " tags=" + strings.Join(t.Tags, ",")
+		}
+		if all {
+			projectKey := t.ProjectKey()
+			if projectKey == "" {
+				projectKey = "(none)"
+			}
+			details += " project=" + projectKey
+		}
+		if t.InTrash() {
+			purgeAt := t.DeletedAt.Add(chat.TrashRetention)
+			details += " purge=" + purgeAt.Local().Format(time.RFC822)
+		}
+		fmt.Println(details)
 	}
 }
 
 func handleThreadCommand(parts []string, history *chat.History, store *chat.ThreadStore, cfg *config.Config) {
 	if len(parts) < 2 {
-		fmt.Println("Usage: /thread [new|open|rename|current] ...")
+		fmt.Println(threadUsage)
 		return
 	}
 
@@ -74,11 +116,160 @@ func handleThreadCommand(parts []string, history *chat.History, store *chat.Thre
 		fmt.Printf("\033[32mRenamed thread:\033[0m %s (%s)\n", ui.Thread(thread.Title), thread.ID)
 	case "current":
 		fmt.Printf("Current thread: %s (%s)\n", ui.Thread(store.ActiveThreadTitle()), store.ActiveThreadID())
+	case "tag", "untag":
+		if len(parts) < 4 {
+			fmt.Printf("Usage: /thread %s <id|current> <tag> [tag...]\n", sub)
+			return
+		}
+		var thread *chat.Thread
+		var err error
+		if sub == "tag" {
+			thread, err = store.TagThread(parts[2], parts[3:])
+		} else {
+			thread, err = store.UntagThread(parts[2], parts[3:])
+		}
+		if err != nil {
+			fmt.Printf("\033[31mError updating tags: %v\033[0m\n", err)
+			return
+		}
+		tags := "(none)"
+		if len(thread.Tags) > 0 {
+			tags = strings.Join(thread.Tags, ", ")
+		}
+		fmt.Printf("\033[32mTags for\033[0m %s: %s\n", ui.Thread(thread.Title), tags)
+	case "pin", "unpin":
+		if len(parts) < 3 {
+			fmt.Printf("Usage: /thread %s <id|current>\n", sub)
+			return
+		}
+		thread, err := store.SetPinned(parts[2], sub == "pin")
+		if err != nil {
+			fmt.Printf("\033[31mError updating thread: %v\033[0m\n", err)
+			return
+		}
+		verb := "Pinned"
+		if !thread.Pinned {
+			verb = "Unpinned"
+		}
+		fmt.Printf("\033[32m%s thread:\033[0m %s (%s)\n", verb, ui.Thread(thread.Title), thread.ID)
+	case "archive", "unarchive":
+		if len(parts) < 3 {
+			fmt.Printf("Usage: /thread %s <id|current>\n", sub)
+			return
+		}
+		thread, err := store.SetArchived(parts[2], sub == "archive")
+		if err != nil {
+			fmt.Printf("\033[31mError updating thread: %v\033[0m\n", err)
+			return
+		}
+		verb := "Archived"
+		if !thread.Archived {
+			verb = "Unarchived"
+		}
+		fmt.Printf("\033[32m%s thread:\033[0m %s (%s)\n", verb, ui.Thread(thread.Title), thread.ID)
+	case "delete":
+		if len(parts) < 3 {
+			fmt.Println("Usage: /thread delete <id|current> [-y]")
+			return
+		}
+		target, err := store.ResolveThread(parts[2])
+		if err != nil {
+			fmt.Printf("\033[31mError deleting thread: %v\033[0m\n", err)
+			return
+		}
+		if !(len(parts) > 3 && parts[3] == "-y") {
+			fmt.Printf("Move thread %s (%s, %d messages) to trash? (y/n): ", ui.Thread(target.Title), target.ID, len(target.Messages))
+			reader := bufio.NewReader(os.Stdin)
+			answer, _ := reader.ReadString('\n')
+			if strings.ToLower(strings.TrimSpace(answer)) != "y" {
+				fmt.Println("Delete cancelled.")
+				return
+			}
+		}
+		thread, err := store.DeleteThread(target.ID, history, cfg.CurrentModel)
+		if err != nil {
+			fmt.Printf("\033[31mError deleting thread: %v\033[0m\n", err)
+			return
+		}
+		days := int(chat.TrashRetention.Hours() / 24)
+		fmt.Printf("\033[32mMoved to trash:\033[0m %s (%s). Restore with /thread restore %s within %d days.\n", ui.Thread(thread.Title), thread.ID, thread.ID, days)
+		if thread.ID != store.ActiveThreadID() {
+			fmt.Printf("Current thread: %s (%s)\n", ui.Thread(store.ActiveThreadTitle()), store.ActiveThreadID())
+		}
+	case "restore":
+		if len(parts) < 3 {
+			fmt.Println("Usage: /thread restore <id>")
+			return
+		}
+		thread, err := store.RestoreThread(parts[2])
+		if err != nil {
+			fmt.Printf("\033[31mError restoring thread: %v\033[0m\n", err)
+			return
+		}
+		fmt.Printf("\033[32mRestored thread:\033[0m %s (%s)\n", ui.Thread(thread.Title), thread.ID)
+	case "show":
+		handleThreadShow(parts[2:], store)
 	default:
-		fmt.Println("Usage: /thread [new|open|rename|current] ...")
+		fmt.Println(threadUsage)
+	}
+}
+
+// handleThreadShow prints a thread's transcript, including the original
+// messages that rolling summarization folded into the summary.
+func handleThreadShow(args []string, store *chat.ThreadStore) {
+	identifier, full := "current", false
+	for _, arg := range args {
+		if arg == "--full" {
+			full = true
+		} else {
+			identifier = arg
+		}
+	}
+	thread, err := store.ResolveThread(identifier)
+	if err != nil {
+		fmt.Printf("\033[31mError: %v\033[0m\n", err)
+		return
+	}
+
+	fmt.Printf("Thread: %s (%s)\n", ui.Thread(thread.Title), thread.ID)
+	if len(thread.Compacted) > 0 {
+		fmt.Printf("\033[90m--- %d earlier message(s), compacted into the summary ---\033[0m\n", len(thread.Compacted))
+		printTranscript(thread.Compacted, full)
+		fmt.Println("\033[90m--- current context ---\033[0m")
+	}
+	printTranscript(thread.Messages, full)
+	if !full {
+		fmt.Println("Use --full to show complete message contents.")
 	}
 }
 
+func printTranscript(messages []api.Message, full bool) {
+	for _, msg := range messages {
+		content := strings.TrimSpace(msg.Content)
+		if msg.Role == "system" && !chat.IsSummaryMessage(msg) {
+			continue
+		}
+		if !full {
+			content = truncateRunes(strings.Join(strings.Fields(content), " "), showPreviewChars)
+		}
+		for _, tc := range msg.ToolCalls {
+			content = strings.TrimSpace(content + fmt.Sprintf(" [tool call: %s]", tc.Function.Name))
+		}
+		if content == "" {
+			continue
+		}
+		fmt.Printf("%s: %s\n", strings.ToUpper(msg.Role), content)
+	}
+}
+
+func truncateRunes(s string, max int) string {
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	return string(runes[:max]) + "..."
+}
+
 func handleSearch(parts []string, store *chat.ThreadStore) {
 	if len(parts) < 2 {
 		fmt.Println("Usage: /search <query> [--sort=updated|title|role|index] [--order=asc|desc]")
@@ -112,6 +303,32 @@ func handleSearch(parts []string, store *chat.ThreadStore) {
 	}
 }
 
+func parseThreadListArgs(args []string) (filter chat.ThreadFilter, all bool, sortBy string, order string) {
+	rest := make([]string, 0, len(args))
+	for _, arg := range args {
+		switch {
+		case arg == "--all":
+			all = true
+		case arg == "--pinned":
+			filter.Pinned = true
+		case arg == "--archived":
+			filter.Archived = true
+		case arg == "--trash":
+			filter.Trash = true
+		case strings.HasPrefix(arg, "--tag="):
+			for _, tag := range strings.Split(strings.TrimPrefix(arg, "--tag="), ",") {
+				if strings.TrimSpace(tag) != "" {
+					filter.Tags = append(filter.Tags, tag)
+				}
+			}
+		default:
+			rest = append(rest, arg)
+		}
+	}
+	filter.Query, sortBy, order = parseQuerySortOrder(rest)
+	return filter, all, sortBy, order
+}
+
 func parseQuerySortOrder(args []string) (query string, sortBy string, order string) {
 	queryParts := make([]string, 0, len(args))
 	for _, arg := range args {
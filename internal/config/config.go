@@ -1,24 +1,58 @@
 package config
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"runtime"
+	"strings"
+	"time"
+
+	"github.com/bilbilaki/ai2go/internal/secure"
 )
 
 type Config struct {
-	APIKey               string `json:"api_key"`
-	BaseURL              string `json:"base_url"`
-	ProxyURL             string `json:"proxy_url"`
-	TimeoutSeconds       int    `json:"timeout_seconds"`
-	AutoAccept           bool   `json:"auto_accept"`
-	SubagentExperimental bool   `json:"subagent_experimental"`
-	AutoSummarize        bool   `json:"auto_summarize"`
-	AutoSummaryThreshold int    `json:"auto_summary_threshold"`
-	CurrentModel         string `json:"current_model"`
-	FirstSetup           bool   `json:"first_setup"`
+	APIKey                string      `json:"api_key"`
+	BaseURL               string      `json:"base_url"`
+	ProxyURL              string      `json:"proxy_url"`
+	TimeoutSeconds        int         `json:"timeout_seconds"`
+	AutoAccept            bool        `json:"auto_accept"`
+	SubagentExperimental  bool        `json:"subagent_experimental"`
+	AutoSummarize         bool        `json:"auto_summarize"`
+	AutoSummaryThreshold  int         `json:"auto_summary_threshold"`
+	SummaryKeepTurns      int         `json:"summary_keep_turns"`
+	CurrentModel          string      `json:"current_model"`
+	FirstSetup            bool        `json:"first_setup"`
+	EncryptAtRest         bool        `json:"encrypt_at_rest,omitempty"`
+	KeyFile               string      `json:"key_file,omitempty"`
+	APIKeyEnv             string      `json:"api_key_env,omitempty"`
+	APIKeyCommand         string      `json:"api_key_command,omitempty"`
+	DisableRedaction      bool        `json:"disable_redaction,omitempty"`
+	RedactPatterns        []string    `json:"redact_patterns,omitempty"`
+	DisableLSP            bool        `json:"disable_lsp,omitempty"`
+	LSPServers            []LSPServer `json:"lsp_servers,omitempty"`
+	DisableAutoCheckpoint bool        `json:"disable_auto_checkpoint,omitempty"`
+	CheckpointMaxFileMB   int         `json:"checkpoint_max_file_mb,omitempty"`
+	CheckpointKeepDays    int         `json:"checkpoint_keep_days,omitempty"`
+	CheckpointKeepCount   int         `json:"checkpoint_keep_count,omitempty"`
+	BackupKeepPerFile     int         `json:"backup_keep_per_file,omitempty"`
+	BackupKeepDays        int         `json:"backup_keep_days,omitempty"`
+	BackupMaxMB           int         `json:"backup_max_mb,omitempty"`
+
+	vault *secure.Vault
+}
+
+// LSPServer adds or overrides (by name) a language server used for
+// diagnostics after edits. Languages maps file extensions to LSP language ids.
+type LSPServer struct {
+	Name        string            `json:"name"`
+	Command     string            `json:"command"`
+	Args        []string          `json:"args,omitempty"`
+	Languages   map[string]string `json:"languages"`
+	RootMarkers []string          `json:"root_markers,omitempty"`
 }
 
 const (
@@ -27,9 +61,18 @@ const (
 	defaultModel                = ""
 	defaultTimeoutSeconds       = 120
 	defaultAutoSummaryThreshold = 16000
+	defaultSummaryKeepTurns     = 4
+	defaultCheckpointMaxFileMB  = 5
+	defaultCheckpointKeepDays   = 14
+	defaultCheckpointKeepCount  = 200
+	defaultBackupKeepPerFile    = 20
+	defaultBackupKeepDays       = 30
+	defaultBackupMaxMB          = 200
+	secretCommandTimeout        = 15 * time.Second
 )
 
-func getConfigPath() (string, error) {
+// Dir returns the ai2go config directory, creating it if needed.
+func Dir() (string, error) {
 	var dir string
 
 	switch runtime.GOOS {
@@ -68,13 +111,28 @@ func getConfigPath() (string, error) {
 	if err := os.MkdirAll(configDir, 0755); err != nil {
 		return "", fmt.Errorf("failed to create config dir: %w", err)
 	}
-	return filepath.Join(configDir, configFile), nil
+	return configDir, nil
+}
+
+func getConfigPath() (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, configFile), nil
 }
 func Load() *Config {
 	cfg := &Config{
 		FirstSetup:           true,
 		TimeoutSeconds:       defaultTimeoutSeconds,
 		AutoSummaryThreshold: defaultAutoSummaryThreshold,
+		SummaryKeepTurns:     defaultSummaryKeepTurns,
+		CheckpointMaxFileMB:  defaultCheckpointMaxFileMB,
+		CheckpointKeepDays:   defaultCheckpointKeepDays,
+		CheckpointKeepCount:  defaultCheckpointKeepCount,
+		BackupKeepPerFile:    defaultBackupKeepPerFile,
+		BackupKeepDays:       defaultBackupKeepDays,
+		BackupMaxMB:          defaultBackupMaxMB,
 	}
 
 	configPath, err := getConfigPath()
@@ -102,6 +160,30 @@ func Load() *Config {
 	if cfg.TimeoutSeconds <= 0 {
 		cfg.TimeoutSeconds = defaultTimeoutSeconds
 	}
+	if cfg.SummaryKeepTurns <= 0 {
+		cfg.SummaryKeepTurns = defaultSummaryKeepTurns
+	}
+	if cfg.CheckpointMaxFileMB == 0 {
+		cfg.CheckpointMaxFileMB = defaultCheckpointMaxFileMB
+	}
+	if cfg.CheckpointKeepDays <= 0 {
+		cfg.CheckpointKeepDays = defaultCheckpointKeepDays
+	}
+	if cfg.CheckpointKeepCount <= 0 {
+		cfg.CheckpointKeepCount = defaultCheckpointKeepCount
+	}
+	if cfg.BackupKeepPerFile <= 0 {
+		cfg.BackupKeepPerFile = defaultBackupKeepPerFile
+	}
+	if cfg.BackupKeepDays <= 0 {
+		cfg.BackupKeepDays = defaultBackupKeepDays
+	}
+	if cfg.BackupMaxMB == 0 {
+		cfg.BackupMaxMB = defaultBackupMaxMB
+	}
+	if err := cfg.resolveExternalAPIKey(); err != nil {
+		fmt.Printf("Warning: %v\n", err)
+	}
 
 	return cfg
 }
@@ -111,15 +193,140 @@ func (c *Config) Save() error {
 	if err != nil {
 		return err
 	}
-	data, err := json.MarshalIndent(c, "", "  ")
+
+	onDisk := *c
+	switch {
+	case c.APIKeyEnv != "" || c.APIKeyCommand != "":
+		// The key is resolved at startup; never persist it.
+		onDisk.APIKey = ""
+	case c.vault != nil && c.APIKey != "" && !secure.IsSealedString(c.APIKey):
+		sealed, err := c.vault.SealString(c.APIKey)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt api key: %w", err)
+		}
+		onDisk.APIKey = sealed
+	}
+
+	data, err := json.MarshalIndent(&onDisk, "", "  ")
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(configPath, data, 0644)
+	if err := os.WriteFile(configPath, data, 0600); err != nil {
+		return err
+	}
+	// WriteFile keeps the mode of an existing file; tighten configs written
+	// by older versions with 0644.
+	return os.Chmod(configPath, 0600)
+}
+
+// Vault returns the unlocked vault, or nil when encryption is off or locked.
+func (c *Config) Vault() *secure.Vault {
+	return c.vault
+}
+
+// AttachVault sets the vault used to encrypt secret fields on Save and
+// decrypts any fields that were loaded in sealed form. Passing nil makes the
+// next Save write secrets in plaintext.
+func (c *Config) AttachVault(v *secure.Vault) error {
+	c.vault = v
+	if v != nil && secure.IsSealedString(c.APIKey) {
+		key, err := v.OpenString(c.APIKey)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt api key: %w", err)
+		}
+		c.APIKey = key
+	}
+	return nil
+}
+
+// APIKeySource describes where the API key comes from, for display.
+func (c *Config) APIKeySource() string {
+	switch {
+	case c.APIKeyEnv != "":
+		return "env " + c.APIKeyEnv
+	case c.APIKeyCommand != "":
+		return "command " + c.APIKeyCommand
+	case c.vault != nil || secure.IsSealedString(c.APIKey):
+		return "config (encrypted)"
+	default:
+		return "config"
+	}
+}
+
+// SetAPIKeySource switches the API key source to "env", "command" or
+// "config" and reloads the key from it. For "config", value is the key
+// itself; the key last read from an env var or command is never kept, so
+// switching does not write it to disk.
+func (c *Config) SetAPIKeySource(kind, value string) error {
+	value = strings.TrimSpace(value)
+	switch kind {
+	case "env":
+		if value == "" {
+			return fmt.Errorf("environment variable name is required")
+		}
+		c.APIKeyEnv, c.APIKeyCommand = value, ""
+	case "command":
+		if value == "" {
+			return fmt.Errorf("command is required")
+		}
+		c.APIKeyEnv, c.APIKeyCommand = "", value
+	case "config":
+		if value == "" {
+			return fmt.Errorf("api key is required")
+		}
+		c.APIKeyEnv, c.APIKeyCommand, c.APIKey = "", "", value
+	default:
+		return fmt.Errorf("unknown api key source %q", kind)
+	}
+	if err := c.resolveExternalAPIKey(); err != nil {
+		return err
+	}
+	return c.Save()
+}
+
+func (c *Config) resolveExternalAPIKey() error {
+	switch {
+	case c.APIKeyEnv != "":
+		key := strings.TrimSpace(os.Getenv(c.APIKeyEnv))
+		if key == "" {
+			return fmt.Errorf("api key env var %s is empty", c.APIKeyEnv)
+		}
+		c.APIKey = key
+	case c.APIKeyCommand != "":
+		key, err := runSecretCommand(c.APIKeyCommand)
+		if err != nil {
+			return fmt.Errorf("api key command failed: %w", err)
+		}
+		c.APIKey = key
+	}
+	return nil
+}
+
+func runSecretCommand(command string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), secretCommandTimeout)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", err
+	}
+	key := strings.TrimSpace(string(out))
+	if key == "" {
+		return "", fmt.Errorf("command produced no output")
+	}
+	return key, nil
 }
 
 func (c *Config) SetAPIKey(key string) {
 	c.APIKey = key
+	c.APIKeyEnv, c.APIKeyCommand = "", ""
 	if err := c.Save(); err != nil {
 		fmt.Printf("Error saving config: %v\n", err)
 	}
@@ -167,6 +374,87 @@ func (c *Config) ToggleAutoSummarize() {
 	}
 }
 
+func (c *Config) SetSummaryKeepTurns(turns int) {
+	if turns <= 0 {
+		turns = defaultSummaryKeepTurns
+	}
+	c.SummaryKeepTurns = turns
+	if err := c.Save(); err != nil {
+		fmt.Printf("Error saving config: %v\n", err)
+	}
+}
+
+func (c *Config) ToggleRedaction() {
+	c.DisableRedaction = !c.DisableRedaction
+	if err := c.Save(); err != nil {
+		fmt.Printf("Error saving config: %v\n", err)
+	}
+}
+
+func (c *Config) ToggleLSP() {
+	c.DisableLSP = !c.DisableLSP
+	if err := c.Save(); err != nil {
+		fmt.Printf("Error saving config: %v\n", err)
+	}
+}
+
+func (c *Config) ToggleAutoCheckpoint() {
+	c.DisableAutoCheckpoint = !c.DisableAutoCheckpoint
+	if err := c.Save(); err != nil {
+		fmt.Printf("Error saving config: %v\n", err)
+	}
+}
+
+// SetCheckpointMaxFileMB sets the size limit for files copied into editor
+// checkpoints; a negative value disables the limit.
+func (c *Config) SetCheckpointMaxFileMB(mb int) {
+	if mb == 0 {
+		mb = defaultCheckpointMaxFileMB
+	}
+	c.CheckpointMaxFileMB = mb
+	if err := c.Save(); err != nil {
+		fmt.Printf("Error saving config: %v\n", err)
+	}
+}
+
+func (c *Config) SetCheckpointRetention(days, count int) {
+	if days <= 0 {
+		days = defaultCheckpointKeepDays
+	}
+	if count <= 0 {
+		count = defaultCheckpointKeepCount
+	}
+	c.CheckpointKeepDays, c.CheckpointKeepCount = days, count
+	if err := c.Save(); err != nil {
+		fmt.Printf("Error saving config: %v\n", err)
+	}
+}
+
+// SetBackupRetention sets how many versions per file, for how many days and
+// how many MB of file backups are kept; a negative maxMB disables the quota.
+func (c *Config) SetBackupRetention(perFile, days, maxMB int) {
+	if perFile <= 0 {
+		perFile = defaultBackupKeepPerFile
+	}
+	if days <= 0 {
+		days = defaultBackupKeepDays
+	}
+	if maxMB == 0 {
+		maxMB = defaultBackupMaxMB
+	}
+	c.BackupKeepPerFile, c.BackupKeepDays, c.BackupMaxMB = perFile, days, maxMB
+	if err := c.Save(); err != nil {
+		fmt.Printf("Error saving config: %v\n", err)
+	}
+}
+
+func (c *Config) SetRedactPatterns(patterns []string) {
+	c.RedactPatterns = patterns
+	if err := c.Save(); err != nil {
+		fmt.Printf("Error saving config: %v\n", err)
+	}
+}
+
 func (c *Config) SetAutoSummaryThreshold(tokens int) {
 	if tokens <= 0 {
 		tokens = defaultAutoSummaryThreshold
@@ -0,0 +1,238 @@
+// Package diff computes line diffs and three-way merges.
+package diff
+
+import "fmt"
+
+// OpKind is the kind of an Op.
+type OpKind int
+
+const (
+	Equal OpKind = iota
+	Delete
+	Insert
+)
+
+// Op is a run of lines: a[A0:A1] is deleted, b[B0:B1] inserted, or the two
+// ranges are equal.
+type Op struct {
+	Kind   OpKind
+	A0, A1 int
+	B0, B1 int
+}
+
+// Algorithm selects how Compute aligns lines.
+type Algorithm string
+
+const (
+	// Myers finds a minimal edit script (the default).
+	Myers Algorithm = "myers"
+	// Patience anchors on lines unique to both sides before falling back to
+	// Myers, which keeps moved or repeated blocks (braces, blank lines) from
+	// being matched out of order.
+	Patience Algorithm = "patience"
+)
+
+// ParseAlgorithm maps a user-supplied name to an Algorithm; empty means Myers.
+func ParseAlgorithm(name string) (Algorithm, error) {
+	switch Algorithm(name) {
+	case "", Myers:
+		return Myers, nil
+	case Patience:
+		return Patience, nil
+	}
+	return "", fmt.Errorf("unsupported diff algorithm %q (use myers or patience)", name)
+}
+
+// Lines returns the operations turning a into b, computed with Myers'
+// linear-space O(ND) algorithm. Deletes come before inserts in each change.
+func Lines(a, b []string) []Op {
+	return Compute(a, b, Myers)
+}
+
+// Compute returns the operations turning a into b using alg.
+func Compute(a, b []string, alg Algorithm) []Op {
+	x, y := intern(a, b)
+	d := &differ{x: x, y: y, delA: make([]bool, len(x)), insB: make([]bool, len(y))}
+	if alg == Patience {
+		d.patience(0, len(x), 0, len(y))
+	} else {
+		d.compare(0, len(x), 0, len(y))
+	}
+	return opsFromMarks(d.delA, d.insB)
+}
+
+// intern maps lines to small ints so comparisons are cheap.
+func intern(a, b []string) ([]int, []int) {
+	ids := map[string]int{}
+	conv := func(lines []string) []int {
+		out := make([]int, len(lines))
+		for i, l := range lines {
+			id, ok := ids[l]
+			if !ok {
+				id = len(ids)
+				ids[l] = id
+			}
+			out[i] = id
+		}
+		return out
+	}
+	return conv(a), conv(b)
+}
+
+type differ struct {
+	x, y       []int
+	delA, insB []bool
+}
+
+func (d *differ) compare(xoff, xlim, yoff, ylim int) {
+	for xoff < xlim && yoff < ylim && d.x[xoff] == d.y[yoff] {
+		xoff++
+		yoff++
+	}
+	for xlim > xoff && ylim > yoff && d.x[xlim-1] == d.y[ylim-1] {
+		xlim--
+		ylim--
+	}
+	if xoff == xlim || yoff == ylim {
+		d.markAll(xoff, xlim, yoff, ylim)
+		return
+	}
+	xmid, ymid, ok := bisect(d.x[xoff:xlim], d.y[yoff:ylim])
+	if !ok {
+		d.markAll(xoff, xlim, yoff, ylim)
+		return
+	}
+	d.compare(xoff, xoff+xmid, yoff, yoff+ymid)
+	d.compare(xoff+xmid, xlim, yoff+ymid, ylim)
+}
+
+func (d *differ) markAll(xoff, xlim, yoff, ylim int) {
+	for i := xoff; i < xlim; i++ {
+		d.delA[i] = true
+	}
+	for j := yoff; j < ylim; j++ {
+		d.insB[j] = true
+	}
+}
+
+// bisect finds the middle snake of a and b, returning a split point that
+// divides the problem into two independent halves. ok is false when the
+// sequences share nothing worth splitting on.
+func bisect(a, b []int) (int, int, bool) {
+	n, m := len(a), len(b)
+	maxD := (n + m + 1) / 2
+	vOffset := maxD
+	vLen := 2*maxD + 2
+	v1 := make([]int, vLen)
+	v2 := make([]int, vLen)
+	for i := range v1 {
+		v1[i], v2[i] = -1, -1
+	}
+	v1[vOffset+1], v2[vOffset+1] = 0, 0
+	delta := n - m
+	front := delta%2 != 0
+	k1start, k1end, k2start, k2end := 0, 0, 0, 0
+
+	for d := 0; d < maxD; d++ {
+		for k1 := -d + k1start; k1 <= d-k1end; k1 += 2 {
+			k1Offset := vOffset + k1
+			var x1 int
+			if k1 == -d || (k1 != d && v1[k1Offset-1] < v1[k1Offset+1]) {
+				x1 = v1[k1Offset+1]
+			} else {
+				x1 = v1[k1Offset-1] + 1
+			}
+			y1 := x1 - k1
+			for x1 < n && y1 < m && a[x1] == b[y1] {
+				x1++
+				y1++
+			}
+			v1[k1Offset] = x1
+			switch {
+			case x1 > n:
+				k1end += 2
+			case y1 > m:
+				k1start += 2
+			case front:
+				k2Offset := vOffset + delta - k1
+				if k2Offset >= 0 && k2Offset < vLen && v2[k2Offset] != -1 && x1 >= n-v2[k2Offset] {
+					return splitPoint(x1, y1, n, m)
+				}
+			}
+		}
+
+		for k2 := -d + k2start; k2 <= d-k2end; k2 += 2 {
+			k2Offset := vOffset + k2
+			var x2 int
+			if k2 == -d || (k2 != d && v2[k2Offset-1] < v2[k2Offset+1]) {
+				x2 = v2[k2Offset+1]
+			} else {
+				x2 = v2[k2Offset-1] + 1
+			}
+			y2 := x2 - k2
+			for x2 < n && y2 < m && a[n-x2-1] == b[m-y2-1] {
+				x2++
+				y2++
+			}
+			v2[k2Offset] = x2
+			switch {
+			case x2 > n:
+				k2end += 2
+			case y2 > m:
+				k2start += 2
+			case !front:
+				k1Offset := vOffset + delta - k2
+				if k1Offset >= 0 && k1Offset < vLen && v1[k1Offset] != -1 {
+					x1 := v1[k1Offset]
+					y1 := vOffset + x1 - k1Offset
+					if x1 >= n-x2 {
+						return splitPoint(x1, y1, n, m)
+					}
+				}
+			}
+		}
+	}
+	return 0, 0, false
+}
+
+// splitPoint rejects splits that would not shrink the problem.
+func splitPoint(x, y, n, m int) (int, int, bool) {
+	if (x == 0 && y == 0) || (x == n && y == m) {
+		return 0, 0, false
+	}
+	return x, y, true
+}
+
+func opsFromMarks(delA, insB []bool) []Op {
+	var ops []Op
+	add := func(kind OpKind, a0, a1, b0, b1 int) {
+		if a0 == a1 && b0 == b1 {
+			return
+		}
+		if n := len(ops); n > 0 && ops[n-1].Kind == kind {
+			ops[n-1].A1, ops[n-1].B1 = a1, b1
+			return
+		}
+		ops = append(ops, Op{Kind: kind, A0: a0, A1: a1, B0: b0, B1: b1})
+	}
+	i, j := 0, 0
+	for i < len(delA) || j < len(insB) {
+		i0, j0 := i, j
+		for i < len(delA) && j < len(insB) && !delA[i] && !insB[j] {
+			i++
+			j++
+		}
+		add(Equal, i0, i, j0, j)
+		i0 = i
+		for i < len(delA) && delA[i] {
+			i++
+		}
+		add(Delete, i0, i, j, j)
+		j0 = j
+		for j < len(insB) && insB[j] {
+			j++
+		}
+		add(Insert, i, i, j0, j)
+	}
+	return ops
+}
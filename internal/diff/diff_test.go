@@ -0,0 +1,144 @@
+package diff
+
+import (
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func split(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, " ")
+}
+
+// apply rebuilds b from a and ops, checking the ops are well formed.
+func apply(t *testing.T, a, b []string, ops []Op) []string {
+	t.Helper()
+	var out []string
+	ai, bi := 0, 0
+	for _, op := range ops {
+		if op.A0 != ai || op.B0 != bi {
+			t.Fatalf("ops are not contiguous at %+v (a=%d b=%d)", op, ai, bi)
+		}
+		switch op.Kind {
+		case Equal:
+			if !equalLines(a[op.A0:op.A1], b[op.B0:op.B1]) {
+				t.Fatalf("equal op %+v covers different lines", op)
+			}
+			out = append(out, a[op.A0:op.A1]...)
+		case Insert:
+			out = append(out, b[op.B0:op.B1]...)
+		}
+		ai, bi = op.A1, op.B1
+	}
+	if ai != len(a) || bi != len(b) {
+		t.Fatalf("ops end at a=%d b=%d, want %d %d", ai, bi, len(a), len(b))
+	}
+	return out
+}
+
+func TestLinesIsMinimal(t *testing.T) {
+	cases := []struct {
+		a, b    string
+		changed int // deleted + inserted lines in a minimal diff
+	}{
+		{"a b c", "a b c", 0},
+		{"", "a b", 2},
+		{"a b", "", 2},
+		{"a b c a b b a", "c b a b a c", 5},
+		{"x a b c", "a b c x", 2},
+		{"1 2 3 4 5 6", "1 2 X 4 5 6", 2},
+	}
+	for _, c := range cases {
+		a, b := split(c.a), split(c.b)
+		ops := Lines(a, b)
+		if got := apply(t, a, b, ops); !equalLines(got, b) {
+			t.Fatalf("%q -> %q: rebuilt %q", c.a, c.b, got)
+		}
+		changed := 0
+		for _, op := range ops {
+			changed += (op.A1 - op.A0 + op.B1 - op.B0) * btoi(op.Kind != Equal)
+		}
+		if changed != c.changed {
+			t.Fatalf("%q -> %q: %d changed lines, want %d (%+v)", c.a, c.b, changed, c.changed, ops)
+		}
+	}
+}
+
+func btoi(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func TestLinesRandomRoundTrip(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	gen := func() []string {
+		out := make([]string, rng.Intn(40))
+		for i := range out {
+			out[i] = string(rune('a' + rng.Intn(4)))
+		}
+		return out
+	}
+	for i := 0; i < 500; i++ {
+		a, b := gen(), gen()
+		if got := apply(t, a, b, Lines(a, b)); !equalLines(got, b) {
+			t.Fatalf("round trip failed for %v -> %v: %v", a, b, got)
+		}
+	}
+}
+
+func TestMerge3(t *testing.T) {
+	base := split("a b c d e f")
+
+	// An insertion on one side must not shift the other side's change.
+	res := Merge3(base, split("NEW a b c d e f"), split("a b c d E f"), MergeOptions{})
+	if len(res.Conflicts) != 0 || strings.Join(res.Lines, " ") != "NEW a b c d E f" {
+		t.Fatalf("clean merge failed: %v %+v", res.Lines, res.Conflicts)
+	}
+
+	// Identical changes on both sides merge cleanly.
+	res = Merge3(base, split("a B c d e f"), split("a B c d e f"), MergeOptions{})
+	if len(res.Conflicts) != 0 || strings.Join(res.Lines, " ") != "a B c d e f" {
+		t.Fatalf("identical change failed: %v", res.Lines)
+	}
+
+	ours, theirs := split("a X1 same c d e f"), split("a Y1 same c d e f")
+	res = Merge3(base, ours, theirs, MergeOptions{OursLabel: "ours", TheirsLabel: "theirs"})
+	want := "a <<<<<<< ours X1 same ||||||| b ======= Y1 same >>>>>>> theirs c d e f"
+	if got := strings.Join(res.Lines, " "); got != want {
+		t.Fatalf("diff3 output:\n%s\nwant\n%s", got, want)
+	}
+	c := res.Conflicts[0]
+	if c.BaseStart != 2 || c.BaseEnd != 2 || c.OutputStart != 2 || c.OutputEnd != 10 || !reflect.DeepEqual(c.Ours, split("X1 same")) {
+		t.Fatalf("unexpected conflict %+v", c)
+	}
+
+	res = Merge3(base, ours, theirs, MergeOptions{Style: StyleZDiff3})
+	if got := strings.Join(res.Lines, " "); got != "a <<<<<<< X1 ||||||| b ======= Y1 >>>>>>> same c d e f" {
+		t.Fatalf("zdiff3 output: %s", got)
+	}
+	res = Merge3(base, ours, theirs, MergeOptions{Style: StyleMerge, Strategy: StrategyUnion})
+	if got := strings.Join(res.Lines, " "); got != "a X1 same Y1 same c d e f" || res.Resolved != 1 || len(res.Conflicts) != 0 {
+		t.Fatalf("union output: %s (%+v)", got, res)
+	}
+}
+
+func TestParseConflictsRoundTrip(t *testing.T) {
+	res := Merge3(split("a b c"), split("a L c"), split("a R c"), MergeOptions{Style: StyleDiff3, OursLabel: "left"})
+	parsed, err := ParseConflicts(res.Lines)
+	if err != nil || len(parsed) != 1 {
+		t.Fatalf("parse: %v %+v", err, parsed)
+	}
+	p, c := parsed[0], res.Conflicts[0]
+	if p.OutputStart != c.OutputStart || p.OutputEnd != c.OutputEnd || !equalLines(p.Ours, c.Ours) || !equalLines(p.Base, c.Base) || !equalLines(p.Theirs, c.Theirs) {
+		t.Fatalf("parsed %+v, merged %+v", p, c)
+	}
+	if _, err := ParseConflicts(split("<<<<<<< x a =======")); err == nil {
+		t.Fatal("expected unterminated conflict error")
+	}
+}
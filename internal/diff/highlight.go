@@ -0,0 +1,134 @@
+package diff
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	ansiReset   = "\033[0m"
+	ansiBold    = "\033[1m"
+	ansiRed     = "\033[31m"
+	ansiGreen   = "\033[32m"
+	ansiCyan    = "\033[36m"
+	ansiReverse = "\033[7m"
+	ansiNoRev   = "\033[27m"
+)
+
+// Colorize adds terminal colors to the unified diffs inside text: removed
+// lines red, added lines green and hunk headers cyan. When a run of removed
+// lines is followed by added lines, paired lines also get the changed words
+// highlighted. Other lines pass through unchanged.
+func Colorize(text string) string {
+	lines := strings.Split(text, "\n")
+	var out []string
+	inHunk := false
+	for i := 0; i < len(lines); i++ {
+		l := lines[i]
+		switch {
+		case strings.HasPrefix(l, "--- ") && i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ "):
+			inHunk = false
+			out = append(out, ansiBold+l+ansiReset, ansiBold+lines[i+1]+ansiReset)
+			i++
+		case strings.HasPrefix(l, "@@"):
+			inHunk = true
+			out = append(out, ansiCyan+l+ansiReset)
+		case inHunk && strings.HasPrefix(l, "-"):
+			j := i
+			for j < len(lines) && strings.HasPrefix(lines[j], "-") {
+				j++
+			}
+			k := j
+			for k < len(lines) && strings.HasPrefix(lines[k], "+") {
+				k++
+			}
+			dels, adds := lines[i:j], lines[j:k]
+			for n, d := range dels {
+				if n < len(adds) {
+					dels[n], adds[n] = HighlightWords(d[1:], adds[n][1:])
+					dels[n], adds[n] = "-"+dels[n], "+"+adds[n]
+				}
+			}
+			for _, d := range dels {
+				out = append(out, ansiRed+d+ansiReset)
+			}
+			for _, a := range adds {
+				out = append(out, ansiGreen+a+ansiReset)
+			}
+			i = k - 1
+		case inHunk && strings.HasPrefix(l, "+"):
+			out = append(out, ansiGreen+l+ansiReset)
+		case inHunk && (strings.HasPrefix(l, " ") || strings.HasPrefix(l, `\`)):
+			out = append(out, l)
+		default:
+			inHunk = false
+			out = append(out, l)
+		}
+	}
+	return strings.Join(out, "\n")
+}
+
+// HighlightWords diffs old and new word by word and returns both with the
+// changed words in reverse video. Lines with nothing in common are returned
+// as is, since highlighting every word adds noise.
+func HighlightWords(old, new string) (string, string) {
+	a, b := splitWords(old), splitWords(new)
+	ops := Lines(a, b)
+	common := 0
+	for _, op := range ops {
+		if op.Kind == Equal {
+			for _, w := range a[op.A0:op.A1] {
+				if strings.TrimSpace(w) != "" {
+					common++
+				}
+			}
+		}
+	}
+	if common == 0 {
+		return old, new
+	}
+	var ob, nb strings.Builder
+	for _, op := range ops {
+		switch op.Kind {
+		case Equal:
+			ob.WriteString(strings.Join(a[op.A0:op.A1], ""))
+			nb.WriteString(strings.Join(b[op.B0:op.B1], ""))
+		case Delete:
+			ob.WriteString(ansiReverse + strings.Join(a[op.A0:op.A1], "") + ansiNoRev)
+		case Insert:
+			nb.WriteString(ansiReverse + strings.Join(b[op.B0:op.B1], "") + ansiNoRev)
+		}
+	}
+	return ob.String(), nb.String()
+}
+
+// splitWords tokenizes s into runs of word characters, runs of spaces and
+// single punctuation characters, so joining the tokens gives back s.
+func splitWords(s string) []string {
+	class := func(r rune) int {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_':
+			return 1
+		case unicode.IsSpace(r):
+			return 2
+		}
+		return 0
+	}
+	var words []string
+	for len(s) > 0 {
+		r, size := utf8.DecodeRuneInString(s)
+		c := class(r)
+		n := size
+		for c != 0 && n < len(s) {
+			r2, size2 := utf8.DecodeRuneInString(s[n:])
+			if class(r2) != c {
+				break
+			}
+			n += size2
+		}
+		words = append(words, s[:n])
+		s = s[n:]
+	}
+	return words
+}
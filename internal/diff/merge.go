@@ -0,0 +1,245 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Conflict marker styles.
+const (
+	StyleMerge  = "merge"  // ours and theirs only
+	StyleDiff3  = "diff3"  // ours, base and theirs
+	StyleZDiff3 = "zdiff3" // diff3 with lines common to both sides moved out
+)
+
+// Conflict resolution strategies. The empty strategy leaves conflict markers.
+const (
+	StrategyOurs   = "ours"
+	StrategyTheirs = "theirs"
+	StrategyUnion  = "union"
+)
+
+const (
+	markerOurs   = "<<<<<<<"
+	markerBase   = "|||||||"
+	markerSep    = "======="
+	markerTheirs = ">>>>>>>"
+)
+
+// MergeOptions controls Merge3. Labels follow the conflict markers.
+type MergeOptions struct {
+	Style       string
+	Strategy    string
+	OursLabel   string
+	BaseLabel   string
+	TheirsLabel string
+}
+
+// Conflict is one region both sides changed differently. Line numbers are
+// 1-based; OutputStart/OutputEnd span the markers in the merged output (zero
+// when a strategy resolved the conflict).
+type Conflict struct {
+	Index       int
+	BaseStart   int
+	BaseEnd     int
+	OutputStart int
+	OutputEnd   int
+	Base        []string
+	Ours        []string
+	Theirs      []string
+}
+
+// MergeResult is the merged text and its conflicts.
+type MergeResult struct {
+	Lines     []string
+	Conflicts []Conflict
+	// Resolved counts conflicts settled by the strategy.
+	Resolved int
+}
+
+// Merge3 performs a diff3 merge of ours and theirs against base. Regions
+// changed on one side only, or identically on both, merge cleanly.
+func Merge3(base, ours, theirs []string, opts MergeOptions) MergeResult {
+	if opts.Style == "" {
+		opts.Style = StyleDiff3
+	}
+	matchOurs := alignment(base, ours)
+	matchTheirs := alignment(base, theirs)
+
+	var res MergeResult
+	i, o, t := 0, 0, 0
+	for i < len(base) || o < len(ours) || t < len(theirs) {
+		// Stable run: base lines matched at the current position on both sides.
+		for i < len(base) && matchOurs[i] == o && matchTheirs[i] == t {
+			res.Lines = append(res.Lines, base[i])
+			i, o, t = i+1, o+1, t+1
+		}
+
+		// Next sync point: the next base line both sides kept.
+		ni, no, nt := i, len(ours), len(theirs)
+		for ; ni < len(base); ni++ {
+			if matchOurs[ni] >= 0 && matchTheirs[ni] >= 0 {
+				no, nt = matchOurs[ni], matchTheirs[ni]
+				break
+			}
+		}
+		if ni == i && no == o && nt == t {
+			break // only reachable at the end of all three inputs
+		}
+
+		b, ob, tb := base[i:ni], ours[o:no], theirs[t:nt]
+		switch {
+		case equalLines(ob, tb), equalLines(tb, b):
+			res.Lines = append(res.Lines, ob...)
+		case equalLines(ob, b):
+			res.Lines = append(res.Lines, tb...)
+		default:
+			res.addConflict(i, b, ob, tb, opts)
+		}
+		i, o, t = ni, no, nt
+	}
+	return res
+}
+
+func (res *MergeResult) addConflict(baseStart int, b, ob, tb []string, opts MergeOptions) {
+	switch opts.Strategy {
+	case StrategyOurs:
+		res.Lines = append(res.Lines, ob...)
+		res.Resolved++
+		return
+	case StrategyTheirs:
+		res.Lines = append(res.Lines, tb...)
+		res.Resolved++
+		return
+	case StrategyUnion:
+		res.Lines = append(res.Lines, ob...)
+		res.Lines = append(res.Lines, tb...)
+		res.Resolved++
+		return
+	}
+
+	c := Conflict{
+		Index:     len(res.Conflicts) + 1,
+		BaseStart: baseStart + 1,
+		BaseEnd:   baseStart + len(b),
+		Base:      b,
+		Ours:      ob,
+		Theirs:    tb,
+	}
+	var common []string
+	if opts.Style == StyleZDiff3 {
+		prefix := commonPrefix(ob, tb)
+		suffix := commonSuffix(ob[prefix:], tb[prefix:])
+		res.Lines = append(res.Lines, ob[:prefix]...)
+		c.Ours, c.Theirs = ob[prefix:len(ob)-suffix], tb[prefix:len(tb)-suffix]
+		common = ob[len(ob)-suffix:]
+	}
+
+	c.OutputStart = len(res.Lines) + 1
+	res.Lines = append(res.Lines, marker(markerOurs, opts.OursLabel))
+	res.Lines = append(res.Lines, c.Ours...)
+	if opts.Style != StyleMerge {
+		res.Lines = append(res.Lines, marker(markerBase, opts.BaseLabel))
+		res.Lines = append(res.Lines, c.Base...)
+	}
+	res.Lines = append(res.Lines, markerSep)
+	res.Lines = append(res.Lines, c.Theirs...)
+	res.Lines = append(res.Lines, marker(markerTheirs, opts.TheirsLabel))
+	c.OutputEnd = len(res.Lines)
+	res.Conflicts = append(res.Conflicts, c)
+	res.Lines = append(res.Lines, common...)
+}
+
+// alignment maps each line of a to the line of b it is matched with, or -1.
+func alignment(a, b []string) []int {
+	match := make([]int, len(a))
+	for i := range match {
+		match[i] = -1
+	}
+	for _, op := range Lines(a, b) {
+		if op.Kind != Equal {
+			continue
+		}
+		for k := 0; k < op.A1-op.A0; k++ {
+			match[op.A0+k] = op.B0 + k
+		}
+	}
+	return match
+}
+
+func marker(m, label string) string {
+	if label == "" {
+		return m
+	}
+	return m + " " + label
+}
+
+func equalLines(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func commonPrefix(a, b []string) int {
+	n := 0
+	for n < len(a) && n < len(b) && a[n] == b[n] {
+		n++
+	}
+	return n
+}
+
+func commonSuffix(a, b []string) int {
+	n := 0
+	for n < len(a) && n < len(b) && a[len(a)-1-n] == b[len(b)-1-n] {
+		n++
+	}
+	return n
+}
+
+// ParseConflicts finds conflict blocks written with git-style markers
+// (with or without a ||||||| base section).
+func ParseConflicts(lines []string) ([]Conflict, error) {
+	var conflicts []Conflict
+	for i := 0; i < len(lines); i++ {
+		if !isMarker(lines[i], markerOurs) {
+			continue
+		}
+		c := Conflict{Index: len(conflicts) + 1, OutputStart: i + 1}
+		section := &c.Ours
+		closed := false
+		for j := i + 1; j < len(lines); j++ {
+			switch l := lines[j]; {
+			case isMarker(l, markerOurs):
+				return nil, fmt.Errorf("conflict %d starting at line %d is not closed before line %d", c.Index, i+1, j+1)
+			case isMarker(l, markerBase) && section == &c.Ours:
+				section = &c.Base
+			case l == markerSep && section != &c.Theirs:
+				section = &c.Theirs
+			case isMarker(l, markerTheirs) && section == &c.Theirs:
+				c.OutputEnd = j + 1
+				closed = true
+			default:
+				*section = append(*section, l)
+			}
+			if closed {
+				i = j
+				break
+			}
+		}
+		if !closed {
+			return nil, fmt.Errorf("conflict %d starting at line %d has no closing %s marker", c.Index, i+1, markerTheirs)
+		}
+		conflicts = append(conflicts, c)
+	}
+	return conflicts, nil
+}
+
+func isMarker(line, m string) bool {
+	return line == m || strings.HasPrefix(line, m+" ")
+}
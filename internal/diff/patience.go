@@ -0,0 +1,91 @@
+package diff
+
+import "sort"
+
+// patience diffs x[xoff:xlim] against y[yoff:ylim] by matching the longest
+// increasing run of lines that occur exactly once on each side, then
+// recursing between those anchors. Ranges without unique lines use Myers.
+func (d *differ) patience(xoff, xlim, yoff, ylim int) {
+	for xoff < xlim && yoff < ylim && d.x[xoff] == d.y[yoff] {
+		xoff++
+		yoff++
+	}
+	for xlim > xoff && ylim > yoff && d.x[xlim-1] == d.y[ylim-1] {
+		xlim--
+		ylim--
+	}
+	if xoff == xlim || yoff == ylim {
+		d.markAll(xoff, xlim, yoff, ylim)
+		return
+	}
+
+	anchors := d.uniqueAnchors(xoff, xlim, yoff, ylim)
+	if len(anchors) == 0 {
+		d.compare(xoff, xlim, yoff, ylim)
+		return
+	}
+	px, py := xoff, yoff
+	for _, a := range anchors {
+		d.patience(px, a[0], py, a[1])
+		px, py = a[0]+1, a[1]+1
+	}
+	d.patience(px, xlim, py, ylim)
+}
+
+// uniqueAnchors returns (x, y) pairs of lines unique to both ranges that
+// appear in the same order on both sides, as the longest such sequence.
+func (d *differ) uniqueAnchors(xoff, xlim, yoff, ylim int) [][2]int {
+	type seen struct{ countX, countY, posX, posY int }
+	lines := map[int]*seen{}
+	for i := xoff; i < xlim; i++ {
+		s := lines[d.x[i]]
+		if s == nil {
+			s = &seen{}
+			lines[d.x[i]] = s
+		}
+		s.countX++
+		s.posX = i
+	}
+	for j := yoff; j < ylim; j++ {
+		if s := lines[d.y[j]]; s != nil {
+			s.countY++
+			s.posY = j
+		}
+	}
+	var pairs [][2]int
+	for _, s := range lines {
+		if s.countX == 1 && s.countY == 1 {
+			pairs = append(pairs, [2]int{s.posX, s.posY})
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i][0] < pairs[j][0] })
+	return longestIncreasingY(pairs)
+}
+
+// longestIncreasingY picks the longest subsequence of pairs (sorted by x)
+// whose y values increase, using patience sorting.
+func longestIncreasingY(pairs [][2]int) [][2]int {
+	if len(pairs) == 0 {
+		return nil
+	}
+	var tops []int // index into pairs of the top card of each pile
+	prev := make([]int, len(pairs))
+	for i, p := range pairs {
+		pile := sort.Search(len(tops), func(k int) bool { return pairs[tops[k]][1] > p[1] })
+		if pile > 0 {
+			prev[i] = tops[pile-1]
+		} else {
+			prev[i] = -1
+		}
+		if pile == len(tops) {
+			tops = append(tops, i)
+		} else {
+			tops[pile] = i
+		}
+	}
+	out := make([][2]int, len(tops))
+	for i, k := len(tops)-1, tops[len(tops)-1]; i >= 0; i, k = i-1, prev[k] {
+		out[i] = pairs[k]
+	}
+	return out
+}
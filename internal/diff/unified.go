@@ -0,0 +1,196 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultContext is the number of unchanged lines shown around each change.
+const DefaultContext = 3
+
+// NoContext requests hunks without surrounding lines (Options.Context of
+// zero means DefaultContext).
+const NoContext = -1
+
+// Options controls Compare and Unified.
+type Options struct {
+	Algorithm Algorithm
+	// Context is the number of unchanged lines around each change; zero
+	// means DefaultContext and NoContext means none.
+	Context int
+	// MaxLines truncates the rendered diff body; zero means unlimited.
+	MaxLines int
+}
+
+func (o Options) context() int {
+	switch {
+	case o.Context == 0:
+		return DefaultContext
+	case o.Context < 0:
+		return 0
+	}
+	return o.Context
+}
+
+// Hunk is a group of nearby changes with their context. A0/A1 and B0/B1 are
+// 0-based half-open ranges in the old and new text; Ops cover exactly those
+// ranges.
+type Hunk struct {
+	A0, A1 int
+	B0, B1 int
+	Ops    []Op
+}
+
+// Header returns the "@@ -l,s +l,s @@" line for h.
+func (h Hunk) Header() string {
+	return fmt.Sprintf("@@ -%s +%s @@", hunkRange(h.A0, h.A1), hunkRange(h.B0, h.B1))
+}
+
+// hunkRange follows diff -u: an empty range names the line before it.
+func hunkRange(start, end int) string {
+	if start == end {
+		return fmt.Sprintf("%d,0", start)
+	}
+	return fmt.Sprintf("%d,%d", start+1, end-start)
+}
+
+// Stats summarizes a diff.
+type Stats struct {
+	Added   int
+	Deleted int
+	Hunks   int
+}
+
+func (s Stats) String() string {
+	return fmt.Sprintf("%d hunk(s), +%d -%d", s.Hunks, s.Added, s.Deleted)
+}
+
+// FileDiff is the comparison of two texts.
+type FileDiff struct {
+	A, B  []string
+	Ops   []Op
+	Hunks []Hunk
+}
+
+// Compare diffs a against b and groups the changes into hunks.
+func Compare(a, b []string, opts Options) *FileDiff {
+	ops := Compute(a, b, opts.Algorithm)
+	return &FileDiff{A: a, B: b, Ops: ops, Hunks: Hunks(ops, opts.context())}
+}
+
+// Equal reports whether the two texts are identical.
+func (d *FileDiff) Equal() bool {
+	return len(d.Hunks) == 0
+}
+
+// Stats counts added and deleted lines and hunks.
+func (d *FileDiff) Stats() Stats {
+	s := Stats{Hunks: len(d.Hunks)}
+	for _, op := range d.Ops {
+		switch op.Kind {
+		case Delete:
+			s.Deleted += op.A1 - op.A0
+		case Insert:
+			s.Added += op.B1 - op.B0
+		}
+	}
+	return s
+}
+
+// Unified renders d as a unified diff. Body lines beyond maxLines (when
+// positive) are replaced by a truncation marker.
+func (d *FileDiff) Unified(fromLabel, toLabel string, maxLines int) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- %s\n+++ %s\n", fromLabel, toLabel)
+	if d.Equal() {
+		b.WriteString("(No differences)")
+		return b.String()
+	}
+	written := 0
+	line := func(prefix byte, text string) bool {
+		if maxLines > 0 && written >= maxLines {
+			return false
+		}
+		b.WriteByte(prefix)
+		b.WriteString(text)
+		b.WriteByte('\n')
+		written++
+		return true
+	}
+	for _, h := range d.Hunks {
+		b.WriteString(h.Header())
+		b.WriteByte('\n')
+		for _, op := range h.Ops {
+			var prefix byte
+			lines := d.B[op.B0:op.B1]
+			switch op.Kind {
+			case Equal:
+				prefix = ' '
+			case Delete:
+				prefix, lines = '-', d.A[op.A0:op.A1]
+			case Insert:
+				prefix = '+'
+			}
+			for _, l := range lines {
+				if !line(prefix, l) {
+					b.WriteString("... [DIFF TRUNCATED] ...")
+					return b.String()
+				}
+			}
+		}
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// Unified diffs a against b and renders the result.
+func Unified(fromLabel, toLabel string, a, b []string, opts Options) string {
+	return Compare(a, b, opts).Unified(fromLabel, toLabel, opts.MaxLines)
+}
+
+// Hunks groups the changes in ops into hunks with context unchanged lines
+// on each side. Changes separated by at most 2*context unchanged lines share
+// a hunk.
+func Hunks(ops []Op, context int) []Hunk {
+	var hunks []Hunk
+	var cur *Hunk
+	add := func(op Op) {
+		if op.A0 == op.A1 && op.B0 == op.B1 {
+			return
+		}
+		if len(cur.Ops) == 0 {
+			cur.A0, cur.B0 = op.A0, op.B0
+		}
+		cur.Ops = append(cur.Ops, op)
+		cur.A1, cur.B1 = op.A1, op.B1
+	}
+	for i, op := range ops {
+		if op.Kind != Equal {
+			if cur == nil {
+				cur = &Hunk{}
+				if i > 0 {
+					prev := ops[i-1]
+					n := min(context, prev.A1-prev.A0)
+					add(Op{Kind: Equal, A0: prev.A1 - n, A1: prev.A1, B0: prev.B1 - n, B1: prev.B1})
+				}
+			}
+			add(op)
+			continue
+		}
+		if cur == nil {
+			continue
+		}
+		n := op.A1 - op.A0
+		if i < len(ops)-1 && n <= 2*context {
+			add(op)
+			continue
+		}
+		lead := min(context, n)
+		add(Op{Kind: Equal, A0: op.A0, A1: op.A0 + lead, B0: op.B0, B1: op.B0 + lead})
+		hunks = append(hunks, *cur)
+		cur = nil
+	}
+	if cur != nil {
+		hunks = append(hunks, *cur)
+	}
+	return hunks
+}
@@ -0,0 +1,99 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func numbered(n int) []string {
+	out := make([]string, n)
+	for i := range out {
+		out[i] = fmt.Sprintf("line %d", i+1)
+	}
+	return out
+}
+
+func TestUnifiedMinimalHunks(t *testing.T) {
+	a := numbered(40)
+	b := append([]string{}, a...)
+	b[4] = "changed 5"
+	b[30] = "changed 31"
+
+	d := Compare(a, b, Options{})
+	if s := d.Stats(); s.Hunks != 2 || s.Added != 2 || s.Deleted != 2 {
+		t.Fatalf("unexpected stats %+v", s)
+	}
+	got := d.Unified("a/f", "b/f", 0)
+	want := strings.Join([]string{
+		"--- a/f", "+++ b/f",
+		"@@ -2,7 +2,7 @@", " line 2", " line 3", " line 4", "-line 5", "+changed 5", " line 6", " line 7", " line 8",
+		"@@ -28,7 +28,7 @@", " line 28", " line 29", " line 30", "-line 31", "+changed 31", " line 32", " line 33", " line 34",
+	}, "\n")
+	if got != want {
+		t.Fatalf("unified output:\n%s\nwant:\n%s", got, want)
+	}
+
+	// Changes closer than twice the context share a hunk.
+	if h := Compare(a, b, Options{Context: 13}).Hunks; len(h) != 1 {
+		t.Fatalf("expected merged hunk, got %d", len(h))
+	}
+	if got := Unified("x", "y", a, b, Options{Context: NoContext}); !strings.Contains(got, "@@ -5,1 +5,1 @@\n-line 5\n+changed 5\n@@") {
+		t.Fatalf("zero-context output:\n%s", got)
+	}
+	if got := Unified("x", "y", a, a, Options{}); got != "--- x\n+++ y\n(No differences)" {
+		t.Fatalf("identical output: %q", got)
+	}
+	if got := Unified("x", "y", nil, []string{"new"}, Options{}); got != "--- x\n+++ y\n@@ -0,0 +1,1 @@\n+new" {
+		t.Fatalf("creation output: %q", got)
+	}
+	if got := d.Unified("x", "y", 3); !strings.HasSuffix(got, " line 4\n... [DIFF TRUNCATED] ...") {
+		t.Fatalf("truncated output:\n%s", got)
+	}
+}
+
+func TestPatienceAnchorsOnUniqueLines(t *testing.T) {
+	a := strings.Split("func a() {\n\treturn 1\n}\n\nfunc b() {\n\treturn 2\n}", "\n")
+	b := strings.Split("func b() {\n\treturn 2\n}\n\nfunc a() {\n\treturn 1\n}", "\n")
+	for _, alg := range []Algorithm{Myers, Patience} {
+		ops := Compute(a, b, alg)
+		if got := apply(t, a, b, ops); !equalLines(got, b) {
+			t.Fatalf("%s: rebuilt %q", alg, got)
+		}
+	}
+	// Patience keeps "func b" together instead of matching stray braces.
+	for _, op := range Compute(a, b, Patience) {
+		if op.Kind == Equal && op.A0 == 4 && op.B0 == 0 && op.A1-op.A0 >= 2 {
+			return
+		}
+	}
+	t.Fatalf("patience did not anchor func b: %+v", Compute(a, b, Patience))
+}
+
+func TestParseAlgorithm(t *testing.T) {
+	if alg, err := ParseAlgorithm(""); err != nil || alg != Myers {
+		t.Fatalf("default algorithm: %v %v", alg, err)
+	}
+	if _, err := ParseAlgorithm("histogram"); err == nil {
+		t.Fatal("expected error for unknown algorithm")
+	}
+}
+
+func TestHighlightWords(t *testing.T) {
+	old, new := HighlightWords("x := compute(a, b)", "x := compute(a, c)")
+	if old != "x := compute(a, "+ansiReverse+"b"+ansiNoRev+")" || new != "x := compute(a, "+ansiReverse+"c"+ansiNoRev+")" {
+		t.Fatalf("unexpected highlight %q / %q", old, new)
+	}
+	if old, new := HighlightWords("alpha", "beta"); old != "alpha" || new != "beta" {
+		t.Fatalf("unrelated lines should not be highlighted: %q / %q", old, new)
+	}
+
+	colored := Colorize("note\n--- a\n+++ b\n@@ -1,2 +1,2 @@\n ctx\n-foo bar\n+foo baz")
+	lines := strings.Split(colored, "\n")
+	if lines[0] != "note" || lines[4] != " ctx" || !strings.HasPrefix(lines[3], ansiCyan) {
+		t.Fatalf("unexpected colorized lines %q", lines)
+	}
+	if lines[5] != ansiRed+"-foo "+ansiReverse+"bar"+ansiNoRev+ansiReset {
+		t.Fatalf("unexpected deleted line %q", lines[5])
+	}
+}
@@ -0,0 +1,229 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	initializeTimeout = 30 * time.Second
+	shutdownTimeout   = 2 * time.Second
+)
+
+type diagEntry struct {
+	items      []Diagnostic
+	at         time.Time
+	version    int
+	hasVersion bool
+}
+
+// Client is a connection to one running language server for one workspace
+// root. Documents are synced with full-text changes.
+type Client struct {
+	name string
+	root string
+	cmd  *exec.Cmd
+	conn *conn
+
+	mu      sync.Mutex
+	docs    map[string]int // uri -> last sent version
+	diags   map[string]diagEntry
+	updated chan struct{} // closed and replaced on every publishDiagnostics
+}
+
+// Start launches the server process for root and completes the initialize
+// handshake.
+func Start(ctx context.Context, server ServerConfig, root string) (*Client, error) {
+	cmd := exec.Command(server.Command, server.Args...)
+	cmd.Dir = root
+	cmd.Stderr = io.Discard
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s stdin: %w", server.Name, err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s stdout: %w", server.Name, err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start %s: %w", server.Name, err)
+	}
+
+	c := newClient(server.Name, root, stdout, stdin)
+	c.cmd = cmd
+	if err := c.initialize(ctx); err != nil {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+		return nil, err
+	}
+	return c, nil
+}
+
+func newClient(name, root string, r io.Reader, w io.WriteCloser) *Client {
+	c := &Client{
+		name:    name,
+		root:    root,
+		docs:    map[string]int{},
+		diags:   map[string]diagEntry{},
+		updated: make(chan struct{}),
+	}
+	c.conn = newConn(r, w, c.handle)
+	return c
+}
+
+func (c *Client) initialize(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, initializeTimeout)
+	defer cancel()
+
+	rootURI := PathToURI(c.root)
+	params := initializeParams{
+		ProcessID:        os.Getpid(),
+		RootURI:          rootURI,
+		WorkspaceFolders: []workspaceFolder{{URI: rootURI, Name: filepath.Base(c.root)}},
+		Capabilities:     clientCapabilities,
+		ClientInfo:       map[string]string{"name": "ai2go"},
+	}
+	if err := c.conn.Call(ctx, "initialize", params, nil); err != nil {
+		return fmt.Errorf("%s initialize failed: %w", c.name, err)
+	}
+	if err := c.conn.Notify("initialized", map[string]any{}); err != nil {
+		return fmt.Errorf("%s initialized notification failed: %w", c.name, err)
+	}
+	return nil
+}
+
+// handle answers server requests and records published diagnostics.
+func (c *Client) handle(method string, params json.RawMessage) any {
+	switch method {
+	case "textDocument/publishDiagnostics":
+		var p publishDiagnosticsParams
+		if json.Unmarshal(params, &p) != nil {
+			return nil
+		}
+		entry := diagEntry{items: p.Diagnostics, at: time.Now()}
+		if p.Version != nil {
+			entry.version, entry.hasVersion = *p.Version, true
+		}
+		c.mu.Lock()
+		c.diags[p.URI] = entry
+		close(c.updated)
+		c.updated = make(chan struct{})
+		c.mu.Unlock()
+	case "workspace/configuration":
+		var p configurationParams
+		_ = json.Unmarshal(params, &p)
+		return make([]any, len(p.Items))
+	}
+	return nil
+}
+
+// Sync sends the current content of path to the server, opening the document
+// on first use. It returns the document version and the diagnostics known
+// before the change (ok is false when the document was not open yet).
+func (c *Client) Sync(path, languageID string) (version int, before []Diagnostic, ok bool, err error) {
+	text, err := os.ReadFile(path)
+	if err != nil {
+		return 0, nil, false, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	uri := PathToURI(path)
+
+	c.mu.Lock()
+	prev, open := c.docs[uri]
+	version = prev + 1
+	c.docs[uri] = version
+	if entry, known := c.diags[uri]; open && known {
+		before, ok = entry.items, true
+	}
+	c.mu.Unlock()
+
+	if !open {
+		err = c.conn.Notify("textDocument/didOpen", didOpenParams{TextDocument: textDocumentItem{
+			URI: uri, LanguageID: languageID, Version: version, Text: string(text),
+		}})
+	} else {
+		err = c.conn.Notify("textDocument/didChange", didChangeParams{
+			TextDocument:   versionedTextDocumentIdentifier{URI: uri, Version: version},
+			ContentChanges: []textDocumentContentChangeEvent{{Text: string(text)}},
+		})
+	}
+	if err != nil {
+		return 0, nil, false, fmt.Errorf("%s sync failed: %w", c.name, err)
+	}
+	return version, before, ok, nil
+}
+
+// WaitDiagnostics waits until the server publishes diagnostics for path at
+// version (or after since, for servers that omit versions) and no further
+// update arrives for settle. It returns the latest diagnostics and whether a
+// fresh publish was seen before ctx expired.
+func (c *Client) WaitDiagnostics(ctx context.Context, path string, version int, since time.Time, settle time.Duration) ([]Diagnostic, bool) {
+	uri := PathToURI(path)
+	for {
+		c.mu.Lock()
+		entry, known := c.diags[uri]
+		updated := c.updated
+		c.mu.Unlock()
+
+		fresh := known && ((entry.hasVersion && entry.version >= version) || (!entry.hasVersion && entry.at.After(since)))
+		wait := time.Hour
+		if fresh {
+			quiet := time.Since(entry.at)
+			if quiet >= settle {
+				return entry.items, true
+			}
+			wait = settle - quiet
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-updated:
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return entry.items, fresh
+		}
+		timer.Stop()
+	}
+}
+
+// Close shuts the server down politely, then kills it if it lingers.
+func (c *Client) Close() {
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if c.conn.Call(ctx, "shutdown", nil, nil) == nil {
+		_ = c.conn.Notify("exit", nil)
+	}
+	if c.cmd == nil {
+		_ = c.conn.Close()
+		return
+	}
+	done := make(chan struct{})
+	go func() {
+		_ = c.cmd.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		_ = c.cmd.Process.Kill()
+		<-done
+	}
+	_ = c.conn.Close()
+}
+
+// Alive reports whether the connection is still usable.
+func (c *Client) Alive() bool {
+	select {
+	case <-c.conn.done:
+		return false
+	default:
+		return true
+	}
+}
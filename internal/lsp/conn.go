@@ -0,0 +1,222 @@
+package lsp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var errConnClosed = errors.New("language server connection closed")
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string {
+	return fmt.Sprintf("rpc error %d: %s", e.Code, e.Message)
+}
+
+// message is any incoming JSON-RPC frame: a response (ID with Result or
+// Error), a request (ID with Method) or a notification (Method only).
+type message struct {
+	ID     json.RawMessage `json:"id,omitempty"`
+	Method string          `json:"method,omitempty"`
+	Params json.RawMessage `json:"params,omitempty"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  *rpcError       `json:"error,omitempty"`
+}
+
+type outRequest struct {
+	JSONRPC string `json:"jsonrpc"`
+	ID      int64  `json:"id"`
+	Method  string `json:"method"`
+	Params  any    `json:"params"`
+}
+
+type outNotification struct {
+	JSONRPC string `json:"jsonrpc"`
+	Method  string `json:"method"`
+	Params  any    `json:"params"`
+}
+
+type outResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result"`
+}
+
+// conn is a JSON-RPC 2.0 connection using LSP base-protocol framing
+// ("Content-Length" headers).
+type conn struct {
+	w       io.WriteCloser
+	r       *bufio.Reader
+	handler func(method string, params json.RawMessage) any
+
+	wmu     sync.Mutex
+	mu      sync.Mutex
+	nextID  int64
+	pending map[int64]chan *message
+	done    chan struct{}
+	err     error
+}
+
+// newConn starts reading from r. handler is called for server requests and
+// notifications; its return value is sent back as the result of requests.
+func newConn(r io.Reader, w io.WriteCloser, handler func(method string, params json.RawMessage) any) *conn {
+	c := &conn{
+		w:       w,
+		r:       bufio.NewReader(r),
+		handler: handler,
+		pending: map[int64]chan *message{},
+		done:    make(chan struct{}),
+	}
+	go c.readLoop()
+	return c
+}
+
+// Call sends a request and decodes the response into result (if non-nil).
+func (c *conn) Call(ctx context.Context, method string, params, result any) error {
+	c.mu.Lock()
+	if c.err != nil {
+		c.mu.Unlock()
+		return c.err
+	}
+	c.nextID++
+	id := c.nextID
+	ch := make(chan *message, 1)
+	c.pending[id] = ch
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+	}()
+
+	if err := c.write(outRequest{JSONRPC: "2.0", ID: id, Method: method, Params: params}); err != nil {
+		return err
+	}
+	select {
+	case msg := <-ch:
+		if msg.Error != nil {
+			return msg.Error
+		}
+		if result != nil && len(msg.Result) > 0 {
+			if err := json.Unmarshal(msg.Result, result); err != nil {
+				return fmt.Errorf("failed to decode %s result: %w", method, err)
+			}
+		}
+		return nil
+	case <-c.done:
+		return c.closedErr()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Notify sends a notification.
+func (c *conn) Notify(method string, params any) error {
+	return c.write(outNotification{JSONRPC: "2.0", Method: method, Params: params})
+}
+
+// Close closes the write side and waits for the read loop to stop.
+func (c *conn) Close() error {
+	err := c.w.Close()
+	<-c.done
+	return err
+}
+
+func (c *conn) closedErr() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return c.err
+	}
+	return errConnClosed
+}
+
+func (c *conn) write(v any) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode message: %w", err)
+	}
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	if _, err := fmt.Fprintf(c.w, "Content-Length: %d\r\n\r\n", len(body)); err != nil {
+		return fmt.Errorf("failed to write message: %w", err)
+	}
+	if _, err := c.w.Write(body); err != nil {
+		return fmt.Errorf("failed to write message: %w", err)
+	}
+	return nil
+}
+
+func (c *conn) readLoop() {
+	var err error
+	defer func() {
+		c.mu.Lock()
+		if c.err == nil {
+			c.err = fmt.Errorf("%w: %v", errConnClosed, err)
+		}
+		c.mu.Unlock()
+		close(c.done)
+	}()
+
+	tp := textproto.NewReader(c.r)
+	for {
+		var header textproto.MIMEHeader
+		header, err = tp.ReadMIMEHeader()
+		if err != nil {
+			return
+		}
+		var length int
+		length, err = strconv.Atoi(strings.TrimSpace(header.Get("Content-Length")))
+		if err != nil || length <= 0 {
+			err = fmt.Errorf("invalid Content-Length header %q", header.Get("Content-Length"))
+			return
+		}
+		body := make([]byte, length)
+		if _, err = io.ReadFull(c.r, body); err != nil {
+			return
+		}
+		var msg message
+		if json.Unmarshal(body, &msg) != nil {
+			continue
+		}
+		c.dispatch(&msg)
+	}
+}
+
+func (c *conn) dispatch(msg *message) {
+	if msg.Method == "" {
+		var id int64
+		if json.Unmarshal(msg.ID, &id) != nil {
+			return
+		}
+		c.mu.Lock()
+		ch := c.pending[id]
+		c.mu.Unlock()
+		if ch != nil {
+			ch <- msg
+		}
+		return
+	}
+
+	var result any
+	if c.handler != nil {
+		result = c.handler(msg.Method, msg.Params)
+	}
+	if len(msg.ID) > 0 {
+		// Servers block on some requests (e.g. workspace/configuration), so
+		// every request gets an answer even when we have nothing to say.
+		_ = c.write(outResponse{JSONRPC: "2.0", ID: msg.ID, Result: result})
+	}
+}
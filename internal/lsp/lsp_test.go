@@ -0,0 +1,130 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeServer answers initialize/shutdown and publishes one error per line
+// containing "BROKEN" whenever a document is opened or changed.
+func fakeServer(t *testing.T, in io.Reader, out io.WriteCloser) {
+	t.Helper()
+	var c *conn
+	c = newConn(in, out, func(method string, params json.RawMessage) any {
+		switch method {
+		case "initialize":
+			return map[string]any{"capabilities": map[string]any{"textDocumentSync": 1}}
+		case "shutdown":
+			return nil
+		case "exit":
+			go out.Close()
+		case "textDocument/didOpen", "textDocument/didChange":
+			var p struct {
+				TextDocument struct {
+					URI     string `json:"uri"`
+					Version int    `json:"version"`
+					Text    string `json:"text"`
+				} `json:"textDocument"`
+				ContentChanges []struct {
+					Text string `json:"text"`
+				} `json:"contentChanges"`
+			}
+			_ = json.Unmarshal(params, &p)
+			text := p.TextDocument.Text
+			if len(p.ContentChanges) > 0 {
+				text = p.ContentChanges[0].Text
+			}
+			diags := []Diagnostic{}
+			for i, line := range strings.Split(text, "\n") {
+				if strings.Contains(line, "BROKEN") {
+					diags = append(diags, Diagnostic{Range: Range{Start: Position{Line: i}}, Severity: SeverityError, Message: strings.TrimSpace(line)})
+				}
+			}
+			diags = append(diags, Diagnostic{Severity: SeverityHint, Message: "ignored hint"})
+			version := p.TextDocument.Version
+			go func() {
+				_ = c.Notify("textDocument/publishDiagnostics", publishDiagnosticsParams{URI: p.TextDocument.URI, Version: &version, Diagnostics: diags})
+			}()
+		}
+		return nil
+	})
+}
+
+func pipeStarter(t *testing.T) func(context.Context, ServerConfig, string) (*Client, error) {
+	return func(ctx context.Context, server ServerConfig, root string) (*Client, error) {
+		clientR, serverW := io.Pipe()
+		serverR, clientW := io.Pipe()
+		fakeServer(t, serverR, serverW)
+		c := newClient(server.Name, root, clientR, clientW)
+		if err := c.initialize(ctx); err != nil {
+			return nil, err
+		}
+		return c, nil
+	}
+}
+
+func TestCheckFilesReportsOnlyNewErrors(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "main.fake")
+	if err := os.WriteFile(path, []byte("ok\nBROKEN one\n"), 0644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	m := NewManager()
+	// Any installed binary works; the starter never executes it.
+	m.Configure(true, []ServerConfig{{Name: "fake", Command: "go", Languages: map[string]string{".fake": "fake"}}})
+	m.starter = pipeStarter(t)
+	defer m.Shutdown()
+
+	reports := m.CheckFiles(context.Background(), []string{path}, 5*time.Second)
+	if len(reports) != 1 || reports[0].Baseline || len(reports[0].New) != 1 {
+		t.Fatalf("unexpected first report: %+v", reports)
+	}
+	if out := FormatReports(reports); !strings.Contains(out, "1 errors (fake)") || !strings.Contains(out, "2:1: BROKEN one") {
+		t.Fatalf("unexpected formatted report:\n%s", out)
+	}
+
+	if err := os.WriteFile(path, []byte("BROKEN two\nok\nBROKEN one\n"), 0644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	reports = m.CheckFiles(context.Background(), []string{path}, 5*time.Second)
+	if len(reports) != 1 || !reports[0].Baseline || len(reports[0].New) != 1 || reports[0].New[0].Message != "BROKEN two" {
+		t.Fatalf("expected only the new error, got %+v", reports)
+	}
+
+	if err := os.WriteFile(path, []byte("ok\n"), 0644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	reports = m.CheckFiles(context.Background(), []string{path}, 5*time.Second)
+	if len(reports) != 1 || reports[0].Fixed != 2 || FormatReports(reports) != "" {
+		t.Fatalf("expected fixed errors and an empty report, got %+v", reports)
+	}
+}
+
+func TestCheckFilesSkipsDisabledAndUnknownFiles(t *testing.T) {
+	m := NewManager()
+	m.starter = func(context.Context, ServerConfig, string) (*Client, error) {
+		t.Fatal("no server should start")
+		return nil, nil
+	}
+	if got := m.CheckFiles(context.Background(), []string{"notes.unknownext"}, time.Second); len(got) != 0 {
+		t.Fatalf("expected no reports, got %+v", got)
+	}
+	m.Configure(false, nil)
+	if got := m.CheckFiles(context.Background(), []string{"main.go"}, time.Second); got != nil {
+		t.Fatalf("expected disabled manager to skip, got %+v", got)
+	}
+}
+
+func TestURIRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a dir", "file.go")
+	if got := URIToPath(PathToURI(path)); got != path {
+		t.Fatalf("round trip mismatch: %q != %q", got, path)
+	}
+}
@@ -0,0 +1,392 @@
+package lsp
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultDiagnosticsTimeout bounds how long an edit waits for diagnostics.
+	DefaultDiagnosticsTimeout = 8 * time.Second
+	diagnosticsSettle         = 400 * time.Millisecond
+	maxReportedDiagnostics    = 20
+)
+
+// ServerConfig describes how to start a language server and which file
+// extensions it handles (extension -> LSP language id).
+type ServerConfig struct {
+	Name        string
+	Command     string
+	Args        []string
+	Languages   map[string]string
+	RootMarkers []string
+}
+
+// DefaultServers returns the built-in server table. Servers that are not
+// installed are skipped at runtime.
+func DefaultServers() []ServerConfig {
+	return []ServerConfig{
+		{Name: "gopls", Command: "gopls", Languages: map[string]string{".go": "go"}, RootMarkers: []string{"go.work", "go.mod"}},
+		{Name: "pyright", Command: "pyright-langserver", Args: []string{"--stdio"}, Languages: map[string]string{".py": "python"}, RootMarkers: []string{"pyrightconfig.json", "pyproject.toml", "setup.py"}},
+		{Name: "typescript", Command: "typescript-language-server", Args: []string{"--stdio"}, Languages: map[string]string{
+			".ts": "typescript", ".tsx": "typescriptreact", ".js": "javascript", ".jsx": "javascriptreact",
+		}, RootMarkers: []string{"tsconfig.json", "jsconfig.json", "package.json"}},
+		{Name: "rust-analyzer", Command: "rust-analyzer", Languages: map[string]string{".rs": "rust"}, RootMarkers: []string{"Cargo.toml"}},
+		{Name: "clangd", Command: "clangd", Languages: map[string]string{
+			".c": "c", ".h": "c", ".cc": "cpp", ".cpp": "cpp", ".hpp": "cpp",
+		}, RootMarkers: []string{"compile_commands.json", "CMakeLists.txt"}},
+	}
+}
+
+type clientKey struct {
+	server string
+	root   string
+}
+
+// Manager starts language servers on demand and tracks the errors last
+// reported for each file so edits can be judged by what they broke.
+type Manager struct {
+	mu       sync.Mutex
+	enabled  bool
+	servers  []ServerConfig
+	clients  map[clientKey]*Client
+	failures map[clientKey]string
+	errors   map[string][]Diagnostic // path -> errors after the last check
+	starter  func(ctx context.Context, server ServerConfig, root string) (*Client, error)
+}
+
+var (
+	managerOnce    sync.Once
+	defaultManager *Manager
+)
+
+// DefaultManager returns the process-wide manager.
+func DefaultManager() *Manager {
+	managerOnce.Do(func() {
+		defaultManager = NewManager()
+	})
+	return defaultManager
+}
+
+// NewManager returns an enabled manager using DefaultServers.
+func NewManager() *Manager {
+	return &Manager{
+		enabled:  true,
+		servers:  DefaultServers(),
+		clients:  map[clientKey]*Client{},
+		failures: map[clientKey]string{},
+		errors:   map[string][]Diagnostic{},
+		starter:  Start,
+	}
+}
+
+// Configure enables or disables the manager and merges user servers over the
+// defaults by name. Running servers are stopped when their config changes.
+func (m *Manager) Configure(enabled bool, overrides []ServerConfig) {
+	servers := DefaultServers()
+	for _, o := range overrides {
+		replaced := false
+		for i := range servers {
+			if servers[i].Name == o.Name {
+				servers[i] = o
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			servers = append(servers, o)
+		}
+	}
+
+	m.mu.Lock()
+	m.enabled = enabled
+	m.servers = servers
+	m.mu.Unlock()
+	m.Shutdown()
+}
+
+// Enabled reports whether diagnostics are collected.
+func (m *Manager) Enabled() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.enabled
+}
+
+// Shutdown stops every running server and forgets start failures, so the
+// next check retries them.
+func (m *Manager) Shutdown() {
+	m.mu.Lock()
+	clients := m.clients
+	m.clients = map[clientKey]*Client{}
+	m.failures = map[clientKey]string{}
+	m.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for _, c := range clients {
+		wg.Add(1)
+		go func(c *Client) {
+			defer wg.Done()
+			c.Close()
+		}(c)
+	}
+	wg.Wait()
+}
+
+// Status describes configured servers and their state, one line each.
+func (m *Manager) Status() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var lines []string
+	for _, s := range m.servers {
+		exts := make([]string, 0, len(s.Languages))
+		for ext := range s.Languages {
+			exts = append(exts, ext)
+		}
+		sort.Strings(exts)
+		state := "idle"
+		if _, err := exec.LookPath(s.Command); err != nil {
+			state = "not installed"
+		}
+		for key, c := range m.clients {
+			if key.server == s.Name && c.Alive() {
+				state = "running (" + key.root + ")"
+			}
+		}
+		for key, msg := range m.failures {
+			if key.server == s.Name {
+				state = "failed: " + msg
+			}
+		}
+		lines = append(lines, fmt.Sprintf("%s [%s] %s: %s", s.Name, strings.Join(exts, " "), s.Command, state))
+	}
+	return lines
+}
+
+// FileReport is the diagnostics outcome for one edited file.
+type FileReport struct {
+	Path     string
+	Server   string
+	Errors   []Diagnostic // all current errors
+	New      []Diagnostic // errors not present before the edit
+	Fixed    int
+	Baseline bool // whether errors from before the edit were known
+	TimedOut bool
+}
+
+// CheckFiles syncs each edited file to its language server and returns the
+// resulting errors. Files without an available server are skipped.
+func (m *Manager) CheckFiles(ctx context.Context, paths []string, timeout time.Duration) []FileReport {
+	if !m.Enabled() || len(paths) == 0 {
+		return nil
+	}
+	if timeout <= 0 {
+		timeout = DefaultDiagnosticsTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	type pendingFile struct {
+		report  FileReport
+		before  []Diagnostic
+		client  *Client
+		version int
+		since   time.Time
+	}
+	var pending []pendingFile
+	seen := map[string]bool{}
+	for _, p := range paths {
+		abs, err := filepath.Abs(p)
+		if err != nil || seen[abs] {
+			continue
+		}
+		seen[abs] = true
+		server, langID, ok := m.serverFor(abs)
+		if !ok {
+			continue
+		}
+		client := m.client(ctx, server, abs)
+		if client == nil {
+			continue
+		}
+		since := time.Now()
+		version, before, known, err := client.Sync(abs, langID)
+		if err != nil {
+			continue
+		}
+		report := FileReport{Path: abs, Server: server.Name}
+		m.mu.Lock()
+		prev, checked := m.errors[abs]
+		m.mu.Unlock()
+		switch {
+		case checked:
+			report.Baseline = true
+			before = prev
+		case known:
+			report.Baseline = true
+			before = onlyErrors(before)
+		}
+		pending = append(pending, pendingFile{report: report, before: before, client: client, version: version, since: since})
+	}
+
+	reports := make([]FileReport, 0, len(pending))
+	for _, p := range pending {
+		diags, fresh := p.client.WaitDiagnostics(ctx, p.report.Path, p.version, p.since, diagnosticsSettle)
+		report := p.report
+		if !fresh {
+			// Whatever is cached may predate the edit; don't report it.
+			report.TimedOut = true
+			reports = append(reports, report)
+			continue
+		}
+		report.Errors = onlyErrors(diags)
+		report.New, report.Fixed = diffDiagnostics(p.before, report.Errors)
+		if !report.Baseline {
+			report.New = report.Errors
+		}
+		m.mu.Lock()
+		m.errors[report.Path] = report.Errors
+		m.mu.Unlock()
+		reports = append(reports, report)
+	}
+	return reports
+}
+
+// serverFor picks the configured server for path by extension, skipping
+// servers that are not installed.
+func (m *Manager) serverFor(path string) (ServerConfig, string, bool) {
+	ext := strings.ToLower(filepath.Ext(path))
+	m.mu.Lock()
+	servers := m.servers
+	m.mu.Unlock()
+	for _, s := range servers {
+		langID, ok := s.Languages[ext]
+		if !ok || strings.TrimSpace(s.Command) == "" {
+			continue
+		}
+		if _, err := exec.LookPath(s.Command); err != nil {
+			continue
+		}
+		return s, langID, true
+	}
+	return ServerConfig{}, "", false
+}
+
+// client returns a running client for the workspace containing path,
+// starting one if needed. Start failures are remembered until Shutdown.
+func (m *Manager) client(ctx context.Context, server ServerConfig, path string) *Client {
+	key := clientKey{server: server.Name, root: findRoot(filepath.Dir(path), server.RootMarkers)}
+
+	m.mu.Lock()
+	if c, ok := m.clients[key]; ok && c.Alive() {
+		m.mu.Unlock()
+		return c
+	}
+	if _, failed := m.failures[key]; failed {
+		m.mu.Unlock()
+		return nil
+	}
+	starter := m.starter
+	m.mu.Unlock()
+
+	c, err := starter(ctx, server, key.root)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err != nil {
+		m.failures[key] = err.Error()
+		return nil
+	}
+	m.clients[key] = c
+	return c
+}
+
+// findRoot walks up from dir to the nearest directory containing one of the
+// markers (or .git), falling back to dir itself.
+func findRoot(dir string, markers []string) string {
+	markers = append(append([]string(nil), markers...), ".git")
+	for _, marker := range markers {
+		for d := dir; ; {
+			if _, err := os.Stat(filepath.Join(d, marker)); err == nil {
+				return d
+			}
+			parent := filepath.Dir(d)
+			if parent == d {
+				break
+			}
+			d = parent
+		}
+	}
+	return dir
+}
+
+func onlyErrors(diags []Diagnostic) []Diagnostic {
+	var out []Diagnostic
+	for _, d := range diags {
+		// Severity is optional; servers that omit it mean "error".
+		if d.Severity == 0 || d.Severity == SeverityError {
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+// diffDiagnostics compares errors by message, ignoring positions that shift
+// with edits. Repeated messages are matched as a multiset.
+func diffDiagnostics(before, after []Diagnostic) (added []Diagnostic, fixed int) {
+	counts := map[string]int{}
+	for _, d := range before {
+		counts[d.Message]++
+	}
+	for _, d := range after {
+		if counts[d.Message] > 0 {
+			counts[d.Message]--
+			continue
+		}
+		added = append(added, d)
+	}
+	for _, n := range counts {
+		fixed += n
+	}
+	return added, fixed
+}
+
+// FormatReports renders reports for a tool response. It returns "" when no
+// file has errors.
+func FormatReports(reports []FileReport) string {
+	var b strings.Builder
+	for _, r := range reports {
+		if len(r.New) == 0 {
+			continue
+		}
+		label := "new errors"
+		if !r.Baseline {
+			label = "errors"
+		}
+		fmt.Fprintf(&b, "%s: %d %s (%s)", r.Path, len(r.New), label, r.Server)
+		if r.Fixed > 0 {
+			fmt.Fprintf(&b, ", %d fixed", r.Fixed)
+		}
+		if existing := len(r.Errors) - len(r.New); r.Baseline && existing > 0 {
+			fmt.Fprintf(&b, ", %d pre-existing", existing)
+		}
+		b.WriteString("\n")
+		for i, d := range r.New {
+			if i >= maxReportedDiagnostics {
+				fmt.Fprintf(&b, "  ... %d more\n", len(r.New)-i)
+				break
+			}
+			fmt.Fprintf(&b, "  %d:%d: %s\n", d.Range.Start.Line+1, d.Range.Start.Character+1, strings.TrimSpace(d.Message))
+		}
+	}
+	if b.Len() == 0 {
+		return ""
+	}
+	return "[Diagnostics] Fix these before moving on:\n" + strings.TrimRight(b.String(), "\n")
+}
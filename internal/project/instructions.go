@@ -0,0 +1,104 @@
+package project
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// InstructionsDir and InstructionsFile name the per-directory instruction
+	// file (.ai2go/instructions.md). AgentsFile is read too for repos that
+	// already ship an AGENTS.md.
+	InstructionsDir  = ".ai2go"
+	InstructionsFile = "instructions.md"
+	AgentsFile       = "AGENTS.md"
+
+	maxInstructionFileBytes = 16 * 1024
+)
+
+// InstructionFile is one instruction file found for a project.
+type InstructionFile struct {
+	Path      string
+	Content   string
+	Truncated bool
+}
+
+// FindInstructionFiles returns instruction file paths in merge order: the
+// user-level file in userDir first, then files from the filesystem root down
+// to workDir, so the most specific instructions come last.
+func FindInstructionFiles(workDir, userDir string) []string {
+	var paths []string
+	seen := map[string]bool{}
+	add := func(path string) {
+		if seen[path] {
+			return
+		}
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			seen[path] = true
+			paths = append(paths, path)
+		}
+	}
+
+	if userDir != "" {
+		add(filepath.Join(userDir, InstructionsFile))
+	}
+
+	var dirs []string
+	if workDir != "" {
+		for current := filepath.Clean(workDir); ; {
+			dirs = append(dirs, current)
+			parent := filepath.Dir(current)
+			if parent == current {
+				break
+			}
+			current = parent
+		}
+	}
+	for i := len(dirs) - 1; i >= 0; i-- {
+		add(filepath.Join(dirs[i], AgentsFile))
+		add(filepath.Join(dirs[i], InstructionsDir, InstructionsFile))
+	}
+	return paths
+}
+
+// LoadInstructions reads the files returned by FindInstructionFiles. Empty or
+// unreadable files are skipped; large files are truncated.
+func LoadInstructions(workDir, userDir string) []InstructionFile {
+	var files []InstructionFile
+	for _, path := range FindInstructionFiles(workDir, userDir) {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		file := InstructionFile{Path: path}
+		if len(content) > maxInstructionFileBytes {
+			content = content[:maxInstructionFileBytes]
+			file.Truncated = true
+		}
+		file.Content = strings.TrimSpace(string(content))
+		if file.Content == "" {
+			continue
+		}
+		files = append(files, file)
+	}
+	return files
+}
+
+// FormatInstructions renders files as a system prompt section, or "" when
+// there are none.
+func FormatInstructions(files []InstructionFile) string {
+	if len(files) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("PROJECT INSTRUCTIONS (from instruction files; later files are more specific and take precedence):\n")
+	for _, f := range files {
+		fmt.Fprintf(&b, "\n--- %s ---\n%s\n", f.Path, f.Content)
+		if f.Truncated {
+			fmt.Fprintf(&b, "[truncated to %d bytes]\n", maxInstructionFileBytes)
+		}
+	}
+	return b.String()
+}
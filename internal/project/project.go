@@ -0,0 +1,61 @@
+package project
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// Info identifies the project ai2go was started in.
+type Info struct {
+	WorkDir string
+	GitRoot string
+}
+
+// Detect resolves the current working directory and its enclosing git root, if any.
+func Detect() Info {
+	wd, err := os.Getwd()
+	if err != nil {
+		return Info{}
+	}
+	if abs, err := filepath.Abs(wd); err == nil {
+		wd = abs
+	}
+	return Info{WorkDir: wd, GitRoot: FindGitRoot(wd)}
+}
+
+// Key returns the identifier used to group threads and per-project state:
+// the git root when there is one, otherwise the working directory.
+func (i Info) Key() string {
+	if i.GitRoot != "" {
+		return i.GitRoot
+	}
+	return i.WorkDir
+}
+
+// Name returns a short display name for the project.
+func (i Info) Name() string {
+	key := i.Key()
+	if key == "" {
+		return ""
+	}
+	return filepath.Base(key)
+}
+
+// FindGitRoot walks up from dir and returns the first directory containing a
+// .git entry (directory or worktree file). It returns "" if none is found.
+func FindGitRoot(dir string) string {
+	if dir == "" {
+		return ""
+	}
+	current := filepath.Clean(dir)
+	for {
+		if _, err := os.Stat(filepath.Join(current, ".git")); err == nil {
+			return current
+		}
+		parent := filepath.Dir(current)
+		if parent == current {
+			return ""
+		}
+		current = parent
+	}
+}
@@ -0,0 +1,74 @@
+package project
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFindGitRootWalksUp(t *testing.T) {
+	root := t.TempDir()
+	if err := os.Mkdir(filepath.Join(root, ".git"), 0755); err != nil {
+		t.Fatalf("mkdir .git: %v", err)
+	}
+	nested := filepath.Join(root, "a", "b")
+	if err := os.MkdirAll(nested, 0755); err != nil {
+		t.Fatalf("mkdir nested: %v", err)
+	}
+
+	if got := FindGitRoot(nested); got != root {
+		t.Fatalf("expected git root %s, got %q", root, got)
+	}
+
+	info := Info{WorkDir: nested, GitRoot: FindGitRoot(nested)}
+	if info.Key() != root {
+		t.Fatalf("expected key to prefer git root, got %q", info.Key())
+	}
+}
+
+func TestKeyFallsBackToWorkDir(t *testing.T) {
+	info := Info{WorkDir: "/tmp/plain"}
+	if info.Key() != "/tmp/plain" {
+		t.Fatalf("unexpected key: %q", info.Key())
+	}
+	if info.Name() != "plain" {
+		t.Fatalf("unexpected name: %q", info.Name())
+	}
+}
+
+func TestLoadInstructionsMergesUserThenOuterToInner(t *testing.T) {
+	root := t.TempDir()
+	userDir := filepath.Join(root, "config")
+	repo := filepath.Join(root, "repo")
+	nested := filepath.Join(repo, "svc")
+	for _, dir := range []string{userDir, filepath.Join(repo, InstructionsDir), filepath.Join(nested, InstructionsDir)} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("mkdir %s: %v", dir, err)
+		}
+	}
+	writes := map[string]string{
+		filepath.Join(userDir, InstructionsFile):                 "user rules",
+		filepath.Join(repo, AgentsFile):                          "repo agents",
+		filepath.Join(repo, InstructionsDir, InstructionsFile):   "repo rules",
+		filepath.Join(nested, InstructionsDir, InstructionsFile): "   ",
+	}
+	for path, content := range writes {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("write %s: %v", path, err)
+		}
+	}
+
+	files := LoadInstructions(nested, userDir)
+	var got []string
+	for _, f := range files {
+		got = append(got, f.Content)
+	}
+	want := []string{"user rules", "repo agents", "repo rules"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	if section := FormatInstructions(files); !strings.Contains(section, filepath.Join(repo, AgentsFile)) {
+		t.Fatalf("expected file paths in section, got %q", section)
+	}
+}
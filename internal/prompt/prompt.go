@@ -0,0 +1,163 @@
+package prompt
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// DefaultPersona is used when a thread has no persona set.
+const DefaultPersona = "default"
+
+const (
+	personaDir = "personas"
+	personaExt = ".tmpl"
+)
+
+//go:embed personas/*.tmpl
+var builtinPersonas embed.FS
+
+// Data is passed to persona templates.
+type Data struct {
+	OS      string
+	CWD     string
+	Model   string
+	Date    string
+	Persona string
+	// Tools lists enabled tool names. A nil slice means every tool is enabled.
+	Tools []string
+}
+
+// HasTool reports whether name is enabled.
+func (d Data) HasTool(name string) bool {
+	if d.Tools == nil {
+		return true
+	}
+	for _, t := range d.Tools {
+		if t == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Rules returns the tool-usage and general rules that apply to the enabled tools.
+func (d Data) Rules() []string {
+	var out []string
+	for _, r := range toolRules {
+		if len(r.tools) == 0 {
+			out = append(out, r.text)
+			continue
+		}
+		for _, name := range r.tools {
+			if d.HasTool(name) {
+				out = append(out, r.text)
+				break
+			}
+		}
+	}
+	return out
+}
+
+// Persona describes an available persona template.
+type Persona struct {
+	Name string
+	// Path is the user template file, or "" for a builtin persona.
+	Path string
+}
+
+// Personas lists builtin personas and user templates in userDir/personas.
+// User templates override builtins with the same name.
+func Personas(userDir string) []Persona {
+	byName := map[string]Persona{}
+	if entries, err := builtinPersonas.ReadDir(personaDir); err == nil {
+		for _, e := range entries {
+			name := strings.TrimSuffix(e.Name(), personaExt)
+			byName[name] = Persona{Name: name}
+		}
+	}
+	if userDir != "" {
+		matches, _ := filepath.Glob(filepath.Join(userDir, personaDir, "*"+personaExt))
+		for _, path := range matches {
+			name := strings.TrimSuffix(filepath.Base(path), personaExt)
+			byName[name] = Persona{Name: name, Path: path}
+		}
+	}
+
+	out := make([]Persona, 0, len(byName))
+	for _, p := range byName {
+		out = append(out, p)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// Exists reports whether persona is a builtin or user template.
+func Exists(persona, userDir string) bool {
+	for _, p := range Personas(userDir) {
+		if p.Name == persona {
+			return true
+		}
+	}
+	return false
+}
+
+// Render executes the template for persona (DefaultPersona when empty).
+func Render(persona, userDir string, data Data) (string, error) {
+	if persona == "" {
+		persona = DefaultPersona
+	}
+	data.Persona = persona
+
+	source, err := loadTemplate(persona, userDir)
+	if err != nil {
+		return "", err
+	}
+	tmpl, err := template.New(persona).Funcs(template.FuncMap{
+		"numbered": numbered,
+		"join":     strings.Join,
+	}).Parse(source)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse persona %q: %w", persona, err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render persona %q: %w", persona, err)
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
+
+func loadTemplate(persona, userDir string) (string, error) {
+	if strings.ContainsAny(persona, `/\`) || strings.HasPrefix(persona, ".") {
+		return "", fmt.Errorf("invalid persona name %q", persona)
+	}
+	if userDir != "" {
+		content, err := os.ReadFile(filepath.Join(userDir, personaDir, persona+personaExt))
+		if err == nil {
+			return string(content), nil
+		}
+		if !os.IsNotExist(err) {
+			return "", fmt.Errorf("failed to read persona %q: %w", persona, err)
+		}
+	}
+	content, err := builtinPersonas.ReadFile(personaDir + "/" + persona + personaExt)
+	if err != nil {
+		return "", fmt.Errorf("unknown persona %q", persona)
+	}
+	return string(content), nil
+}
+
+// numbered renders items as a 1-based numbered list.
+func numbered(items []string) string {
+	var b strings.Builder
+	for i, item := range items {
+		fmt.Fprintf(&b, "%d. %s\n", i+1, item)
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
@@ -0,0 +1,55 @@
+package prompt
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRenderGeneratesRulesForEnabledTools(t *testing.T) {
+	data := Data{OS: "Linux/Mac", CWD: "/src/app", Model: "m", Date: "2026-01-02", Tools: []string{"run_command", "read_file"}}
+	out, err := Render("", "", data)
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	if !strings.Contains(out, "1. You can use 'run_command'") || !strings.Contains(out, "Working directory: /src/app") {
+		t.Fatalf("unexpected prompt:\n%s", out)
+	}
+	if strings.Contains(out, "subagent_factory") || strings.Contains(out, "patch_file") {
+		t.Fatalf("prompt mentions disabled tools:\n%s", out)
+	}
+
+	all, err := Render(DefaultPersona, "", Data{})
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	if !strings.Contains(all, "subagent_factory") {
+		t.Fatal("expected nil Tools to enable every rule")
+	}
+}
+
+func TestUserPersonaOverridesBuiltin(t *testing.T) {
+	userDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(userDir, personaDir), 0755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	custom := filepath.Join(userDir, personaDir, "reviewer.tmpl")
+	if err := os.WriteFile(custom, []byte("custom {{.Persona}} on {{.Model}}"), 0644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	out, err := Render("reviewer", userDir, Data{Model: "m1"})
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	if out != "custom reviewer on m1" {
+		t.Fatalf("unexpected output %q", out)
+	}
+	if !Exists("ops", userDir) || Exists("missing", userDir) {
+		t.Fatal("Exists misreported personas")
+	}
+	if _, err := Render("../escape", userDir, Data{}); err == nil {
+		t.Fatal("expected invalid persona name to fail")
+	}
+}
@@ -0,0 +1,77 @@
+package prompt
+
+// rule is one numbered line of the system prompt. A rule with no tools is
+// always included; otherwise it is included when any listed tool is enabled.
+type rule struct {
+	tools []string
+	text  string
+}
+
+// toolRules mirrors the order of the original hardcoded prompt so that the
+// default persona renders the same rules when every tool is enabled.
+var toolRules = []rule{
+	{[]string{"run_command"}, "You can use 'run_command' to execute shell commands."},
+	{[]string{"read_file"}, "You can use 'read_file' to inspect files with line numbers."},
+	{[]string{"patch_file"}, "Use 'patch_file' as the default editor for file changes."},
+	{[]string{"edit_file"}, "Prefer 'edit_file' over line-number edits for changes to existing files: copy old_string exactly from 'read_file' output (without the line-number prefixes) with enough context to match once."},
+	{[]string{"apply_unified_diff_patch"}, `Use 'apply_unified_diff_patch' only when multi-file atomic edits are required.
+   - Required args: 'work_tree', 'patch'
+   - Optional: 'verify_mode': 'none', 'syntax', 'tests', 'default' or a project profile from .ai2go/verify.json
+   - Patch needs ---/+++ headers and @@ hunks; line numbers may be approximate. Use /dev/null paths to create or delete files.`},
+	{[]string{"apply_unified_diff_patch"}, "If 'apply_unified_diff_patch' rejects hunks, nothing was written: re-read the lines named in each rejection and resend the patch with corrected context. Switch to 'patch_file' only for parse errors or repeated rejections."},
+	{[]string{"read_file"}, "After editing a file, re-run 'read_file' on the changed range to verify the result before claiming completion."},
+	{[]string{"edit_transaction"}, "For changes spanning several files (renames, refactors, new files plus edits), use one 'edit_transaction' so a failing step leaves the tree untouched instead of half-edited."},
+	{[]string{"patch_file", "edit_file", "edit_transaction", "apply_unified_diff_patch", "go_rename_symbol", "go_replace_function_body", "go_edit_imports", "go_add_struct_field", "go_add_method"}, "If an edit result ends with a '[Diagnostics]' section, fix those errors before moving on to other work."},
+	{[]string{"patch_file", "edit_file", "edit_transaction", "apply_unified_diff_patch", "go_rename_symbol", "go_replace_function_body", "go_edit_imports", "go_add_struct_field", "go_add_method"}, "Pass 'verify' (or 'verify_mode') for edits that could break the build; a failing '[Verify]' report means the edit was undone unless it says the changes were kept."},
+	{[]string{"patch_file", "edit_file", "edit_transaction", "apply_unified_diff_patch", "replace_line_range"}, "A '[Format]' section in an edit result means the project's formatter rewrote the file as shown; do not re-indent by hand, and re-read line numbers before further line-based edits."},
+	{nil, "If user scope says one file, stay on that file unless user expands scope."},
+	{[]string{"create_checkpoint", "editor_history", "undo_checkpoints"}, "You can use 'create_checkpoint', 'editor_history', and 'undo_checkpoints' for manual checkpoint workflow. Pass a 'checkpoint' hash to 'editor_history' to see what that checkpoint changed."},
+	{[]string{"restore_checkpoint_files", "tag_checkpoint"}, "To roll back part of your work, prefer 'restore_checkpoint_files' with the affected paths over 'undo_checkpoints'; it leaves other files alone. 'tag_checkpoint' names a known-good state before risky changes."},
+	{[]string{"get_process_cpu_usage_sample", "send_process_signal", "get_page_size"}, `You can use process/system helpers when needed:
+   - 'get_process_cpu_usage_sample' for PID CPU sampling
+   - 'send_process_signal' for process tree signals
+   - 'get_page_size' for OS page size`},
+	{[]string{"subagent_factory"}, "You can use 'subagent_factory' to split a mega task into concurrent subagent tasks and generate a report (requires experimental mode ON)."},
+	{[]string{"subagent_context_provider"}, "You can use 'subagent_context_provider' with task_id to fetch summarized volatile context from a subagent run."},
+	{[]string{"project_architect"}, "You can use 'project_architect' to transform a rough project request into a detailed, implementation-ready step/task plan."},
+	{[]string{"project_architect"}, "If user asks to create a big project, or asks for long multi-step work with subagents, FIRST call 'project_architect' using the user request as prompt, then split/delegate tasks to subagents."},
+	{[]string{"subagent_factory"}, "For delegated execution, decide required subagent count from the generated plan and assign one concrete task per subagent."},
+	{[]string{"project_architect"}, "Subagents do not need 'project_architect'; planner is for main agent orchestration."},
+	{[]string{"subagent_factory"}, "When calling 'subagent_factory' for coding tasks, pass explicit 'timeout_sec' and 'max_concurrency'. Prefer lower concurrency for tasks that touch shared files."},
+	{[]string{"subagent_factory"}, "Do not run dependent file-overlapping tasks in parallel. Run them step-by-step if they modify the same modules."},
+	{nil, `HANDLING LONG OUTPUT:
+   - If a command returns "[OUTPUT TRUNCATED]", DO NOT apologize.
+   - IMMEDIATELY run a new command to filter the data (e.g., 'grep "error" file.log', 'tail -n 10 file.log').
+   - Never output huge chunks of text yourself.`},
+	{[]string{"ask_user"}, `Use 'ask_user' when requirements are ambiguous or there are multiple valid solution paths.
+    - Pass a clear 'question'.
+    - Add 'options' only if useful; otherwise ask free text.
+    - You may ask follow-up questions via repeated 'ask_user' calls until requirements are clear.`},
+	{[]string{"organize_media_files"}, `For large messy media folders, prefer 'organize_media_files' instead of long shell loops:
+    - First run with dry_run=true and show preview summary.
+    - Then ask for confirmation and run with dry_run=false.`},
+	{[]string{"find_files", "grep_code"}, "Prefer 'find_files' and 'grep_code' over shell find/grep loops to locate files and code; they respect .gitignore and cap output. Narrow with 'glob', 'extensions' or 'path' when results are truncated."},
+	{[]string{"go_list_symbols", "go_show_function", "go_find_definition", "go_find_references"}, `For Go code, navigate by symbol instead of reading whole files:
+    - 'go_list_symbols' for declarations and exact line ranges in a file or package
+    - 'go_show_function' to read one function or 'Type.Method'
+    - 'go_find_definition' and 'go_find_references' for an identifier at a file/line
+    - Use the returned line numbers for 'read_file' ranges and line-based edits.`},
+	{[]string{"go_rename_symbol", "go_replace_function_body", "go_edit_imports", "go_add_struct_field", "go_add_method"}, `For structural Go changes, prefer the AST tools over line edits; they gofmt the result and return a diff:
+    - 'go_rename_symbol' instead of search-and-replace renames
+    - 'go_replace_function_body', 'go_add_method' and 'go_add_struct_field' to change declarations by name
+    - 'go_edit_imports' to add or drop imports after changing code`},
+	{[]string{"remove_lines", "replace_line_range", "batch_line_operations", "delete_lines_by_pattern", "extract_line_range", "reorder_line_range", "remove_duplicate_lines"}, `You can use line/text-edit tools for precise file operations:
+    - 'remove_lines', 'replace_line_range', 'batch_line_operations'
+    - 'delete_lines_by_pattern', 'extract_line_range'
+    - 'reorder_line_range', 'remove_duplicate_lines'`},
+	{[]string{"show_file_diff", "compare_files_side_by_side", "create_file_backup", "restore_file_backup", "list_file_backups", "merge_files", "resolve_merge_conflict", "detect_file_type"}, `You can use file-management tools when working with versions/compare/merge:
+    - 'show_file_diff', 'compare_files_side_by_side'
+    - 'create_file_backup', 'restore_file_backup', 'list_file_backups'
+    - 'merge_files', 'resolve_merge_conflict', 'detect_file_type'`},
+	{[]string{"show_file_diff"}, "'show_file_diff' returns minimal hunks; use algorithm=patience when a diff of moved or brace-heavy code looks scrambled."},
+	{[]string{"resolve_merge_conflict"}, "After 'merge_files' reports conflicts, resolve them one at a time with 'resolve_merge_conflict', re-listing conflicts when unsure of the numbering."},
+	{[]string{"mini_editor_helper"}, "For delegated text-only work, use 'mini_editor_helper' with a focused prompt; it runs a minimal helper loop and returns a report."},
+	{[]string{"mini_file_helper"}, "For delegated file-management work, use 'mini_file_helper' with a focused prompt."},
+	{[]string{"remember", "recall", "forget"}, "Use 'remember' to save durable facts (build commands, conventions, user preferences) for future threads, 'recall' to look them up, and 'forget' to drop outdated ones."},
+	{nil, "Always explain your plan briefly before executing commands."},
+}
@@ -0,0 +1,425 @@
+package redact
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/bilbilaki/ai2go/internal/secure"
+)
+
+const (
+	minEntropyTokenLen = 32
+	minTokenEntropy    = 4.3
+)
+
+// placeholderPattern matches placeholders produced by Redact, e.g. [REDACTED:aws_access_key:1a2b3c4d].
+var placeholderPattern = regexp.MustCompile(`\[REDACTED:[a-z0-9_]+:[0-9a-f]{8}\]`)
+
+type rule struct {
+	kind string
+	re   *regexp.Regexp
+	// code, when set, reports matches that are source code rather than a
+	// literal secret, e.g. password=os.Getenv("X").
+	code func(value string) bool
+}
+
+// builtinRules cover common credential formats. Rules with a "secret" group
+// only replace that group so surrounding keys like `password=` stay readable.
+var builtinRules = []rule{
+	{"private_key", regexp.MustCompile(`-----BEGIN [A-Z0-9 ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z0-9 ]*PRIVATE KEY-----`), nil},
+	{"aws_access_key", regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`), nil},
+	{"aws_secret_key", regexp.MustCompile(`(?i)aws_secret_access_key["']?\s*[=:]\s*["']?(?P<secret>[A-Za-z0-9/+=]{40})`), nil},
+	{"gcp_api_key", regexp.MustCompile(`\bAIza[0-9A-Za-z_\-]{35}\b`), nil},
+	{"github_token", regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,})\b`), nil},
+	{"slack_token", regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9-]{10,}\b`), nil},
+	{"stripe_key", regexp.MustCompile(`\b[rs]k_(?:live|test)_[A-Za-z0-9]{16,}\b`), nil},
+	{"api_key", regexp.MustCompile(`\bsk-[A-Za-z0-9_\-]{20,}\b`), nil},
+	{"jwt", regexp.MustCompile(`\beyJ[A-Za-z0-9_\-]{8,}\.eyJ[A-Za-z0-9_\-]{8,}\.[A-Za-z0-9_\-]{8,}\b`), nil},
+	{"kube_client_key", regexp.MustCompile(`client-key-data:\s*(?P<secret>[A-Za-z0-9+/=]{20,})`), nil},
+	{"assigned_secret", regexp.MustCompile(`(?i)\b[a-z0-9_.\-]*(?:password|passwd|secret|token|api[_\-]?key|access[_\-]?key|private[_\-]?key)["']?\s*[=:]\s*["']?(?P<secret>[^\s"'` + "`" + `]{8,})`), isCodeReference},
+}
+
+var (
+	codeSelector   = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(?:\.[A-Za-z_][A-Za-z0-9_]*)+$`)
+	codeIdentifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z_]*$`)
+	camelCaseHump  = regexp.MustCompile(`[a-z][A-Z]`)
+)
+
+// isCodeReference reports whether an assigned value is an expression rather
+// than a literal: a call, a selector such as cfg.Password, a variable
+// reference like $DB_PASS or {{ .Token }}, or a camelCase or snake_case
+// identifier without digits. Lower-case words such as "hunter2hunter2" or
+// "correcthorse" are still treated as secrets.
+func isCodeReference(value string) bool {
+	value = strings.TrimRight(strings.TrimLeft(value, "="), ",;")
+	switch {
+	case value == "":
+		return false
+	case strings.ContainsAny(value, "()"), strings.ContainsAny(value[:1], "$%{<"):
+		return true
+	case codeSelector.MatchString(value):
+		return true
+	}
+	return codeIdentifier.MatchString(value) && (strings.Contains(value, "_") || camelCaseHump.MatchString(value))
+}
+
+var entropyCandidate = regexp.MustCompile(`[A-Za-z0-9+/_\-=]{32,}`)
+
+// Redactor replaces secrets in text with stable placeholders and keeps the
+// placeholder-to-value mapping so tool arguments can be restored locally.
+// Placeholders are keyed with a random key, so they do not reveal a hash of
+// the underlying value. A Redactor returned by Load persists the key and the
+// mapping, so placeholders saved in threads still restore after a restart.
+type Redactor struct {
+	mu      sync.Mutex
+	key     []byte
+	rules   []rule
+	custom  []string
+	byValue map[string]string
+	byToken map[string]string
+
+	path    string
+	vault   *secure.Vault
+	saveErr error
+}
+
+// storeData is the on-disk state of a persistent Redactor.
+type storeData struct {
+	Key     string            `json:"key"`
+	Secrets map[string]string `json:"secrets"`
+}
+
+// New returns an in-memory Redactor with the builtin rules plus the given
+// user regexes. Its placeholders are only stable within the process.
+func New(customPatterns []string) (*Redactor, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate redaction key: %w", err)
+	}
+	r := &Redactor{
+		key:     key,
+		byValue: map[string]string{},
+		byToken: map[string]string{},
+	}
+	if err := r.SetCustomPatterns(customPatterns); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Load returns a Redactor whose key and placeholder mapping persist in path,
+// created on first use. The file holds the real secret values, so it is
+// written 0600 and sealed with vault when encryption at rest is on.
+func Load(path string, vault *secure.Vault, customPatterns []string) (*Redactor, error) {
+	r, err := New(customPatterns)
+	if err != nil {
+		return nil, err
+	}
+	r.path, r.vault = path, vault
+
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return r, r.save()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read redaction store: %w", err)
+	}
+	if secure.IsSealed(content) {
+		if vault == nil {
+			return nil, fmt.Errorf("redaction store is encrypted; enable encryption in config and unlock it")
+		}
+		if content, err = vault.Open(content); err != nil {
+			return nil, fmt.Errorf("failed to decrypt redaction store: %w", err)
+		}
+	}
+	var data storeData
+	if err := json.Unmarshal(content, &data); err != nil {
+		return nil, fmt.Errorf("failed to parse redaction store: %w", err)
+	}
+	if key, err := hex.DecodeString(data.Key); err == nil && len(key) == len(r.key) {
+		r.key = key
+	} else {
+		return nil, fmt.Errorf("redaction store has an invalid key")
+	}
+	for token, value := range data.Secrets {
+		r.byToken[token] = value
+		r.byValue[value] = token
+	}
+	return r, nil
+}
+
+// SetVault switches encryption of the persisted mapping on (non-nil) or off
+// (nil) and rewrites it.
+func (r *Redactor) SetVault(vault *secure.Vault) error {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.vault = vault
+	return r.save()
+}
+
+// Err returns the last error from persisting new placeholders, if any.
+func (r *Redactor) Err() error {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.saveErr
+}
+
+// save writes the key and mapping; callers hold r.mu or own r exclusively.
+func (r *Redactor) save() error {
+	if r.path == "" {
+		return nil
+	}
+	content, err := json.MarshalIndent(storeData{Key: hex.EncodeToString(r.key), Secrets: r.byToken}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal redaction store: %w", err)
+	}
+	if r.vault != nil {
+		if content, err = r.vault.Seal(content); err != nil {
+			return fmt.Errorf("failed to encrypt redaction store: %w", err)
+		}
+	}
+	if err := os.WriteFile(r.path, content, 0600); err != nil {
+		return fmt.Errorf("failed to write redaction store: %w", err)
+	}
+	if err := os.Chmod(r.path, 0600); err != nil {
+		return fmt.Errorf("failed to restrict redaction store permissions: %w", err)
+	}
+	return nil
+}
+
+// SetCustomPatterns replaces the user-defined regexes, keeping known mappings.
+func (r *Redactor) SetCustomPatterns(patterns []string) error {
+	rules := make([]rule, 0, len(builtinRules)+len(patterns))
+	rules = append(rules, builtinRules...)
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return fmt.Errorf("invalid redaction pattern %q: %w", p, err)
+		}
+		rules = append(rules, rule{kind: "custom", re: re})
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.rules = rules
+	r.custom = append([]string(nil), patterns...)
+	return nil
+}
+
+// Redact replaces every detected secret in text and returns the redacted
+// text along with the number of replacements made.
+func (r *Redactor) Redact(text string) (string, int) {
+	if r == nil || text == "" {
+		return text, 0
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	count, known := 0, len(r.byToken)
+	for _, rl := range r.rules {
+		text = r.replaceRule(text, rl, &count)
+	}
+	text = entropyCandidate.ReplaceAllStringFunc(text, func(tok string) string {
+		if placeholderPattern.MatchString(tok) || !looksRandom(tok) {
+			return tok
+		}
+		count++
+		return r.placeholder("token", tok)
+	})
+	if len(r.byToken) != known {
+		r.saveErr = r.save()
+	}
+	return text, count
+}
+
+// Restore swaps known placeholders in text back to their real values.
+func (r *Redactor) Restore(text string) string {
+	return r.restore(text, false)
+}
+
+// RestoreJSON is like Restore but JSON-escapes values so it is safe to run on
+// raw tool-call argument strings.
+func (r *Redactor) RestoreJSON(raw string) string {
+	return r.restore(raw, true)
+}
+
+// Count returns the number of distinct secrets seen so far.
+func (r *Redactor) Count() int {
+	if r == nil {
+		return 0
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.byToken)
+}
+
+// CustomPatterns returns the user-defined regexes currently in use.
+func (r *Redactor) CustomPatterns() []string {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.custom...)
+}
+
+func (r *Redactor) kinds() map[string]int {
+	out := map[string]int{}
+	if r == nil {
+		return out
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for token := range r.byToken {
+		parts := strings.Split(strings.Trim(token, "[]"), ":")
+		if len(parts) == 3 {
+			out[parts[1]]++
+		}
+	}
+	return out
+}
+
+// SortedKinds returns the number of distinct secrets seen per kind as
+// "kind=N" strings in a stable order.
+func (r *Redactor) SortedKinds() []string {
+	kinds := r.kinds()
+	out := make([]string, 0, len(kinds))
+	for kind, n := range kinds {
+		out = append(out, fmt.Sprintf("%s=%d", kind, n))
+	}
+	sort.Strings(out)
+	return out
+}
+
+func (r *Redactor) replaceRule(text string, rl rule, count *int) string {
+	secretIdx := rl.re.SubexpIndex("secret")
+	matches := rl.re.FindAllStringSubmatchIndex(text, -1)
+	if len(matches) == 0 {
+		return text
+	}
+
+	existing := placeholderPattern.FindAllStringIndex(text, -1)
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		if overlapsAny(m[0], m[1], existing) {
+			continue
+		}
+		start, end := m[0], m[1]
+		if secretIdx > 0 && m[2*secretIdx] >= 0 {
+			start, end = m[2*secretIdx], m[2*secretIdx+1]
+		}
+		if start < last {
+			continue
+		}
+		value := text[start:end]
+		if rl.code != nil && rl.code(value) {
+			continue
+		}
+		b.WriteString(text[last:start])
+		b.WriteString(r.placeholder(rl.kind, value))
+		last = end
+		*count++
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+// overlapsAny reports whether [start, end) intersects one of spans, so earlier
+// placeholders are never rewritten by later rules.
+func overlapsAny(start, end int, spans [][]int) bool {
+	for _, span := range spans {
+		if start < span[1] && span[0] < end {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Redactor) placeholder(kind, value string) string {
+	if token, ok := r.byValue[value]; ok {
+		return token
+	}
+	mac := hmac.New(sha256.New, r.key)
+	mac.Write([]byte(value))
+	token := fmt.Sprintf("[REDACTED:%s:%s]", kind, hex.EncodeToString(mac.Sum(nil))[:8])
+	r.byValue[value] = token
+	r.byToken[token] = value
+	return token
+}
+
+func (r *Redactor) restore(text string, jsonEscape bool) string {
+	if r == nil || !strings.Contains(text, "[REDACTED:") {
+		return text
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return placeholderPattern.ReplaceAllStringFunc(text, func(token string) string {
+		value, ok := r.byToken[token]
+		if !ok {
+			return token
+		}
+		if jsonEscape {
+			encoded, err := json.Marshal(value)
+			if err != nil {
+				return token
+			}
+			return string(encoded[1 : len(encoded)-1])
+		}
+		return value
+	})
+}
+
+// looksRandom reports whether tok has enough entropy and character variety to
+// be a generated credential rather than a path, identifier or hex digest.
+func looksRandom(tok string) bool {
+	if len(tok) < minEntropyTokenLen || strings.Count(tok, "/") > 2 {
+		return false
+	}
+	var upper, lower, digit bool
+	for _, c := range tok {
+		switch {
+		case c >= 'A' && c <= 'Z':
+			upper = true
+		case c >= 'a' && c <= 'z':
+			lower = true
+		case c >= '0' && c <= '9':
+			digit = true
+		}
+	}
+	if !(upper && lower && digit) {
+		return false
+	}
+	return shannonEntropy(tok) >= minTokenEntropy
+}
+
+func shannonEntropy(s string) float64 {
+	freq := map[rune]float64{}
+	for _, c := range s {
+		freq[c]++
+	}
+	n := float64(len([]rune(s)))
+	entropy := 0.0
+	for _, f := range freq {
+		p := f / n
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
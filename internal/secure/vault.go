@@ -0,0 +1,243 @@
+package secure
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+const (
+	vaultFile       = "vault.json"
+	vaultVersion    = 1
+	sealedPrefix    = "enc:v1:"
+	envelopeMarker  = "ai2go_encrypted"
+	keyLen          = 32
+	saltLen         = 16
+	argonTime       = 3
+	argonMemoryKiB  = 64 * 1024
+	argonThreads    = 4
+	verifyPlaintext = "ai2go-vault-check"
+)
+
+// ErrWrongSecret is returned by Unlock when the passphrase or key file does not match.
+var ErrWrongSecret = errors.New("wrong passphrase or key file")
+
+// Vault holds an unlocked data key used to encrypt the thread store and
+// secret config fields. Keys are derived with argon2id from a passphrase or
+// the contents of a key file.
+type Vault struct {
+	key []byte
+}
+
+type vaultMeta struct {
+	Version int    `json:"version"`
+	KDF     string `json:"kdf"`
+	Time    uint32 `json:"time"`
+	Memory  uint32 `json:"memory_kib"`
+	Threads uint8  `json:"threads"`
+	Salt    string `json:"salt"`
+	Check   string `json:"check"`
+}
+
+type envelope struct {
+	Marker     int    `json:"ai2go_encrypted"`
+	Ciphertext string `json:"ciphertext"`
+}
+
+// Exists reports whether a vault has been initialised in dir.
+func Exists(dir string) bool {
+	_, err := os.Stat(filepath.Join(dir, vaultFile))
+	return err == nil
+}
+
+// Init creates a new vault in dir keyed by secret, replacing any existing one.
+func Init(dir string, secret []byte) (*Vault, error) {
+	if len(secret) == 0 {
+		return nil, fmt.Errorf("passphrase cannot be empty")
+	}
+	salt := make([]byte, saltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+	meta := vaultMeta{
+		Version: vaultVersion,
+		KDF:     "argon2id",
+		Time:    argonTime,
+		Memory:  argonMemoryKiB,
+		Threads: argonThreads,
+		Salt:    base64.StdEncoding.EncodeToString(salt),
+	}
+	v := &Vault{key: deriveKey(secret, salt, meta)}
+	check, err := v.SealString(verifyPlaintext)
+	if err != nil {
+		return nil, err
+	}
+	meta.Check = check
+
+	blob, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal vault metadata: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, vaultFile), blob, 0600); err != nil {
+		return nil, fmt.Errorf("failed to write vault metadata: %w", err)
+	}
+	return v, nil
+}
+
+// Unlock derives the vault key from secret and verifies it against dir's vault.
+func Unlock(dir string, secret []byte) (*Vault, error) {
+	blob, err := os.ReadFile(filepath.Join(dir, vaultFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read vault metadata: %w", err)
+	}
+	var meta vaultMeta
+	if err := json.Unmarshal(blob, &meta); err != nil {
+		return nil, fmt.Errorf("failed to parse vault metadata: %w", err)
+	}
+	if meta.Version != vaultVersion || meta.KDF != "argon2id" {
+		return nil, fmt.Errorf("unsupported vault format (version=%d kdf=%s)", meta.Version, meta.KDF)
+	}
+	salt, err := base64.StdEncoding.DecodeString(meta.Salt)
+	if err != nil {
+		return nil, fmt.Errorf("invalid vault salt: %w", err)
+	}
+
+	v := &Vault{key: deriveKey(secret, salt, meta)}
+	check, err := v.OpenString(meta.Check)
+	if err != nil || check != verifyPlaintext {
+		return nil, ErrWrongSecret
+	}
+	return v, nil
+}
+
+// Remove deletes the vault metadata from dir.
+func Remove(dir string) error {
+	if err := os.Remove(filepath.Join(dir, vaultFile)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove vault metadata: %w", err)
+	}
+	return nil
+}
+
+// ReadKeyFile loads key material from path. Surrounding whitespace is ignored
+// so key files written by `openssl rand -base64 32 > key` work as-is.
+func ReadKeyFile(path string) ([]byte, error) {
+	blob, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key file: %w", err)
+	}
+	secret := []byte(strings.TrimSpace(string(blob)))
+	if len(secret) < 16 {
+		return nil, fmt.Errorf("key file %s is too short (need at least 16 bytes)", path)
+	}
+	return secret, nil
+}
+
+// Seal encrypts a whole file payload into a JSON envelope.
+func (v *Vault) Seal(plain []byte) ([]byte, error) {
+	ct, err := v.encrypt(plain)
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(envelope{Marker: vaultVersion, Ciphertext: ct}, "", "  ")
+}
+
+// Open decrypts a payload produced by Seal.
+func (v *Vault) Open(data []byte) ([]byte, error) {
+	var env envelope
+	if err := json.Unmarshal(data, &env); err != nil || env.Marker == 0 {
+		return nil, fmt.Errorf("data is not an encrypted envelope")
+	}
+	return v.decrypt(env.Ciphertext)
+}
+
+// SealString encrypts a single config value into an "enc:v1:" string.
+func (v *Vault) SealString(plain string) (string, error) {
+	ct, err := v.encrypt([]byte(plain))
+	if err != nil {
+		return "", err
+	}
+	return sealedPrefix + ct, nil
+}
+
+// OpenString decrypts a value produced by SealString.
+func (v *Vault) OpenString(sealed string) (string, error) {
+	if !IsSealedString(sealed) {
+		return "", fmt.Errorf("value is not encrypted")
+	}
+	plain, err := v.decrypt(strings.TrimPrefix(sealed, sealedPrefix))
+	if err != nil {
+		return "", err
+	}
+	return string(plain), nil
+}
+
+// IsSealed reports whether data is an encrypted envelope written by Seal.
+func IsSealed(data []byte) bool {
+	var probe map[string]json.RawMessage
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return false
+	}
+	_, ok := probe[envelopeMarker]
+	return ok
+}
+
+// IsSealedString reports whether s was produced by SealString.
+func IsSealedString(s string) bool {
+	return strings.HasPrefix(s, sealedPrefix)
+}
+
+func (v *Vault) encrypt(plain []byte) (string, error) {
+	gcm, err := v.gcm()
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	sealed := gcm.Seal(nonce, nonce, plain, nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func (v *Vault) decrypt(encoded string) ([]byte, error) {
+	raw, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ciphertext encoding: %w", err)
+	}
+	gcm, err := v.gcm()
+	if err != nil {
+		return nil, err
+	}
+	if len(raw) < gcm.NonceSize() {
+		return nil, fmt.Errorf("ciphertext too short")
+	}
+	plain, err := gcm.Open(nil, raw[:gcm.NonceSize()], raw[gcm.NonceSize():], nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt: %w", err)
+	}
+	return plain, nil
+}
+
+func (v *Vault) gcm() (cipher.AEAD, error) {
+	if v == nil || len(v.key) != keyLen {
+		return nil, fmt.Errorf("vault is locked")
+	}
+	block, err := aes.NewCipher(v.key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func deriveKey(secret, salt []byte, meta vaultMeta) []byte {
+	return argon2.IDKey(secret, salt, meta.Time, meta.Memory, meta.Threads, keyLen)
+}
@@ -0,0 +1,56 @@
+package secure
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestVaultSealOpenRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	v, err := Init(dir, []byte("correct horse battery"))
+	if err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+
+	payload := []byte(`{"threads":[]}`)
+	sealed, err := v.Seal(payload)
+	if err != nil {
+		t.Fatalf("Seal: %v", err)
+	}
+	if !IsSealed(sealed) || IsSealed(payload) {
+		t.Fatal("IsSealed misclassified payloads")
+	}
+	if bytes.Contains(sealed, []byte("threads")) {
+		t.Fatalf("sealed payload leaks plaintext: %s", sealed)
+	}
+
+	reopened, err := Unlock(dir, []byte("correct horse battery"))
+	if err != nil {
+		t.Fatalf("Unlock: %v", err)
+	}
+	plain, err := reopened.Open(sealed)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if !bytes.Equal(plain, payload) {
+		t.Fatalf("unexpected plaintext %q", plain)
+	}
+
+	secret, err := reopened.SealString("sk-test")
+	if err != nil {
+		t.Fatalf("SealString: %v", err)
+	}
+	if got, err := v.OpenString(secret); err != nil || got != "sk-test" {
+		t.Fatalf("OpenString = %q, %v", got, err)
+	}
+}
+
+func TestUnlockRejectsWrongSecret(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := Init(dir, []byte("right passphrase")); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	if _, err := Unlock(dir, []byte("wrong passphrase")); err != ErrWrongSecret {
+		t.Fatalf("expected ErrWrongSecret, got %v", err)
+	}
+}
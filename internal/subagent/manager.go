@@ -17,6 +17,7 @@ import (
 	"time"
 
 	"github.com/bilbilaki/ai2go/internal/api"
+	"github.com/bilbilaki/ai2go/internal/diff"
 	"github.com/bilbilaki/ai2go/internal/tools"
 )
 
@@ -599,7 +600,7 @@ func (m *Manager) RunMiniEditorHelper(ctx context.Context, client *api.Client, d
 		}
 
 		for _, tc := range resp.ToolCalls {
-			toolOutput := miniExecuteToolCall(tc)
+			toolOutput := miniExecuteToolCall(ctx, tc)
 			if snip := snippet(toolOutput, 500); strings.TrimSpace(snip) != "" {
 				progress.WriteString("ToolCall: ")
 				progress.WriteString(tc.Function.Name)
@@ -666,7 +667,9 @@ func (m *Manager) RunMiniFileHelper(ctx context.Context, client *api.Client, def
 		tools.GetFileComparisonTool(),
 		tools.GetCreateFileBackupTool(),
 		tools.GetRestoreFileBackupTool(),
+		tools.GetListFileBackupsTool(),
 		tools.GetFileMergingTool(),
+		tools.GetResolveMergeConflictTool(),
 		tools.GetFileTypeDetectionTool(),
 	}
 
@@ -717,7 +720,7 @@ func (m *Manager) RunMiniFileHelper(ctx context.Context, client *api.Client, def
 	return "", fmt.Errorf("mini file helper exceeded maximum tool iterations (%d)", maxMiniHelperIters)
 }
 
-func miniExecuteToolCall(tc api.ToolCall) string {
+func miniExecuteToolCall(ctx context.Context, tc api.ToolCall) string {
 	switch tc.Function.Name {
 	case "read_file":
 		var args map[string]string
@@ -734,7 +737,7 @@ func miniExecuteToolCall(tc api.ToolCall) string {
 		}
 		return out
 	default:
-		handled, output := tools.ExecuteLineTool(tc.Function.Name, tc.Function.Arguments)
+		handled, output := tools.ExecuteVerifiedLineTool(ctx, tc.Function.Name, tc.Function.Arguments)
 		if handled {
 			return output
 		}
@@ -993,10 +996,14 @@ func (a Agent) Run(ctx context.Context, taskPrompt, instruction string) (string,
 		tools.GetCLITool(),
 		tools.GetReadFileTool(),
 		tools.GetPatchFileTool(),
+		tools.GetEditFileTool(),
+		tools.GetEditTransactionTool(),
 		tools.GetApplyUnifiedDiffPatchTool(),
 		tools.GetCreateCheckpointTool(),
 		tools.GetUndoCheckpointsTool(),
 		tools.GetEditorHistoryTool(),
+		tools.GetRestoreCheckpointFilesTool(),
+		tools.GetTagCheckpointTool(),
 		tools.GetCPUUsageSampleTool(),
 		tools.GetProcessSignalTool(),
 		tools.GetPageSizeTool(),
@@ -1011,7 +1018,9 @@ func (a Agent) Run(ctx context.Context, taskPrompt, instruction string) (string,
 		tools.GetFileComparisonTool(),
 		tools.GetCreateFileBackupTool(),
 		tools.GetRestoreFileBackupTool(),
+		tools.GetListFileBackupsTool(),
 		tools.GetFileMergingTool(),
+		tools.GetResolveMergeConflictTool(),
 		tools.GetFileTypeDetectionTool(),
 		tools.GetSubagentContextProviderTool(),
 	}
@@ -1160,7 +1169,37 @@ func (a Agent) executeToolCall(ctx context.Context, tc api.ToolCall) string {
 		if strings.TrimSpace(patch) == "" {
 			return "Error: patch_file requires a non-empty 'patch' argument."
 		}
-		out, err := tools.ApplyFilePatch(path, patch)
+		out, err := tools.VerifyFileEdit(ctx, []string{path}, args["verify"], func() (string, error) {
+			out, err := tools.ApplyFilePatch(path, patch)
+			if err != nil {
+				return out, err
+			}
+			return tools.AppendFormatReport(ctx, []string{path}, out), nil
+		})
+		if err != nil {
+			return fmt.Sprintf("Error: %v", err)
+		}
+		return out
+	case "edit_file":
+		args, err := tools.ParseEditFileArgs(tc.Function.Arguments)
+		if err != nil {
+			return fmt.Sprintf("Error: %v", err)
+		}
+		out, err := tools.VerifyFileEdit(ctx, []string{args.Path}, args.Verify, func() (string, error) {
+			return tools.EditFile(ctx, args.Path, args.Edits)
+		})
+		if err != nil {
+			return fmt.Sprintf("Error: %v", err)
+		}
+		return out
+	case "edit_transaction":
+		args, err := tools.ParseEditTransactionArgs(tc.Function.Arguments)
+		if err != nil {
+			return fmt.Sprintf("Error: %v", err)
+		}
+		out, err := tools.VerifyFileEdit(ctx, args.Paths(), args.Verify, func() (string, error) {
+			return tools.EditTransaction(ctx, args)
+		})
 		if err != nil {
 			return fmt.Sprintf("Error: %v", err)
 		}
@@ -1182,7 +1221,7 @@ func (a Agent) executeToolCall(ctx context.Context, tc api.ToolCall) string {
 		if strings.TrimSpace(patch) == "" {
 			return "Error: apply_unified_diff_patch requires a non-empty 'patch' argument."
 		}
-		out, err := tools.ApplyUnifiedDiffPatch(workTree, patch, verifyMode)
+		out, err := tools.ApplyUnifiedDiffPatch(ctx, workTree, patch, verifyMode)
 		if err != nil {
 			return fmt.Sprintf("Error: %v", err)
 		}
@@ -1248,6 +1287,14 @@ func (a Agent) executeToolCall(ctx context.Context, tc api.ToolCall) string {
 				}
 			}
 		}
+		if checkpoint, _ := args["checkpoint"].(string); strings.TrimSpace(checkpoint) != "" {
+			compareTo, _ := args["compare_to"].(string)
+			out, err := tools.ShowCheckpointDiff(workTree, strings.TrimSpace(checkpoint), compareTo, diff.Options{})
+			if err != nil {
+				return fmt.Sprintf("Error: %v", err)
+			}
+			return out
+		}
 		history, err := tools.EditorHistory(workTree, limit)
 		if err != nil {
 			return fmt.Sprintf("Error: %v", err)
@@ -1342,12 +1389,15 @@ func (a Agent) executeToolCall(ctx context.Context, tc api.ToolCall) string {
 		}
 		return FormatBatchReport(report)
 	default:
-		if handled, output := tools.ExecuteLineTool(tc.Function.Name, tc.Function.Arguments); handled {
+		if handled, output := tools.ExecuteVerifiedLineTool(ctx, tc.Function.Name, tc.Function.Arguments); handled {
 			return output
 		}
 		if handled, output := tools.ExecuteFileManagementTool(tc.Function.Name, tc.Function.Arguments); handled {
 			return output
 		}
+		if handled, output := tools.ExecuteCheckpointTool(tc.Function.Name, tc.Function.Arguments); handled {
+			return output
+		}
 		return fmt.Sprintf("Error: unsupported tool '%s'", tc.Function.Name)
 	}
 }
@@ -0,0 +1,645 @@
+package tools
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Backups live in a content-addressed store: file contents are kept once per
+// sha256 under objects/, and index.json maps short backup IDs to the file
+// they were taken from.
+const (
+	backupIndexFile  = "index.json"
+	backupObjectsDir = "objects"
+)
+
+// BackupRetention limits the backup store. Zero fields mean no limit. The
+// newest backup of each file is always kept.
+type BackupRetention struct {
+	KeepPerFile int
+	KeepDays    int
+	MaxBytes    int64
+}
+
+// DefaultBackupRetention is used until the config sets BackupRetentionPolicy.
+var DefaultBackupRetention = BackupRetention{KeepPerFile: 20, KeepDays: 30, MaxBytes: 200 << 20}
+
+// BackupRetentionPolicy is applied after every new backup.
+var BackupRetentionPolicy = DefaultBackupRetention
+
+// BackupEntry is one backed-up version of a file.
+type BackupEntry struct {
+	ID      string    `json:"id"`
+	Path    string    `json:"path"`
+	Hash    string    `json:"sha256"`
+	Size    int64     `json:"size"`
+	Created time.Time `json:"created"`
+}
+
+// backupIndex lists backups oldest first.
+type backupIndex struct {
+	Next    int           `json:"next"`
+	Entries []BackupEntry `json:"entries"`
+}
+
+// backupMu serializes index updates within the process.
+var backupMu sync.Mutex
+
+// legacyBackupMeta is the sidecar written next to loose .bak files by older
+// versions.
+type legacyBackupMeta struct {
+	BackupID   string `json:"backup_id"`
+	SourcePath string `json:"source_path"`
+	CreatedAt  string `json:"created_at"`
+}
+
+func resolveBackupRoot() (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil || strings.TrimSpace(cacheDir) == "" {
+		cacheDir = os.TempDir()
+	}
+	root := filepath.Join(cacheDir, "ai2go", "file_backups")
+	if err := os.MkdirAll(filepath.Join(root, backupObjectsDir), 0755); err != nil {
+		return "", fmt.Errorf("failed to create backup directory: %w", err)
+	}
+	return root, nil
+}
+
+func backupObjectPath(root, hash string) string {
+	return filepath.Join(root, backupObjectsDir, hash[:2], hash)
+}
+
+func hashBlob(blob []byte) string {
+	sum := sha256.Sum256(blob)
+	return hex.EncodeToString(sum[:])
+}
+
+// writeBackupObject stores blob under its hash unless it is already there.
+func writeBackupObject(root, hash string, blob []byte) error {
+	path := backupObjectPath(root, hash)
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+	if err := writeFileAtomic(path, blob); err != nil {
+		return fmt.Errorf("failed to write backup: %w", err)
+	}
+	return nil
+}
+
+func writeFileAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func loadBackupIndex(root string) (*backupIndex, error) {
+	index := &backupIndex{Next: 1}
+	data, err := os.ReadFile(filepath.Join(root, backupIndexFile))
+	if errors.Is(err, os.ErrNotExist) {
+		if err := importLegacyBackups(root, index); err != nil {
+			return nil, err
+		}
+		return index, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backup index: %w", err)
+	}
+	if err := json.Unmarshal(data, index); err != nil {
+		return nil, fmt.Errorf("failed to parse backup index: %w", err)
+	}
+	return index, nil
+}
+
+func saveBackupIndex(root string, index *backupIndex) error {
+	data, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode backup index: %w", err)
+	}
+	if err := writeFileAtomic(filepath.Join(root, backupIndexFile), data); err != nil {
+		return fmt.Errorf("failed to write backup index: %w", err)
+	}
+	return nil
+}
+
+// importLegacyBackups moves loose .bak files into the store, keeping their
+// old IDs so earlier tool output still resolves.
+func importLegacyBackups(root string, index *backupIndex) error {
+	baks, _ := filepath.Glob(filepath.Join(root, "*.bak"))
+	type legacy struct {
+		entry BackupEntry
+		files []string
+	}
+	var found []legacy
+	for _, bak := range baks {
+		id := strings.TrimSuffix(filepath.Base(bak), ".bak")
+		blob, err := os.ReadFile(bak)
+		if err != nil {
+			continue
+		}
+		entry := BackupEntry{ID: id, Hash: hashBlob(blob), Size: int64(len(blob))}
+		if info, err := os.Stat(bak); err == nil {
+			entry.Created = info.ModTime().UTC()
+		}
+		metaPath := filepath.Join(root, id+".meta.json")
+		var meta legacyBackupMeta
+		if data, err := os.ReadFile(metaPath); err == nil && json.Unmarshal(data, &meta) == nil {
+			entry.Path = meta.SourcePath
+			if t, err := time.Parse(time.RFC3339, meta.CreatedAt); err == nil {
+				entry.Created = t
+			}
+		}
+		if err := writeBackupObject(root, entry.Hash, blob); err != nil {
+			return err
+		}
+		found = append(found, legacy{entry: entry, files: []string{bak, metaPath}})
+	}
+	if len(found) == 0 {
+		return nil
+	}
+	sort.SliceStable(found, func(i, j int) bool { return found[i].entry.Created.Before(found[j].entry.Created) })
+	for _, l := range found {
+		index.Entries = append(index.Entries, l.entry)
+	}
+	if err := saveBackupIndex(root, index); err != nil {
+		return err
+	}
+	for _, l := range found {
+		for _, f := range l.files {
+			os.Remove(f)
+		}
+	}
+	return nil
+}
+
+func (idx *backupIndex) find(id string) *BackupEntry {
+	for i := range idx.Entries {
+		if idx.Entries[i].ID == id {
+			return &idx.Entries[i]
+		}
+	}
+	return nil
+}
+
+// latest returns the newest backup of path.
+func (idx *backupIndex) latest(path string) *BackupEntry {
+	for i := len(idx.Entries) - 1; i >= 0; i-- {
+		if idx.Entries[i].Path == path {
+			return &idx.Entries[i]
+		}
+	}
+	return nil
+}
+
+// addBackup stores blob as a new version of path. A version identical to the
+// newest backup of path is not stored again; its entry is returned with
+// created set to false.
+func addBackup(root string, index *backupIndex, path string, blob []byte) (entry BackupEntry, created bool, err error) {
+	hash := hashBlob(blob)
+	if last := index.latest(path); last != nil && last.Hash == hash {
+		if _, err := os.Stat(backupObjectPath(root, hash)); err == nil {
+			return *last, false, nil
+		}
+	}
+	if err := writeBackupObject(root, hash, blob); err != nil {
+		return BackupEntry{}, false, err
+	}
+	entry = BackupEntry{
+		ID:      "b" + strconv.Itoa(index.Next),
+		Path:    path,
+		Hash:    hash,
+		Size:    int64(len(blob)),
+		Created: time.Now().UTC(),
+	}
+	index.Next++
+	index.Entries = append(index.Entries, entry)
+	return entry, true, nil
+}
+
+func CreateFileBackup(path string) (string, error) {
+	clean := strings.TrimSpace(path)
+	if clean == "" {
+		return "", fmt.Errorf("path is required")
+	}
+	abs, err := filepath.Abs(clean)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve path: %w", err)
+	}
+	blob, err := os.ReadFile(abs)
+	if err != nil {
+		return "", fmt.Errorf("failed to read source file: %w", err)
+	}
+
+	backupMu.Lock()
+	defer backupMu.Unlock()
+	root, err := resolveBackupRoot()
+	if err != nil {
+		return "", err
+	}
+	index, err := loadBackupIndex(root)
+	if err != nil {
+		return "", err
+	}
+	entry, created, err := addBackup(root, index, abs, blob)
+	if err != nil {
+		return "", err
+	}
+	if !created {
+		return fmt.Sprintf("File unchanged since its last backup. backup_id=%s", entry.ID), nil
+	}
+	pruneBackupIndex(root, index, BackupRetentionPolicy, time.Now())
+	if err := saveBackupIndex(root, index); err != nil {
+		return "", err
+	}
+	removeUnreferencedObjects(root, index)
+	return fmt.Sprintf("Backup created. backup_id=%s\n%s, sha256 %s", entry.ID, FormatBytes(entry.Size), entry.Hash[:12]), nil
+}
+
+// LookupBackup returns the index entry for backupID.
+func LookupBackup(backupID string) (BackupEntry, error) {
+	id := strings.TrimSpace(backupID)
+	if id == "" {
+		return BackupEntry{}, fmt.Errorf("backup_id is required")
+	}
+	backupMu.Lock()
+	defer backupMu.Unlock()
+	root, err := resolveBackupRoot()
+	if err != nil {
+		return BackupEntry{}, err
+	}
+	index, err := loadBackupIndex(root)
+	if err != nil {
+		return BackupEntry{}, err
+	}
+	entry := index.find(id)
+	if entry == nil {
+		return BackupEntry{}, fmt.Errorf("backup id not found: %s", id)
+	}
+	return *entry, nil
+}
+
+// readBackup returns the content of backupID after checking it against its
+// recorded hash.
+func readBackup(backupID string) (BackupEntry, []byte, error) {
+	entry, err := LookupBackup(backupID)
+	if err != nil {
+		return entry, nil, err
+	}
+	root, err := resolveBackupRoot()
+	if err != nil {
+		return entry, nil, err
+	}
+	blob, err := os.ReadFile(backupObjectPath(root, entry.Hash))
+	if err != nil {
+		return entry, nil, fmt.Errorf("failed to read backup %s: %w", entry.ID, err)
+	}
+	if hashBlob(blob) != entry.Hash {
+		return entry, nil, fmt.Errorf("backup %s is corrupted (sha256 mismatch)", entry.ID)
+	}
+	return entry, blob, nil
+}
+
+// RestoreFileBackup writes backupID to path, or to the file it was taken
+// from when path is empty. The current content is backed up first so the
+// restore can itself be reverted.
+func RestoreFileBackup(path, backupID string) (string, error) {
+	entry, blob, err := readBackup(backupID)
+	if err != nil {
+		return "", err
+	}
+	clean := strings.TrimSpace(path)
+	if clean == "" {
+		clean = entry.Path
+	}
+	if clean == "" {
+		return "", fmt.Errorf("backup %s does not record its source path; path is required", entry.ID)
+	}
+	abs, err := filepath.Abs(clean)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve path: %w", err)
+	}
+
+	mode := os.FileMode(0644)
+	saved := ""
+	if info, err := os.Stat(abs); err == nil {
+		mode = info.Mode().Perm()
+		current, err := os.ReadFile(abs)
+		if err != nil {
+			return "", fmt.Errorf("failed to read current file: %w", err)
+		}
+		if hashBlob(current) == entry.Hash {
+			return fmt.Sprintf("%s already matches backup_id=%s", abs, entry.ID), nil
+		}
+		out, err := CreateFileBackup(abs)
+		if err != nil {
+			return "", fmt.Errorf("failed to back up current content: %w", err)
+		}
+		saved = extractBackupIDFromOutput(out)
+	} else if err := os.MkdirAll(filepath.Dir(abs), 0755); err != nil {
+		return "", fmt.Errorf("failed to create parent directory: %w", err)
+	}
+	if err := writeFileAtomic(abs, blob); err != nil {
+		return "", fmt.Errorf("failed to restore backup: %w", err)
+	}
+	if err := os.Chmod(abs, mode); err != nil {
+		return "", fmt.Errorf("failed to restore file mode: %w", err)
+	}
+	msg := fmt.Sprintf("Restored %s from backup_id=%s", abs, entry.ID)
+	if saved != "" {
+		msg += fmt.Sprintf("\nPrevious content saved as backup_id=%s", saved)
+	}
+	return msg, nil
+}
+
+func extractBackupIDFromOutput(out string) string {
+	if i := strings.Index(out, "backup_id="); i >= 0 {
+		return strings.Fields(out[i+len("backup_id="):])[0]
+	}
+	return ""
+}
+
+// ListBackups returns backups of path (all backups when path is empty),
+// newest first.
+func ListBackups(path string) ([]BackupEntry, error) {
+	filter := ""
+	if strings.TrimSpace(path) != "" {
+		abs, err := filepath.Abs(strings.TrimSpace(path))
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve path: %w", err)
+		}
+		filter = abs
+	}
+	backupMu.Lock()
+	defer backupMu.Unlock()
+	root, err := resolveBackupRoot()
+	if err != nil {
+		return nil, err
+	}
+	index, err := loadBackupIndex(root)
+	if err != nil {
+		return nil, err
+	}
+	var out []BackupEntry
+	for i := len(index.Entries) - 1; i >= 0; i-- {
+		if filter == "" || index.Entries[i].Path == filter {
+			out = append(out, index.Entries[i])
+		}
+	}
+	return out, nil
+}
+
+// ListFileBackups renders up to limit backups of path, newest first.
+func ListFileBackups(path string, limit int) (string, error) {
+	entries, err := ListBackups(path)
+	if err != nil {
+		return "", err
+	}
+	if len(entries) == 0 {
+		if strings.TrimSpace(path) == "" {
+			return "No backups yet.", nil
+		}
+		return fmt.Sprintf("No backups of %s.", path), nil
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d backup(s)", len(entries))
+	if limit > 0 && len(entries) > limit {
+		fmt.Fprintf(&b, ", showing the newest %d", limit)
+		entries = entries[:limit]
+	}
+	b.WriteString(":\n")
+	for _, e := range entries {
+		fmt.Fprintf(&b, "%-6s %s  %9s  %s  %s\n", e.ID, e.Created.Local().Format("2006-01-02 15:04:05"), FormatBytes(e.Size), e.Hash[:12], backupPathLabel(e.Path))
+	}
+	return strings.TrimRight(b.String(), "\n"), nil
+}
+
+func backupPathLabel(path string) string {
+	if path == "" {
+		return "(unknown path)"
+	}
+	return path
+}
+
+// BackupPruneReport summarizes PruneBackups.
+type BackupPruneReport struct {
+	Dropped        int
+	Kept           int
+	ObjectsRemoved int
+	SizeBefore     int64
+	SizeAfter      int64
+}
+
+func (r BackupPruneReport) String() string {
+	return fmt.Sprintf("Dropped %d backup(s), kept %d; removed %d object(s); %s -> %s",
+		r.Dropped, r.Kept, r.ObjectsRemoved, FormatBytes(r.SizeBefore), FormatBytes(r.SizeAfter))
+}
+
+// PruneBackups applies keep to the store and deletes content no backup
+// refers to any more.
+func PruneBackups(keep BackupRetention) (BackupPruneReport, error) {
+	backupMu.Lock()
+	defer backupMu.Unlock()
+	report := BackupPruneReport{}
+	root, err := resolveBackupRoot()
+	if err != nil {
+		return report, err
+	}
+	index, err := loadBackupIndex(root)
+	if err != nil {
+		return report, err
+	}
+	report.SizeBefore = dirSize(filepath.Join(root, backupObjectsDir))
+	report.Dropped = pruneBackupIndex(root, index, keep, time.Now())
+	report.Kept = len(index.Entries)
+	if err := saveBackupIndex(root, index); err != nil {
+		return report, err
+	}
+	report.ObjectsRemoved = removeUnreferencedObjects(root, index)
+	report.SizeAfter = dirSize(filepath.Join(root, backupObjectsDir))
+	return report, nil
+}
+
+// pruneBackupIndex drops entries outside keep and returns how many it
+// dropped. Per-file count and age go first; the size quota then drops the
+// oldest remaining versions, counting shared content once.
+func pruneBackupIndex(root string, index *backupIndex, keep BackupRetention, now time.Time) int {
+	newest := map[string]int{}
+	seen := map[string]int{}
+	drop := make([]bool, len(index.Entries))
+	for i := len(index.Entries) - 1; i >= 0; i-- {
+		e := index.Entries[i]
+		seen[e.Path]++
+		if seen[e.Path] == 1 {
+			newest[e.Path] = i
+			continue
+		}
+		if keep.KeepPerFile > 0 && seen[e.Path] > keep.KeepPerFile {
+			drop[i] = true
+		}
+		if keep.KeepDays > 0 && now.Sub(e.Created) > time.Duration(keep.KeepDays)*24*time.Hour {
+			drop[i] = true
+		}
+	}
+
+	if keep.MaxBytes > 0 {
+		refs := map[string]int{}
+		sizes := map[string]int64{}
+		var total int64
+		for i, e := range index.Entries {
+			if drop[i] {
+				continue
+			}
+			if refs[e.Hash] == 0 {
+				total += e.Size
+			}
+			refs[e.Hash]++
+			sizes[e.Hash] = e.Size
+		}
+		for i := 0; i < len(index.Entries) && total > keep.MaxBytes; i++ {
+			e := index.Entries[i]
+			if drop[i] || newest[e.Path] == i {
+				continue
+			}
+			drop[i] = true
+			if refs[e.Hash]--; refs[e.Hash] == 0 {
+				total -= sizes[e.Hash]
+			}
+		}
+	}
+
+	kept := index.Entries[:0]
+	dropped := 0
+	for i, e := range index.Entries {
+		if drop[i] {
+			dropped++
+			continue
+		}
+		kept = append(kept, e)
+	}
+	index.Entries = kept
+	return dropped
+}
+
+// removeUnreferencedObjects deletes stored content no entry points at.
+func removeUnreferencedObjects(root string, index *backupIndex) int {
+	live := map[string]bool{}
+	for _, e := range index.Entries {
+		live[e.Hash] = true
+	}
+	objects, _ := filepath.Glob(filepath.Join(root, backupObjectsDir, "*", "*"))
+	removed := 0
+	for _, obj := range objects {
+		if live[filepath.Base(obj)] {
+			continue
+		}
+		if os.Remove(obj) == nil {
+			removed++
+		}
+	}
+	return removed
+}
+
+// BackupVerifyReport lists backups whose content is missing or damaged.
+type BackupVerifyReport struct {
+	Checked   int
+	Missing   []string
+	Corrupted []string
+}
+
+func (r BackupVerifyReport) OK() bool {
+	return len(r.Missing) == 0 && len(r.Corrupted) == 0
+}
+
+func (r BackupVerifyReport) String() string {
+	if r.OK() {
+		return fmt.Sprintf("Verified %d backup(s): all intact.", r.Checked)
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "Verified %d backup(s):", r.Checked)
+	if len(r.Missing) > 0 {
+		fmt.Fprintf(&b, "\nmissing content: %s", strings.Join(r.Missing, ", "))
+	}
+	if len(r.Corrupted) > 0 {
+		fmt.Fprintf(&b, "\ncorrupted (sha256 mismatch): %s", strings.Join(r.Corrupted, ", "))
+	}
+	return b.String()
+}
+
+// VerifyBackups rehashes the content of every backup.
+func VerifyBackups() (BackupVerifyReport, error) {
+	backupMu.Lock()
+	defer backupMu.Unlock()
+	report := BackupVerifyReport{}
+	root, err := resolveBackupRoot()
+	if err != nil {
+		return report, err
+	}
+	index, err := loadBackupIndex(root)
+	if err != nil {
+		return report, err
+	}
+	status := map[string]string{}
+	for _, e := range index.Entries {
+		report.Checked++
+		if _, ok := status[e.Hash]; !ok {
+			blob, err := os.ReadFile(backupObjectPath(root, e.Hash))
+			switch {
+			case err != nil:
+				status[e.Hash] = "missing"
+			case hashBlob(blob) != e.Hash:
+				status[e.Hash] = "corrupted"
+			default:
+				status[e.Hash] = ""
+			}
+		}
+		switch status[e.Hash] {
+		case "missing":
+			report.Missing = append(report.Missing, e.ID)
+		case "corrupted":
+			report.Corrupted = append(report.Corrupted, e.ID)
+		}
+	}
+	return report, nil
+}
+
+// BackupUsage reports the number of backups, distinct files and stored bytes.
+func BackupUsage() (backups, files int, size int64, err error) {
+	backupMu.Lock()
+	defer backupMu.Unlock()
+	root, err := resolveBackupRoot()
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	index, err := loadBackupIndex(root)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	paths := map[string]bool{}
+	for _, e := range index.Entries {
+		paths[e.Path] = true
+	}
+	return len(index.Entries), len(paths), dirSize(filepath.Join(root, backupObjectsDir)), nil
+}
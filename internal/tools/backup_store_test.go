@@ -0,0 +1,146 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBackupStoreDedupListAndRestore(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	dir := t.TempDir()
+	a, b := filepath.Join(dir, "a.txt"), filepath.Join(dir, "b.txt")
+	writeTestFile(t, a, "same\n")
+	writeTestFile(t, b, "same\n")
+
+	out1, err := CreateFileBackup(a)
+	if err != nil {
+		t.Fatalf("CreateFileBackup: %v", err)
+	}
+	out2, err := CreateFileBackup(a)
+	if err != nil {
+		t.Fatalf("CreateFileBackup: %v", err)
+	}
+	id := extractBackupID(out1)
+	if id != "b1" || extractBackupID(out2) != id || !strings.Contains(out2, "unchanged") {
+		t.Fatalf("unchanged content should reuse the backup: %q / %q", out1, out2)
+	}
+	if _, err := CreateFileBackup(b); err != nil {
+		t.Fatalf("CreateFileBackup: %v", err)
+	}
+	objects, _ := filepath.Glob(filepath.Join(os.Getenv("XDG_CACHE_HOME"), "ai2go", "file_backups", "objects", "*", "*"))
+	if len(objects) != 1 {
+		t.Fatalf("identical content should be stored once, got %d objects", len(objects))
+	}
+
+	writeTestFile(t, a, "changed\n")
+	if _, err := CreateFileBackup(a); err != nil {
+		t.Fatalf("CreateFileBackup: %v", err)
+	}
+	list, err := ListFileBackups(a, 0)
+	if err != nil {
+		t.Fatalf("ListFileBackups: %v", err)
+	}
+	lines := strings.Split(list, "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[1], "b3 ") || !strings.HasPrefix(lines[2], "b1 ") || strings.Contains(list, b) {
+		t.Fatalf("unexpected listing:\n%s", list)
+	}
+
+	writeTestFile(t, a, "edited again\n")
+	if err := os.Chmod(a, 0755); err != nil {
+		t.Fatal(err)
+	}
+	out, err := RestoreFileBackup("", id)
+	if err != nil {
+		t.Fatalf("RestoreFileBackup: %v", err)
+	}
+	if readTestFile(a) != "same\n" || !strings.Contains(out, "Previous content saved as backup_id=b4") {
+		t.Fatalf("restore: %q, content %q", out, readTestFile(a))
+	}
+	if info, err := os.Stat(a); err != nil || info.Mode().Perm() != 0755 {
+		t.Fatalf("restore should keep the file mode: %v %v", info, err)
+	}
+	if _, err := RestoreFileBackup("", "b4"); err != nil || readTestFile(a) != "edited again\n" {
+		t.Fatalf("restoring the saved content: %v, %q", err, readTestFile(a))
+	}
+}
+
+func TestBackupStoreVerifyDetectsCorruption(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	path := filepath.Join(t.TempDir(), "f.txt")
+	writeTestFile(t, path, "original\n")
+	out, err := CreateFileBackup(path)
+	if err != nil {
+		t.Fatalf("CreateFileBackup: %v", err)
+	}
+	entry, err := LookupBackup(extractBackupID(out))
+	if err != nil {
+		t.Fatalf("LookupBackup: %v", err)
+	}
+	root, _ := resolveBackupRoot()
+	if err := os.WriteFile(backupObjectPath(root, entry.Hash), []byte("tampered\n"), 0644); err != nil {
+		t.Fatalf("tamper: %v", err)
+	}
+
+	report, err := VerifyBackups()
+	if err != nil || report.OK() || len(report.Corrupted) != 1 || report.Corrupted[0] != entry.ID {
+		t.Fatalf("VerifyBackups: %+v %v", report, err)
+	}
+	writeTestFile(t, path, "current\n")
+	if _, err := RestoreFileBackup(path, entry.ID); err == nil || !strings.Contains(err.Error(), "corrupted") {
+		t.Fatalf("expected corruption error, got %v", err)
+	}
+	if readTestFile(path) != "current\n" {
+		t.Fatal("a corrupted backup must not be written")
+	}
+}
+
+func TestPruneBackupIndex(t *testing.T) {
+	now := time.Now()
+	old := now.Add(-10 * 24 * time.Hour)
+	index := &backupIndex{Entries: []BackupEntry{
+		{ID: "b1", Path: "/a", Hash: "h1", Size: 100, Created: old},
+		{ID: "b2", Path: "/a", Hash: "h2", Size: 100, Created: now},
+		{ID: "b3", Path: "/a", Hash: "h3", Size: 100, Created: now},
+		{ID: "b4", Path: "/b", Hash: "h4", Size: 100, Created: old},
+		{ID: "b5", Path: "/c", Hash: "h3", Size: 100, Created: now},
+	}}
+	if dropped := pruneBackupIndex("", index, BackupRetention{KeepDays: 5}, now); dropped != 1 {
+		t.Fatalf("expected only the old non-newest version dropped, got %d", dropped)
+	}
+	if index.find("b1") != nil || index.find("b4") == nil {
+		t.Fatal("age limit must keep the newest version of each file")
+	}
+
+	// b2 is the only version that is neither newest nor sharing content.
+	if dropped := pruneBackupIndex("", index, BackupRetention{MaxBytes: 250}, now); dropped != 1 || index.find("b2") != nil {
+		t.Fatalf("quota pruning dropped %d, entries %+v", dropped, index.Entries)
+	}
+	if dropped := pruneBackupIndex("", index, BackupRetention{KeepPerFile: 1, MaxBytes: 1}, now); dropped != 0 {
+		t.Fatalf("newest versions are never pruned, dropped %d", dropped)
+	}
+}
+
+func TestLegacyBackupsAreImported(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	root, err := resolveBackupRoot()
+	if err != nil {
+		t.Fatalf("resolveBackupRoot: %v", err)
+	}
+	id := "a.txt__0123456789__1700000000000000000"
+	writeTestFile(t, filepath.Join(root, id+".bak"), "legacy\n")
+	writeTestFile(t, filepath.Join(root, id+".meta.json"), `{"backup_id":"`+id+`","source_path":"/tmp/a.txt","created_at":"2024-01-02T03:04:05Z"}`)
+
+	entry, err := LookupBackup(id)
+	if err != nil || entry.Path != "/tmp/a.txt" || entry.Created.Year() != 2024 {
+		t.Fatalf("legacy backup not imported: %+v %v", entry, err)
+	}
+	if _, err := os.Stat(filepath.Join(root, id+".bak")); !os.IsNotExist(err) {
+		t.Fatal("legacy files should be removed after import")
+	}
+	if _, blob, err := readBackup(id); err != nil || string(blob) != "legacy\n" {
+		t.Fatalf("readBackup: %q %v", blob, err)
+	}
+}
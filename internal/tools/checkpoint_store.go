@@ -0,0 +1,530 @@
+package tools
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bilbilaki/ai2go/internal/project"
+)
+
+// CheckpointIgnoreFile lists extra ignore patterns (gitignore syntax) for
+// editor checkpoints, at .ai2go/checkpointignore in the work tree. Patterns
+// there are applied after the built-in ones, so "!build/" re-includes a
+// directory ignored by default.
+const CheckpointIgnoreFile = "checkpointignore"
+
+// DefaultCheckpointMaxFileSize is the largest file copied into checkpoints.
+const DefaultCheckpointMaxFileSize int64 = 5 << 20
+
+// CheckpointMaxFileSize is the size limit applied when staging checkpoints;
+// larger files are left out of the shadow repo. Zero or less disables it.
+var CheckpointMaxFileSize = DefaultCheckpointMaxFileSize
+
+// defaultCheckpointExcludes keeps dependency caches and build output out of
+// checkpoints. The work tree's own .gitignore files apply as well.
+var defaultCheckpointExcludes = []string{
+	"node_modules/",
+	"bower_components/",
+	".venv/",
+	"venv/",
+	"__pycache__/",
+	".tox/",
+	".mypy_cache/",
+	".pytest_cache/",
+	".gradle/",
+	".next/",
+	".nuxt/",
+	".terraform/",
+	".cache/",
+	"target/",
+	"dist/",
+	"build/",
+	"*.pyc",
+	"*.o",
+	"*.class",
+}
+
+const (
+	shadowWorkTreeFile = "worktree"
+	largeFilesHeader   = "# files over the checkpoint size limit"
+)
+
+// writeShadowRepoInfo records which work tree a shadow repo belongs to and
+// rewrites its exclude file from the defaults, the project's
+// .git/info/exclude and .ai2go/checkpointignore.
+func writeShadowRepoInfo(workTree, gitDir string) error {
+	marker := filepath.Join(filepath.Dir(gitDir), shadowWorkTreeFile)
+	if current, err := os.ReadFile(marker); err != nil || string(current) != workTree {
+		if err := os.WriteFile(marker, []byte(workTree), 0644); err != nil {
+			return fmt.Errorf("failed to record worktree path: %w", err)
+		}
+	}
+	return writeCheckpointExcludes(workTree, gitDir, nil)
+}
+
+func writeCheckpointExcludes(workTree, gitDir string, large []string) error {
+	var b strings.Builder
+	b.WriteString("# Managed by ai2go; edit .ai2go/checkpointignore instead.\n")
+	for _, p := range defaultCheckpointExcludes {
+		b.WriteString(p + "\n")
+	}
+	extra := []string{filepath.Join(workTree, ".git", "info", "exclude")}
+	extra = append(extra, filepath.Join(workTree, project.InstructionsDir, CheckpointIgnoreFile))
+	for _, path := range extra {
+		if data, err := os.ReadFile(path); err == nil {
+			fmt.Fprintf(&b, "# from %s\n%s\n", path, strings.TrimRight(string(data), "\n"))
+		}
+	}
+	if len(large) > 0 {
+		b.WriteString(largeFilesHeader + "\n")
+		for _, p := range large {
+			b.WriteString("/" + escapeIgnorePattern(p) + "\n")
+		}
+	}
+
+	path := filepath.Join(gitDir, "info", "exclude")
+	if current, err := os.ReadFile(path); err == nil && string(current) == b.String() {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create shadow repo info dir: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(b.String()), 0644); err != nil {
+		return fmt.Errorf("failed to write checkpoint excludes: %w", err)
+	}
+	return nil
+}
+
+// escapeIgnorePattern quotes characters with special meaning in gitignore
+// patterns so a path matches only itself.
+func escapeIgnorePattern(path string) string {
+	var b strings.Builder
+	for i, r := range path {
+		if strings.ContainsRune(`*?[]\`, r) || (i == 0 && (r == '!' || r == '#')) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// stageCheckpoint stages filePath, or the whole work tree when it is empty,
+// leaving out ignored files and files over CheckpointMaxFileSize.
+func stageCheckpoint(workTree, filePath string) error {
+	absWorkTree, err := filepath.Abs(workTree)
+	if err != nil {
+		return fmt.Errorf("failed to resolve worktree path: %w", err)
+	}
+	gitDir, err := editorGitDir(absWorkTree)
+	if err != nil {
+		return err
+	}
+
+	var paths []string
+	if filePath != "" {
+		paths = []string{filePath}
+	}
+	large, err := oversizedCandidates(absWorkTree, paths)
+	if err != nil {
+		return err
+	}
+	if err := writeCheckpointExcludes(absWorkTree, gitDir, large); err != nil {
+		return err
+	}
+	for _, p := range large {
+		// Drop files that grew past the limit since an earlier checkpoint.
+		if _, err := runGit(absWorkTree, "rm", "--cached", "--quiet", "--ignore-unmatch", "--", p); err != nil {
+			return err
+		}
+	}
+
+	if filePath == "" {
+		_, err := runGit(absWorkTree, "add", "-A")
+		return err
+	}
+	if _, err := runGit(absWorkTree, "check-ignore", "-q", "--no-index", "--", filePath); err == nil {
+		return nil // ignored or too large: the checkpoint simply leaves it out
+	}
+	_, err = runGit(absWorkTree, "add", "--", filePath)
+	return err
+}
+
+// excludedPaths returns the files (work-tree-relative, slash separated)
+// that exist on disk but are left out of checkpoints because they are
+// ignored or larger than CheckpointMaxFileSize. A snapshot shows them as
+// deleted, which says nothing about their content.
+func excludedPaths(workTree string, files []string) (map[string]bool, error) {
+	excluded := map[string]bool{}
+	var present []string
+	for _, f := range files {
+		info, err := os.Lstat(filepath.Join(workTree, filepath.FromSlash(f)))
+		if err != nil {
+			continue
+		}
+		if CheckpointMaxFileSize > 0 && info.Mode().IsRegular() && info.Size() > CheckpointMaxFileSize {
+			excluded[f] = true
+			continue
+		}
+		present = append(present, f)
+	}
+	if len(present) == 0 {
+		return excluded, nil
+	}
+	args := append([]string{"--literal-pathspecs", "ls-files", "-z", "--others", "--ignored", "--exclude-standard", "--"}, present...)
+	out, err := runGitRaw(workTree, args...)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range strings.Split(string(out), "\x00") {
+		if p != "" {
+			excluded[p] = true
+		}
+	}
+	return excluded, nil
+}
+
+// oversizedCandidates lists new or modified, non-ignored files (limited to
+// paths when given) that exceed CheckpointMaxFileSize.
+func oversizedCandidates(workTree string, paths []string) ([]string, error) {
+	if CheckpointMaxFileSize <= 0 {
+		return nil, nil
+	}
+	args := append([]string{"ls-files", "-z", "--others", "--modified", "--exclude-standard", "--"}, paths...)
+	out, err := runGitRaw(workTree, args...)
+	if err != nil {
+		return nil, err
+	}
+	var large []string
+	seen := map[string]bool{}
+	for _, p := range strings.Split(string(out), "\x00") {
+		if p == "" || seen[p] {
+			continue
+		}
+		seen[p] = true
+		info, err := os.Lstat(filepath.Join(workTree, filepath.FromSlash(p)))
+		if err == nil && info.Mode().IsRegular() && info.Size() > CheckpointMaxFileSize {
+			large = append(large, p)
+		}
+	}
+	sort.Strings(large)
+	return large, nil
+}
+
+// CheckpointRepo describes one shadow repo under the user cache.
+type CheckpointRepo struct {
+	Dir         string
+	WorkTree    string // empty when the repo predates worktree markers
+	SizeBytes   int64
+	Checkpoints int
+	Turns       int
+	LastUsed    time.Time
+}
+
+// Orphaned reports whether the repo's work tree no longer exists.
+func (r CheckpointRepo) Orphaned() bool {
+	if r.WorkTree == "" {
+		return false
+	}
+	_, err := os.Stat(r.WorkTree)
+	return errors.Is(err, os.ErrNotExist)
+}
+
+func editorCacheRoot() (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate user cache dir: %w", err)
+	}
+	return filepath.Join(cacheDir, "ai2go", "editor"), nil
+}
+
+// ListCheckpointRepos reports the disk usage of every shadow repo, largest
+// first.
+func ListCheckpointRepos() ([]CheckpointRepo, error) {
+	root, err := editorCacheRoot()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(root)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", root, err)
+	}
+
+	var repos []CheckpointRepo
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		dir := filepath.Join(root, e.Name())
+		repo := CheckpointRepo{Dir: dir}
+		if data, err := os.ReadFile(filepath.Join(dir, shadowWorkTreeFile)); err == nil {
+			repo.WorkTree = strings.TrimSpace(string(data))
+		}
+		_ = filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return nil
+			}
+			if info, err := d.Info(); err == nil {
+				repo.SizeBytes += info.Size()
+				if info.ModTime().After(repo.LastUsed) {
+					repo.LastUsed = info.ModTime()
+				}
+			}
+			return nil
+		})
+		if repo.WorkTree != "" && !repo.Orphaned() {
+			if n, err := runGit(repo.WorkTree, "rev-list", "--count", "HEAD"); err == nil {
+				repo.Checkpoints, _ = strconv.Atoi(n)
+			}
+			if turns, err := ListTurns(repo.WorkTree); err == nil {
+				repo.Turns = len(turns)
+			}
+		}
+		repos = append(repos, repo)
+	}
+	sort.Slice(repos, func(i, j int) bool { return repos[i].SizeBytes > repos[j].SizeBytes })
+	return repos, nil
+}
+
+// CheckpointRetention decides which checkpoints GCCheckpoints keeps: those
+// newer than KeepDays, plus the latest KeepCheckpoints regardless of age.
+type CheckpointRetention struct {
+	KeepDays        int
+	KeepCheckpoints int
+}
+
+// GCReport summarizes a GCCheckpoints run.
+type GCReport struct {
+	WorkTree     string
+	Dropped      int
+	Kept         int
+	TurnsDropped int
+	SizeBefore   int64
+	SizeAfter    int64
+}
+
+func (r GCReport) String() string {
+	return fmt.Sprintf("%s: dropped %d checkpoint(s) and %d turn(s), kept %d; %s -> %s",
+		r.WorkTree, r.Dropped, r.TurnsDropped, r.Kept, FormatBytes(r.SizeBefore), FormatBytes(r.SizeAfter))
+}
+
+// GCCheckpoints drops checkpoints outside the retention policy by rewriting
+// the kept ones onto a new root, remaps the turn journal (turns whose start
+// was dropped are forgotten) and prunes unreachable objects.
+func GCCheckpoints(workTree string, keep CheckpointRetention) (GCReport, error) {
+	report := GCReport{WorkTree: workTree}
+	if err := EnsureEditorGitRepo(workTree); err != nil {
+		return report, err
+	}
+	gitDir, err := editorGitDir(absPath(workTree))
+	if err != nil {
+		return report, err
+	}
+	report.SizeBefore = dirSize(filepath.Dir(gitDir))
+
+	out, err := runGit(workTree, "log", "--reverse", "--format=%H %ct %T")
+	if err != nil {
+		// No checkpoints yet: nothing to rewrite.
+		report.SizeAfter = report.SizeBefore
+		return report, nil
+	}
+	type commit struct {
+		hash, tree string
+		time       int64
+	}
+	var commits []commit
+	for _, line := range strings.Split(out, "\n") {
+		f := strings.Fields(line)
+		if len(f) != 3 {
+			continue
+		}
+		ts, _ := strconv.ParseInt(f[1], 10, 64)
+		commits = append(commits, commit{hash: f[0], time: ts, tree: f[2]})
+	}
+
+	first := len(commits)
+	if keep.KeepDays > 0 {
+		cutoff := time.Now().AddDate(0, 0, -keep.KeepDays).Unix()
+		for i, c := range commits {
+			if c.time >= cutoff {
+				first = i
+				break
+			}
+		}
+	}
+	if keep.KeepCheckpoints > 0 {
+		first = min(first, max(len(commits)-keep.KeepCheckpoints, 0))
+	}
+	if first == len(commits) && len(commits) > 0 {
+		first = len(commits) - 1 // always keep the latest checkpoint
+	}
+	// Tagged checkpoints are kept regardless of age.
+	tags := map[string]string{} // tag name -> commit
+	tagged := map[string]bool{}
+	if refs, err := runGit(workTree, "for-each-ref", "--format=%(refname:short) %(objectname)", "refs/tags"); err == nil {
+		for _, line := range strings.Split(refs, "\n") {
+			if f := strings.Fields(line); len(f) == 2 {
+				tags[f[0]] = f[1]
+				tagged[f[1]] = true
+			}
+		}
+	}
+	for i, c := range commits[:first] {
+		if tagged[c.hash] {
+			first = i
+			break
+		}
+	}
+	report.Dropped, report.Kept = first, len(commits)-first
+
+	remap := map[string]string{}
+	if first > 0 {
+		parent := ""
+		for _, c := range commits[first:] {
+			meta, err := runGit(workTree, "log", "-1", "--format=%an%x00%ae%x00%aD%x00%cD%x00%B", c.hash)
+			if err != nil {
+				return report, err
+			}
+			f := strings.SplitN(meta, "\x00", 5)
+			if len(f) != 5 {
+				return report, fmt.Errorf("unexpected metadata for checkpoint %s", c.hash)
+			}
+			env := []string{
+				"GIT_AUTHOR_NAME=" + f[0], "GIT_AUTHOR_EMAIL=" + f[1], "GIT_AUTHOR_DATE=" + f[2],
+				"GIT_COMMITTER_DATE=" + f[3],
+			}
+			args := []string{"commit-tree", c.tree, "-m", f[4]}
+			if parent != "" {
+				args = append(args, "-p", parent)
+			}
+			newHash, err := runGitEnv(workTree, env, args...)
+			if err != nil {
+				return report, err
+			}
+			parent = strings.TrimSpace(string(newHash))
+			remap[c.hash] = parent
+		}
+		if _, err := runGit(workTree, "update-ref", "HEAD", parent); err != nil {
+			return report, err
+		}
+		for name, hash := range tags {
+			if newHash, ok := remap[hash]; ok {
+				if _, err := runGit(workTree, "tag", "-f", name, newHash); err != nil {
+					return report, err
+				}
+			}
+		}
+		logged := make(map[string]bool, len(commits))
+		for _, c := range commits {
+			logged[c.hash] = true
+		}
+		if report.TurnsDropped, err = remapTurnJournal(workTree, remap, logged); err != nil {
+			return report, err
+		}
+	}
+
+	if _, err := runGit(workTree, "reflog", "expire", "--expire=now", "--all"); err != nil {
+		return report, err
+	}
+	if _, err := runGit(workTree, "gc", "--prune=now", "--quiet"); err != nil {
+		return report, err
+	}
+	report.SizeAfter = dirSize(filepath.Dir(gitDir))
+	return report, nil
+}
+
+// remapTurnJournal rewrites turn snapshots after a gc and drops turns that
+// reference removed checkpoints. Snapshots that were not on the rewritten
+// history (logged), e.g. left behind by undo_checkpoints, stay as they are;
+// their turn refs keep them alive.
+func remapTurnJournal(workTree string, remap map[string]string, logged map[string]bool) (int, error) {
+	journal, err := loadTurnJournal(workTree)
+	if err != nil {
+		return 0, err
+	}
+	mapSnapshot := func(rev string) (string, bool) {
+		if newRev, ok := remap[rev]; ok {
+			return newRev, true
+		}
+		return rev, !logged[rev]
+	}
+	kept := journal.Turns[:0]
+	live := map[int]bool{}
+	for _, t := range journal.Turns {
+		before, okB := mapSnapshot(t.Before)
+		after, okA := mapSnapshot(t.After)
+		if !okB || !okA {
+			if err := unpinTurn(workTree, t.ID); err != nil {
+				return 0, err
+			}
+			continue
+		}
+		t.Before, t.After = before, after
+		if err := pinTurn(workTree, t); err != nil {
+			return 0, err
+		}
+		kept = append(kept, t)
+		live[t.ID] = true
+	}
+	dropped := len(journal.Turns) - len(kept)
+	journal.Turns = kept
+	redo := journal.Redo[:0]
+	for _, id := range journal.Redo {
+		if live[id] {
+			redo = append(redo, id)
+		}
+	}
+	journal.Redo = redo
+	return dropped, saveTurnJournal(workTree, journal)
+}
+
+// RemoveCheckpointRepo deletes a shadow repo directory from the cache.
+func RemoveCheckpointRepo(repo CheckpointRepo) error {
+	root, err := editorCacheRoot()
+	if err != nil {
+		return err
+	}
+	if filepath.Dir(repo.Dir) != root {
+		return fmt.Errorf("%s is not a checkpoint repo", repo.Dir)
+	}
+	if err := os.RemoveAll(repo.Dir); err != nil {
+		return fmt.Errorf("failed to remove %s: %w", repo.Dir, err)
+	}
+	return nil
+}
+
+func dirSize(dir string) int64 {
+	var total int64
+	_ = filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			if info, err := d.Info(); err == nil {
+				total += info.Size()
+			}
+		}
+		return nil
+	})
+	return total
+}
+
+// FormatBytes renders n with a binary unit suffix.
+func FormatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,186 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bilbilaki/ai2go/internal/diff"
+)
+
+func TestCheckpointSkipsIgnoredAndLargeFiles(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	defer func(old int64) { CheckpointMaxFileSize = old }(CheckpointMaxFileSize)
+	CheckpointMaxFileSize = 1024
+
+	workTree := t.TempDir()
+	files := map[string]string{
+		"main.go":                   "package main\n",
+		"node_modules/dep/index.js": "module.exports = 1\n",
+		".ai2go/checkpointignore":   "*.tmp\n!dist/\n",
+		"scratch.tmp":               "scratch\n",
+		"dist/app.js":               "bundle\n",
+		".gitignore":                "secret.env\n",
+		"secret.env":                "TOKEN=x\n",
+		"data.bin":                  strings.Repeat("x", 2048),
+	}
+	for name, content := range files {
+		path := filepath.Join(workTree, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+
+	if _, err := SnapshotWorkTree(workTree, "snapshot"); err != nil {
+		t.Fatalf("SnapshotWorkTree: %v", err)
+	}
+	tracked, err := runGit(workTree, "ls-files")
+	if err != nil {
+		t.Fatalf("ls-files: %v", err)
+	}
+	got := strings.Fields(tracked)
+	want := []string{".ai2go/checkpointignore", ".gitignore", "dist/app.js", "main.go"}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Fatalf("tracked %v, want %v", got, want)
+	}
+
+	// A tracked file that grows past the limit drops out of later checkpoints.
+	if err := os.WriteFile(filepath.Join(workTree, "main.go"), []byte(strings.Repeat("y", 4096)), 0644); err != nil {
+		t.Fatalf("grow main.go: %v", err)
+	}
+	if _, err := CreateCheckpoint(workTree, "main.go", "grown"); err != nil {
+		t.Fatalf("CreateCheckpoint: %v", err)
+	}
+	if tracked, _ := runGit(workTree, "ls-files", "main.go"); tracked != "" {
+		t.Fatalf("expected oversized main.go to be untracked, got %q", tracked)
+	}
+}
+
+func TestGCCheckpointsKeepsRecentAndRemapsTurns(t *testing.T) {
+	cache := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", cache)
+	workTree := t.TempDir()
+	file := filepath.Join(workTree, "f.txt")
+
+	var recs []*Turn
+	for i, content := range []string{"1\n", "2\n", "3\n", "4\n"} {
+		rec, err := BeginTurn(workTree, "turn")
+		if err != nil {
+			t.Fatalf("BeginTurn: %v", err)
+		}
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+		turn, err := rec.End()
+		if err != nil || turn == nil {
+			t.Fatalf("End %d: %+v %v", i, turn, err)
+		}
+		recs = append(recs, turn)
+	}
+	// Each turn starts from the previous turn's end: empty, 1, 2, 3, 4.
+	count, _ := runGit(workTree, "rev-list", "--count", "HEAD")
+	if count != "5" {
+		t.Fatalf("expected 5 checkpoints, got %s", count)
+	}
+
+	report, err := GCCheckpoints(workTree, CheckpointRetention{KeepCheckpoints: 3})
+	if err != nil {
+		t.Fatalf("GCCheckpoints: %v", err)
+	}
+	if report.Dropped != 2 || report.Kept != 3 || report.TurnsDropped != 2 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+	turns, err := ListTurns(workTree)
+	if err != nil || len(turns) != 2 || turns[0].ID != 3 {
+		t.Fatalf("turns after gc: %+v %v", turns, err)
+	}
+	if turns[0].Before == recs[2].Before {
+		t.Fatal("expected remapped snapshot hashes")
+	}
+	out, err := TurnDiff(workTree, 4, diff.Options{})
+	if err != nil || !strings.Contains(out, "-3\n+4") {
+		t.Fatalf("TurnDiff after gc: %q %v", out, err)
+	}
+	if _, err := UndoTurn(workTree, false); err != nil {
+		t.Fatalf("UndoTurn after gc: %v", err)
+	}
+	if data, _ := os.ReadFile(file); string(data) != "3\n" {
+		t.Fatalf("undo after gc left %q", data)
+	}
+
+	repos, err := ListCheckpointRepos()
+	if err != nil || len(repos) != 1 || repos[0].WorkTree != workTree || repos[0].SizeBytes == 0 || repos[0].Turns != 2 {
+		t.Fatalf("ListCheckpointRepos: %+v %v", repos, err)
+	}
+	if err := os.RemoveAll(workTree); err != nil {
+		t.Fatalf("remove worktree: %v", err)
+	}
+	if repos, _ := ListCheckpointRepos(); !repos[0].Orphaned() {
+		t.Fatal("expected orphaned repo after removing the work tree")
+	}
+	if err := RemoveCheckpointRepo(repos[0]); err != nil {
+		t.Fatalf("RemoveCheckpointRepo: %v", err)
+	}
+	if err := RemoveCheckpointRepo(CheckpointRepo{Dir: cache}); err == nil {
+		t.Fatal("expected refusal to remove a non-checkpoint directory")
+	}
+}
+
+func TestTurnUndoLeavesFilesThatGrewPastTheLimit(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	defer func(old int64) { CheckpointMaxFileSize = old }(CheckpointMaxFileSize)
+	CheckpointMaxFileSize = 1024
+
+	workTree := t.TempDir()
+	big := filepath.Join(workTree, "data.txt")
+	small := filepath.Join(workTree, "notes.txt")
+	for path, content := range map[string]string{big: "small\n", small: "v1\n"} {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+
+	rec, err := BeginTurn(workTree, "grow data")
+	if err != nil {
+		t.Fatalf("BeginTurn: %v", err)
+	}
+	grown := strings.Repeat("x", 4096)
+	if err := os.WriteFile(big, []byte(grown), 0644); err != nil {
+		t.Fatalf("grow: %v", err)
+	}
+	if err := os.WriteFile(small, []byte("v2\n"), 0644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	turn, err := rec.End()
+	if err != nil || turn == nil {
+		t.Fatalf("End: %+v %v", turn, err)
+	}
+	if strings.Join(turn.Files, ",") != "notes.txt" {
+		t.Fatalf("oversized file should not be part of the turn: %v", turn.Files)
+	}
+
+	if _, err := UndoTurn(workTree, false); err != nil {
+		t.Fatalf("UndoTurn: %v", err)
+	}
+	if got := readTestFile(big); got != grown {
+		t.Fatalf("undo rewrote the oversized file to %q", got)
+	}
+	if _, err := RedoTurn(workTree, false); err != nil {
+		t.Fatalf("RedoTurn: %v", err)
+	}
+	if got := readTestFile(big); got != grown {
+		t.Fatalf("redo changed the oversized file: %q", got)
+	}
+
+	// Restoring it explicitly is refused rather than writing stale content.
+	if _, err := RestoreCheckpointFiles(workTree, turn.Before, []string{"data.txt"}); err == nil || !strings.Contains(err.Error(), "data.txt") {
+		t.Fatalf("expected data.txt to be refused, got %v", err)
+	}
+	if got := readTestFile(big); got != grown {
+		t.Fatalf("restore changed the oversized file: %q", got)
+	}
+}
@@ -0,0 +1,191 @@
+package tools
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// restorePaths makes each file match its content at rev, deleting files
+// that do not exist there. Other files are left alone. Files left out of
+// checkpoints (see excludedPaths) are skipped and reported in the error,
+// since rev does not hold their content.
+func restorePaths(workTree, rev string, files []string) error {
+	excluded, err := excludedPaths(workTree, files)
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		if excluded[f] {
+			continue
+		}
+		if _, err := runGit(workTree, "cat-file", "-e", rev+":"+f); err == nil {
+			if _, err := runGit(workTree, "checkout", rev, "--", f); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := os.Remove(filepath.Join(workTree, filepath.FromSlash(f))); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to remove %s: %w", f, err)
+		}
+	}
+	if len(excluded) > 0 {
+		skipped := slices.Sorted(maps.Keys(excluded))
+		return fmt.Errorf("left %s as is: ignored or larger than the checkpoint size limit, so no checkpoint holds its content", strings.Join(skipped, ", "))
+	}
+	return nil
+}
+
+// resolveCheckpoint turns a hash, tag or other revision into a full hash.
+func resolveCheckpoint(workTree, ref string) (string, error) {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return "", errors.New("checkpoint is required")
+	}
+	rev, err := runGit(workTree, "rev-parse", "--verify", "--quiet", ref+"^{commit}")
+	if err != nil {
+		return "", fmt.Errorf("unknown checkpoint %q (see editor_history)", ref)
+	}
+	return rev, nil
+}
+
+// checkpointPathspecs converts paths (absolute or relative to workTree) into
+// work-tree-relative pathspecs, rejecting paths outside it.
+func checkpointPathspecs(workTree string, paths []string) ([]string, error) {
+	root := absPath(workTree)
+	var specs []string
+	for _, p := range paths {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		if !filepath.IsAbs(p) {
+			p = filepath.Join(root, p)
+		}
+		rel, err := filepath.Rel(root, filepath.Clean(p))
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return nil, fmt.Errorf("path %s is outside the work tree %s", p, root)
+		}
+		specs = append(specs, filepath.ToSlash(rel))
+	}
+	if len(specs) == 0 {
+		return nil, errors.New("at least one path is required")
+	}
+	return specs, nil
+}
+
+// RestoreCheckpointFiles brings the given files or directories back to
+// their state at checkpoint, leaving every other file untouched. Files under
+// the paths that did not exist at the checkpoint are removed. The current
+// state is checkpointed first so the restore can itself be reverted.
+func RestoreCheckpointFiles(workTree, checkpoint string, paths []string) (string, error) {
+	paths = slices.Clone(paths)
+	for i, p := range paths {
+		if p = strings.TrimSpace(p); p != "" && !filepath.IsAbs(p) {
+			paths[i] = filepath.Join(absPath(workTree), p)
+		}
+	}
+	workTree = CheckpointRoot(workTree)
+	if err := EnsureEditorGitRepo(workTree); err != nil {
+		return "", err
+	}
+	rev, err := resolveCheckpoint(workTree, checkpoint)
+	if err != nil {
+		return "", err
+	}
+	specs, err := checkpointPathspecs(workTree, paths)
+	if err != nil {
+		return "", err
+	}
+	excluded, err := excludedPaths(workTree, specs)
+	if err != nil {
+		return "", err
+	}
+	if len(excluded) > 0 {
+		return "", fmt.Errorf("cannot restore %s: ignored or larger than the checkpoint size limit, so no checkpoint holds its content", strings.Join(slices.Sorted(maps.Keys(excluded)), ", "))
+	}
+
+	safety, err := SnapshotWorkTree(workTree, "editor checkpoint: before restoring from "+shortHash(rev))
+	if err != nil {
+		return "", err
+	}
+	files, err := changedPaths(workTree, rev, safety, specs...)
+	if err != nil {
+		return "", err
+	}
+	if len(files) == 0 {
+		return fmt.Sprintf("Nothing to restore: %s already match checkpoint %s.", strings.Join(specs, ", "), shortHash(rev)), nil
+	}
+	if err := restorePaths(workTree, rev, files); err != nil {
+		return "", err
+	}
+	after, err := SnapshotWorkTree(workTree, fmt.Sprintf("editor checkpoint: restore %s from %s", strings.Join(specs, ", "), shortHash(rev)))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Restored %d file(s) from checkpoint %s: %s\nCheckpoint: %s. To reverse, restore the same paths from %s.",
+		len(files), shortHash(rev), strings.Join(files, ", "), shortHash(after), shortHash(safety)), nil
+}
+
+// TagCheckpoint names a checkpoint (HEAD when ref is empty) so it can be
+// found and restored later; an existing tag of the same name is moved.
+func TagCheckpoint(workTree, name, ref string) (string, error) {
+	workTree = CheckpointRoot(workTree)
+	if err := EnsureEditorGitRepo(workTree); err != nil {
+		return "", err
+	}
+	name = strings.TrimSpace(name)
+	if _, err := runGit(workTree, "check-ref-format", "refs/tags/"+name); err != nil || name == "" {
+		return "", fmt.Errorf("invalid checkpoint name %q", name)
+	}
+	if strings.TrimSpace(ref) == "" {
+		ref = "HEAD"
+	}
+	rev, err := resolveCheckpoint(workTree, ref)
+	if err != nil {
+		return "", err
+	}
+	if _, err := runGit(workTree, "tag", "-f", name, rev); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Tagged checkpoint %s as %q.", shortHash(rev), name), nil
+}
+
+// ExecuteCheckpointTool handles the checkpoint browsing tools. handled is
+// false for other tool names.
+func ExecuteCheckpointTool(name, rawArgs string) (handled bool, output string) {
+	switch name {
+	case "restore_checkpoint_files", "tag_checkpoint":
+	default:
+		return false, ""
+	}
+	var args struct {
+		WorkTree   string   `json:"work_tree"`
+		Checkpoint string   `json:"checkpoint"`
+		Paths      []string `json:"paths"`
+		Name       string   `json:"name"`
+	}
+	if err := json.Unmarshal([]byte(rawArgs), &args); err != nil {
+		return true, fmt.Sprintf("Error: invalid arguments for %s: %v", name, err)
+	}
+	if strings.TrimSpace(args.WorkTree) == "" {
+		return true, fmt.Sprintf("Error: %s requires a non-empty 'work_tree' argument.", name)
+	}
+
+	var err error
+	switch name {
+	case "restore_checkpoint_files":
+		output, err = RestoreCheckpointFiles(args.WorkTree, args.Checkpoint, args.Paths)
+	case "tag_checkpoint":
+		output, err = TagCheckpoint(args.WorkTree, args.Name, args.Checkpoint)
+	}
+	if err != nil {
+		return true, fmt.Sprintf("Error: %v", err)
+	}
+	return true, output
+}
@@ -0,0 +1,153 @@
+package tools
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bilbilaki/ai2go/internal/diff"
+)
+
+func writeTestFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("write %s: %v", path, err)
+	}
+}
+
+func readTestFile(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "<missing>"
+	}
+	return string(data)
+}
+
+func TestRestoreCheckpointFilesIsSelective(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	workTree := t.TempDir()
+	a, b := filepath.Join(workTree, "a.txt"), filepath.Join(workTree, "pkg", "b.txt")
+	writeTestFile(t, a, "a1\n")
+	writeTestFile(t, b, "b1\n")
+	first, err := CreateCheckpoint(workTree, "", "first")
+	if err != nil {
+		t.Fatalf("CreateCheckpoint: %v", err)
+	}
+	if _, err := TagCheckpoint(workTree, "baseline", ""); err != nil {
+		t.Fatalf("TagCheckpoint: %v", err)
+	}
+	if _, err := TagCheckpoint(workTree, "bad name", ""); err == nil {
+		t.Fatal("expected invalid tag name error")
+	}
+
+	writeTestFile(t, a, "a2\n")
+	writeTestFile(t, b, "b2\n")
+	created := filepath.Join(workTree, "pkg", "new.txt")
+	writeTestFile(t, created, "new\n")
+	if _, err := CreateCheckpoint(workTree, "", "second"); err != nil {
+		t.Fatalf("CreateCheckpoint: %v", err)
+	}
+
+	history, err := EditorHistory(workTree, 5)
+	if err != nil || !strings.Contains(history, "first (tag: baseline)") {
+		t.Fatalf("history should show the tag: %q %v", history, err)
+	}
+
+	_, out := ExecuteCheckpointTool("restore_checkpoint_files", mustJSON(t, map[string]any{
+		"work_tree": workTree, "checkpoint": "baseline", "paths": []string{"pkg"},
+	}))
+	if !strings.Contains(out, "Restored 2 file(s)") {
+		t.Fatalf("unexpected restore output: %s", out)
+	}
+	if readTestFile(a) != "a2\n" || readTestFile(b) != "b1\n" || readTestFile(created) != "<missing>" {
+		t.Fatalf("restore touched the wrong files: a=%q b=%q new=%q", readTestFile(a), readTestFile(b), readTestFile(created))
+	}
+	if _, err := RestoreCheckpointFiles(workTree, first, []string{"../outside"}); err == nil {
+		t.Fatal("expected error for a path outside the work tree")
+	}
+
+	out, err = ShowCheckpointDiff(workTree, "baseline", CompareWorkingTree, diff.Options{})
+	if err != nil || !strings.Contains(out, "vs working tree") || !strings.Contains(out, "-a1\n+a2") || strings.Contains(out, "b.txt") {
+		t.Fatalf("ShowCheckpointDiff vs working tree: %q %v", out, err)
+	}
+}
+
+func TestUndoLastCheckpointsKeepsUntrackedAndRefusesDrift(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	workTree := t.TempDir()
+	file := filepath.Join(workTree, "note.txt")
+	writeTestFile(t, file, "one\n")
+	first, err := CreateCheckpoint(workTree, "", "first")
+	if err != nil {
+		t.Fatalf("CreateCheckpoint: %v", err)
+	}
+	writeTestFile(t, file, "two\n")
+	if _, err := CreateCheckpoint(workTree, "", "second"); err != nil {
+		t.Fatalf("CreateCheckpoint: %v", err)
+	}
+
+	// Never-checkpointed user work survives the undo.
+	untracked := filepath.Join(workTree, "scratch.txt")
+	writeTestFile(t, untracked, "mine\n")
+	// Uncheckpointed edits to a file being restored block it.
+	writeTestFile(t, file, "three\n")
+	if _, err := UndoLastCheckpoints(workTree, 1); err == nil || !strings.Contains(err.Error(), "note.txt") {
+		t.Fatalf("expected drift error, got %v", err)
+	}
+	if readTestFile(file) != "three\n" {
+		t.Fatalf("refused undo must not touch files, got %q", readTestFile(file))
+	}
+
+	writeTestFile(t, file, "two\n")
+	head, err := UndoLastCheckpoints(workTree, 1)
+	if err != nil || head != first {
+		t.Fatalf("UndoLastCheckpoints: %s %v", head, err)
+	}
+	if readTestFile(file) != "one\n" || readTestFile(untracked) != "mine\n" {
+		t.Fatalf("after undo: note=%q scratch=%q", readTestFile(file), readTestFile(untracked))
+	}
+	if _, err := UndoLastCheckpoints(workTree, 5); err == nil {
+		t.Fatal("expected error when undoing past the first checkpoint")
+	}
+}
+
+func TestGCCheckpointsKeepsTagged(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	workTree := t.TempDir()
+	file := filepath.Join(workTree, "f.txt")
+	for i, content := range []string{"1\n", "2\n", "3\n", "4\n"} {
+		writeTestFile(t, file, content)
+		if _, err := CreateCheckpoint(workTree, "", content); err != nil {
+			t.Fatalf("CreateCheckpoint: %v", err)
+		}
+		if i == 1 {
+			if _, err := TagCheckpoint(workTree, "keep-me", ""); err != nil {
+				t.Fatalf("TagCheckpoint: %v", err)
+			}
+		}
+	}
+	report, err := GCCheckpoints(workTree, CheckpointRetention{KeepCheckpoints: 1})
+	if err != nil || report.Kept != 3 {
+		t.Fatalf("GCCheckpoints: %+v %v", report, err)
+	}
+	if _, err := RestoreCheckpointFiles(workTree, "keep-me", []string{"f.txt"}); err != nil {
+		t.Fatalf("restore from remapped tag: %v", err)
+	}
+	if readTestFile(file) != "2\n" {
+		t.Fatalf("restored %q", readTestFile(file))
+	}
+}
+
+func mustJSON(t *testing.T, v any) string {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	return string(data)
+}
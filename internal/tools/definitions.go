@@ -307,7 +307,8 @@ func GetPatchFileTool() api.Tool {
 				"type": "object",
 				"properties": {
 					"path": { "type": "string" },
-					"patch": { "type": "string", "description": "The patch string (e.g., '10++ new_code\\n20--')" }
+					"patch": { "type": "string", "description": "The patch string (e.g., '10++ new_code\\n20--')" },
+					"verify": { "type": "string", "description": "Optional verify profile run after the edit ('syntax', 'tests', 'default' or a .ai2go/verify.json profile); a failing check undoes the edit." }
 				},
 				"required": ["path", "patch"]
 			}`),
@@ -315,12 +316,87 @@ func GetPatchFileTool() api.Tool {
 	}
 }
 
+func GetEditFileTool() api.Tool {
+	return api.Tool{
+		Type: "function",
+		Function: api.ToolFunction{
+			Name:        "edit_file",
+			Description: "Edits a file by exact search and replace. Each old_string must match the file exactly once (copy it from read_file output without the line-number prefixes and include enough context to be unique) unless replace_all is set. Edits apply in order and nothing is written unless all match. An empty old_string creates a new file. Returns a unified diff and records an editor checkpoint.",
+			Parameters: json.RawMessage(`{
+				"type": "object",
+				"properties": {
+					"path": { "type": "string", "description": "Target file path." },
+					"edits": {
+						"type": "array",
+						"description": "Replacements applied in order.",
+						"items": {
+							"type": "object",
+							"properties": {
+								"old_string": { "type": "string", "description": "Exact text to replace." },
+								"new_string": { "type": "string", "description": "Replacement text." },
+								"replace_all": { "type": "boolean", "description": "Replace every occurrence instead of requiring exactly one. Default false." }
+							},
+							"required": ["old_string", "new_string"]
+						}
+					},
+					"old_string": { "type": "string", "description": "Shorthand for a single edit." },
+					"new_string": { "type": "string", "description": "Replacement for old_string." },
+					"replace_all": { "type": "boolean", "description": "Applies to the single old_string edit." },
+					"verify": { "type": "string", "description": "Optional verify profile run after the edit ('syntax', 'tests', 'default' or a .ai2go/verify.json profile); a failing check undoes the edit." }
+				},
+				"required": ["path"]
+			}`),
+		},
+	}
+}
+
+func GetEditTransactionTool() api.Tool {
+	return api.Tool{
+		Type: "function",
+		Function: api.ToolFunction{
+			Name:        "edit_transaction",
+			Description: "Applies a list of file operations across one or more files as a single all-or-nothing change. Every operation is validated in order against the staged result of the earlier ones before anything is written; if one fails, no file changes. Files are written via temp files and renames and restored if a write fails. Returns one combined diff and records an editor checkpoint. Use it for multi-file refactors instead of a series of single-file edits.",
+			Parameters: json.RawMessage(`{
+				"type": "object",
+				"properties": {
+					"work_tree": { "type": "string", "description": "Project directory relative paths are resolved against. Defaults to the current git root. Paths outside it are rejected." },
+					"operations": {
+						"type": "array",
+						"description": "Operations applied in order.",
+						"items": {
+							"type": "object",
+							"properties": {
+								"op": { "type": "string", "enum": ["replace", "insert", "delete", "create", "rename", "chmod"], "description": "replace: exact search and replace (old_string must match once unless replace_all). insert: add text before 'line' (omit line to append). delete: remove lines start_line..end_line, or the whole file when no line range is given. create: new file with content (overwrite to replace an existing one). rename: move path to 'to'. chmod: set octal permissions from 'mode'." },
+								"path": { "type": "string", "description": "File the operation applies to." },
+								"old_string": { "type": "string", "description": "replace: exact text to replace." },
+								"new_string": { "type": "string", "description": "replace: replacement text." },
+								"replace_all": { "type": "boolean", "description": "replace: replace every occurrence." },
+								"line": { "type": "integer", "description": "insert: 1-based line to insert before." },
+								"text": { "type": "string", "description": "insert: lines to insert." },
+								"start_line": { "type": "integer", "description": "delete: first line to remove (1-based)." },
+								"end_line": { "type": "integer", "description": "delete: last line to remove (defaults to start_line)." },
+								"content": { "type": "string", "description": "create: file content." },
+								"overwrite": { "type": "boolean", "description": "create: replace an existing file." },
+								"to": { "type": "string", "description": "rename: destination path." },
+								"mode": { "type": "string", "description": "chmod: octal permissions such as \"755\"." }
+							},
+							"required": ["op", "path"]
+						}
+					},
+					"verify": { "type": "string", "description": "Optional verify profile run after the transaction ('syntax', 'tests', 'default' or a .ai2go/verify.json profile); a failing check undoes it." }
+				},
+				"required": ["operations"]
+			}`),
+		},
+	}
+}
+
 func GetApplyUnifiedDiffPatchTool() api.Tool {
 	return api.Tool{
 		Type: "function",
 		Function: api.ToolFunction{
 			Name:        "apply_unified_diff_patch",
-			Description: "Applies a standard unified diff patch for multi-file atomic edits using the editor git engine with checkpoint + rollback safety. Patch must include valid unified diff headers/hunks; if parse/header errors occur, switch to patch_file.",
+			Description: "Applies a unified diff for multi-file atomic edits with checkpoint + rollback safety. Hunks are matched with offset search, fuzz and whitespace-insensitive context, so line numbers may be approximate; /dev/null old/new paths create or delete files and git rename headers are honored. Nothing is written unless every hunk applies; rejections name the closest matching lines.",
 			Parameters: json.RawMessage(`{
 				"type": "object",
 				"properties": {
@@ -328,8 +404,7 @@ func GetApplyUnifiedDiffPatchTool() api.Tool {
 					"patch": { "type": "string", "description": "Unified diff content (git diff format)." },
 					"verify_mode": {
 						"type": "string",
-						"enum": ["none", "syntax", "tests"],
-						"description": "Post-apply verification mode. Default: none."
+						"description": "Verify profile run after applying: 'none' (default), built-in 'syntax' or 'tests' (mapped to the project's toolchain), 'default', or a profile from .ai2go/verify.json. Failing checks roll the patch back unless the profile disables rollback."
 					}
 				},
 				"required": ["work_tree", "patch"]
@@ -362,7 +437,7 @@ func GetUndoCheckpointsTool() api.Tool {
 		Type: "function",
 		Function: api.ToolFunction{
 			Name:        "undo_checkpoints",
-			Description: "Undo the last N editor checkpoints in a worktree.",
+			Description: "Undo the last N editor checkpoints in a worktree. Only files those checkpoints changed are restored; refuses if they have uncheckpointed edits. Use restore_checkpoint_files to restore specific paths instead.",
 			Parameters: json.RawMessage(`{
 				"type": "object",
 				"properties": {
@@ -380,12 +455,14 @@ func GetEditorHistoryTool() api.Tool {
 		Type: "function",
 		Function: api.ToolFunction{
 			Name:        "editor_history",
-			Description: "Shows recent editor checkpoint history for a worktree.",
+			Description: "Shows recent editor checkpoint history for a worktree, or the diff recorded by one checkpoint.",
 			Parameters: json.RawMessage(`{
 				"type": "object",
 				"properties": {
 					"work_tree": { "type": "string", "description": "Target project directory/worktree." },
-					"limit": { "type": "integer", "description": "Number of history entries to return. Default: 10." }
+					"limit": { "type": "integer", "description": "Number of history entries to return. Default: 10." },
+					"checkpoint": { "type": "string", "description": "Optional checkpoint hash or tag from the history; shows the changes it recorded instead of the history." },
+					"compare_to": { "type": "string", "description": "With 'checkpoint': diff it against another checkpoint, or 'working_tree' for the files on disk." }
 				},
 				"required": ["work_tree"]
 			}`),
@@ -393,6 +470,44 @@ func GetEditorHistoryTool() api.Tool {
 	}
 }
 
+func GetRestoreCheckpointFilesTool() api.Tool {
+	return api.Tool{
+		Type: "function",
+		Function: api.ToolFunction{
+			Name:        "restore_checkpoint_files",
+			Description: "Restore specific files or directories to their state at an editor checkpoint without touching other files. Files under the paths that did not exist at the checkpoint are removed.",
+			Parameters: json.RawMessage(`{
+				"type": "object",
+				"properties": {
+					"work_tree": { "type": "string", "description": "Target project directory/worktree." },
+					"checkpoint": { "type": "string", "description": "Checkpoint hash or tag (see editor_history)." },
+					"paths": { "type": "array", "items": { "type": "string" }, "description": "Files or directories to restore, relative to work_tree or absolute." }
+				},
+				"required": ["work_tree", "checkpoint", "paths"]
+			}`),
+		},
+	}
+}
+
+func GetTagCheckpointTool() api.Tool {
+	return api.Tool{
+		Type: "function",
+		Function: api.ToolFunction{
+			Name:        "tag_checkpoint",
+			Description: "Give an editor checkpoint a name (e.g. before-refactor) so it can be diffed or restored later. Tagged checkpoints survive checkpoint garbage collection.",
+			Parameters: json.RawMessage(`{
+				"type": "object",
+				"properties": {
+					"work_tree": { "type": "string", "description": "Target project directory/worktree." },
+					"name": { "type": "string", "description": "Tag name; an existing tag with this name is moved." },
+					"checkpoint": { "type": "string", "description": "Checkpoint hash to tag. Default: the latest checkpoint." }
+				},
+				"required": ["work_tree", "name"]
+			}`),
+		},
+	}
+}
+
 func GetPageSizeTool() api.Tool {
 	return api.Tool{
 		Type: "function",
@@ -516,7 +631,8 @@ func GetRemoveLinesTool() api.Tool {
 						"description": "Line/range specs like [\"10\", \"15-20\"]."
 					},
 					"start_line": { "type": "integer", "description": "Fallback single range start." },
-					"end_line": { "type": "integer", "description": "Fallback single range end (optional)." }
+					"end_line": { "type": "integer", "description": "Fallback single range end (optional)." },
+					"verify": { "type": "string", "description": "Optional verify profile run after the edit ('syntax', 'tests', 'default' or a .ai2go/verify.json profile); a failing check undoes the edit." }
 				},
 				"required": ["path"]
 			}`),
@@ -536,7 +652,8 @@ func GetReplaceLineRangeTool() api.Tool {
 					"path": { "type": "string", "description": "Target file path." },
 					"start_line": { "type": "integer", "description": "Start line (1-based)." },
 					"end_line": { "type": "integer", "description": "End line (1-based, inclusive)." },
-					"replacement": { "type": "string", "description": "Replacement text. Use \\n for multiline." }
+					"replacement": { "type": "string", "description": "Replacement text. Use \\n for multiline." },
+					"verify": { "type": "string", "description": "Optional verify profile run after the edit ('syntax', 'tests', 'default' or a .ai2go/verify.json profile); a failing check undoes the edit." }
 				},
 				"required": ["path", "start_line", "end_line", "replacement"]
 			}`),
@@ -570,7 +687,8 @@ func GetBatchLineOperationsTool() api.Tool {
 							},
 							"required": ["op", "line"]
 						}
-					}
+					},
+					"verify": { "type": "string", "description": "Optional verify profile run after the edit ('syntax', 'tests', 'default' or a .ai2go/verify.json profile); a failing check undoes the edit." }
 				},
 				"required": ["path", "operations"]
 			}`),
@@ -589,7 +707,8 @@ func GetDeleteLinesByPatternTool() api.Tool {
 				"properties": {
 					"path": { "type": "string", "description": "Target file path." },
 					"pattern": { "type": "string", "description": "Regex pattern to match lines." },
-					"case_sensitive": { "type": "boolean", "description": "Case-sensitive regex match. Default false." }
+					"case_sensitive": { "type": "boolean", "description": "Case-sensitive regex match. Default false." },
+					"verify": { "type": "string", "description": "Optional verify profile run after the edit ('syntax', 'tests', 'default' or a .ai2go/verify.json profile); a failing check undoes the edit." }
 				},
 				"required": ["path", "pattern"]
 			}`),
@@ -628,7 +747,8 @@ func GetReorderLineRangeTool() api.Tool {
 					"path": { "type": "string", "description": "Target file path." },
 					"start_line": { "type": "integer", "description": "Start line of block." },
 					"end_line": { "type": "integer", "description": "End line of block." },
-					"target_line": { "type": "integer", "description": "Insert block before this line." }
+					"target_line": { "type": "integer", "description": "Insert block before this line." },
+					"verify": { "type": "string", "description": "Optional verify profile run after the edit ('syntax', 'tests', 'default' or a .ai2go/verify.json profile); a failing check undoes the edit." }
 				},
 				"required": ["path", "start_line", "end_line", "target_line"]
 			}`),
@@ -647,7 +767,8 @@ func GetRemoveDuplicateLinesTool() api.Tool {
 				"properties": {
 					"path": { "type": "string", "description": "Target file path." },
 					"case_sensitive": { "type": "boolean", "description": "Treat case differences as unique if true. Default false." },
-					"ignore_blank": { "type": "boolean", "description": "Skip duplicate detection for blank lines. Default false." }
+					"ignore_blank": { "type": "boolean", "description": "Skip duplicate detection for blank lines. Default false." },
+					"verify": { "type": "string", "description": "Optional verify profile run after the edit ('syntax', 'tests', 'default' or a .ai2go/verify.json profile); a failing check undoes the edit." }
 				},
 				"required": ["path"]
 			}`),
@@ -680,13 +801,15 @@ func GetFileDiffViewerTool() api.Tool {
 		Type: "function",
 		Function: api.ToolFunction{
 			Name:        "show_file_diff",
-			Description: "Show differences between file versions. Compare path vs compare_path or a backup_id.",
+			Description: "Show differences between file versions as a minimal unified diff with stats. Compare path vs compare_path or a backup_id.",
 			Parameters: json.RawMessage(`{
 				"type": "object",
 				"properties": {
 					"path": { "type": "string", "description": "Primary file path." },
 					"compare_path": { "type": "string", "description": "Second file path to compare against." },
-					"backup_id": { "type": "string", "description": "Optional backup id to diff against." }
+					"backup_id": { "type": "string", "description": "Optional backup id to diff against." },
+					"context_lines": { "type": "integer", "description": "Unchanged lines shown around each change. Default: 3." },
+					"algorithm": { "type": "string", "enum": ["myers", "patience"], "description": "Diff algorithm. 'patience' keeps moved blocks and repeated lines (braces, blank lines) better aligned. Default: myers." }
 				},
 				"required": ["path"]
 			}`),
@@ -699,7 +822,7 @@ func GetFileComparisonTool() api.Tool {
 		Type: "function",
 		Function: api.ToolFunction{
 			Name:        "compare_files_side_by_side",
-			Description: "Compare two files side by side line-by-line.",
+			Description: "Compare two files side by side, aligning unchanged lines. Markers: '|' changed, '<' left only, '>' right only.",
 			Parameters: json.RawMessage(`{
 				"type": "object",
 				"properties": {
@@ -718,7 +841,7 @@ func GetCreateFileBackupTool() api.Tool {
 		Type: "function",
 		Function: api.ToolFunction{
 			Name:        "create_file_backup",
-			Description: "Create a file backup snapshot and return backup_id.",
+			Description: "Back up a file's current content and return a short backup_id. Unchanged content is not stored twice.",
 			Parameters: json.RawMessage(`{
 				"type": "object",
 				"properties": {
@@ -735,14 +858,31 @@ func GetRestoreFileBackupTool() api.Tool {
 		Type: "function",
 		Function: api.ToolFunction{
 			Name:        "restore_file_backup",
-			Description: "Restore a file from backup_id.",
+			Description: "Restore a file from backup_id. The content being overwritten is backed up first.",
 			Parameters: json.RawMessage(`{
 				"type": "object",
 				"properties": {
-					"path": { "type": "string", "description": "Target file path to overwrite." },
-					"backup_id": { "type": "string", "description": "Backup ID returned by create_file_backup." }
+					"path": { "type": "string", "description": "Target file path to overwrite. Defaults to the file the backup was taken from." },
+					"backup_id": { "type": "string", "description": "Backup ID returned by create_file_backup or list_file_backups." }
 				},
-				"required": ["path", "backup_id"]
+				"required": ["backup_id"]
+			}`),
+		},
+	}
+}
+
+func GetListFileBackupsTool() api.Tool {
+	return api.Tool{
+		Type: "function",
+		Function: api.ToolFunction{
+			Name:        "list_file_backups",
+			Description: "List stored file backups, newest first, with backup_id, time, size and content hash.",
+			Parameters: json.RawMessage(`{
+				"type": "object",
+				"properties": {
+					"path": { "type": "string", "description": "Only list backups of this file. Omit to list all backups." },
+					"limit": { "type": "integer", "description": "Maximum number of backups to list (default 20)." }
+				}
 			}`),
 		},
 	}
@@ -753,14 +893,16 @@ func GetFileMergingTool() api.Tool {
 		Type: "function",
 		Function: api.ToolFunction{
 			Name:        "merge_files",
-			Description: "Merge base, left, and right files using simple 3-way merge with conflict markers.",
+			Description: "Three-way (diff3) merge of left and right against base. Changes made on only one side merge cleanly; overlapping changes become conflict blocks, listed with their line numbers so they can be resolved one at a time with resolve_merge_conflict.",
 			Parameters: json.RawMessage(`{
 				"type": "object",
 				"properties": {
 					"base_path": { "type": "string", "description": "Base/original file path." },
 					"left_path": { "type": "string", "description": "Left variant file path." },
 					"right_path": { "type": "string", "description": "Right variant file path." },
-					"output_path": { "type": "string", "description": "Optional output path. Defaults to base_path + .merged" }
+					"output_path": { "type": "string", "description": "Optional output path. Defaults to base_path + .merged" },
+					"style": { "type": "string", "enum": ["merge", "diff3", "zdiff3"], "description": "Conflict marker style. diff3 (default) includes the base section; zdiff3 also moves lines common to both sides out of the conflict." },
+					"strategy": { "type": "string", "enum": ["ours", "theirs", "union"], "description": "Optional automatic resolution of conflicts: keep left, keep right, or keep both." }
 				},
 				"required": ["base_path", "left_path", "right_path"]
 			}`),
@@ -768,6 +910,26 @@ func GetFileMergingTool() api.Tool {
 	}
 }
 
+func GetResolveMergeConflictTool() api.Tool {
+	return api.Tool{
+		Type: "function",
+		Function: api.ToolFunction{
+			Name:        "resolve_merge_conflict",
+			Description: "Lists or resolves conflict blocks (<<<<<<< ... >>>>>>>) in a file. Without 'conflict' it lists all conflicts; with it, replaces that conflict with the chosen side or custom text. Conflict numbers shift after each resolution, so re-list when unsure.",
+			Parameters: json.RawMessage(`{
+				"type": "object",
+				"properties": {
+					"path": { "type": "string", "description": "File containing conflict markers." },
+					"conflict": { "type": "integer", "description": "1-based conflict number to resolve. Omit to list conflicts." },
+					"resolution": { "type": "string", "enum": ["left", "right", "base", "both", "custom"], "description": "Which content replaces the conflict block." },
+					"text": { "type": "string", "description": "Replacement text for resolution=custom." }
+				},
+				"required": ["path"]
+			}`),
+		},
+	}
+}
+
 func GetFileTypeDetectionTool() api.Tool {
 	return api.Tool{
 		Type: "function",
@@ -868,3 +1030,317 @@ func GetProjectArchitectTool() api.Tool {
 		},
 	}
 }
+
+func GetRememberTool() api.Tool {
+	return api.Tool{
+		Type: "function",
+		Function: api.ToolFunction{
+			Name:        "remember",
+			Description: "Saves a durable fact to long-term memory so future threads start with it (e.g. 'this repo builds with make, not go build').",
+			Parameters: json.RawMessage(`{
+				"type": "object",
+				"properties": {
+					"content": {
+						"type": "string",
+						"description": "The fact to remember, written as a short standalone sentence."
+					},
+					"scope": {
+						"type": "string",
+						"enum": ["project", "global"],
+						"description": "'project' (default) applies only to the current repository; 'global' applies everywhere."
+					},
+					"tags": {
+						"type": "array",
+						"items": { "type": "string" },
+						"description": "Optional keywords that help recall."
+					}
+				},
+				"required": ["content"]
+			}`),
+		},
+	}
+}
+
+func GetRecallTool() api.Tool {
+	return api.Tool{
+		Type: "function",
+		Function: api.ToolFunction{
+			Name:        "recall",
+			Description: "Searches long-term memory for facts relevant to the current project.",
+			Parameters: json.RawMessage(`{
+				"type": "object",
+				"properties": {
+					"query": {
+						"type": "string",
+						"description": "Keywords to search for. Empty returns the most relevant memories."
+					},
+					"limit": {
+						"type": "integer",
+						"description": "Maximum number of memories to return (default: 8)."
+					}
+				}
+			}`),
+		},
+	}
+}
+
+func GetForgetTool() api.Tool {
+	return api.Tool{
+		Type: "function",
+		Function: api.ToolFunction{
+			Name:        "forget",
+			Description: "Deletes an outdated or wrong memory by id.",
+			Parameters: json.RawMessage(`{
+				"type": "object",
+				"properties": {
+					"id": {
+						"type": "string",
+						"description": "Memory id as shown by recall or in the system prompt (e.g. mem_1712345678)."
+					}
+				},
+				"required": ["id"]
+			}`),
+		},
+	}
+}
+
+func GetFindFilesTool() api.Tool {
+	return api.Tool{
+		Type: "function",
+		Function: api.ToolFunction{
+			Name:        "find_files",
+			Description: "Finds files under a directory by glob, extension and path filters. Respects .gitignore and skips hidden files by default. Faster and safer than shell find loops on large trees.",
+			Parameters: json.RawMessage(`{
+				"type": "object",
+				"properties": {
+					"path": { "type": "string", "description": "Directory to search (default: current directory)." },
+					"glob": { "type": "string", "description": "Glob such as '*_test.go' (matches file names) or 'internal/**/*.go' (matches paths relative to 'path')." },
+					"extensions": {
+						"type": "array",
+						"items": { "type": "string" },
+						"description": "Allowed extensions, e.g. [\".go\", \".md\"]."
+					},
+					"include_paths": { "type": "string", "description": "Comma-separated path wildcards to keep, e.g. 'internal/*'." },
+					"exclude_paths": { "type": "string", "description": "Comma-separated path wildcards to skip, e.g. 'vendor/,testdata/'." },
+					"contains": { "type": "string", "description": "Optional regex; only files whose content matches are returned." },
+					"ignore_case": { "type": "boolean", "description": "Case-insensitive 'contains' match (default: false)." },
+					"max_results": { "type": "integer", "description": "Maximum files to return (default: 200, max: 2000)." },
+					"include_hidden": { "type": "boolean", "description": "Include dotfiles and dot-directories (default: false)." },
+					"no_ignore": { "type": "boolean", "description": "Do not apply .gitignore rules (default: false)." },
+					"format": { "type": "string", "enum": ["text", "json"], "description": "Output format (default: text)." }
+				}
+			}`),
+		},
+	}
+}
+
+func GetGrepCodeTool() api.Tool {
+	return api.Tool{
+		Type: "function",
+		Function: api.ToolFunction{
+			Name:        "grep_code",
+			Description: "Searches file contents with a regular expression and returns matching lines with line numbers and optional context. Respects .gitignore and skips binary files.",
+			Parameters: json.RawMessage(`{
+				"type": "object",
+				"properties": {
+					"pattern": { "type": "string", "description": "Go (RE2) regular expression matched against each line." },
+					"path": { "type": "string", "description": "Directory to search (default: current directory)." },
+					"glob": { "type": "string", "description": "Only search files matching this glob, e.g. '*.go' or 'cmd/**/*.go'." },
+					"extensions": {
+						"type": "array",
+						"items": { "type": "string" },
+						"description": "Allowed extensions, e.g. [\".go\"]."
+					},
+					"include_paths": { "type": "string", "description": "Comma-separated path wildcards to keep." },
+					"exclude_paths": { "type": "string", "description": "Comma-separated path wildcards to skip." },
+					"ignore_case": { "type": "boolean", "description": "Case-insensitive match (default: false)." },
+					"context_lines": { "type": "integer", "description": "Lines of context before and after each match (default: 0, max: 10)." },
+					"max_results": { "type": "integer", "description": "Maximum matching lines to return (default: 200, max: 2000)." },
+					"include_hidden": { "type": "boolean", "description": "Include dotfiles and dot-directories (default: false)." },
+					"no_ignore": { "type": "boolean", "description": "Do not apply .gitignore rules (default: false)." },
+					"format": { "type": "string", "enum": ["text", "json"], "description": "Output format (default: text, grep style 'path:line:text')." }
+				},
+				"required": ["pattern"]
+			}`),
+		},
+	}
+}
+
+func GetGoListSymbolsTool() api.Tool {
+	return api.Tool{
+		Type: "function",
+		Function: api.ToolFunction{
+			Name:        "go_list_symbols",
+			Description: "Lists top-level Go declarations (funcs, methods, types, vars, consts) with signatures and exact line ranges for a .go file or package directory.",
+			Parameters: json.RawMessage(`{
+				"type": "object",
+				"properties": {
+					"path": { "type": "string", "description": "A .go file or a package directory." },
+					"exported_only": { "type": "boolean", "description": "Only list exported symbols (default: false)." }
+				},
+				"required": ["path"]
+			}`),
+		},
+	}
+}
+
+func GetGoShowFunctionTool() api.Tool {
+	return api.Tool{
+		Type: "function",
+		Function: api.ToolFunction{
+			Name:        "go_show_function",
+			Description: "Shows the source of a Go function or method by name, with its doc comment and line numbers in read_file format.",
+			Parameters: json.RawMessage(`{
+				"type": "object",
+				"properties": {
+					"path": { "type": "string", "description": "A .go file or a package directory to search." },
+					"name": { "type": "string", "description": "Function name ('FindFiles') or method as 'Type.Method' ('History.AddUserMessage')." }
+				},
+				"required": ["path", "name"]
+			}`),
+		},
+	}
+}
+
+func GetGoFindDefinitionTool() api.Tool {
+	return api.Tool{
+		Type: "function",
+		Function: api.ToolFunction{
+			Name:        "go_find_definition",
+			Description: "Jumps to the declaration of a Go identifier used at a given file and line, resolving across packages of the current module, and shows the declaration source.",
+			Parameters: json.RawMessage(`{
+				"type": "object",
+				"properties": {
+					"path": { "type": "string", "description": "The .go file where the identifier is used." },
+					"line": { "type": "integer", "description": "1-based line of the identifier." },
+					"name": { "type": "string", "description": "The identifier, e.g. 'AddToolResponse' (not 'history.AddToolResponse')." },
+					"column": { "type": "integer", "description": "Optional 1-based column when the identifier appears more than once on the line." }
+				},
+				"required": ["path", "line", "name"]
+			}`),
+		},
+	}
+}
+
+func GetGoFindReferencesTool() api.Tool {
+	return api.Tool{
+		Type: "function",
+		Function: api.ToolFunction{
+			Name:        "go_find_references",
+			Description: "Finds every reference to a Go identifier across the current module using type information, so same-named but unrelated identifiers are excluded.",
+			Parameters: json.RawMessage(`{
+				"type": "object",
+				"properties": {
+					"path": { "type": "string", "description": "A .go file where the identifier is declared or used." },
+					"line": { "type": "integer", "description": "1-based line of the identifier." },
+					"name": { "type": "string", "description": "The identifier name." },
+					"column": { "type": "integer", "description": "Optional 1-based column when the identifier appears more than once on the line." }
+				},
+				"required": ["path", "line", "name"]
+			}`),
+		},
+	}
+}
+
+func GetGoRenameSymbolTool() api.Tool {
+	return api.Tool{
+		Type: "function",
+		Function: api.ToolFunction{
+			Name:        "go_rename_symbol",
+			Description: "Renames a Go identifier and every reference to it using type information: across its package, and across the current module for exported names. Refuses renames that would collide with or be shadowed by existing names. Files excluded by build tags are not updated. Changed files are gofmt-ed and returned as a diff.",
+			Parameters: json.RawMessage(`{
+				"type": "object",
+				"properties": {
+					"path": { "type": "string", "description": "A .go file where the identifier is declared or used." },
+					"line": { "type": "integer", "description": "1-based line of the identifier." },
+					"name": { "type": "string", "description": "The current identifier name." },
+					"new_name": { "type": "string", "description": "The new identifier name." },
+					"column": { "type": "integer", "description": "Optional 1-based column when the identifier appears more than once on the line." },
+					"verify": { "type": "string", "description": "Optional verify profile run after the edit ('syntax', 'tests', 'default' or a .ai2go/verify.json profile); a failing check undoes the edit." }
+				},
+				"required": ["path", "line", "name", "new_name"]
+			}`),
+		},
+	}
+}
+
+func GetGoReplaceFunctionBodyTool() api.Tool {
+	return api.Tool{
+		Type: "function",
+		Function: api.ToolFunction{
+			Name:        "go_replace_function_body",
+			Description: "Replaces the body of a Go function or method by name, keeping its signature and doc comment. The file is gofmt-ed and the change returned as a diff; a body that does not parse leaves the file untouched.",
+			Parameters: json.RawMessage(`{
+				"type": "object",
+				"properties": {
+					"path": { "type": "string", "description": "A .go file or a package directory to search." },
+					"name": { "type": "string", "description": "Function name ('FindFiles') or method as 'Type.Method' ('History.AddUserMessage')." },
+					"body": { "type": "string", "description": "The new statements between the braces, without the braces themselves." },
+					"verify": { "type": "string", "description": "Optional verify profile run after the edit ('syntax', 'tests', 'default' or a .ai2go/verify.json profile); a failing check undoes the edit." }
+				},
+				"required": ["path", "name", "body"]
+			}`),
+		},
+	}
+}
+
+func GetGoEditImportsTool() api.Tool {
+	return api.Tool{
+		Type: "function",
+		Function: api.ToolFunction{
+			Name:        "go_edit_imports",
+			Description: "Adds and/or removes imports in a Go file, keeping standard library imports grouped first. Imports that are still used are not removed. The file is gofmt-ed and the change returned as a diff.",
+			Parameters: json.RawMessage(`{
+				"type": "object",
+				"properties": {
+					"path": { "type": "string", "description": "The .go file to edit." },
+					"add": { "type": "array", "items": { "type": "string" }, "description": "Imports to add: 'strings' or with a name, 'yaml gopkg.in/yaml.v3'." },
+					"remove": { "type": "array", "items": { "type": "string" }, "description": "Import paths to remove." },
+					"verify": { "type": "string", "description": "Optional verify profile run after the edit ('syntax', 'tests', 'default' or a .ai2go/verify.json profile); a failing check undoes the edit." }
+				},
+				"required": ["path"]
+			}`),
+		},
+	}
+}
+
+func GetGoAddStructFieldTool() api.Tool {
+	return api.Tool{
+		Type: "function",
+		Function: api.ToolFunction{
+			Name:        "go_add_struct_field",
+			Description: "Adds one or more fields to a Go struct type, at the end or after a named field. Refuses names the struct already has. The file is gofmt-ed and the change returned as a diff.",
+			Parameters: json.RawMessage(`{
+				"type": "object",
+				"properties": {
+					"path": { "type": "string", "description": "A .go file or a package directory declaring the type." },
+					"type": { "type": "string", "description": "The struct type name." },
+					"field": { "type": "string", "description": "Field declaration(s) as written in the struct, optionally with doc comments, e.g. 'Retries int ` + "`json:\\\"retries\\\"`" + `'." },
+					"after": { "type": "string", "description": "Optional existing field to insert after; defaults to the end of the struct." },
+					"verify": { "type": "string", "description": "Optional verify profile run after the edit ('syntax', 'tests', 'default' or a .ai2go/verify.json profile); a failing check undoes the edit." }
+				},
+				"required": ["path", "type", "field"]
+			}`),
+		},
+	}
+}
+
+func GetGoAddMethodTool() api.Tool {
+	return api.Tool{
+		Type: "function",
+		Function: api.ToolFunction{
+			Name:        "go_add_method",
+			Description: "Inserts a method on a Go type after the type's last method in the file that declares it. Refuses a method name the type already has as a method or field. The file is gofmt-ed and the change returned as a diff.",
+			Parameters: json.RawMessage(`{
+				"type": "object",
+				"properties": {
+					"path": { "type": "string", "description": "A .go file or a package directory declaring the type." },
+					"type": { "type": "string", "description": "The receiver type name." },
+					"source": { "type": "string", "description": "The complete method declaration, optionally preceded by its doc comment." },
+					"verify": { "type": "string", "description": "Optional verify profile run after the edit ('syntax', 'tests', 'default' or a .ai2go/verify.json profile); a failing check undoes the edit." }
+				},
+				"required": ["path", "type", "source"]
+			}`),
+		},
+	}
+}
@@ -0,0 +1,245 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// StringEdit replaces OldString with NewString. OldString must occur exactly
+// once unless ReplaceAll is set.
+type StringEdit struct {
+	OldString  string `json:"old_string"`
+	NewString  string `json:"new_string"`
+	ReplaceAll bool   `json:"replace_all,omitempty"`
+}
+
+// EditFileArgs are the arguments of the edit_file tool. A single top-level
+// old_string/new_string pair is folded into Edits.
+type EditFileArgs struct {
+	Path       string       `json:"path"`
+	Edits      []StringEdit `json:"edits"`
+	OldString  *string      `json:"old_string"`
+	NewString  string       `json:"new_string"`
+	ReplaceAll bool         `json:"replace_all"`
+	Verify     string       `json:"verify"`
+}
+
+// ParseEditFileArgs decodes and validates edit_file arguments.
+func ParseEditFileArgs(rawArgs string) (EditFileArgs, error) {
+	var args EditFileArgs
+	if err := json.Unmarshal([]byte(rawArgs), &args); err != nil {
+		return args, fmt.Errorf("invalid arguments for edit_file: %w", err)
+	}
+	args.Path = strings.TrimSpace(args.Path)
+	if args.Path == "" {
+		return args, errors.New("edit_file requires a non-empty 'path' argument")
+	}
+	if args.OldString != nil {
+		args.Edits = append([]StringEdit{{OldString: *args.OldString, NewString: args.NewString, ReplaceAll: args.ReplaceAll}}, args.Edits...)
+	}
+	if len(args.Edits) == 0 {
+		return args, errors.New("edit_file requires 'edits' or an 'old_string'/'new_string' pair")
+	}
+	return args, nil
+}
+
+// EditFile applies edits to path in order and writes the result only when
+// every edit matches. An empty old_string creates a new (or fills an empty)
+// file. The project's formatters run on the result (see FormatEditedFiles),
+// which is then recorded as an editor checkpoint and returned as a unified
+// diff.
+func EditFile(ctx context.Context, path string, edits []StringEdit) (string, error) {
+	info, statErr := os.Stat(path)
+	exists := statErr == nil
+	if statErr != nil && !errors.Is(statErr, os.ErrNotExist) {
+		return "", fmt.Errorf("failed to stat %s: %w", path, statErr)
+	}
+	if exists && info.IsDir() {
+		return "", fmt.Errorf("%s is a directory", path)
+	}
+
+	var original string
+	format := defaultTextFormat()
+	if exists {
+		var err error
+		if original, format, err = readTextFile(path); err != nil {
+			return "", err
+		}
+	}
+
+	content := original
+	replacements := 0
+	for i, e := range edits {
+		if e.OldString == "" {
+			newStr := e.NewString
+			if !exists && strings.Contains(newStr, "\r\n") {
+				format.newline = "\r\n"
+			}
+			newStr = strings.ReplaceAll(newStr, "\r\n", "\n")
+			if newStr == "" {
+				return "", fmt.Errorf("edit %d: old_string and new_string are identical", i+1)
+			}
+			if content != "" {
+				return "", fmt.Errorf("edit %d: empty old_string only creates new or empty files; %s already has content", i+1, path)
+			}
+			content = newStr
+			replacements++
+			continue
+		}
+		if !exists {
+			return "", fmt.Errorf("%s does not exist; use an empty old_string to create it", path)
+		}
+		var n int
+		var err error
+		if content, n, err = replaceString(content, path, e); err != nil {
+			return "", fmt.Errorf("edit %d: %w", i+1, err)
+		}
+		replacements += n
+	}
+	if content == original && exists {
+		return "", fmt.Errorf("edits left %s unchanged", path)
+	}
+
+	workTree := editWorkTree(path)
+	rel, err := filepath.Rel(workTree, absPath(path))
+	if err != nil {
+		rel = path
+	}
+	var notes []string
+	if exists {
+		if _, err := CreateCheckpoint(workTree, rel, "editor checkpoint: before edit_file "+rel); err != nil {
+			notes = append(notes, fmt.Sprintf("pre-edit checkpoint skipped: %v", err))
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", fmt.Errorf("failed to create directory: %w", err)
+	}
+	if err := format.writeText(path, content); err != nil {
+		return "", fmt.Errorf("failed to save file: %w", err)
+	}
+	if note := format.lineEndingNote(path); note != "" {
+		notes = append(notes, note)
+	}
+	formatted := FormatEditedFiles(ctx, []string{path})
+
+	checkpoint := ""
+	if head, err := CreateCheckpoint(workTree, rel, "editor checkpoint: edit_file "+rel); err != nil {
+		notes = append(notes, fmt.Sprintf("checkpoint skipped: %v", err))
+	} else {
+		checkpoint = fmt.Sprintf(" Checkpoint: %s (work_tree %s).", shortHash(head), workTree)
+	}
+
+	var b strings.Builder
+	action := "Edited"
+	if !exists {
+		action = "Created"
+	}
+	fmt.Fprintf(&b, "%s %s (%d replacement(s)).%s\n", action, path, replacements, checkpoint)
+	for _, n := range notes {
+		fmt.Fprintf(&b, "Note: %s\n", n)
+	}
+	b.WriteString(BuildSimpleUnifiedDiff("a/"+filepath.ToSlash(rel), "b/"+filepath.ToSlash(rel), splitDiffLines(original), splitDiffLines(content)))
+	return withFormatReport(b.String(), formatted), nil
+}
+
+// replaceString applies one non-empty search-and-replace to content, which
+// has LF line endings (see readTextFile). It returns the number of
+// replacements.
+func replaceString(content, path string, e StringEdit) (string, int, error) {
+	oldStr := strings.ReplaceAll(e.OldString, "\r\n", "\n")
+	newStr := strings.ReplaceAll(e.NewString, "\r\n", "\n")
+	if oldStr == newStr {
+		return "", 0, errors.New("old_string and new_string are identical")
+	}
+	count := strings.Count(content, oldStr)
+	switch {
+	case count == 0:
+		return "", 0, fmt.Errorf("old_string not found in %s%s", path, nearMatchHint(content, oldStr))
+	case count > 1 && !e.ReplaceAll:
+		return "", 0, fmt.Errorf("old_string matches %d times in %s (lines %s); include more surrounding context or set replace_all",
+			count, path, matchLines(content, oldStr))
+	case e.ReplaceAll:
+		return strings.ReplaceAll(content, oldStr, newStr), count, nil
+	}
+	return strings.Replace(content, oldStr, newStr, 1), 1, nil
+}
+
+func splitDiffLines(s string) []string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	if s == "" {
+		return []string{}
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// matchLines lists the 1-based lines where needle starts, at most five.
+func matchLines(content, needle string) string {
+	var lines []string
+	offset := 0
+	for len(lines) < 5 {
+		idx := strings.Index(content[offset:], needle)
+		if idx < 0 {
+			break
+		}
+		pos := offset + idx
+		lines = append(lines, fmt.Sprint(strings.Count(content[:pos], "\n")+1))
+		offset = pos + max(len(needle), 1)
+	}
+	return strings.Join(lines, ", ")
+}
+
+// nearMatchHint looks for old's lines in content ignoring indentation and
+// trailing whitespace, and points at the block if found.
+func nearMatchHint(content, old string) string {
+	want := splitDiffLines(old)
+	have := splitDiffLines(content)
+	if len(want) == 0 || len(want) > len(have) {
+		return ""
+	}
+	for at := 0; at+len(want) <= len(have); at++ {
+		matched := true
+		for i, w := range want {
+			if strings.TrimSpace(have[at+i]) != strings.TrimSpace(w) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return fmt.Sprintf(" (lines %d-%d match except for whitespace; copy them exactly from read_file)", at+1, at+len(want))
+		}
+	}
+	if first := strings.TrimSpace(want[0]); first != "" {
+		for i, line := range have {
+			if strings.TrimSpace(line) == first {
+				return fmt.Sprintf(" (its first line appears at line %d, but the following lines differ; re-read the file)", i+1)
+			}
+		}
+	}
+	return "; re-read the file and copy old_string exactly, without line-number prefixes"
+}
+
+// editWorkTree picks the checkpoint work tree for path, the same one turn
+// snapshots and the patch tools use (see CheckpointRoot).
+func editWorkTree(path string) string {
+	return CheckpointRoot(filepath.Dir(absPath(path)))
+}
+
+func absPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
+
+func shortHash(hash string) string {
+	if len(hash) > 12 {
+		return hash[:12]
+	}
+	return hash
+}
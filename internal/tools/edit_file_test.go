@@ -0,0 +1,91 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEditFileAppliesEditsAndCheckpoints(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	dir := t.TempDir()
+	path := filepath.Join(dir, "main.go")
+	original := "package main\n\nfunc a() int { return 1 }\n\nfunc b() int { return 1 }\n"
+	if err := os.WriteFile(path, []byte(original), 0644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	args, err := ParseEditFileArgs(`{"path":"` + filepath.ToSlash(path) + `","old_string":"func a() int { return 1 }","new_string":"func a() int { return 2 }","edits":[{"old_string":"return 1","new_string":"return 3","replace_all":true}]}`)
+	if err != nil {
+		t.Fatalf("parse args: %v", err)
+	}
+	out, err := EditFile(context.Background(), path, args.Edits)
+	if err != nil {
+		t.Fatalf("edit: %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	if want := "package main\n\nfunc a() int { return 2 }\n\nfunc b() int { return 3 }\n"; string(data) != want {
+		t.Fatalf("unexpected content %q", data)
+	}
+	if !strings.Contains(out, "(2 replacement(s)). Checkpoint:") || !strings.Contains(out, "+func b() int { return 3 }") {
+		t.Fatalf("unexpected output:\n%s", out)
+	}
+
+	if _, err := UndoLastCheckpoints(dir, 1); err != nil {
+		t.Fatalf("undo: %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != original {
+		t.Fatalf("expected undo to restore the original, got %q", data)
+	}
+}
+
+func TestEditFileRequiresUniqueExactMatch(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	dir := t.TempDir()
+	path := filepath.Join(dir, "notes.txt")
+	original := "alpha\n    beta\nalpha\n"
+	if err := os.WriteFile(path, []byte(original), 0644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	cases := []struct {
+		edits []StringEdit
+		want  string
+	}{
+		{[]StringEdit{{OldString: "alpha", NewString: "x"}}, "matches 2 times in " + path + " (lines 1, 3)"},
+		{[]StringEdit{{OldString: "  beta\n  alpha", NewString: "x"}}, "lines 2-3 match except for whitespace"},
+		{[]StringEdit{{OldString: "gamma", NewString: "x"}}, "old_string not found"},
+		{[]StringEdit{{OldString: "x", NewString: "x"}}, "identical"},
+		{[]StringEdit{{OldString: "    beta", NewString: "b"}, {OldString: "missing", NewString: "y"}}, "edit 2: old_string not found"},
+	}
+	for _, c := range cases {
+		if _, err := EditFile(context.Background(), path, c.edits); err == nil || !strings.Contains(err.Error(), c.want) {
+			t.Fatalf("edits %+v: expected error containing %q, got %v", c.edits, c.want, err)
+		}
+	}
+	if data, _ := os.ReadFile(path); string(data) != original {
+		t.Fatalf("failed edits must not write, got %q", data)
+	}
+}
+
+func TestEditFileCreatesFilesAndKeepsCRLF(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	dir := t.TempDir()
+	created := filepath.Join(dir, "sub", "new.txt")
+	if out, err := EditFile(context.Background(), created, []StringEdit{{NewString: "hello\n"}}); err != nil || !strings.HasPrefix(out, "Created ") {
+		t.Fatalf("create: %q, %v", out, err)
+	}
+
+	win := filepath.Join(dir, "win.txt")
+	if err := os.WriteFile(win, []byte("one\r\ntwo\r\nthree\r\n"), 0644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if _, err := EditFile(context.Background(), win, []StringEdit{{OldString: "one\ntwo", NewString: "one\n2"}}); err != nil {
+		t.Fatalf("edit: %v", err)
+	}
+	if data, _ := os.ReadFile(win); string(data) != "one\r\n2\r\nthree\r\n" {
+		t.Fatalf("unexpected content %q", data)
+	}
+}
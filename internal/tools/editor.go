@@ -2,6 +2,8 @@ package tools
 
 import (
 	"bufio"
+	"bytes"
+	"context"
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
@@ -12,9 +14,12 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+
+	"github.com/bilbilaki/ai2go/internal/diff"
 )
 
-// VerifyMode controls post-patch verification behavior.
+// VerifyMode names the verify profile run after a patch. The constants are
+// the built-in profiles; projects can add their own in .ai2go/verify.json.
 type VerifyMode string
 
 const (
@@ -74,11 +79,19 @@ func ReadFileWithLines(path, lineRange string) (string, error) {
 	scanner := bufio.NewScanner(file)
 	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
 	lineNum := 1
+	linesRead := 0
 	totalChars := 0
 	lineLimited := false
 	charLimited := false
 	for scanner.Scan() {
-		if lineNum > maxReadFileLines {
+		if lineNum < startLine {
+			lineNum++
+			continue
+		}
+		if lineNum > endLine {
+			break
+		}
+		if linesRead >= maxReadFileLines {
 			lineLimited = true
 			break
 		}
@@ -94,7 +107,7 @@ func ReadFileWithLines(path, lineRange string) (string, error) {
 				}
 				result.WriteString(fmt.Sprintf("%d | %s\n", lineNum, line))
 				totalChars += len([]rune(line))
-				lineNum++
+				linesRead++
 			}
 			charLimited = true
 			break
@@ -103,6 +116,7 @@ func ReadFileWithLines(path, lineRange string) (string, error) {
 		// Format: 1 | <content>
 		result.WriteString(fmt.Sprintf("%d | %s\n", lineNum, line))
 		totalChars += lineChars
+		linesRead++
 		lineNum++
 	}
 
@@ -117,7 +131,7 @@ func ReadFileWithLines(path, lineRange string) (string, error) {
 	}
 
 	if lineLimited || charLimited {
-		result.WriteString(truncationNotice(path, lineNum-1, totalChars, lineLimited, charLimited))
+		result.WriteString(truncationNotice(path, linesRead, totalChars, lineLimited, charLimited))
 	}
 
 	if strings.TrimSpace(result.String()) == "" {
@@ -130,16 +144,10 @@ func ReadFileWithLines(path, lineRange string) (string, error) {
 // ApplyFilePatch applies the custom "26++" / "26--" syntax.
 // It uses original line numbers to ensure stability.
 func ApplyFilePatch(path, patchContent string) (string, error) {
-	// 1. Read the original file
-	content, err := os.ReadFile(path)
+	// 1. Read the original file, remembering its encoding and line endings
+	originalLines, format, err := readTextLines(path)
 	if err != nil {
-		return "", fmt.Errorf("failed to read file: %w", err)
-	}
-	originalLines := strings.Split(string(content), "\n")
-
-	// Fix split edge case: if file ends with newline, Split gives an empty string at the end.
-	if len(originalLines) > 0 && originalLines[len(originalLines)-1] == "" {
-		originalLines = originalLines[:len(originalLines)-1]
+		return "", err
 	}
 
 	// 2. Parse Patch
@@ -225,15 +233,8 @@ func ApplyFilePatch(path, patchContent string) (string, error) {
 		newLines = append(newLines, op.Lines...)
 	}
 
-	// 4. Write to disk
-	finalContent := strings.Join(newLines, "\n")
-	// Ensure single trailing newline
-	if !strings.HasSuffix(finalContent, "\n") {
-		finalContent += "\n"
-	}
-
-	err = os.WriteFile(path, []byte(finalContent), 0644)
-	if err != nil {
+	// 4. Write to disk in the file's original format
+	if err := writeTextLines(path, newLines, format); err != nil {
 		return "", fmt.Errorf("failed to save file: %w", err)
 	}
 
@@ -278,11 +279,12 @@ func EnsureEditorGitRepo(workTree string) error {
 		return err
 	}
 
-	return nil
+	return writeShadowRepoInfo(absWorkTree, gitDir)
 }
 
 // CreateCheckpoint creates a commit checkpoint for a file or the whole worktree when filePath is empty.
 func CreateCheckpoint(workTree, filePath, message string) (string, error) {
+	workTree, filePath = checkpointPath(workTree, filePath)
 	if err := EnsureEditorGitRepo(workTree); err != nil {
 		return "", err
 	}
@@ -291,14 +293,8 @@ func CreateCheckpoint(workTree, filePath, message string) (string, error) {
 		message = "editor checkpoint"
 	}
 
-	if strings.TrimSpace(filePath) == "" {
-		if _, err := runGit(workTree, "add", "-A"); err != nil {
-			return "", err
-		}
-	} else {
-		if _, err := runGit(workTree, "add", "--", filePath); err != nil {
-			return "", err
-		}
+	if err := stageCheckpoint(workTree, filePath); err != nil {
+		return "", err
 	}
 
 	if _, err := runGit(workTree, "commit", "--allow-empty", "-m", message); err != nil {
@@ -312,65 +308,150 @@ func CreateCheckpoint(workTree, filePath, message string) (string, error) {
 	return strings.TrimSpace(head), nil
 }
 
-// ApplyUnifiedDiffPatch applies a unified diff patch with checkpointing and optional verification.
-func ApplyUnifiedDiffPatch(workTree, patchContent string, verifyMode VerifyMode) (string, error) {
-	pre, err := CreateCheckpoint(workTree, "", "editor checkpoint: pre-apply")
+// ApplyUnifiedDiffPatch applies a unified diff patch with checkpointing and
+// optional verification. verifyMode names a verify profile (see
+// LoadVerifyProfiles); "none" or empty skips verification.
+func ApplyUnifiedDiffPatch(ctx context.Context, workTree, patchContent string, verifyMode VerifyMode) (string, error) {
+	files, err := ParseUnifiedDiff(patchContent)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse unified diff: %w", err)
+	}
+	var targets []string
+	for _, fp := range files {
+		if fp.NewPath != "" {
+			targets = append(targets, fp.NewPath)
+		}
+	}
+
+	var profile VerifyProfile
+	verify := verifyMode != VerifyModeNone && verifyMode != ""
+	if verify {
+		if profile, err = ResolveVerifyProfile(workTree, string(verifyMode), targets); err != nil {
+			return "", err
+		}
+	}
+
+	// Checkpoints go to the shared shadow repo, whose root may be above
+	// workTree; a rollback only restores the files the patch touched.
+	root := CheckpointRoot(workTree)
+	var touched []string
+	for _, fp := range files {
+		for _, p := range []string{fp.OldPath, fp.NewPath} {
+			if p == "" {
+				continue
+			}
+			if rel, err := filepath.Rel(root, filepath.Join(absPath(workTree), filepath.FromSlash(p))); err == nil {
+				touched = append(touched, filepath.ToSlash(rel))
+			}
+		}
+	}
+	rollback := func(pre string) { _ = restorePaths(root, pre, touched) }
+
+	pre, err := CreateCheckpoint(root, "", "editor checkpoint: pre-apply")
 	if err != nil {
 		return "", fmt.Errorf("failed to create pre-apply checkpoint: %w", err)
 	}
 
-	if _, err := runGitWithInput(workTree, patchContent, "apply", "--whitespace=nowarn", "-"); err != nil {
-		_ = rollbackTo(workTree, pre)
-		return "", fmt.Errorf("failed to apply unified diff (rolled back): %w", err)
+	results, err := applyFilePatches(workTree, files)
+	if err != nil {
+		rollback(pre)
+		return "", fmt.Errorf("failed to apply unified diff: %w", err)
+	}
+	report := "\n" + FormatPatchResults(results)
+	var absTargets []string
+	for _, t := range targets {
+		absTargets = append(absTargets, filepath.Join(workTree, t))
+	}
+	if formatted := FormatEditedFiles(ctx, absTargets); formatted != "" {
+		report += "\n" + formatted
 	}
 
-	if err := runVerification(workTree, verifyMode); err != nil {
-		_ = rollbackTo(workTree, pre)
-		return "", fmt.Errorf("verification failed and changes were rolled back: %w", err)
+	if verify {
+		result := RunVerifyProfile(ctx, workTree, profile, targets)
+		report += "\n" + result.Format()
+		if !result.Passed {
+			if profile.Rollback() {
+				rollback(pre)
+				return "", fmt.Errorf("verification failed and changes were rolled back:\n%s", result.Format())
+			}
+			report += "\n(changes kept; profile does not roll back)"
+		}
 	}
 
-	post, err := CreateCheckpoint(workTree, "", "editor checkpoint: post-apply")
+	post, err := CreateCheckpoint(root, "", "editor checkpoint: post-apply")
 	if err != nil {
-		_ = rollbackTo(workTree, pre)
+		rollback(pre)
 		return "", fmt.Errorf("failed to create post-apply checkpoint; rolled back: %w", err)
 	}
 
-	return fmt.Sprintf("Patch applied successfully. Checkpoints: pre=%s post=%s", pre, post), nil
+	return fmt.Sprintf("Patch applied successfully. Checkpoints: pre=%s post=%s%s", pre, post, report), nil
 }
 
-// UndoLastCheckpoints undoes the last N checkpoints.
+// UndoLastCheckpoints moves back N checkpoints, restoring only the files
+// those checkpoints changed. Other files, including untracked files that
+// were never checkpointed, are left alone. It refuses when a file to be
+// restored has uncheckpointed changes, since those would be lost.
 func UndoLastCheckpoints(workTree string, steps int) (string, error) {
 	if steps < 1 {
 		return "", fmt.Errorf("steps must be >= 1")
 	}
+	workTree = CheckpointRoot(workTree)
 	if err := EnsureEditorGitRepo(workTree); err != nil {
 		return "", err
 	}
 
-	target := fmt.Sprintf("HEAD~%d", steps)
-	if _, err := runGit(workTree, "reset", "--hard", target); err != nil {
+	head, err := runGit(workTree, "rev-parse", "--verify", "--quiet", "HEAD")
+	if err != nil {
+		return "", fmt.Errorf("no checkpoints to undo")
+	}
+	target, err := runGit(workTree, "rev-parse", "--verify", "--quiet", fmt.Sprintf("HEAD~%d^{commit}", steps))
+	if err != nil {
+		return "", fmt.Errorf("cannot undo %d checkpoint(s): history is shorter", steps)
+	}
+	files, err := changedPaths(workTree, target, head)
+	if err != nil {
 		return "", err
 	}
-	if _, err := runGit(workTree, "clean", "-fd"); err != nil {
+
+	if len(files) == 0 {
+		_, err := runGit(workTree, "reset", "--quiet", target)
+		return target, err
+	}
+
+	if err := stageCheckpoint(workTree, ""); err != nil {
 		return "", err
 	}
-	head, err := runGit(workTree, "rev-parse", "HEAD")
+	out, err := runGit(workTree, append([]string{"diff", "--cached", "--name-only", head, "--"}, files...)...)
 	if err != nil {
 		return "", err
 	}
-	return strings.TrimSpace(head), nil
+	if drifted := strings.Fields(out); len(drifted) > 0 {
+		_, _ = runGit(workTree, "reset", "--quiet", head)
+		return "", fmt.Errorf("uncheckpointed changes in %s would be lost; create a checkpoint first or use restore_checkpoint_files",
+			strings.Join(drifted, ", "))
+	}
+
+	if err := restorePaths(workTree, target, files); err != nil {
+		return "", err
+	}
+	if _, err := runGit(workTree, "reset", "--quiet", target); err != nil {
+		return "", err
+	}
+	return target, nil
 }
 
-// EditorHistory returns latest N checkpoints as `sha message` lines.
+// EditorHistory returns latest N checkpoints as `sha message` lines, with
+// checkpoint tags appended as "(tag: name)".
 func EditorHistory(workTree string, n int) (string, error) {
 	if n < 1 {
 		return "", fmt.Errorf("n must be >= 1")
 	}
+	workTree = CheckpointRoot(workTree)
 	if err := EnsureEditorGitRepo(workTree); err != nil {
 		return "", err
 	}
 
-	out, err := runGit(workTree, "log", fmt.Sprintf("-%d", n), "--pretty=format:%h %s")
+	out, err := runGit(workTree, "log", fmt.Sprintf("-%d", n), "--decorate-refs=refs/tags/", "--pretty=format:%h %s%d")
 	if err != nil {
 		return "", err
 	}
@@ -380,6 +461,114 @@ func EditorHistory(workTree string, n int) (string, error) {
 	return out, nil
 }
 
+// emptyTreeHash is git's well-known empty tree, the parent side of a diff
+// against the first checkpoint.
+const emptyTreeHash = "4b825dc642cb6eb9a060e54bf8d69288fbee4904"
+
+// CompareWorkingTree is the ShowCheckpointDiff compareTo value that diffs a
+// checkpoint against the files on disk.
+const CompareWorkingTree = "working_tree"
+
+// ShowCheckpointDiff returns the changes recorded by one checkpoint,
+// relative to the checkpoint before it. With compareTo set it instead diffs
+// the checkpoint against another checkpoint or CompareWorkingTree.
+func ShowCheckpointDiff(workTree, checkpoint, compareTo string, opts diff.Options) (string, error) {
+	workTree = CheckpointRoot(workTree)
+	if err := EnsureEditorGitRepo(workTree); err != nil {
+		return "", err
+	}
+	rev, err := resolveCheckpoint(workTree, checkpoint)
+	if err != nil {
+		return "", err
+	}
+	subject, _ := runGit(workTree, "log", "-1", "--decorate-refs=refs/tags/", "--pretty=format:%h %s%d", rev)
+
+	from, to, header := rev, "", "Checkpoint %s vs working tree\n%s"
+	switch compareTo = strings.TrimSpace(compareTo); compareTo {
+	case "":
+		header = "Checkpoint %s\n%s"
+		if from, err = runGit(workTree, "rev-parse", "--verify", "--quiet", rev+"^"); err != nil {
+			from = emptyTreeHash
+		}
+		to = rev
+	case CompareWorkingTree:
+	default:
+		if to, err = resolveCheckpoint(workTree, compareTo); err != nil {
+			return "", err
+		}
+		header = "Checkpoint %s vs " + shortHash(to) + "\n%s"
+	}
+	body, err := CheckpointDiff(workTree, from, to, opts)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf(header, subject, body), nil
+}
+
+// CheckpointDiff diffs the files that changed between two checkpoints (or
+// between from and the working tree when to is empty), one unified diff per
+// file.
+func CheckpointDiff(workTree, from, to string, opts diff.Options) (string, error) {
+	args := []string{"diff", "--name-only", "-z", from}
+	if to != "" {
+		args = append(args, to)
+	}
+	out, err := runGitRaw(workTree, args...)
+	if err != nil {
+		return "", err
+	}
+	var paths []string
+	for _, p := range strings.Split(string(out), "\x00") {
+		if p != "" {
+			paths = append(paths, p)
+		}
+	}
+	if len(paths) == 0 {
+		return "(No changes)", nil
+	}
+	if opts.MaxLines == 0 {
+		opts.MaxLines = maxFileDiffOutputLines
+	}
+
+	var total diff.Stats
+	var body strings.Builder
+	for _, p := range paths {
+		oldBlob, oldErr := checkpointBlob(workTree, from, p)
+		newBlob, newErr := checkpointBlob(workTree, to, p)
+		fromLabel, toLabel := "a/"+p, "b/"+p
+		if oldErr != nil {
+			fromLabel = "/dev/null"
+		}
+		if newErr != nil {
+			toLabel = "/dev/null"
+		}
+		if looksBinary(oldBlob) || looksBinary(newBlob) {
+			fmt.Fprintf(&body, "Binary file %s differs\n", p)
+			continue
+		}
+		d := diff.Compare(splitDiffLines(string(oldBlob)), splitDiffLines(string(newBlob)), opts)
+		s := d.Stats()
+		total.Added += s.Added
+		total.Deleted += s.Deleted
+		total.Hunks += s.Hunks
+		body.WriteString(d.Unified(fromLabel, toLabel, opts.MaxLines))
+		body.WriteString("\n")
+	}
+	return fmt.Sprintf("%d file(s) changed, %s\n%s", len(paths), total, strings.TrimRight(body.String(), "\n")), nil
+}
+
+// checkpointBlob reads path at rev, or from the working tree when rev is
+// empty. An error means the file does not exist there.
+func checkpointBlob(workTree, rev, path string) ([]byte, error) {
+	if rev == "" {
+		return os.ReadFile(filepath.Join(workTree, filepath.FromSlash(path)))
+	}
+	if rev == emptyTreeHash {
+		return nil, os.ErrNotExist
+	}
+	return runGitRaw(workTree, "show", rev+":"+path)
+}
+
 func editorGitDir(workTree string) (string, error) {
 	cacheDir, err := os.UserCacheDir()
 	if err != nil {
@@ -390,17 +579,24 @@ func editorGitDir(workTree string) (string, error) {
 }
 
 func runGit(workTree string, args ...string) (string, error) {
-	return runGitWithInput(workTree, "", args...)
+	output, err := runGitRaw(workTree, args...)
+	return strings.TrimSpace(string(output)), err
 }
 
-func runGitWithInput(workTree, input string, args ...string) (string, error) {
+// runGitRaw is runGit without trimming, for reading file contents.
+func runGitRaw(workTree string, args ...string) ([]byte, error) {
+	return runGitEnv(workTree, nil, args...)
+}
+
+// runGitEnv runs git against the shadow repo with extra environment entries.
+func runGitEnv(workTree string, env []string, args ...string) ([]byte, error) {
 	absWorkTree, err := filepath.Abs(workTree)
 	if err != nil {
-		return "", fmt.Errorf("failed to resolve worktree path: %w", err)
+		return nil, fmt.Errorf("failed to resolve worktree path: %w", err)
 	}
 	gitDir, err := editorGitDir(absWorkTree)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	cmd := exec.Command("git", args...)
@@ -409,47 +605,13 @@ func runGitWithInput(workTree, input string, args ...string) (string, error) {
 		"GIT_DIR="+gitDir,
 		"GIT_WORK_TREE="+absWorkTree,
 	)
-	if input != "" {
-		cmd.Stdin = strings.NewReader(input)
-	}
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return "", fmt.Errorf("git %s failed: %w\n%s", strings.Join(args, " "), err, strings.TrimSpace(string(output)))
-	}
-	return strings.TrimSpace(string(output)), nil
-}
-
-func runVerification(workTree string, mode VerifyMode) error {
-	switch mode {
-	case VerifyModeNone, "":
-		return nil
-	case VerifyModeSyntax:
-		_, err := runCommandInDir(workTree, "go", "test", "./...", "-run=^$")
-		return err
-	case VerifyModeTests:
-		_, err := runCommandInDir(workTree, "go", "test", "./...")
-		return err
-	default:
-		return fmt.Errorf("unsupported verify mode: %s", mode)
-	}
-}
-
-func rollbackTo(workTree, commit string) error {
-	if _, err := runGit(workTree, "reset", "--hard", commit); err != nil {
-		return err
-	}
-	if _, err := runGit(workTree, "clean", "-fd"); err != nil {
-		return err
-	}
-	return nil
-}
-
-func runCommandInDir(dir, name string, args ...string) (string, error) {
-	cmd := exec.Command(name, args...)
-	cmd.Dir = dir
-	output, err := cmd.CombinedOutput()
+	cmd.Env = append(cmd.Env, env...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
 	if err != nil {
-		return "", fmt.Errorf("%s %s failed: %w\n%s", name, strings.Join(args, " "), err, strings.TrimSpace(string(output)))
+		msg := strings.TrimSpace(stderr.String() + string(output))
+		return nil, fmt.Errorf("git %s failed: %w\n%s", strings.Join(args, " "), err, msg)
 	}
-	return strings.TrimSpace(string(output)), nil
+	return output, nil
 }
@@ -1,11 +1,14 @@
 package tools
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+
+	"github.com/bilbilaki/ai2go/internal/diff"
 )
 
 func TestReadFileWithLinesUsesRaisedLineLimit(t *testing.T) {
@@ -36,6 +39,21 @@ func TestReadFileWithLinesUsesRaisedLineLimit(t *testing.T) {
 	}
 }
 
+func TestReadFileWithLinesHonorsRange(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "range.txt")
+	if err := os.WriteFile(path, []byte("a\nb\nc\nd\n"), 0644); err != nil {
+		t.Fatalf("failed to create fixture: %v", err)
+	}
+
+	out, err := ReadFileWithLines(path, "2-3")
+	if err != nil {
+		t.Fatalf("ReadFileWithLines returned error: %v", err)
+	}
+	if out != "2 | b\n3 | c\n" {
+		t.Fatalf("unexpected range output %q", out)
+	}
+}
+
 func TestEditorGitCheckpointHistoryAndUndo(t *testing.T) {
 	workTree := t.TempDir()
 	file := filepath.Join(workTree, "note.txt")
@@ -87,6 +105,53 @@ func TestEditorGitCheckpointHistoryAndUndo(t *testing.T) {
 	}
 }
 
+func TestShowCheckpointDiff(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	workTree := t.TempDir()
+	file := filepath.Join(workTree, "note.txt")
+	if err := os.WriteFile(file, []byte("  indented\ntwo\n"), 0644); err != nil {
+		t.Fatalf("write fixture: %v", err)
+	}
+	first, err := CreateCheckpoint(workTree, "", "first")
+	if err != nil {
+		t.Fatalf("CreateCheckpoint(first): %v", err)
+	}
+	if err := os.WriteFile(file, []byte("  indented\nTWO\n"), 0644); err != nil {
+		t.Fatalf("write update: %v", err)
+	}
+	second, err := CreateCheckpoint(workTree, "", "second")
+	if err != nil {
+		t.Fatalf("CreateCheckpoint(second): %v", err)
+	}
+
+	out, err := ShowCheckpointDiff(workTree, first, "", diff.Options{})
+	if err != nil {
+		t.Fatalf("ShowCheckpointDiff(first): %v", err)
+	}
+	if !strings.Contains(out, "--- /dev/null\n+++ b/note.txt\n@@ -0,0 +1,2 @@\n+  indented\n+two") {
+		t.Fatalf("unexpected first checkpoint diff:\n%s", out)
+	}
+
+	out, err = ShowCheckpointDiff(workTree, second[:8], "", diff.Options{})
+	if err != nil {
+		t.Fatalf("ShowCheckpointDiff(second): %v", err)
+	}
+	if !strings.Contains(out, "1 file(s) changed, 1 hunk(s), +1 -1") || !strings.Contains(out, "-two\n+TWO") {
+		t.Fatalf("unexpected second checkpoint diff:\n%s", out)
+	}
+
+	if err := os.WriteFile(file, []byte("  indented\nTWO\nthree\n"), 0644); err != nil {
+		t.Fatalf("write working change: %v", err)
+	}
+	out, err = CheckpointDiff(workTree, second, "", diff.Options{})
+	if err != nil || !strings.Contains(out, "+three") {
+		t.Fatalf("CheckpointDiff against working tree: %q %v", out, err)
+	}
+	if _, err := ShowCheckpointDiff(workTree, "nosuchref", "", diff.Options{}); err == nil {
+		t.Fatal("expected error for unknown checkpoint")
+	}
+}
+
 func TestApplyUnifiedDiffPatchRollsBackOnVerifyFailure(t *testing.T) {
 	workTree := t.TempDir()
 	if err := os.WriteFile(filepath.Join(workTree, "go.mod"), []byte("module example.com/editor\n\ngo 1.22\n"), 0644); err != nil {
@@ -109,7 +174,7 @@ func TestApplyUnifiedDiffPatchRollsBackOnVerifyFailure(t *testing.T) {
 		"",
 	}, "\n")
 
-	_, err := ApplyUnifiedDiffPatch(workTree, patch, VerifyModeSyntax)
+	_, err := ApplyUnifiedDiffPatch(context.Background(), workTree, patch, VerifyModeSyntax)
 	if err == nil {
 		t.Fatal("expected verification failure")
 	}
@@ -1,9 +1,8 @@
 package tools
 
 import (
-	"crypto/sha1"
-	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
@@ -11,8 +10,8 @@ import (
 	"sort"
 	"strconv"
 	"strings"
-	"time"
-	"unicode/utf8"
+
+	"github.com/bilbilaki/ai2go/internal/diff"
 )
 
 const (
@@ -20,226 +19,34 @@ const (
 	defaultCompareWidth    = 120
 )
 
-type fileBackupMeta struct {
-	BackupID   string `json:"backup_id"`
-	SourcePath string `json:"source_path"`
-	BackupPath string `json:"backup_path"`
-	CreatedAt  string `json:"created_at"`
-	SizeBytes  int64  `json:"size_bytes"`
-}
-
 func readLinesNoEOL(path string) ([]string, error) {
 	blob, err := os.ReadFile(path)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read file: %w", err)
 	}
+	return splitLinesNoEOL(blob), nil
+}
+
+func splitLinesNoEOL(blob []byte) []string {
 	txt := strings.ReplaceAll(string(blob), "\r\n", "\n")
 	if strings.HasSuffix(txt, "\n") {
 		txt = strings.TrimSuffix(txt, "\n")
 	}
 	if txt == "" {
-		return []string{}, nil
-	}
-	return strings.Split(txt, "\n"), nil
-}
-
-func commonPrefixLines(a, b []string) int {
-	n := len(a)
-	if len(b) < n {
-		n = len(b)
-	}
-	for i := 0; i < n; i++ {
-		if a[i] != b[i] {
-			return i
-		}
+		return []string{}
 	}
-	return n
-}
-
-func commonSuffixLines(a, b []string, prefix int) int {
-	i := len(a) - 1
-	j := len(b) - 1
-	count := 0
-	for i >= prefix && j >= prefix {
-		if a[i] != b[j] {
-			break
-		}
-		count++
-		i--
-		j--
-	}
-	return count
+	return strings.Split(txt, "\n")
 }
 
+// BuildSimpleUnifiedDiff renders a unified diff of two line slices with the
+// default context, truncated after maxFileDiffOutputLines body lines.
 func BuildSimpleUnifiedDiff(fromLabel, toLabel string, fromLines, toLines []string) string {
-	prefix := commonPrefixLines(fromLines, toLines)
-	suffix := commonSuffixLines(fromLines, toLines, prefix)
-
-	fromStart := prefix
-	toStart := prefix
-	fromEnd := len(fromLines) - suffix
-	toEnd := len(toLines) - suffix
-	if fromEnd < fromStart {
-		fromEnd = fromStart
-	}
-	if toEnd < toStart {
-		toEnd = toStart
-	}
-
-	var out strings.Builder
-	out.WriteString(fmt.Sprintf("--- %s\n", fromLabel))
-	out.WriteString(fmt.Sprintf("+++ %s\n", toLabel))
-	out.WriteString(fmt.Sprintf("@@ -%d,%d +%d,%d @@\n", fromStart+1, fromEnd-fromStart, toStart+1, toEnd-toStart))
-
-	written := 0
-	for i := fromStart; i < fromEnd; i++ {
-		out.WriteString("-")
-		out.WriteString(fromLines[i])
-		out.WriteString("\n")
-		written++
-		if written >= maxFileDiffOutputLines {
-			out.WriteString("... [DIFF TRUNCATED] ...\n")
-			return strings.TrimRight(out.String(), "\n")
-		}
-	}
-	for i := toStart; i < toEnd; i++ {
-		out.WriteString("+")
-		out.WriteString(toLines[i])
-		out.WriteString("\n")
-		written++
-		if written >= maxFileDiffOutputLines {
-			out.WriteString("... [DIFF TRUNCATED] ...\n")
-			return strings.TrimRight(out.String(), "\n")
-		}
-	}
-
-	if fromStart == fromEnd && toStart == toEnd {
-		out.WriteString("(No differences)\n")
-	}
-	return strings.TrimRight(out.String(), "\n")
+	return diff.Unified(fromLabel, toLabel, fromLines, toLines, diff.Options{MaxLines: maxFileDiffOutputLines})
 }
 
-func resolveBackupRoot() (string, error) {
-	cacheDir, err := os.UserCacheDir()
-	if err != nil || strings.TrimSpace(cacheDir) == "" {
-		cacheDir = os.TempDir()
-	}
-	root := filepath.Join(cacheDir, "ai2go", "file_backups")
-	if err := os.MkdirAll(root, 0755); err != nil {
-		return "", fmt.Errorf("failed to create backup directory: %w", err)
-	}
-	return root, nil
-}
-
-func backupPathID(sourcePath string) string {
-	sum := sha1.Sum([]byte(sourcePath))
-	return hex.EncodeToString(sum[:])[:10]
-}
-
-func sanitizeFileName(s string) string {
-	s = strings.TrimSpace(s)
-	if s == "" {
-		return "file"
-	}
-	replacer := strings.NewReplacer("/", "_", "\\", "_", " ", "_", ":", "_", "*", "_", "?", "_", "\"", "_", "<", "_", ">", "_", "|", "_")
-	s = replacer.Replace(s)
-	if len(s) > 80 {
-		s = s[len(s)-80:]
-	}
-	return s
-}
-
-func CreateFileBackup(path string) (string, error) {
-	clean := strings.TrimSpace(path)
-	if clean == "" {
-		return "", fmt.Errorf("path is required")
-	}
-	abs, err := filepath.Abs(clean)
-	if err != nil {
-		return "", fmt.Errorf("failed to resolve path: %w", err)
-	}
-	blob, err := os.ReadFile(abs)
-	if err != nil {
-		return "", fmt.Errorf("failed to read source file: %w", err)
-	}
-
-	root, err := resolveBackupRoot()
-	if err != nil {
-		return "", err
-	}
-
-	base := sanitizeFileName(filepath.Base(abs))
-	id := fmt.Sprintf("%s__%s__%d", base, backupPathID(abs), time.Now().UTC().UnixNano())
-	backupPath := filepath.Join(root, id+".bak")
-	metaPath := filepath.Join(root, id+".meta.json")
-
-	if err := os.WriteFile(backupPath, blob, 0644); err != nil {
-		return "", fmt.Errorf("failed to write backup: %w", err)
-	}
-	meta := fileBackupMeta{
-		BackupID:   id,
-		SourcePath: abs,
-		BackupPath: backupPath,
-		CreatedAt:  time.Now().UTC().Format(time.RFC3339),
-		SizeBytes:  int64(len(blob)),
-	}
-	metaBlob, _ := json.MarshalIndent(meta, "", "  ")
-	_ = os.WriteFile(metaPath, metaBlob, 0644)
-	return fmt.Sprintf("Backup created. backup_id=%s\nbackup_file=%s", id, backupPath), nil
-}
-
-func resolveBackupByID(backupID string) (fileBackupMeta, error) {
-	id := strings.TrimSpace(backupID)
-	if id == "" {
-		return fileBackupMeta{}, fmt.Errorf("backup_id is required")
-	}
-	root, err := resolveBackupRoot()
-	if err != nil {
-		return fileBackupMeta{}, err
-	}
-	metaPath := filepath.Join(root, id+".meta.json")
-	metaBlob, err := os.ReadFile(metaPath)
-	if err == nil {
-		meta := fileBackupMeta{}
-		if uErr := json.Unmarshal(metaBlob, &meta); uErr == nil {
-			if strings.TrimSpace(meta.BackupPath) != "" {
-				return meta, nil
-			}
-		}
-	}
-
-	backupPath := filepath.Join(root, id+".bak")
-	if _, statErr := os.Stat(backupPath); statErr == nil {
-		return fileBackupMeta{BackupID: id, BackupPath: backupPath}, nil
-	}
-	return fileBackupMeta{}, fmt.Errorf("backup id not found: %s", id)
-}
-
-func RestoreFileBackup(path, backupID string) (string, error) {
-	clean := strings.TrimSpace(path)
-	if clean == "" {
-		return "", fmt.Errorf("path is required")
-	}
-	abs, err := filepath.Abs(clean)
-	if err != nil {
-		return "", fmt.Errorf("failed to resolve path: %w", err)
-	}
-
-	meta, err := resolveBackupByID(backupID)
-	if err != nil {
-		return "", err
-	}
-	blob, err := os.ReadFile(meta.BackupPath)
-	if err != nil {
-		return "", fmt.Errorf("failed to read backup file: %w", err)
-	}
-	if err := os.WriteFile(abs, blob, 0644); err != nil {
-		return "", fmt.Errorf("failed to restore backup: %w", err)
-	}
-	return fmt.Sprintf("Restored %s from backup_id=%s", abs, meta.BackupID), nil
-}
-
-func ShowFileDiff(path, comparePath, backupID string) (string, error) {
+// ShowFileDiff diffs path against compare_path or a backup and prefixes the
+// unified diff with its stats.
+func ShowFileDiff(path, comparePath, backupID string, opts diff.Options) (string, error) {
 	clean := strings.TrimSpace(path)
 	if clean == "" {
 		return "", fmt.Errorf("path is required")
@@ -258,15 +65,12 @@ func ShowFileDiff(path, comparePath, backupID string) (string, error) {
 	toLabel := ""
 
 	if strings.TrimSpace(backupID) != "" {
-		meta, err := resolveBackupByID(backupID)
-		if err != nil {
-			return "", err
-		}
-		rhs, err = readLinesNoEOL(meta.BackupPath)
+		entry, blob, err := readBackup(backupID)
 		if err != nil {
 			return "", err
 		}
-		toLabel = meta.BackupPath
+		rhs = splitLinesNoEOL(blob)
+		toLabel = fmt.Sprintf("%s (backup %s, %s)", entry.Path, entry.ID, entry.Created.Local().Format("2006-01-02 15:04:05"))
 	} else {
 		other := strings.TrimSpace(comparePath)
 		if other == "" {
@@ -283,7 +87,11 @@ func ShowFileDiff(path, comparePath, backupID string) (string, error) {
 		toLabel = absOther
 	}
 
-	return BuildSimpleUnifiedDiff(fromLabel, toLabel, lhs, rhs), nil
+	if opts.MaxLines == 0 {
+		opts.MaxLines = maxFileDiffOutputLines
+	}
+	d := diff.Compare(lhs, rhs, opts)
+	return fmt.Sprintf("Stats: %s\n%s", d.Stats(), d.Unified(fromLabel, toLabel, opts.MaxLines)), nil
 }
 
 func truncateRunes(s string, max int) string {
@@ -329,44 +137,75 @@ func CompareFilesSideBySide(leftPath, rightPath string, width int) (string, erro
 	if width < 60 {
 		width = 60
 	}
-	colWidth := (width - 7) / 2
+	colWidth := (width - 13) / 2
 	if colWidth < 20 {
 		colWidth = 20
 	}
 
+	d := diff.Compare(left, right, diff.Options{})
 	var b strings.Builder
-	b.WriteString(fmt.Sprintf("LEFT: %s\nRIGHT: %s\n", leftAbs, rightAbs))
-	b.WriteString(strings.Repeat("-", colWidth*2+7) + "\n")
-
-	n := len(left)
-	if len(right) > n {
-		n = len(right)
-	}
-	for i := 0; i < n; i++ {
-		l := ""
-		r := ""
-		if i < len(left) {
-			l = left[i]
-		}
-		if i < len(right) {
-			r = right[i]
-		}
-		marker := " "
-		if l != r {
-			marker = "|"
-		}
+	b.WriteString(fmt.Sprintf("LEFT: %s\nRIGHT: %s\nStats: %s\n", leftAbs, rightAbs, d.Stats()))
+	b.WriteString(strings.Repeat("-", colWidth*2+13) + "\n")
+
+	// Changed lines are paired up ("|"); the rest of a change shows as "<"
+	// (left only) or ">" (right only).
+	rows := 0
+	row := func(ln, l, marker, rn, r string) bool {
 		l = padRightRunes(truncateRunes(l, colWidth), colWidth)
 		r = padRightRunes(truncateRunes(r, colWidth), colWidth)
-		b.WriteString(fmt.Sprintf("%4d %s %s %s\n", i+1, l, marker, r))
-		if i+1 >= maxFileDiffOutputLines {
+		b.WriteString(fmt.Sprintf("%4s %s %s %4s %s\n", ln, l, marker, rn, r))
+		rows++
+		if rows >= maxFileDiffOutputLines {
 			b.WriteString("... [COMPARISON TRUNCATED] ...\n")
-			break
+			return false
+		}
+		return true
+	}
+	num := func(i int) string { return strconv.Itoa(i + 1) }
+	ops := d.Ops
+rowsLoop:
+	for k := 0; k < len(ops); k++ {
+		op := ops[k]
+		if op.Kind == diff.Equal {
+			for i := 0; i < op.A1-op.A0; i++ {
+				if !row(num(op.A0+i), left[op.A0+i], " ", num(op.B0+i), right[op.B0+i]) {
+					break rowsLoop
+				}
+			}
+			continue
+		}
+		dels, adds := diff.Op{}, diff.Op{}
+		if op.Kind == diff.Delete {
+			dels = op
+			if k+1 < len(ops) && ops[k+1].Kind == diff.Insert {
+				adds = ops[k+1]
+				k++
+			}
+		} else {
+			adds = op
+		}
+		nd, na := dels.A1-dels.A0, adds.B1-adds.B0
+		for i := 0; i < max(nd, na); i++ {
+			var ok bool
+			switch {
+			case i < nd && i < na:
+				ok = row(num(dels.A0+i), left[dels.A0+i], "|", num(adds.B0+i), right[adds.B0+i])
+			case i < nd:
+				ok = row(num(dels.A0+i), left[dels.A0+i], "<", "", "")
+			default:
+				ok = row("", "", ">", num(adds.B0+i), right[adds.B0+i])
+			}
+			if !ok {
+				break rowsLoop
+			}
 		}
 	}
 	return strings.TrimRight(b.String(), "\n"), nil
 }
 
-func MergeFiles(basePath, leftPath, rightPath, outputPath string) (string, error) {
+// MergeFiles runs a diff3 merge of left and right against base and writes
+// the result to outputPath (base_path + ".merged" by default).
+func MergeFiles(basePath, leftPath, rightPath, outputPath string, opts diff.MergeOptions) (string, error) {
 	baseAbs, err := filepath.Abs(strings.TrimSpace(basePath))
 	if err != nil {
 		return "", fmt.Errorf("failed to resolve base_path: %w", err)
@@ -379,62 +218,42 @@ func MergeFiles(basePath, leftPath, rightPath, outputPath string) (string, error
 	if err != nil {
 		return "", fmt.Errorf("failed to resolve right_path: %w", err)
 	}
+	switch opts.Style {
+	case "", diff.StyleMerge, diff.StyleDiff3, diff.StyleZDiff3:
+	default:
+		return "", fmt.Errorf("unsupported merge style %q (use merge, diff3 or zdiff3)", opts.Style)
+	}
+	switch opts.Strategy {
+	case "", diff.StrategyOurs, diff.StrategyTheirs, diff.StrategyUnion:
+	default:
+		return "", fmt.Errorf("unsupported merge strategy %q (use ours, theirs or union)", opts.Strategy)
+	}
 
-	base, err := readLinesNoEOL(baseAbs)
+	base, _, err := readTextLines(baseAbs)
 	if err != nil {
 		return "", err
 	}
-	left, err := readLinesNoEOL(leftAbs)
+	// The merge output is written in the left file's encoding and newline
+	// style.
+	left, format, err := readTextLines(leftAbs)
 	if err != nil {
 		return "", err
 	}
-	right, err := readLinesNoEOL(rightAbs)
+	right, _, err := readTextLines(rightAbs)
 	if err != nil {
 		return "", err
 	}
 
-	n := len(base)
-	if len(left) > n {
-		n = len(left)
+	if opts.OursLabel == "" {
+		opts.OursLabel = strings.TrimSpace(leftPath)
 	}
-	if len(right) > n {
-		n = len(right)
+	if opts.BaseLabel == "" {
+		opts.BaseLabel = strings.TrimSpace(basePath)
 	}
-
-	pick := func(lines []string, idx int) string {
-		if idx >= 0 && idx < len(lines) {
-			return lines[idx]
-		}
-		return ""
-	}
-
-	merged := make([]string, 0, n)
-	conflicts := 0
-	for i := 0; i < n; i++ {
-		b := pick(base, i)
-		l := pick(left, i)
-		r := pick(right, i)
-
-		switch {
-		case l == r:
-			merged = append(merged, l)
-		case l == b:
-			merged = append(merged, r)
-		case r == b:
-			merged = append(merged, l)
-		default:
-			conflicts++
-			merged = append(merged,
-				"<<<<<<< LEFT",
-				l,
-				"||||||| BASE",
-				b,
-				"=======",
-				r,
-				">>>>>>> RIGHT",
-			)
-		}
+	if opts.TheirsLabel == "" {
+		opts.TheirsLabel = strings.TrimSpace(rightPath)
 	}
+	result := diff.Merge3(base, left, right, opts)
 
 	out := strings.TrimSpace(outputPath)
 	if out == "" {
@@ -445,14 +264,116 @@ func MergeFiles(basePath, leftPath, rightPath, outputPath string) (string, error
 		return "", fmt.Errorf("failed to resolve output_path: %w", err)
 	}
 
-	content := strings.Join(merged, "\n")
-	if len(merged) > 0 {
-		content += "\n"
-	}
-	if err := os.WriteFile(outAbs, []byte(content), 0644); err != nil {
+	format.trailing = true
+	if err := writeTextLines(outAbs, result.Lines, format); err != nil {
 		return "", fmt.Errorf("failed to write merge output: %w", err)
 	}
-	return fmt.Sprintf("Merge completed. output=%s conflicts=%d", outAbs, conflicts), nil
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Merge completed. output=%s conflicts=%d", outAbs, len(result.Conflicts))
+	if result.Resolved > 0 {
+		fmt.Fprintf(&b, " resolved_by_%s=%d", opts.Strategy, result.Resolved)
+	}
+	if len(result.Conflicts) > 0 {
+		b.WriteString("\n" + formatConflicts(result.Conflicts))
+		b.WriteString("\nResolve one at a time with resolve_merge_conflict (path=output, conflict=N).")
+	}
+	return b.String(), nil
+}
+
+// formatConflicts lists conflicts compactly, with each side truncated.
+func formatConflicts(conflicts []diff.Conflict) string {
+	const maxSideLines = 8
+	side := func(b *strings.Builder, label string, lines []string) {
+		fmt.Fprintf(b, "  %s (%d lines):\n", label, len(lines))
+		for i, l := range lines {
+			if i == maxSideLines {
+				fmt.Fprintf(b, "    ... %d more\n", len(lines)-i)
+				break
+			}
+			fmt.Fprintf(b, "    %s\n", l)
+		}
+	}
+	var b strings.Builder
+	for _, c := range conflicts {
+		fmt.Fprintf(&b, "Conflict %d: output lines %d-%d", c.Index, c.OutputStart, c.OutputEnd)
+		if c.BaseStart > 0 {
+			if c.BaseEnd >= c.BaseStart {
+				fmt.Fprintf(&b, ", base lines %d-%d", c.BaseStart, c.BaseEnd)
+			} else {
+				fmt.Fprintf(&b, ", insertion after base line %d", c.BaseStart-1)
+			}
+		}
+		b.WriteString("\n")
+		side(&b, "left", c.Ours)
+		side(&b, "base", c.Base)
+		side(&b, "right", c.Theirs)
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// ListMergeConflicts reports the conflict blocks left in a file.
+func ListMergeConflicts(path string) (string, error) {
+	lines, err := readLinesNoEOL(path)
+	if err != nil {
+		return "", err
+	}
+	conflicts, err := diff.ParseConflicts(lines)
+	if err != nil {
+		return "", err
+	}
+	if len(conflicts) == 0 {
+		return fmt.Sprintf("No conflict markers in %s.", path), nil
+	}
+	return fmt.Sprintf("%s has %d conflict(s):\n%s", path, len(conflicts), formatConflicts(conflicts)), nil
+}
+
+// ResolveMergeConflict replaces conflict number index in path with the chosen
+// side ("left", "right", "base", "both") or with custom text.
+func ResolveMergeConflict(path string, index int, resolution, text string) (string, error) {
+	lines, format, err := readTextLines(path)
+	if err != nil {
+		return "", err
+	}
+	conflicts, err := diff.ParseConflicts(lines)
+	if err != nil {
+		return "", err
+	}
+	if index < 1 || index > len(conflicts) {
+		return "", fmt.Errorf("conflict %d not found; %s has %d conflict(s)", index, path, len(conflicts))
+	}
+	c := conflicts[index-1]
+
+	var replacement []string
+	switch strings.ToLower(strings.TrimSpace(resolution)) {
+	case "left", "ours":
+		replacement = c.Ours
+	case "right", "theirs":
+		replacement = c.Theirs
+	case "base":
+		replacement = c.Base
+	case "both", "union":
+		replacement = append(append([]string{}, c.Ours...), c.Theirs...)
+	case "custom", "":
+		if resolution == "" && text == "" {
+			return "", fmt.Errorf("resolution is required (left, right, base, both or custom with text)")
+		}
+		if text != "" {
+			replacement = strings.Split(strings.TrimSuffix(strings.ReplaceAll(text, "\r\n", "\n"), "\n"), "\n")
+		}
+	default:
+		return "", fmt.Errorf("unsupported resolution %q (use left, right, base, both or custom)", resolution)
+	}
+
+	newLines := make([]string, 0, len(lines))
+	newLines = append(newLines, lines[:c.OutputStart-1]...)
+	newLines = append(newLines, replacement...)
+	newLines = append(newLines, lines[c.OutputEnd:]...)
+	if err := writeTextLines(path, newLines, format); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Resolved conflict %d in %s (lines %d-%d -> %d lines). %d conflict(s) remain.",
+		index, path, c.OutputStart, c.OutputEnd, len(replacement), len(conflicts)-1), nil
 }
 
 func DetectFileType(path string) (string, error) {
@@ -474,21 +395,22 @@ func DetectFileType(path string) (string, error) {
 		sample = sample[:512]
 	}
 	mime := http.DetectContentType(sample)
-	isBinary := looksBinary(sample)
-	encoding := "utf-8"
-	if isBinary {
+	text, format, decodeErr := decodeText(blob)
+	isBinary := errors.Is(decodeErr, errBinaryText)
+	encoding := format.encoding
+	switch {
+	case isBinary:
 		encoding = "binary"
-	} else if !utf8.Valid(blob) {
-		encoding = "non-utf8-text"
+	case decodeErr != nil:
+		encoding = "unknown"
 	}
 
 	newline := "none"
-	s := string(blob)
-	switch {
-	case strings.Contains(s, "\r\n"):
-		newline = "crlf"
-	case strings.Contains(s, "\n"):
+	if decodeErr == nil && strings.Contains(text, "\n") {
 		newline = "lf"
+		if format.newline == "\r\n" {
+			newline = "crlf"
+		}
 	}
 
 	res := map[string]any{
@@ -498,8 +420,12 @@ func DetectFileType(path string) (string, error) {
 		"mime":          mime,
 		"is_binary":     isBinary,
 		"encoding":      encoding,
+		"bom":           format.bom,
 		"newline_style": newline,
 	}
+	if decodeErr == nil && text != "" {
+		res["trailing_newline"] = format.trailing
+	}
 	keys := make([]string, 0, len(res))
 	for k := range res {
 		keys = append(keys, k)
@@ -543,7 +469,15 @@ func ExecuteFileManagementTool(name string, rawArgs string) (handled bool, outpu
 
 	switch name {
 	case "show_file_diff":
-		out, err := ShowFileDiff(getStr("path"), getStr("compare_path"), getStr("backup_id"))
+		alg, err := diff.ParseAlgorithm(strings.ToLower(getStr("algorithm")))
+		if err != nil {
+			return true, fmt.Sprintf("Error: %v", err)
+		}
+		context := getInt("context_lines", diff.DefaultContext)
+		if context == 0 {
+			context = diff.NoContext
+		}
+		out, err := ShowFileDiff(getStr("path"), getStr("compare_path"), getStr("backup_id"), diff.Options{Algorithm: alg, Context: context})
 		if err != nil {
 			return true, fmt.Sprintf("Error: %v", err)
 		}
@@ -571,8 +505,36 @@ func ExecuteFileManagementTool(name string, rawArgs string) (handled bool, outpu
 			return true, fmt.Sprintf("Error: %v", err)
 		}
 		return true, out
+	case "list_file_backups":
+		out, err := ListFileBackups(getStr("path"), getInt("limit", 20))
+		if err != nil {
+			return true, fmt.Sprintf("Error: %v", err)
+		}
+		return true, out
 	case "merge_files":
-		out, err := MergeFiles(getStr("base_path"), getStr("left_path"), getStr("right_path"), getStr("output_path"))
+		out, err := MergeFiles(getStr("base_path"), getStr("left_path"), getStr("right_path"), getStr("output_path"), diff.MergeOptions{
+			Style:    strings.ToLower(getStr("style")),
+			Strategy: strings.ToLower(getStr("strategy")),
+		})
+		if err != nil {
+			return true, fmt.Sprintf("Error: %v", err)
+		}
+		return true, out
+	case "resolve_merge_conflict":
+		path := getStr("path")
+		if path == "" {
+			return true, "Error: resolve_merge_conflict requires a non-empty 'path' argument."
+		}
+		index := getInt("conflict", 0)
+		if index == 0 {
+			out, err := ListMergeConflicts(path)
+			if err != nil {
+				return true, fmt.Sprintf("Error: %v", err)
+			}
+			return true, out
+		}
+		text, _ := args["text"].(string)
+		out, err := ResolveMergeConflict(path, index, getStr("resolution"), text)
 		if err != nil {
 			return true, fmt.Sprintf("Error: %v", err)
 		}
@@ -5,6 +5,8 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+
+	"github.com/bilbilaki/ai2go/internal/diff"
 )
 
 func extractBackupID(out string) string {
@@ -18,6 +20,7 @@ func extractBackupID(out string) string {
 }
 
 func TestFileBackupRestoreAndDiff(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
 	dir := t.TempDir()
 	path := filepath.Join(dir, "a.txt")
 	if err := os.WriteFile(path, []byte("one\ntwo\n"), 0644); err != nil {
@@ -37,7 +40,7 @@ func TestFileBackupRestoreAndDiff(t *testing.T) {
 		t.Fatalf("write modified file: %v", err)
 	}
 
-	diffOut, err := ShowFileDiff(path, "", backupID)
+	diffOut, err := ShowFileDiff(path, "", backupID, diff.Options{})
 	if err != nil {
 		t.Fatalf("ShowFileDiff: %v", err)
 	}
@@ -85,7 +88,23 @@ func TestCompareMergeAndTypeDetect(t *testing.T) {
 		t.Fatalf("expected change marker in side-by-side output: %q", cmpOut)
 	}
 
-	mergeOut, err := MergeFiles(base, left, right, "")
+	// An inserted line must not throw the rest of the comparison out of step.
+	if err := os.WriteFile(right, []byte("aa\nnew\nbb\ncc\n"), 0644); err != nil {
+		t.Fatalf("write right: %v", err)
+	}
+	cmpOut, err = CompareFilesSideBySide(left, right, 60)
+	if err != nil {
+		t.Fatalf("CompareFilesSideBySide: %v", err)
+	}
+	rows := strings.Split(cmpOut, "\n")[4:]
+	if len(rows) != 4 || !strings.Contains(rows[1], ">    2 new") || strings.ContainsAny(rows[2]+rows[3], "|<>") {
+		t.Fatalf("unexpected aligned comparison:\n%s", cmpOut)
+	}
+	if err := os.WriteFile(right, []byte("aa\nBB\ncc\n"), 0644); err != nil {
+		t.Fatalf("write right: %v", err)
+	}
+
+	mergeOut, err := MergeFiles(base, left, right, "", diff.MergeOptions{})
 	if err != nil {
 		t.Fatalf("MergeFiles: %v", err)
 	}
@@ -101,3 +120,51 @@ func TestCompareMergeAndTypeDetect(t *testing.T) {
 		t.Fatalf("unexpected detect output: %q", typeOut)
 	}
 }
+
+func TestMergeFilesConflictAndResolve(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+		return path
+	}
+	base := write("base.txt", "a\nb\nc\nd\n")
+	// left inserts a line at the top, which used to misalign index-based merging.
+	left := write("left.txt", "top\na\nLEFT\nc\nd\n")
+	right := write("right.txt", "a\nRIGHT\nc\nD\n")
+	out := filepath.Join(dir, "out.txt")
+
+	mergeOut, err := MergeFiles(base, left, right, out, diff.MergeOptions{})
+	if err != nil {
+		t.Fatalf("MergeFiles: %v", err)
+	}
+	if !strings.Contains(mergeOut, "conflicts=1") || !strings.Contains(mergeOut, "Conflict 1: output lines 3-9, base lines 2-2") {
+		t.Fatalf("unexpected merge output: %q", mergeOut)
+	}
+
+	listOut, err := ListMergeConflicts(out)
+	if err != nil || !strings.Contains(listOut, "1 conflict(s)") {
+		t.Fatalf("ListMergeConflicts: %q %v", listOut, err)
+	}
+	if _, err := ResolveMergeConflict(out, 2, "left", ""); err == nil {
+		t.Fatal("expected error for missing conflict")
+	}
+	if _, err := ResolveMergeConflict(out, 1, "custom", "MERGED\n"); err != nil {
+		t.Fatalf("ResolveMergeConflict: %v", err)
+	}
+	got, _ := os.ReadFile(out)
+	if string(got) != "top\na\nMERGED\nc\nD\n" {
+		t.Fatalf("unexpected resolved content: %q", string(got))
+	}
+
+	union := filepath.Join(dir, "union.txt")
+	if _, err := MergeFiles(base, left, right, union, diff.MergeOptions{Strategy: diff.StrategyUnion}); err != nil {
+		t.Fatalf("MergeFiles union: %v", err)
+	}
+	got, _ = os.ReadFile(union)
+	if string(got) != "top\na\nLEFT\nRIGHT\nc\nD\n" {
+		t.Fatalf("unexpected union content: %q", string(got))
+	}
+}
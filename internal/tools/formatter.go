@@ -0,0 +1,596 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bilbilaki/ai2go/internal/config"
+	"github.com/bilbilaki/ai2go/internal/project"
+)
+
+const (
+	// FormatConfigFile holds the per-project post-edit formatters
+	// (.ai2go/format.json under the project root).
+	FormatConfigFile = "format.json"
+
+	// FormatterGofmt and FormatterGoimports are built-in Go formatters that
+	// run in-process instead of through the shell.
+	FormatterGofmt     = "gofmt"
+	FormatterGoimports = "goimports"
+
+	defaultFormatTimeout = 30 * time.Second
+
+	// formatApprovalsFile, in the user's config dir, lists the shell
+	// formatter commands the user allowed per project root.
+	formatApprovalsFile = "format_approvals.json"
+)
+
+// FormatterSpec is the formatter for one file extension: a built-in name or
+// a shell command run in the project root. {file} expands to the edited
+// file; without it the file is appended to the command. In format.json a
+// spec is either a command string or {"command": ..., "timeout_seconds": N}.
+type FormatterSpec struct {
+	Command        string `json:"command"`
+	TimeoutSeconds int    `json:"timeout_seconds,omitempty"`
+}
+
+func (s *FormatterSpec) UnmarshalJSON(data []byte) error {
+	var command string
+	if err := json.Unmarshal(data, &command); err == nil {
+		*s = FormatterSpec{Command: command}
+		return nil
+	}
+	type plain FormatterSpec
+	return json.Unmarshal(data, (*plain)(s))
+}
+
+func (s FormatterSpec) MarshalJSON() ([]byte, error) {
+	if s.TimeoutSeconds == 0 {
+		return json.Marshal(s.Command)
+	}
+	type plain FormatterSpec
+	return json.Marshal(plain(s))
+}
+
+// FormatConfig is the post-edit formatting setup of a project. Formatting is
+// opt-in: it runs only when the project has a format.json and Enabled is not
+// false. Shell commands also need the user's approval, since format.json may
+// come with a cloned repository.
+type FormatConfig struct {
+	Enabled    *bool                    `json:"enabled,omitempty"`
+	Formatters map[string]FormatterSpec `json:"formatters"`
+}
+
+// IsEnabled reports whether edits should be formatted (default true once the
+// file exists).
+func (c FormatConfig) IsEnabled() bool {
+	return c.Enabled == nil || *c.Enabled
+}
+
+// formatterFor returns the formatter configured for path's extension.
+func (c FormatConfig) formatterFor(path string) (FormatterSpec, bool) {
+	ext := strings.ToLower(filepath.Ext(path))
+	if ext == "" {
+		return FormatterSpec{}, false
+	}
+	spec, ok := c.Formatters[ext]
+	return spec, ok && strings.TrimSpace(spec.Command) != ""
+}
+
+// ExternalCommands returns the shell commands among c's formatters, sorted.
+// Unlike the built-in formatters they only run once the user approved them
+// for the project (see ApproveFormatCommands).
+func (c FormatConfig) ExternalCommands() []string {
+	seen := map[string]bool{}
+	var commands []string
+	for _, spec := range c.Formatters {
+		command := strings.TrimSpace(spec.Command)
+		if command == "" || command == FormatterGofmt || command == FormatterGoimports || seen[command] {
+			continue
+		}
+		seen[command] = true
+		commands = append(commands, command)
+	}
+	sort.Strings(commands)
+	return commands
+}
+
+func formatApprovalsPath() (string, error) {
+	dir, err := config.Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, formatApprovalsFile), nil
+}
+
+func loadFormatApprovals() (map[string][]string, error) {
+	path, err := formatApprovalsPath()
+	if err != nil {
+		return nil, err
+	}
+	approvals := map[string][]string{}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return approvals, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if err := json.Unmarshal(data, &approvals); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return approvals, nil
+}
+
+// ApproveFormatCommands allows cfg's external formatter commands to run
+// after edits in root. Commands approved earlier but no longer in cfg are
+// dropped; a format.json that changes later needs approving again.
+func ApproveFormatCommands(root string, cfg FormatConfig) error {
+	approvals, err := loadFormatApprovals()
+	if err != nil {
+		return err
+	}
+	root = absPath(root)
+	if commands := cfg.ExternalCommands(); len(commands) > 0 {
+		approvals[root] = commands
+	} else {
+		delete(approvals, root)
+	}
+	data, err := json.MarshalIndent(approvals, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode formatter approvals: %w", err)
+	}
+	path, err := formatApprovalsPath()
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(path, append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// FormatCommandApproved reports whether the user approved command for root.
+func FormatCommandApproved(root, command string) bool {
+	approvals, err := loadFormatApprovals()
+	if err != nil {
+		return false
+	}
+	return slices.Contains(approvals[absPath(root)], strings.TrimSpace(command))
+}
+
+// FormatConfigPath returns where root's format.json lives.
+func FormatConfigPath(root string) string {
+	return filepath.Join(root, project.InstructionsDir, FormatConfigFile)
+}
+
+// LoadFormatConfig reads root's format.json. It reports false when the
+// project has none. Extensions are normalized to lower case with a dot.
+func LoadFormatConfig(root string) (FormatConfig, bool, error) {
+	path := FormatConfigPath(root)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return FormatConfig{}, false, nil
+	}
+	if err != nil {
+		return FormatConfig{}, false, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	var cfg FormatConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return FormatConfig{}, false, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	normalized := make(map[string]FormatterSpec, len(cfg.Formatters))
+	for ext, spec := range cfg.Formatters {
+		ext = strings.ToLower(strings.TrimSpace(ext))
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		normalized[ext] = spec
+	}
+	cfg.Formatters = normalized
+	return cfg, true, nil
+}
+
+// SaveFormatConfig writes cfg as root's format.json.
+func SaveFormatConfig(root string, cfg FormatConfig) error {
+	data, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode format config: %w", err)
+	}
+	path := FormatConfigPath(root)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+	}
+	if err := writeFileAtomic(path, append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// DefaultFormatConfig suggests formatters for the project in root: gofmt for
+// Go, plus rustfmt, black and prettier when the project uses them and the
+// tool is installed.
+func DefaultFormatConfig(root string) FormatConfig {
+	has := func(marker string) bool {
+		_, err := os.Stat(filepath.Join(root, marker))
+		return err == nil
+	}
+	installed := func(name string) bool {
+		_, err := exec.LookPath(name)
+		return err == nil
+	}
+	cfg := FormatConfig{Formatters: map[string]FormatterSpec{".go": {Command: FormatterGofmt}}}
+	if has("Cargo.toml") && installed("rustfmt") {
+		cfg.Formatters[".rs"] = FormatterSpec{Command: "rustfmt --edition 2021 {file}"}
+	}
+	if (has("pyproject.toml") || has("setup.py") || has("requirements.txt")) && installed("black") {
+		cfg.Formatters[".py"] = FormatterSpec{Command: "black -q {file}"}
+	}
+	if has(filepath.Join("node_modules", ".bin", "prettier")) {
+		for _, ext := range []string{".js", ".jsx", ".ts", ".tsx", ".css", ".scss", ".md"} {
+			cfg.Formatters[ext] = FormatterSpec{Command: "npx --no-install prettier --write {file}"}
+		}
+	}
+	return cfg
+}
+
+// findFormatRoot walks up from path to the nearest directory with a
+// format.json, or returns "" when the project has not opted in. The walk
+// stops at the project (see CheckpointRoot), so a format.json above it is
+// never picked up.
+func findFormatRoot(path string) string {
+	dir := filepath.Dir(absPath(path))
+	limit := CheckpointRoot(dir)
+	for d := dir; ; {
+		if _, err := os.Stat(FormatConfigPath(d)); err == nil {
+			return d
+		}
+		parent := filepath.Dir(d)
+		if d == limit || parent == d {
+			return ""
+		}
+		d = parent
+	}
+}
+
+// FormatEditedFiles runs the project's formatters on paths after an edit and
+// returns a "[Format]" report with the diff of every file a formatter
+// changed, or "" when nothing was reformatted. Formatter failures are
+// reported and leave the file as edited.
+func FormatEditedFiles(ctx context.Context, paths []string) string {
+	var reports []string
+	seen := map[string]bool{}
+	for _, p := range paths {
+		abs := absPath(strings.TrimSpace(p))
+		if abs == "" || seen[abs] {
+			continue
+		}
+		seen[abs] = true
+		if info, err := os.Stat(abs); err != nil || info.IsDir() {
+			continue
+		}
+		root := findFormatRoot(abs)
+		if root == "" {
+			continue
+		}
+		cfg, _, err := LoadFormatConfig(root)
+		if err != nil {
+			reports = append(reports, fmt.Sprintf("[Format] skipped: %v", err))
+			continue
+		}
+		spec, ok := cfg.formatterFor(abs)
+		if !cfg.IsEnabled() || !ok {
+			continue
+		}
+		if report := formatFile(ctx, root, abs, spec); report != "" {
+			reports = append(reports, report)
+		}
+	}
+	return strings.Join(reports, "\n")
+}
+
+// formatFile runs spec on path and describes the result.
+func formatFile(ctx context.Context, root, path string, spec FormatterSpec) string {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		rel = path
+	}
+	rel = filepath.ToSlash(rel)
+	before, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Sprintf("[Format] %s: %v", rel, err)
+	}
+	name := formatterName(spec.Command)
+
+	switch spec.Command {
+	case FormatterGofmt, FormatterGoimports:
+		err = formatGoFile(path, spec.Command == FormatterGoimports)
+	default:
+		if !FormatCommandApproved(root, spec.Command) {
+			return fmt.Sprintf("[Format] %s skipped on %s: %q from %s is not approved; run /format approve to allow it", name, rel, spec.Command, FormatConfigPath(root))
+		}
+		err = runExternalFormatter(ctx, root, rel, spec)
+	}
+	if err != nil {
+		return fmt.Sprintf("[Format] %s failed on %s (file left as edited): %v", name, rel, err)
+	}
+
+	after, err := os.ReadFile(path)
+	if err != nil || string(after) == string(before) {
+		return ""
+	}
+	oldText, _, errOld := decodeText(before)
+	newText, _, errNew := decodeText(after)
+	if errOld != nil || errNew != nil {
+		return fmt.Sprintf("[Format] %s reformatted %s", name, rel)
+	}
+	if oldText == newText {
+		return fmt.Sprintf("[Format] %s changed only line endings or encoding of %s", name, rel)
+	}
+	return fmt.Sprintf("[Format] %s reformatted %s\n%s", name, rel,
+		strings.TrimRight(BuildSimpleUnifiedDiff("a/"+rel, "b/"+rel, splitDiffLines(oldText), splitDiffLines(newText)), "\n"))
+}
+
+func formatterName(command string) string {
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return "formatter"
+	}
+	if fields[0] == "npx" {
+		for _, f := range fields[1:] {
+			if !strings.HasPrefix(f, "-") {
+				return f
+			}
+		}
+	}
+	return filepath.Base(fields[0])
+}
+
+// runExternalFormatter runs a formatter command on rel in root.
+func runExternalFormatter(ctx context.Context, root, rel string, spec FormatterSpec) error {
+	command := spec.Command
+	if strings.Contains(command, "{file}") {
+		command = strings.ReplaceAll(command, "{file}", shellQuoteAll([]string{rel}))
+	} else {
+		command += " " + shellQuoteAll([]string{rel})
+	}
+	timeout := defaultFormatTimeout
+	if spec.TimeoutSeconds > 0 {
+		timeout = time.Duration(spec.TimeoutSeconds) * time.Second
+	}
+	runCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	cmd := prepareCommand(runCtx, command)
+	cmd.Dir = root
+	output, err := cmd.CombinedOutput()
+	if errors.Is(runCtx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("timed out after %s", timeout)
+	}
+	if err != nil {
+		if out := compactVerifyOutput(sanitizeText(string(output))); strings.TrimSpace(out) != "" {
+			return fmt.Errorf("%v\n%s", err, out)
+		}
+		return err
+	}
+	return nil
+}
+
+// formatGoFile gofmts path in-process, keeping its line endings. With
+// imports set it also drops unused standard library (and explicitly named)
+// imports and adds missing standard library imports whose package name is
+// unambiguous, like a minimal goimports.
+func formatGoFile(path string, imports bool) error {
+	orig, tf, err := readTextFile(path)
+	if err != nil {
+		return err
+	}
+	text := orig
+	if imports {
+		if fixed, err := fixGoImports(path, text); err != nil {
+			return err
+		} else if fixed != "" {
+			text = fixed
+		}
+	}
+	out, err := format.Source([]byte(text))
+	if err != nil {
+		return err
+	}
+	if string(out) == orig {
+		return nil
+	}
+	return tf.writeText(path, string(out))
+}
+
+// fixGoImports returns text with its imports fixed, or "" when nothing needs
+// to change.
+func fixGoImports(path, text string) (string, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, path, text, parser.ParseComments)
+	if err != nil {
+		return "", err
+	}
+	std := stdPackagesByName()
+
+	src := &goSourceFile{path: path, src: text, fset: fset, file: file}
+	imported := map[string]bool{}
+	var remove []string
+	for _, imp := range file.Imports {
+		spec := importSpecOf(imp)
+		local := spec.name
+		if local == "" {
+			local = guessPackageName(spec.path)
+		}
+		imported[local] = true
+		// The package name of other imports is only known by loading them,
+		// so only named and standard library imports are dropped.
+		if local == "_" || local == "." || (spec.name == "" && !isGorootPackage(spec.path)) {
+			continue
+		}
+		if goImportUseLine(src, imp) == 0 {
+			remove = append(remove, spec.String())
+		}
+	}
+
+	declared := goPackageLevelNames(path, file.Name.Name)
+	missing := map[string]bool{}
+	ast.Inspect(file, func(n ast.Node) bool {
+		sel, ok := n.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+		id, ok := sel.X.(*ast.Ident)
+		if !ok || id.Obj != nil || imported[id.Name] || declared[id.Name] || types.Universe.Lookup(id.Name) != nil {
+			return true
+		}
+		if pkgPath := std[id.Name]; pkgPath != "" {
+			missing[pkgPath] = true
+		}
+		return true
+	})
+	var add []string
+	for p := range missing {
+		add = append(add, p)
+	}
+	sort.Strings(add)
+	if len(add) == 0 && len(remove) == 0 {
+		return "", nil
+	}
+	plan, err := planGoImports(path, add, remove)
+	if err != nil {
+		return "", err
+	}
+	return plan.files[path], nil
+}
+
+// goPackageLevelNames returns the top-level names declared by the other
+// files of pkgName in path's directory.
+func goPackageLevelNames(path, pkgName string) map[string]bool {
+	names := map[string]bool{}
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		return names
+	}
+	fset := token.NewFileSet()
+	for _, e := range entries {
+		other := filepath.Join(filepath.Dir(path), e.Name())
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".go") || other == path {
+			continue
+		}
+		f, _ := parser.ParseFile(fset, other, nil, parser.SkipObjectResolution)
+		if f == nil || f.Name.Name != pkgName {
+			continue
+		}
+		for _, decl := range f.Decls {
+			switch d := decl.(type) {
+			case *ast.FuncDecl:
+				if d.Recv == nil {
+					names[d.Name.Name] = true
+				}
+			case *ast.GenDecl:
+				for _, spec := range d.Specs {
+					switch s := spec.(type) {
+					case *ast.TypeSpec:
+						names[s.Name.Name] = true
+					case *ast.ValueSpec:
+						for _, n := range s.Names {
+							names[n.Name] = true
+						}
+					}
+				}
+			}
+		}
+	}
+	return names
+}
+
+var (
+	stdPackagesOnce sync.Once
+	stdPackages     map[string]string
+)
+
+// stdPackagesByName maps package names to standard library import paths,
+// leaving out names shared by several packages (math/rand, crypto/rand).
+func stdPackagesByName() map[string]string {
+	stdPackagesOnce.Do(func() {
+		stdPackages = map[string]string{}
+		ambiguous := map[string]bool{}
+		src := filepath.Join(build.Default.GOROOT, "src")
+		_ = filepath.WalkDir(src, func(p string, d fs.DirEntry, err error) error {
+			if err != nil || !d.IsDir() {
+				return nil
+			}
+			rel, _ := filepath.Rel(src, p)
+			rel = filepath.ToSlash(rel)
+			name := d.Name()
+			if rel == "cmd" || name == "internal" || name == "vendor" || name == "testdata" {
+				return filepath.SkipDir
+			}
+			if rel == "." || !goDirHasSources(p) {
+				return nil
+			}
+			if _, dup := stdPackages[name]; dup || ambiguous[name] {
+				ambiguous[name] = true
+				delete(stdPackages, name)
+				return nil
+			}
+			stdPackages[name] = rel
+			return nil
+		})
+	})
+	return stdPackages
+}
+
+// isGorootPackage reports whether importPath is a standard library package.
+func isGorootPackage(importPath string) bool {
+	if !isStdImport(importPath) {
+		return false
+	}
+	info, err := os.Stat(filepath.Join(build.Default.GOROOT, "src", filepath.FromSlash(importPath)))
+	return err == nil && info.IsDir()
+}
+
+func goDirHasSources(dir string) bool {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return false
+	}
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasSuffix(e.Name(), ".go") && !strings.HasSuffix(e.Name(), "_test.go") {
+			return true
+		}
+	}
+	return false
+}
+
+// AppendFormatReport formats paths and adds the report to an edit tool's
+// output. It is for tools that keep no editor checkpoints; the ones that do
+// format before their post-edit checkpoint so it records the formatted file.
+func AppendFormatReport(ctx context.Context, paths []string, output string) string {
+	return withFormatReport(output, FormatEditedFiles(ctx, paths))
+}
+
+// withFormatReport adds a FormatEditedFiles report to an edit tool's output.
+func withFormatReport(output, report string) string {
+	if report != "" {
+		return output + "\n\n" + report
+	}
+	return output
+}
@@ -0,0 +1,154 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func writeFormatConfig(t *testing.T, root, content string) {
+	t.Helper()
+	writeTestFile(t, FormatConfigPath(root), content)
+}
+
+func TestFormatEditedFilesIsOptIn(t *testing.T) {
+	root := t.TempDir()
+	path := filepath.Join(root, "main.go")
+	writeTestFile(t, path, "package main\nfunc main(){}\n")
+
+	if report := FormatEditedFiles(context.Background(), []string{path}); report != "" {
+		t.Fatalf("expected no formatting without format.json, got:\n%s", report)
+	}
+	writeFormatConfig(t, root, `{"enabled": false, "formatters": {".go": "gofmt"}}`)
+	if report := FormatEditedFiles(context.Background(), []string{path}); report != "" {
+		t.Fatalf("expected no formatting when disabled, got:\n%s", report)
+	}
+	if got := readTestFile(path); got != "package main\nfunc main(){}\n" {
+		t.Fatalf("file changed: %q", got)
+	}
+}
+
+func TestVerifyFileEditFormatsGo(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	root := t.TempDir()
+	writeFormatConfig(t, root, `{"formatters": {"go": "gofmt"}}`)
+	path := filepath.Join(root, "main.go")
+	writeTestFile(t, path, "package main\r\n\r\nfunc main() {\r\n}\r\n")
+
+	rawArgs := `{"path": ` + strconv.Quote(path) + `, "start_line": 3, "end_line": 4, "replacement": "func main() {\nx:=1\n_ = x\n}"}`
+	_, out := ExecuteVerifiedLineTool(context.Background(), "replace_line_range", rawArgs)
+	if !strings.Contains(out, "[Format] gofmt reformatted main.go") || !strings.Contains(out, "+\tx := 1") {
+		t.Fatalf("missing format report:\n%s", out)
+	}
+	if got := readTestFile(path); got != "package main\r\n\r\nfunc main() {\r\n\tx := 1\r\n\t_ = x\r\n}\r\n" {
+		t.Fatalf("unexpected formatted file: %q", got)
+	}
+
+	// Checkpointing tools format before their post-edit checkpoint, so the
+	// checkpoint holds the formatted file.
+	out, err := VerifyFileEdit(context.Background(), []string{path}, "", func() (string, error) {
+		return EditFile(context.Background(), path, []StringEdit{{OldString: "_ = x", NewString: "y:=x\n_ = y"}})
+	})
+	if err != nil || !strings.Contains(out, "[Format] gofmt reformatted main.go") {
+		t.Fatalf("EditFile: %v\n%s", err, out)
+	}
+	if head, err := runGit(CheckpointRoot(root), "show", "HEAD:main.go"); err != nil || !strings.Contains(head, "\ty := x") {
+		t.Fatalf("checkpoint does not hold the formatted file: %q %v", head, err)
+	}
+
+	writeTestFile(t, path, "package main\n\nfunc main() {\n\tx := (\n}\n")
+	if report := FormatEditedFiles(context.Background(), []string{path}); !strings.Contains(report, "gofmt failed on main.go (file left as edited)") {
+		t.Fatalf("expected syntax error report, got:\n%s", report)
+	}
+}
+
+func TestGoimportsFormatterFixesImports(t *testing.T) {
+	root := t.TempDir()
+	writeFormatConfig(t, root, `{"formatters": {".go": {"command": "goimports"}}}`)
+	writeTestFile(t, filepath.Join(root, "other.go"), "package main\n\nvar path = \"x\"\n")
+	main := filepath.Join(root, "main.go")
+	writeTestFile(t, main, "package main\n\nimport (\n\t\"os\"\n\tyaml \"gopkg.in/yaml.v3\"\n\t\"example.com/kept\"\n)\n\nfunc main() {\n\tprintln(strings.ToUpper(path.Base), filepath.Base(\"a\"))\n}\n")
+
+	report := FormatEditedFiles(context.Background(), []string{main})
+	if !strings.Contains(report, "[Format] goimports reformatted main.go") {
+		t.Fatalf("unexpected report:\n%s", report)
+	}
+	want := "package main\n\nimport (\n\t\"path/filepath\"\n\t\"strings\"\n\n\t\"example.com/kept\"\n)\n\nfunc main() {\n\tprintln(strings.ToUpper(path.Base), filepath.Base(\"a\"))\n}\n"
+	if got := readTestFile(main); got != want {
+		t.Fatalf("unexpected imports:\n%s", got)
+	}
+}
+
+func TestExternalFormatter(t *testing.T) {
+	root := t.TempDir()
+	writeFormatConfig(t, root, `{"formatters": {".txt": "sed -i.bak 's/  */ /g' {file} && rm -f {file}.bak", ".md": "false"}}`)
+	txt := filepath.Join(root, "notes.txt")
+	md := filepath.Join(root, "README.md")
+	writeTestFile(t, txt, "a    b\n")
+	writeTestFile(t, md, "# x\n")
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("USERPROFILE", os.Getenv("HOME"))
+
+	report := FormatEditedFiles(context.Background(), []string{txt, md})
+	if !strings.Contains(report, "is not approved") || readTestFile(txt) != "a    b\n" {
+		t.Fatalf("unapproved formatter ran:\n%s", report)
+	}
+	cfg, _, err := LoadFormatConfig(root)
+	if err != nil {
+		t.Fatalf("LoadFormatConfig: %v", err)
+	}
+	if err := ApproveFormatCommands(root, cfg); err != nil {
+		t.Fatalf("ApproveFormatCommands: %v", err)
+	}
+
+	report = FormatEditedFiles(context.Background(), []string{txt, md, filepath.Join(root, "missing.txt")})
+	if !strings.Contains(report, "[Format] sed reformatted notes.txt") || !strings.Contains(report, "+a b") {
+		t.Fatalf("missing external formatter diff:\n%s", report)
+	}
+	if !strings.Contains(report, "[Format] false failed on README.md") {
+		t.Fatalf("missing failure report:\n%s", report)
+	}
+	if got := readTestFile(txt); got != "a b\n" {
+		t.Fatalf("unexpected formatted file: %q", got)
+	}
+	if _, err := os.Stat(txt + ".bak"); err == nil {
+		t.Fatal("formatter leftovers not removed")
+	}
+}
+
+func TestFormatConfigStopsAtGitRoot(t *testing.T) {
+	parent := t.TempDir()
+	writeFormatConfig(t, parent, `{"formatters": {".go": "gofmt"}}`)
+	repo := filepath.Join(parent, "repo")
+	if err := os.MkdirAll(filepath.Join(repo, ".git"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(repo, "main.go")
+	writeTestFile(t, path, "package main\nfunc main(){}\n")
+
+	if report := FormatEditedFiles(context.Background(), []string{path}); report != "" {
+		t.Fatalf("format.json above the git root was used:\n%s", report)
+	}
+}
+
+func TestFormatConfigRoundTrip(t *testing.T) {
+	root := t.TempDir()
+	off := false
+	cfg := FormatConfig{Enabled: &off, Formatters: map[string]FormatterSpec{".go": {Command: FormatterGofmt}, ".py": {Command: "black -q {file}", TimeoutSeconds: 5}}}
+	if err := SaveFormatConfig(root, cfg); err != nil {
+		t.Fatalf("SaveFormatConfig: %v", err)
+	}
+	if data := readTestFile(FormatConfigPath(root)); !strings.Contains(data, `".go": "gofmt"`) {
+		t.Fatalf("expected string shorthand, got:\n%s", data)
+	}
+	loaded, found, err := LoadFormatConfig(root)
+	if err != nil || !found {
+		t.Fatalf("LoadFormatConfig: %v %v", found, err)
+	}
+	if loaded.IsEnabled() || loaded.Formatters[".py"].TimeoutSeconds != 5 || loaded.Formatters[".go"].Command != FormatterGofmt {
+		t.Fatalf("unexpected config %+v", loaded)
+	}
+}
@@ -0,0 +1,183 @@
+package tools
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// ignoreRule is one compiled .gitignore line, relative to the directory that
+// holds the .gitignore file.
+type ignoreRule struct {
+	re      *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+// ignoreMatcher applies .gitignore files found while walking a tree. Rules
+// from deeper directories are checked last so they can override parents.
+type ignoreMatcher struct {
+	root  string
+	rules map[string][]ignoreRule // keyed by slash-separated dir relative to root
+}
+
+func newIgnoreMatcher(root string) *ignoreMatcher {
+	return &ignoreMatcher{root: root, rules: map[string][]ignoreRule{}}
+}
+
+// loadDir reads the .gitignore in dir (if any). It must be called before the
+// walker descends into dir.
+func (m *ignoreMatcher) loadDir(dir string) {
+	rel, err := filepath.Rel(m.root, dir)
+	if err != nil {
+		return
+	}
+	rel = filepath.ToSlash(rel)
+	if rel == "." {
+		rel = ""
+	}
+	f, err := os.Open(filepath.Join(dir, ".gitignore"))
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	var rules []ignoreRule
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if r, ok := parseIgnoreLine(scanner.Text()); ok {
+			rules = append(rules, r)
+		}
+	}
+	if len(rules) > 0 {
+		m.rules[rel] = rules
+	}
+}
+
+// ignored reports whether the slash-separated path rel (relative to root) is
+// excluded by the loaded rules.
+func (m *ignoreMatcher) ignored(rel string, isDir bool) bool {
+	if m == nil || len(m.rules) == 0 {
+		return false
+	}
+	ignored := false
+	dirs := append([]string{""}, parentDirs(rel)...)
+	for _, dir := range dirs {
+		rules, ok := m.rules[dir]
+		if !ok {
+			continue
+		}
+		sub := rel
+		if dir != "" {
+			sub = strings.TrimPrefix(rel, dir+"/")
+		}
+		for _, r := range rules {
+			if r.dirOnly && !isDir {
+				continue
+			}
+			if r.re.MatchString(sub) {
+				ignored = !r.negate
+			}
+		}
+	}
+	return ignored
+}
+
+// parentDirs returns every ancestor directory of rel, shallowest first,
+// excluding the root itself.
+func parentDirs(rel string) []string {
+	var out []string
+	for i := 0; i < len(rel); i++ {
+		if rel[i] == '/' {
+			out = append(out, rel[:i])
+		}
+	}
+	return out
+}
+
+func parseIgnoreLine(line string) (ignoreRule, bool) {
+	line = strings.TrimRight(line, " \t\r")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return ignoreRule{}, false
+	}
+	var r ignoreRule
+	if strings.HasPrefix(line, "!") {
+		r.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\`) {
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		r.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if line == "" {
+		return ignoreRule{}, false
+	}
+
+	// A slash anywhere but the end anchors the pattern to the .gitignore dir;
+	// otherwise it matches a name at any depth.
+	anchored := strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+	expr := globToRegexp(line)
+	if !anchored {
+		expr = "(?:.*/)?" + expr
+	}
+	re, err := regexp.Compile("^" + expr + "$")
+	if err != nil {
+		return ignoreRule{}, false
+	}
+	r.re = re
+	return r, true
+}
+
+// globToRegexp converts a gitignore-style glob into an unanchored regular
+// expression. "*" and "?" stay within one path segment, "**" spans segments.
+func globToRegexp(glob string) string {
+	var b strings.Builder
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch c {
+		case '*':
+			if i+1 < len(glob) && glob[i+1] == '*' {
+				i++
+				if i+1 < len(glob) && glob[i+1] == '/' {
+					i++
+					b.WriteString("(?:.*/)?")
+				} else {
+					b.WriteString(".*")
+				}
+			} else {
+				b.WriteString("[^/]*")
+			}
+		case '?':
+			b.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + class + "]")
+			i += end + 1
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return b.String()
+}
+
+// compileGlob compiles a find_files glob. Globs containing "/" match the path
+// relative to the search root; others match the file name only.
+func compileGlob(glob string) (*regexp.Regexp, bool, error) {
+	glob = strings.TrimPrefix(strings.TrimSpace(filepath.ToSlash(glob)), "./")
+	matchPath := strings.Contains(glob, "/")
+	re, err := regexp.Compile("^" + globToRegexp(glob) + "$")
+	return re, matchPath, err
+}
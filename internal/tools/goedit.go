@@ -0,0 +1,1210 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// goEditPlan holds the new source of every file a Go structural edit
+// touches, before gofmt. Sources are LF text as returned by readTextFile.
+type goEditPlan struct {
+	summary string
+	files   map[string]string
+}
+
+func (p goEditPlan) paths() []string {
+	paths := make([]string, 0, len(p.files))
+	for path := range p.files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// goTextEdit replaces text[start:end] with text.
+type goTextEdit struct {
+	start, end int
+	text       string
+}
+
+// spliceGo applies edits to src. Edits must not overlap; insertions at the
+// same offset are applied in the order given, before a deletion starting
+// there.
+func spliceGo(src string, edits []goTextEdit) (string, error) {
+	sort.SliceStable(edits, func(i, j int) bool {
+		if edits[i].start != edits[j].start {
+			return edits[i].start < edits[j].start
+		}
+		return edits[i].end == edits[i].start && edits[j].end != edits[j].start
+	})
+	var b strings.Builder
+	last := 0
+	for _, e := range edits {
+		if e.start < last || e.end < e.start || e.end > len(src) {
+			return "", errors.New("internal error: overlapping source edits")
+		}
+		b.WriteString(src[last:e.start])
+		b.WriteString(e.text)
+		last = e.end
+	}
+	b.WriteString(src[last:])
+	return b.String(), nil
+}
+
+// goSourceFile is a parsed Go file. Offsets of its AST refer to src, which
+// is the decoded text of the file.
+type goSourceFile struct {
+	path string
+	src  string
+	fset *token.FileSet
+	file *ast.File
+}
+
+func (f *goSourceFile) offset(pos token.Pos) int {
+	return f.fset.Position(pos).Offset
+}
+
+func (f *goSourceFile) line(pos token.Pos) int {
+	return f.fset.Position(pos).Line
+}
+
+// lineStart returns the offset of the first byte of the line holding off.
+func (f *goSourceFile) lineStart(off int) int {
+	return strings.LastIndexByte(f.src[:off], '\n') + 1
+}
+
+// lineEnd returns the offset just past the newline ending the line holding
+// off, or the end of the file.
+func (f *goSourceFile) lineEnd(off int) int {
+	if idx := strings.IndexByte(f.src[off:], '\n'); idx >= 0 {
+		return off + idx + 1
+	}
+	return len(f.src)
+}
+
+func parseGoSource(path string) (*goSourceFile, error) {
+	text, _, err := readTextFile(path)
+	if err != nil {
+		return nil, err
+	}
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, path, text, parser.ParseComments)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s (fix syntax errors before structural edits): %w", path, err)
+	}
+	return &goSourceFile{path: path, src: text, fset: fset, file: file}, nil
+}
+
+// goTargetFiles parses target, a .go file or a package directory. External
+// test packages (package foo_test) in a directory are skipped.
+func goTargetFiles(target string) ([]*goSourceFile, error) {
+	abs, err := filepath.Abs(strings.TrimSpace(target))
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve path: %w", err)
+	}
+	info, err := os.Stat(abs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat path: %w", err)
+	}
+	if !info.IsDir() {
+		if !strings.HasSuffix(abs, ".go") {
+			return nil, fmt.Errorf("%s is not a .go file", abs)
+		}
+		f, err := parseGoSource(abs)
+		if err != nil {
+			return nil, err
+		}
+		return []*goSourceFile{f}, nil
+	}
+
+	entries, err := os.ReadDir(abs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dir: %w", err)
+	}
+	var files []*goSourceFile
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".go") {
+			continue
+		}
+		f, err := parseGoSource(filepath.Join(abs, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		if strings.HasSuffix(f.file.Name.Name, "_test") {
+			continue
+		}
+		files = append(files, f)
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no Go files in %s", abs)
+	}
+	return files, nil
+}
+
+// goDeclLocation formats where node is declared for ambiguity errors.
+func goDeclLocation(f *goSourceFile, node ast.Node) string {
+	return fmt.Sprintf("%s:%d", f.path, f.line(node.Pos()))
+}
+
+// findGoFuncDecl finds the function "Func" or method "Type.Method" in target.
+func findGoFuncDecl(target, name string) (*goSourceFile, *ast.FuncDecl, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, nil, errors.New("name is required")
+	}
+	files, err := goTargetFiles(target)
+	if err != nil {
+		return nil, nil, err
+	}
+	recv, fn, isMethod := strings.Cut(name, ".")
+	if !isMethod {
+		fn, recv = recv, ""
+	}
+
+	var foundFile *goSourceFile
+	var found *ast.FuncDecl
+	var all []string
+	for _, f := range files {
+		for _, decl := range f.file.Decls {
+			d, ok := decl.(*ast.FuncDecl)
+			if !ok || d.Name.Name != fn {
+				continue
+			}
+			declRecv := ""
+			if d.Recv != nil && len(d.Recv.List) > 0 {
+				declRecv = receiverTypeName(d.Recv.List[0].Type)
+			}
+			if isMethod && declRecv != recv {
+				continue
+			}
+			qualified := fn
+			if declRecv != "" {
+				qualified = declRecv + "." + fn
+			}
+			all = append(all, fmt.Sprintf("%s (%s)", qualified, goDeclLocation(f, d)))
+			foundFile, found = f, d
+		}
+	}
+	switch len(all) {
+	case 0:
+		return nil, nil, fmt.Errorf("no function named %s in %s", name, target)
+	case 1:
+		return foundFile, found, nil
+	}
+	return nil, nil, fmt.Errorf("%s is ambiguous; use Type.Method: %s", name, strings.Join(all, ", "))
+}
+
+// findGoTypeSpec finds the declaration of the named type in target.
+func findGoTypeSpec(target, typeName string) (*goSourceFile, *ast.GenDecl, *ast.TypeSpec, error) {
+	typeName = strings.TrimSpace(typeName)
+	if typeName == "" {
+		return nil, nil, nil, errors.New("type is required")
+	}
+	files, err := goTargetFiles(target)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	for _, f := range files {
+		for _, decl := range f.file.Decls {
+			gd, ok := decl.(*ast.GenDecl)
+			if !ok || gd.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range gd.Specs {
+				if ts := spec.(*ast.TypeSpec); ts.Name.Name == typeName {
+					return f, gd, ts, nil
+				}
+			}
+		}
+	}
+	return nil, nil, nil, fmt.Errorf("type %s is not declared in %s", typeName, target)
+}
+
+// planGoFunctionBody replaces the body of function or method name with body,
+// the statements between the braces.
+func planGoFunctionBody(target, name, body string) (goEditPlan, error) {
+	f, fn, err := findGoFuncDecl(target, name)
+	if err != nil {
+		return goEditPlan{}, err
+	}
+	if fn.Body == nil {
+		return goEditPlan{}, fmt.Errorf("%s is declared without a body", name)
+	}
+	body = strings.Trim(strings.ReplaceAll(body, "\r\n", "\n"), "\n")
+	text, err := spliceGo(f.src, []goTextEdit{{
+		start: f.offset(fn.Body.Lbrace),
+		end:   f.offset(fn.Body.Rbrace) + 1,
+		text:  "{\n" + body + "\n}",
+	}})
+	if err != nil {
+		return goEditPlan{}, err
+	}
+	return goEditPlan{
+		summary: fmt.Sprintf("Replaced the body of %s in %s", name, f.path),
+		files:   map[string]string{f.path: text},
+	}, nil
+}
+
+// planGoStructField adds field, one or more field declaration lines, to the
+// struct typeName. The fields go after the field named after, or at the end.
+func planGoStructField(target, typeName, field, after string) (goEditPlan, error) {
+	field = strings.Trim(strings.ReplaceAll(field, "\r\n", "\n"), "\n")
+	if strings.TrimSpace(field) == "" {
+		return goEditPlan{}, errors.New("field is required")
+	}
+	f, _, ts, err := findGoTypeSpec(target, typeName)
+	if err != nil {
+		return goEditPlan{}, err
+	}
+	st, ok := ts.Type.(*ast.StructType)
+	if !ok {
+		return goEditPlan{}, fmt.Errorf("%s is not a struct type", typeName)
+	}
+
+	snippet, err := parser.ParseFile(token.NewFileSet(), "", "package p\ntype _ struct {\n"+field+"\n}\n", parser.ParseComments)
+	if err != nil {
+		return goEditPlan{}, fmt.Errorf("field does not parse as a struct field declaration: %w", err)
+	}
+	newFields := snippet.Decls[0].(*ast.GenDecl).Specs[0].(*ast.TypeSpec).Type.(*ast.StructType).Fields.List
+	if len(newFields) == 0 {
+		return goEditPlan{}, errors.New("field does not declare any struct field")
+	}
+	existing := map[string]bool{}
+	var afterField *ast.Field
+	for _, fld := range st.Fields.List {
+		for _, n := range goFieldNames(fld) {
+			existing[n] = true
+			if n == after {
+				afterField = fld
+			}
+		}
+	}
+	var added []string
+	for _, fld := range newFields {
+		for _, n := range goFieldNames(fld) {
+			if existing[n] {
+				return goEditPlan{}, fmt.Errorf("%s already has a field named %s", typeName, n)
+			}
+			added = append(added, n)
+		}
+	}
+
+	var edit goTextEdit
+	closing := f.offset(st.Fields.Closing)
+	switch {
+	case after != "" && afterField == nil:
+		return goEditPlan{}, fmt.Errorf("%s has no field named %s", typeName, after)
+	case afterField != nil:
+		end := afterField.End()
+		if afterField.Comment != nil {
+			end = afterField.Comment.End()
+		}
+		off := f.lineEnd(f.offset(end))
+		edit = goTextEdit{start: off, end: off, text: field + "\n"}
+	case strings.TrimSpace(f.src[f.lineStart(closing):closing]) == "":
+		off := f.lineStart(closing)
+		edit = goTextEdit{start: off, end: off, text: field + "\n"}
+	default:
+		edit = goTextEdit{start: closing, end: closing, text: "\n" + field + "\n"}
+	}
+	text, err := spliceGo(f.src, []goTextEdit{edit})
+	if err != nil {
+		return goEditPlan{}, err
+	}
+	return goEditPlan{
+		summary: fmt.Sprintf("Added field(s) %s to %s in %s", strings.Join(added, ", "), typeName, f.path),
+		files:   map[string]string{f.path: text},
+	}, nil
+}
+
+// goFieldNames returns the names a field declares; an embedded field is
+// named after its type.
+func goFieldNames(fld *ast.Field) []string {
+	if len(fld.Names) == 0 {
+		return []string{receiverTypeName(fld.Type)}
+	}
+	names := make([]string, 0, len(fld.Names))
+	for _, n := range fld.Names {
+		names = append(names, n.Name)
+	}
+	return names
+}
+
+// planGoMethod inserts source, a complete method declaration on typeName,
+// after the type's last method in the file that declares the type.
+func planGoMethod(target, typeName, source string) (goEditPlan, error) {
+	source = strings.TrimSpace(strings.ReplaceAll(source, "\r\n", "\n"))
+	if source == "" {
+		return goEditPlan{}, errors.New("source is required")
+	}
+	snippet, err := parser.ParseFile(token.NewFileSet(), "", "package p\n\n"+source+"\n", parser.ParseComments)
+	if err != nil {
+		return goEditPlan{}, fmt.Errorf("source does not parse as a method declaration: %w", err)
+	}
+	if len(snippet.Decls) != 1 {
+		return goEditPlan{}, fmt.Errorf("source must contain exactly one method declaration, found %d declarations", len(snippet.Decls))
+	}
+	method, ok := snippet.Decls[0].(*ast.FuncDecl)
+	if !ok || method.Recv == nil || len(method.Recv.List) == 0 {
+		return goEditPlan{}, errors.New("source must be a method declaration with a receiver")
+	}
+	if recv := receiverTypeName(method.Recv.List[0].Type); recv != typeName {
+		return goEditPlan{}, fmt.Errorf("method receiver is %s, not %s", recv, typeName)
+	}
+	if method.Body == nil {
+		return goEditPlan{}, errors.New("method has no body")
+	}
+
+	f, gd, ts, err := findGoTypeSpec(target, typeName)
+	if err != nil {
+		return goEditPlan{}, err
+	}
+	// Methods and fields may be declared in any file of the package.
+	pkgFiles, err := goTargetFiles(filepath.Dir(f.path))
+	if err != nil {
+		return goEditPlan{}, err
+	}
+	name := method.Name.Name
+	for _, pf := range pkgFiles {
+		for _, decl := range pf.file.Decls {
+			d, ok := decl.(*ast.FuncDecl)
+			if ok && d.Name.Name == name && d.Recv != nil && len(d.Recv.List) > 0 && receiverTypeName(d.Recv.List[0].Type) == typeName {
+				return goEditPlan{}, fmt.Errorf("%s.%s already exists at %s", typeName, name, goDeclLocation(pf, d))
+			}
+		}
+	}
+	if st, ok := ts.Type.(*ast.StructType); ok {
+		for _, fld := range st.Fields.List {
+			for _, n := range goFieldNames(fld) {
+				if n == name {
+					return goEditPlan{}, fmt.Errorf("%s has a field named %s; a method cannot share its name", typeName, name)
+				}
+			}
+		}
+	}
+
+	var anchor ast.Node = gd
+	for _, decl := range f.file.Decls {
+		if d, ok := decl.(*ast.FuncDecl); ok && d.Recv != nil && len(d.Recv.List) > 0 &&
+			receiverTypeName(d.Recv.List[0].Type) == typeName && d.End() > anchor.End() {
+			anchor = d
+		}
+	}
+	off := f.lineEnd(f.offset(anchor.End()))
+	text := "\n" + source + "\n\n"
+	if off == len(f.src) && !strings.HasSuffix(f.src, "\n") {
+		text = "\n" + text
+	}
+	out, err := spliceGo(f.src, []goTextEdit{{start: off, end: off, text: text}})
+	if err != nil {
+		return goEditPlan{}, err
+	}
+	return goEditPlan{
+		summary: fmt.Sprintf("Added method %s.%s to %s", typeName, name, f.path),
+		files:   map[string]string{f.path: out},
+	}, nil
+}
+
+// goImportSpec is an import as given to go_edit_imports: "path" or
+// "name path", with or without quotes.
+type goImportSpec struct {
+	name string
+	path string
+}
+
+func parseGoImportSpec(s string) (goImportSpec, error) {
+	fields := strings.Fields(s)
+	var spec goImportSpec
+	switch len(fields) {
+	case 1:
+		spec.path = fields[0]
+	case 2:
+		spec.name, spec.path = fields[0], fields[1]
+	default:
+		return spec, fmt.Errorf("invalid import %q; use \"path\" or \"name path\"", s)
+	}
+	if unquoted, err := strconv.Unquote(spec.path); err == nil {
+		spec.path = unquoted
+	}
+	if spec.path == "" || strings.ContainsAny(spec.path, "\" \t") {
+		return spec, fmt.Errorf("invalid import path in %q", s)
+	}
+	if spec.name != "" && spec.name != "_" && spec.name != "." && !token.IsIdentifier(spec.name) {
+		return spec, fmt.Errorf("invalid import name %q", spec.name)
+	}
+	return spec, nil
+}
+
+func (s goImportSpec) String() string {
+	if s.name != "" {
+		return s.name + " " + strconv.Quote(s.path)
+	}
+	return strconv.Quote(s.path)
+}
+
+// isStdImport reports whether path looks like a standard library package,
+// whose first element has no dot.
+func isStdImport(path string) bool {
+	first, _, _ := strings.Cut(path, "/")
+	return !strings.Contains(first, ".")
+}
+
+func importSpecOf(imp *ast.ImportSpec) goImportSpec {
+	spec := goImportSpec{}
+	spec.path, _ = strconv.Unquote(imp.Path.Value)
+	if imp.Name != nil {
+		spec.name = imp.Name.Name
+	}
+	return spec
+}
+
+// planGoImports adds and removes imports of one Go file. Imports that are
+// still referenced are not removed.
+func planGoImports(path string, add, remove []string) (goEditPlan, error) {
+	if len(add) == 0 && len(remove) == 0 {
+		return goEditPlan{}, errors.New("nothing to do; pass 'add' and/or 'remove'")
+	}
+	files, err := goTargetFiles(path)
+	if err != nil {
+		return goEditPlan{}, err
+	}
+	if len(files) != 1 || files[0].path != absPath(path) {
+		return goEditPlan{}, errors.New("go_edit_imports needs a single .go file as 'path'")
+	}
+	f := files[0]
+
+	removed := map[*ast.ImportSpec]bool{}
+	var removedNames []string
+	for _, r := range remove {
+		want, err := parseGoImportSpec(r)
+		if err != nil {
+			return goEditPlan{}, err
+		}
+		matched := false
+		for _, imp := range f.file.Imports {
+			have := importSpecOf(imp)
+			if have.path != want.path || (want.name != "" && have.name != want.name) {
+				continue
+			}
+			if line := goImportUseLine(f, imp); line > 0 {
+				return goEditPlan{}, fmt.Errorf("%s is still used at line %d; remove those uses first", have.path, line)
+			}
+			removed[imp] = true
+			matched = true
+		}
+		if !matched {
+			return goEditPlan{}, fmt.Errorf("%s is not imported by %s", want.path, f.path)
+		}
+		removedNames = append(removedNames, want.path)
+	}
+
+	var toAdd []goImportSpec
+	var addedNames []string
+	for _, a := range add {
+		want, err := parseGoImportSpec(a)
+		if err != nil {
+			return goEditPlan{}, err
+		}
+		present := false
+		for _, imp := range f.file.Imports {
+			if have := importSpecOf(imp); have == want && !removed[imp] {
+				present = true
+			}
+		}
+		if present {
+			continue
+		}
+		for _, other := range toAdd {
+			if other == want {
+				present = true
+			}
+		}
+		if !present {
+			toAdd = append(toAdd, want)
+			addedNames = append(addedNames, want.String())
+		}
+	}
+	if len(toAdd) == 0 && len(removed) == 0 {
+		return goEditPlan{}, errors.New("imports are already as requested")
+	}
+
+	var edits []goTextEdit
+	var keep *ast.GenDecl
+	for _, decl := range f.file.Decls {
+		gd, ok := decl.(*ast.GenDecl)
+		if !ok || gd.Tok != token.IMPORT {
+			continue
+		}
+		surviving := 0
+		for _, spec := range gd.Specs {
+			if !removed[spec.(*ast.ImportSpec)] {
+				surviving++
+			}
+		}
+		if surviving == 0 {
+			start := gd.Pos()
+			if gd.Doc != nil {
+				start = gd.Doc.Pos()
+			}
+			edits = append(edits, goTextEdit{start: f.lineStart(f.offset(start)), end: f.lineEnd(f.offset(gd.End()))})
+			continue
+		}
+		for _, spec := range gd.Specs {
+			if imp := spec.(*ast.ImportSpec); removed[imp] {
+				edits = append(edits, goImportRemoval(f, gd, imp))
+			}
+		}
+		if keep == nil || (!keep.Lparen.IsValid() && gd.Lparen.IsValid()) {
+			keep = gd
+		}
+	}
+	if len(toAdd) > 0 {
+		edits = append(edits, goImportAdditions(f, keep, removed, toAdd)...)
+	}
+
+	text, err := spliceGo(f.src, edits)
+	if err != nil {
+		return goEditPlan{}, err
+	}
+	var parts []string
+	if len(addedNames) > 0 {
+		parts = append(parts, "added "+strings.Join(addedNames, ", "))
+	}
+	if len(removedNames) > 0 {
+		parts = append(parts, "removed "+strings.Join(removedNames, ", "))
+	}
+	return goEditPlan{
+		summary: fmt.Sprintf("Updated imports of %s: %s", f.path, strings.Join(parts, "; ")),
+		files:   map[string]string{f.path: text},
+	}, nil
+}
+
+// goImportUseLine returns the first line that refers to the package imported
+// by imp, or 0. Blank and dot imports are never reported as used.
+func goImportUseLine(f *goSourceFile, imp *ast.ImportSpec) int {
+	spec := importSpecOf(imp)
+	local := spec.name
+	if local == "_" || local == "." {
+		return 0
+	}
+	if local == "" {
+		local = guessPackageName(spec.path)
+	}
+	line := 0
+	ast.Inspect(f.file, func(n ast.Node) bool {
+		if line > 0 {
+			return false
+		}
+		sel, ok := n.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+		// Package references are the selectors the parser left unresolved.
+		if id, ok := sel.X.(*ast.Ident); ok && id.Name == local && id.Obj == nil {
+			line = f.line(id.Pos())
+		}
+		return true
+	})
+	return line
+}
+
+// goImportRemoval deletes imp from gd, which keeps other imports.
+func goImportRemoval(f *goSourceFile, gd *ast.GenDecl, imp *ast.ImportSpec) goTextEdit {
+	start, end := imp.Pos(), imp.End()
+	if imp.Doc != nil {
+		start = imp.Doc.Pos()
+	}
+	if imp.Comment != nil {
+		end = imp.Comment.End()
+	}
+	shared := false
+	for _, spec := range gd.Specs {
+		if spec != imp && (f.line(spec.Pos()) == f.line(start) || f.line(spec.End()) == f.line(end)) {
+			shared = true
+		}
+	}
+	if shared {
+		off := f.offset(end)
+		if off < len(f.src) && f.src[off] == ';' {
+			off++
+		}
+		return goTextEdit{start: f.offset(start), end: off}
+	}
+	return goTextEdit{start: f.lineStart(f.offset(start)), end: f.lineEnd(f.offset(end))}
+}
+
+// goImportAdditions inserts specs into gd, converting a single-line import
+// into a block, or adds a new import declaration after the package clause
+// when gd is nil. Standard library imports are grouped before the others.
+func goImportAdditions(f *goSourceFile, gd *ast.GenDecl, removed map[*ast.ImportSpec]bool, specs []goImportSpec) []goTextEdit {
+	var std, other []string
+	for _, s := range specs {
+		if isStdImport(s.path) {
+			std = append(std, "\t"+s.String()+"\n")
+		} else {
+			other = append(other, "\t"+s.String()+"\n")
+		}
+	}
+
+	if gd == nil {
+		off := f.lineEnd(f.offset(f.file.Name.End()))
+		block := "\nimport (\n" + strings.Join(std, "")
+		if len(std) > 0 && len(other) > 0 {
+			block += "\n"
+		}
+		block += strings.Join(other, "") + ")\n"
+		if off == len(f.src) && !strings.HasSuffix(f.src, "\n") {
+			block = "\n" + block
+		}
+		return []goTextEdit{{start: off, end: off, text: block}}
+	}
+
+	if !gd.Lparen.IsValid() {
+		imp := gd.Specs[0].(*ast.ImportSpec)
+		end := imp.End()
+		if imp.Comment != nil {
+			end = imp.Comment.End()
+		}
+		existing := "\t" + f.src[f.offset(imp.Pos()):f.offset(end)] + "\n"
+		if isStdImport(importSpecOf(imp).path) {
+			std = append([]string{existing}, std...)
+		} else {
+			other = append([]string{existing}, other...)
+		}
+		block := "import (\n" + strings.Join(std, "")
+		if len(std) > 0 && len(other) > 0 {
+			block += "\n"
+		}
+		block += strings.Join(other, "") + ")"
+		return []goTextEdit{{start: f.offset(gd.Pos()), end: f.offset(end), text: block}}
+	}
+
+	// Add each group after the last surviving import of the same kind.
+	var lastStd, lastOther *ast.ImportSpec
+	for _, spec := range gd.Specs {
+		imp := spec.(*ast.ImportSpec)
+		if removed[imp] {
+			continue
+		}
+		if isStdImport(importSpecOf(imp).path) {
+			lastStd = imp
+		} else {
+			lastOther = imp
+		}
+	}
+	after := func(imp *ast.ImportSpec) int {
+		end := imp.End()
+		if imp.Comment != nil {
+			end = imp.Comment.End()
+		}
+		return f.lineEnd(f.offset(end))
+	}
+	var edits []goTextEdit
+	if len(std) > 0 {
+		if lastStd != nil {
+			off := after(lastStd)
+			edits = append(edits, goTextEdit{start: off, end: off, text: strings.Join(std, "")})
+		} else {
+			off := f.lineEnd(f.offset(gd.Lparen))
+			edits = append(edits, goTextEdit{start: off, end: off, text: strings.Join(std, "") + "\n"})
+		}
+	}
+	if len(other) > 0 {
+		if lastOther != nil {
+			off := after(lastOther)
+			edits = append(edits, goTextEdit{start: off, end: off, text: strings.Join(other, "")})
+		} else {
+			off := f.lineStart(f.offset(gd.Rparen))
+			edits = append(edits, goTextEdit{start: off, end: off, text: "\n" + strings.Join(other, "")})
+		}
+	}
+	return edits
+}
+
+// planGoRename renames the identifier name at file:line (and column, when
+// non-zero) and every reference to the same object. Exported identifiers
+// are renamed across the enclosing module.
+func planGoRename(ctx context.Context, file string, line, column int, name, newName string) (goEditPlan, error) {
+	name, newName = strings.TrimSpace(name), strings.TrimSpace(newName)
+	if !token.IsIdentifier(newName) || newName == "_" {
+		return goEditPlan{}, fmt.Errorf("%q is not a valid Go identifier", newName)
+	}
+	if name == newName {
+		return goEditPlan{}, errors.New("new_name is the same as name")
+	}
+	abs, err := filepath.Abs(strings.TrimSpace(file))
+	if err != nil {
+		return goEditPlan{}, fmt.Errorf("failed to resolve path: %w", err)
+	}
+	l := newGoLoader(filepath.Dir(abs))
+	obj, _, err := l.resolveGoIdent(abs, line, column, name)
+	if err != nil {
+		return goEditPlan{}, err
+	}
+	switch {
+	case obj.Pkg() == nil:
+		return goEditPlan{}, fmt.Errorf("%s is predeclared and cannot be renamed", name)
+	case !obj.Pos().IsValid():
+		return goEditPlan{}, fmt.Errorf("%s is declared outside the module (%s) and cannot be renamed", name, obj.Pkg().Path())
+	}
+	if _, ok := obj.(*types.PkgName); ok {
+		return goEditPlan{}, fmt.Errorf("%s is an imported package name; use go_edit_imports to change the import", name)
+	}
+	if obj.Exported() {
+		if err := l.loadModule(ctx, filepath.Dir(abs)); err != nil {
+			return goEditPlan{}, err
+		}
+	}
+
+	if files := goRenameSkippedFiles(l, obj); len(files) > 0 {
+		return goEditPlan{}, fmt.Errorf("%s may be used in files excluded by build constraints on this host (%s); they cannot be type-checked, so nothing was renamed", name, strings.Join(files, ", "))
+	}
+
+	// Renaming a type also renames fields that embed it.
+	matches := func(o types.Object) bool {
+		if sameGoObject(o, obj) {
+			return true
+		}
+		if v, ok := o.(*types.Var); ok && v.Embedded() {
+			if tn, ok := obj.(*types.TypeName); ok {
+				t := v.Type()
+				if p, ok := t.(*types.Pointer); ok {
+					t = p.Elem()
+				}
+				if named, ok := t.(*types.Named); ok && named.Obj() == tn {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	type ref struct {
+		file         string
+		line, column int
+	}
+	seen := map[token.Pos]bool{}
+	var refs []ref
+	for _, pkg := range l.packages() {
+		for _, m := range []map[*ast.Ident]types.Object{pkg.info.Defs, pkg.info.Uses} {
+			for id, o := range m {
+				if o == nil || seen[id.Pos()] || !matches(o) {
+					continue
+				}
+				seen[id.Pos()] = true
+				// Only unqualified references, which all live in obj's own
+				// package, can be captured by another declaration.
+				if pkg.types == obj.Pkg() && sameGoObject(o, obj) {
+					if conflict := goRenameConflict(l.fset, pkg, id, obj, newName); conflict != "" {
+						return goEditPlan{}, errors.New(conflict)
+					}
+				}
+				if pkg.types != obj.Pkg() && !token.IsExported(newName) {
+					return goEditPlan{}, fmt.Errorf("%s is used from package %s; %s would not be visible there", name, pkg.path, newName)
+				}
+				p := l.fset.Position(id.Pos())
+				refs = append(refs, ref{file: p.Filename, line: p.Line, column: p.Column})
+			}
+		}
+	}
+	if conflict := goMemberConflict(obj, newName); conflict != nil {
+		return goEditPlan{}, fmt.Errorf("%s would conflict with %s declared at %s", newName, conflict.Name(), l.fset.Position(conflict.Pos()))
+	}
+	if conflict := goInterfaceConflict(l, obj); conflict != "" {
+		return goEditPlan{}, errors.New(conflict)
+	}
+
+	byFile := map[string][]ref{}
+	for _, r := range refs {
+		byFile[r.file] = append(byFile[r.file], r)
+	}
+	plan := goEditPlan{
+		summary: fmt.Sprintf("Renamed %s to %s: %d reference(s) in %d file(s)", name, newName, len(refs), len(byFile)),
+		files:   map[string]string{},
+	}
+	for path, fileRefs := range byFile {
+		text, _, err := readTextFile(path)
+		if err != nil {
+			return goEditPlan{}, err
+		}
+		lineStarts := []int{0}
+		for i := 0; i < len(text); i++ {
+			if text[i] == '\n' {
+				lineStarts = append(lineStarts, i+1)
+			}
+		}
+		var edits []goTextEdit
+		for _, r := range fileRefs {
+			if r.line > len(lineStarts) {
+				return goEditPlan{}, fmt.Errorf("%s changed while renaming; try again", path)
+			}
+			off := lineStarts[r.line-1] + r.column - 1
+			if off+len(name) > len(text) || text[off:off+len(name)] != name {
+				return goEditPlan{}, fmt.Errorf("%s:%d:%d does not hold %s (line directives or generated code?)", path, r.line, r.column, name)
+			}
+			edits = append(edits, goTextEdit{start: off, end: off + len(name), text: newName})
+		}
+		if plan.files[path], err = spliceGo(text, edits); err != nil {
+			return goEditPlan{}, err
+		}
+	}
+	return plan, nil
+}
+
+// goRenameConflict reports when newName is already visible where id, a
+// reference to obj, appears, so the renamed reference would mean something
+// else or shadow another declaration.
+func goRenameConflict(fset *token.FileSet, pkg *goPackage, id *ast.Ident, obj types.Object, newName string) string {
+	if obj.Parent() == nil || pkg.types == nil {
+		return ""
+	}
+	scope := pkg.types.Scope().Innermost(id.Pos())
+	if scope == nil {
+		return ""
+	}
+	if _, other := scope.LookupParent(newName, id.Pos()); other != nil && other != obj {
+		if other.Pkg() == nil {
+			return fmt.Sprintf("%s is predeclared; renaming %s to it would shadow the builtin", newName, obj.Name())
+		}
+		return fmt.Sprintf("%s is already declared at %s and visible where %s is used (%s)", newName, fset.Position(other.Pos()), obj.Name(), fset.Position(id.Pos()))
+	}
+	// A package-level rename must not collide with declarations in the same
+	// package scope that are not visible from id (e.g. declared later).
+	if obj.Parent() == obj.Pkg().Scope() && obj.Pkg().Scope().Lookup(newName) != nil {
+		return fmt.Sprintf("%s is already declared in package %s at %s", newName, obj.Pkg().Path(), fset.Position(obj.Pkg().Scope().Lookup(newName).Pos()))
+	}
+	return ""
+}
+
+// goMemberConflict returns an existing field or method called newName on
+// the type that declares obj, when obj is a field or method.
+func goMemberConflict(obj types.Object, newName string) types.Object {
+	var recv types.Type
+	switch o := obj.(type) {
+	case *types.Func:
+		if sig, ok := o.Type().(*types.Signature); ok && sig.Recv() != nil {
+			recv = sig.Recv().Type()
+		}
+	case *types.Var:
+		if !o.IsField() {
+			return nil
+		}
+		scope := obj.Pkg().Scope()
+		for _, n := range scope.Names() {
+			tn, ok := scope.Lookup(n).(*types.TypeName)
+			if !ok {
+				continue
+			}
+			if st, ok := tn.Type().Underlying().(*types.Struct); ok {
+				for i := 0; i < st.NumFields(); i++ {
+					if st.Field(i) == o {
+						recv = tn.Type()
+					}
+				}
+			}
+		}
+	}
+	if recv == nil {
+		return nil
+	}
+	found, _, _ := types.LookupFieldOrMethod(recv, true, obj.Pkg(), newName)
+	return found
+}
+
+// goRenameSkippedFiles lists files left out by build constraints (see
+// goPackage.skipped) that mention obj's name where it could refer to obj:
+// any identifier in obj's own package, and selectors elsewhere.
+func goRenameSkippedFiles(l *goLoader, obj types.Object) []string {
+	name := obj.Name()
+	var files []string
+	for _, pkg := range l.packages() {
+		own := pkg.types == obj.Pkg()
+		if !own && !obj.Exported() {
+			continue
+		}
+		for _, f := range pkg.skipped {
+			found := false
+			ast.Inspect(f, func(n ast.Node) bool {
+				switch n := n.(type) {
+				case *ast.Ident:
+					found = found || (own && n.Name == name)
+				case *ast.SelectorExpr:
+					found = found || n.Sel.Name == name
+				}
+				return !found
+			})
+			if found {
+				files = append(files, l.fset.Position(f.Package).Filename)
+			}
+		}
+	}
+	return files
+}
+
+// goInterfaceConflict reports when obj is a method whose rename would break
+// interface satisfaction: a concrete method that implements a method of an
+// interface in the loaded packages, or an interface method that loaded
+// types implement. Such renames have to change the interface and every
+// implementation together, which go_rename_symbol does not do.
+func goInterfaceConflict(l *goLoader, obj types.Object) string {
+	fn, ok := obj.(*types.Func)
+	if !ok {
+		return ""
+	}
+	sig, ok := fn.Type().(*types.Signature)
+	if !ok || sig.Recv() == nil {
+		return ""
+	}
+	declares := func(iface *types.Interface) bool {
+		for i := 0; i < iface.NumMethods(); i++ {
+			if m := iface.Method(i); m.Name() == fn.Name() && (fn.Exported() || m.Pkg() == fn.Pkg()) {
+				return true
+			}
+		}
+		return false
+	}
+	implements := func(t types.Type, iface *types.Interface) bool {
+		if named, ok := t.(*types.Named); ok && named.TypeParams().Len() > 0 {
+			return false
+		}
+		return types.Implements(t, iface) || types.Implements(types.NewPointer(t), iface)
+	}
+
+	recv := sig.Recv().Type()
+	if p, ok := recv.(*types.Pointer); ok {
+		recv = p.Elem()
+	}
+	if recvIface, ok := recv.Underlying().(*types.Interface); ok {
+		// Renaming an interface method: look for named types implementing it.
+		for _, pkg := range l.packages() {
+			if pkg.types == nil {
+				continue
+			}
+			scope := pkg.types.Scope()
+			for _, n := range scope.Names() {
+				tn, ok := scope.Lookup(n).(*types.TypeName)
+				if !ok || tn.IsAlias() || types.IsInterface(tn.Type()) || !implements(tn.Type(), recvIface) {
+					continue
+				}
+				return fmt.Sprintf("%s.%s is implemented by %s (%s); renaming the interface method alone would break it", recv, fn.Name(), tn.Name(), l.fset.Position(tn.Pos()))
+			}
+		}
+		return ""
+	}
+
+	// Renaming a concrete method: look for interfaces it helps satisfy,
+	// named ones first, then interface literals.
+	for _, pkg := range l.packages() {
+		if pkg.types == nil {
+			continue
+		}
+		scope := pkg.types.Scope()
+		for _, n := range scope.Names() {
+			tn, ok := scope.Lookup(n).(*types.TypeName)
+			if !ok {
+				continue
+			}
+			if iface, ok := tn.Type().Underlying().(*types.Interface); ok && declares(iface) && implements(recv, iface) {
+				return fmt.Sprintf("%s satisfies interface %s (%s), which declares %s; renaming the method alone would break it", recv, tn.Name(), l.fset.Position(tn.Pos()), fn.Name())
+			}
+		}
+	}
+	for _, pkg := range l.packages() {
+		for expr, tv := range pkg.info.Types {
+			if _, lit := expr.(*ast.InterfaceType); !lit || !tv.IsType() {
+				continue
+			}
+			if iface, ok := tv.Type.Underlying().(*types.Interface); ok && declares(iface) && implements(recv, iface) {
+				return fmt.Sprintf("%s satisfies the interface at %s, which declares %s; renaming the method alone would break it", recv, l.fset.Position(expr.Pos()), fn.Name())
+			}
+		}
+	}
+	return ""
+}
+
+// goEditRoot is the directory Go edits are checkpointed and reported in:
+// CheckpointRoot, or the module when it lies above that (outside a repository).
+func goEditRoot(path string) string {
+	dir := filepath.Dir(absPath(path))
+	root := CheckpointRoot(dir)
+	if mod, _ := findGoModule(dir); mod != "" && mod != root && pathWithin(root, mod) {
+		return mod
+	}
+	return root
+}
+
+// applyGoEdits gofmts every file in plan and writes them all or none, then
+// runs the project's formatters on them, with editor checkpoints around both.
+// It returns the summary and a unified diff per changed file.
+func applyGoEdits(ctx context.Context, tool string, plan goEditPlan) (string, error) {
+	paths := plan.paths()
+	if len(paths) == 0 {
+		return "", errors.New("nothing to change")
+	}
+	root := goEditRoot(paths[0])
+	files := map[string]*txFile{}
+	var changed []string
+	for _, path := range paths {
+		rel, err := filepath.Rel(root, path)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return "", fmt.Errorf("%s is outside %s; nothing was written", path, root)
+		}
+		formatted, err := format.Source([]byte(plan.files[path]))
+		if err != nil {
+			return "", fmt.Errorf("%s does not parse after the edit: %v; nothing was written", filepath.ToSlash(rel), err)
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to stat %s: %w", path, err)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		orig, tf, err := decodeText(data)
+		if err != nil {
+			return "", fmt.Errorf("%s %w", path, err)
+		}
+		f := &txFile{
+			pendingFile: pendingFile{
+				content: strings.TrimPrefix(string(formatted), "\uFEFF"),
+				exists:  true,
+				onDisk:  true,
+				mode:    info.Mode().Perm(),
+				format:  tf,
+			},
+			origData:    data,
+			origContent: orig,
+			origMode:    info.Mode().Perm(),
+		}
+		files[rel] = f
+		if f.changed() {
+			changed = append(changed, rel)
+		}
+	}
+	if len(changed) == 0 {
+		return "", errors.New("the edit leaves every file unchanged")
+	}
+
+	var notes []string
+	if _, err := CreateCheckpoint(root, "", "editor checkpoint: before "+tool); err != nil {
+		notes = append(notes, fmt.Sprintf("pre-edit checkpoint skipped: %v", err))
+	}
+	if err := commitTxFiles(root, changed, files); err != nil {
+		return "", err
+	}
+	var written []string
+	for _, rel := range changed {
+		written = append(written, filepath.Join(root, rel))
+	}
+	formatted := FormatEditedFiles(ctx, written)
+	checkpoint := ""
+	if head, err := CreateCheckpoint(root, "", "editor checkpoint: "+tool); err != nil {
+		notes = append(notes, fmt.Sprintf("checkpoint skipped: %v", err))
+	} else {
+		checkpoint = fmt.Sprintf(" Checkpoint: %s (work_tree %s).", shortHash(head), root)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s. %d file(s) changed.%s\n", plan.summary, len(changed), checkpoint)
+	for _, n := range notes {
+		fmt.Fprintf(&b, "Note: %s\n", n)
+	}
+	for _, rel := range changed {
+		f := files[rel]
+		b.WriteString(BuildSimpleUnifiedDiff("a/"+filepath.ToSlash(rel), "b/"+filepath.ToSlash(rel), splitDiffLines(f.origContent), splitDiffLines(f.content)))
+		b.WriteByte('\n')
+	}
+	return withFormatReport(strings.TrimRight(b.String(), "\n"), formatted), nil
+}
+
+// ExecuteGoEditTool runs the Go structural edit tools from raw tool
+// arguments. Every tool gofmts the files it changes and returns a diff.
+func ExecuteGoEditTool(ctx context.Context, name string, rawArgs string) (handled bool, output string) {
+	switch name {
+	case "go_rename_symbol", "go_replace_function_body", "go_edit_imports", "go_add_struct_field", "go_add_method":
+	default:
+		return false, ""
+	}
+	args := map[string]any{}
+	if err := json.Unmarshal([]byte(rawArgs), &args); err != nil {
+		return true, fmt.Sprintf("Error: invalid arguments for %s: %v", name, err)
+	}
+	getStr := func(key string) string {
+		v, _ := args[key].(string)
+		return v
+	}
+	getInt := func(key string) int {
+		switch v := args[key].(type) {
+		case float64:
+			return int(v)
+		case string:
+			n, _ := strconv.Atoi(strings.TrimSpace(v))
+			return n
+		}
+		return 0
+	}
+	getList := func(key string) []string {
+		var out []string
+		switch v := args[key].(type) {
+		case string:
+			if strings.TrimSpace(v) != "" {
+				out = append(out, v)
+			}
+		case []any:
+			for _, item := range v {
+				if s, ok := item.(string); ok && strings.TrimSpace(s) != "" {
+					out = append(out, s)
+				}
+			}
+		}
+		return out
+	}
+
+	target := strings.TrimSpace(getStr("path"))
+	if target == "" {
+		return true, fmt.Sprintf("Error: %s requires a non-empty 'path' argument.", name)
+	}
+
+	var plan goEditPlan
+	var err error
+	switch name {
+	case "go_rename_symbol":
+		ident, line := strings.TrimSpace(getStr("name")), getInt("line")
+		if ident == "" || line <= 0 || strings.TrimSpace(getStr("new_name")) == "" {
+			return true, "Error: go_rename_symbol requires 'name', 'new_name' and a positive 'line'."
+		}
+		plan, err = planGoRename(ctx, target, line, getInt("column"), ident, getStr("new_name"))
+	case "go_replace_function_body":
+		plan, err = planGoFunctionBody(target, getStr("name"), getStr("body"))
+	case "go_edit_imports":
+		plan, err = planGoImports(target, getList("add"), getList("remove"))
+	case "go_add_struct_field":
+		plan, err = planGoStructField(target, getStr("type"), getStr("field"), strings.TrimSpace(getStr("after")))
+	case "go_add_method":
+		plan, err = planGoMethod(target, strings.TrimSpace(getStr("type")), getStr("source"))
+	}
+	if err != nil {
+		return true, fmt.Sprintf("Error: %v", err)
+	}
+
+	out, err := VerifyFileEdit(ctx, plan.paths(), getStr("verify"), func() (string, error) {
+		return applyGoEdits(ctx, name, plan)
+	})
+	if err != nil {
+		return true, fmt.Sprintf("Error: %v", err)
+	}
+	return true, out
+}
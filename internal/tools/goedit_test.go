@@ -0,0 +1,216 @@
+package tools
+
+import (
+	"context"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func runGoEdit(t *testing.T, name string, args map[string]any) string {
+	t.Helper()
+	handled, out := ExecuteGoEditTool(context.Background(), name, mustJSON(t, args))
+	if !handled {
+		t.Fatalf("%s not handled", name)
+	}
+	return out
+}
+
+func TestGoRenameSymbolAcrossPackages(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	root := writeGoFixture(t)
+	mainFile := filepath.Join(root, "main.go")
+	storeFile := filepath.Join(root, "store", "store.go")
+
+	out := runGoEdit(t, "go_rename_symbol", map[string]any{"path": mainFile, "line": 7, "name": "Add", "new_name": "Append"})
+	if !strings.Contains(out, "Renamed Add to Append: 3 reference(s) in 2 file(s)") || !strings.Contains(out, "+\ts.Append(\"a\")") {
+		t.Fatalf("unexpected output:\n%s", out)
+	}
+	main := readTestFile(mainFile)
+	if !strings.Contains(main, `s.Append("b")`) || !strings.Contains(main, "func (other) Add(string) {}") {
+		t.Fatalf("main.go not renamed correctly:\n%s", main)
+	}
+	if !strings.Contains(readTestFile(storeFile), "func (s *Store) Append(item string)") {
+		t.Fatalf("store.go not renamed:\n%s", readTestFile(storeFile))
+	}
+
+	out = runGoEdit(t, "go_rename_symbol", map[string]any{"path": storeFile, "line": 16, "name": "helper", "new_name": "Store"})
+	if !strings.HasPrefix(out, "Error:") || !strings.Contains(out, "already declared") {
+		t.Fatalf("expected conflict, got:\n%s", out)
+	}
+	out = runGoEdit(t, "go_rename_symbol", map[string]any{"path": mainFile, "line": 6, "name": "Store", "new_name": "store"})
+	if !strings.Contains(out, "would not be visible") {
+		t.Fatalf("expected unexported rename across packages to fail, got:\n%s", out)
+	}
+}
+
+func TestGoReplaceFunctionBodyAndAddMethod(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	root := writeGoFixture(t)
+	storeDir := filepath.Join(root, "store")
+	storeFile := filepath.Join(storeDir, "store.go")
+
+	out := runGoEdit(t, "go_replace_function_body", map[string]any{"path": storeDir, "name": "Store.Add", "body": "s.items = append(s.items, item)"})
+	if strings.HasPrefix(out, "Error:") {
+		t.Fatalf("replace body: %s", out)
+	}
+	if src := readTestFile(storeFile); !strings.Contains(src, "func (s *Store) Add(item string) {\n\ts.items = append(s.items, item)\n}\n") {
+		t.Fatalf("body not replaced:\n%s", src)
+	}
+	out = runGoEdit(t, "go_replace_function_body", map[string]any{"path": storeDir, "name": "helper", "body": "return ("})
+	if !strings.Contains(out, "does not parse after the edit") {
+		t.Fatalf("expected syntax error, got:\n%s", out)
+	}
+
+	out = runGoEdit(t, "go_add_method", map[string]any{"path": storeDir, "type": "Store", "source": "// Len returns the item count.\nfunc (s *Store) Len() int { return len(s.items) }"})
+	if strings.HasPrefix(out, "Error:") {
+		t.Fatalf("add method: %s", out)
+	}
+	src := readTestFile(storeFile)
+	addIdx, lenIdx, helperIdx := strings.Index(src, ") Add("), strings.Index(src, "// Len returns"), strings.Index(src, "func helper")
+	if addIdx < 0 || lenIdx < addIdx || helperIdx < lenIdx {
+		t.Fatalf("method not inserted after Add:\n%s", src)
+	}
+	if out := runGoEdit(t, "go_add_method", map[string]any{"path": storeFile, "type": "Store", "source": "func (s Store) Len() int { return 0 }"}); !strings.Contains(out, "already exists") {
+		t.Fatalf("expected duplicate method error, got:\n%s", out)
+	}
+	if out := runGoEdit(t, "go_add_method", map[string]any{"path": storeFile, "type": "Store", "source": "func (o other) X() {}"}); !strings.Contains(out, "not Store") {
+		t.Fatalf("expected receiver mismatch, got:\n%s", out)
+	}
+}
+
+func TestGoAddStructField(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	dir := t.TempDir()
+	path := filepath.Join(dir, "cfg.go")
+	writeTestFile(t, path, "package cfg\n\ntype Config struct {\n\tName string // display name\n\tPort int\n}\n\ntype Empty struct{}\n")
+
+	if out := runGoEdit(t, "go_add_struct_field", map[string]any{"path": path, "type": "Config", "field": "Host string `json:\"host\"`", "after": "Name"}); strings.HasPrefix(out, "Error:") {
+		t.Fatalf("add field: %s", out)
+	}
+	if out := runGoEdit(t, "go_add_struct_field", map[string]any{"path": path, "type": "Empty", "field": "// ID is unique.\nID int"}); strings.HasPrefix(out, "Error:") {
+		t.Fatalf("add field to empty struct: %s", out)
+	}
+	want := "package cfg\n\ntype Config struct {\n\tName string // display name\n\tHost string `json:\"host\"`\n\tPort int\n}\n\ntype Empty struct {\n\t// ID is unique.\n\tID int\n}\n"
+	if got := readTestFile(path); got != want {
+		t.Fatalf("unexpected file:\n%s", got)
+	}
+	if out := runGoEdit(t, "go_add_struct_field", map[string]any{"path": path, "type": "Config", "field": "Port string"}); !strings.Contains(out, "already has a field named Port") {
+		t.Fatalf("expected duplicate field error, got:\n%s", out)
+	}
+}
+
+func TestGoEditImports(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a.go")
+	writeTestFile(t, path, "package a\n\nimport \"fmt\"\n\nfunc A() { fmt.Println() }\n")
+
+	if out := runGoEdit(t, "go_edit_imports", map[string]any{"path": path, "add": []string{"strings", "yaml gopkg.in/yaml.v3", "fmt"}}); strings.HasPrefix(out, "Error:") {
+		t.Fatalf("add imports: %s", out)
+	}
+	want := "package a\n\nimport (\n\t\"fmt\"\n\t\"strings\"\n\n\tyaml \"gopkg.in/yaml.v3\"\n)\n\nfunc A() { fmt.Println() }\n"
+	if got := readTestFile(path); got != want {
+		t.Fatalf("unexpected imports:\n%s", got)
+	}
+
+	if out := runGoEdit(t, "go_edit_imports", map[string]any{"path": path, "remove": []string{"fmt"}}); !strings.Contains(out, "still used at line") {
+		t.Fatalf("expected in-use error, got:\n%s", out)
+	}
+	if out := runGoEdit(t, "go_edit_imports", map[string]any{"path": path, "remove": []string{"strings", "gopkg.in/yaml.v3"}, "add": "os"}); strings.HasPrefix(out, "Error:") {
+		t.Fatalf("remove imports: %s", out)
+	}
+	want = "package a\n\nimport (\n\t\"fmt\"\n\t\"os\"\n)\n\nfunc A() { fmt.Println() }\n"
+	if got := readTestFile(path); got != want {
+		t.Fatalf("unexpected imports after removal:\n%s", got)
+	}
+
+	bare := filepath.Join(dir, "b.go")
+	writeTestFile(t, bare, "package a\n\nfunc B() {}\n")
+	if out := runGoEdit(t, "go_edit_imports", map[string]any{"path": bare, "add": []string{"example.com/x", "os"}}); strings.HasPrefix(out, "Error:") {
+		t.Fatalf("add imports to bare file: %s", out)
+	}
+	want = "package a\n\nimport (\n\t\"os\"\n\n\t\"example.com/x\"\n)\n\nfunc B() {}\n"
+	if got := readTestFile(bare); got != want {
+		t.Fatalf("unexpected new import block:\n%s", got)
+	}
+}
+
+func TestGoEditKeepsCRLF(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	dir := t.TempDir()
+	path := filepath.Join(dir, "w.go")
+	writeTestFile(t, path, "package w\r\n\r\nfunc W() int {\r\n\treturn 1\r\n}\r\n")
+
+	if out := runGoEdit(t, "go_replace_function_body", map[string]any{"path": path, "name": "W", "body": "return 2"}); strings.HasPrefix(out, "Error:") {
+		t.Fatalf("replace body: %s", out)
+	}
+	if got := readTestFile(path); got != "package w\r\n\r\nfunc W() int {\r\n\treturn 2\r\n}\r\n" {
+		t.Fatalf("CRLF not kept: %q", got)
+	}
+}
+
+func TestGoRenameSymbolExternalTestsAndInterfaces(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	root := t.TempDir()
+	writeSearchFixture(t, root, map[string]string{
+		"go.mod":        "module example.com/m\n\ngo 1.22\n",
+		"a.go":          "package m\n\ntype T struct{}\n\nfunc Foo() int { return 1 }\n\nfunc (T) Run() {}\n\ntype Runner interface{ Run() }\n\nvar _ Runner = T{}\n",
+		"a_ext_test.go": "package m_test\n\nimport (\n\t\"testing\"\n\n\t\"example.com/m\"\n)\n\nfunc TestFoo(t *testing.T) { _ = m.Foo() }\n",
+	})
+	a := filepath.Join(root, "a.go")
+	ext := filepath.Join(root, "a_ext_test.go")
+
+	out := runGoEdit(t, "go_rename_symbol", map[string]any{"path": a, "line": 5, "name": "Foo", "new_name": "Bar"})
+	if !strings.Contains(out, "2 reference(s) in 2 file(s)") {
+		t.Fatalf("unexpected output:\n%s", out)
+	}
+	if src := readTestFile(ext); !strings.Contains(src, "m.Bar()") {
+		t.Fatalf("external test package not renamed:\n%s", src)
+	}
+
+	out = runGoEdit(t, "go_rename_symbol", map[string]any{"path": a, "line": 7, "name": "Run", "new_name": "Exec"})
+	if !strings.Contains(out, "satisfies interface Runner") {
+		t.Fatalf("expected interface conflict, got:\n%s", out)
+	}
+	out = runGoEdit(t, "go_rename_symbol", map[string]any{"path": a, "line": 9, "name": "Run", "new_name": "Exec"})
+	if !strings.Contains(out, "implemented by T") {
+		t.Fatalf("expected implementation conflict, got:\n%s", out)
+	}
+	if src := readTestFile(a); !strings.Contains(src, "func (T) Run() {}") || !strings.Contains(src, "Runner interface{ Run() }") {
+		t.Fatalf("refused rename changed the file:\n%s", src)
+	}
+}
+
+func TestGoRenameSymbolRefusesFilesForOtherPlatforms(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	other := "windows"
+	if runtime.GOOS == "windows" {
+		other = "linux"
+	}
+	root := t.TempDir()
+	writeSearchFixture(t, root, map[string]string{
+		"go.mod":               "module example.com/m\n\ngo 1.22\n",
+		"p/a.go":               "package p\n\nfunc helper() int { return 1 }\n\nfunc Use() int { return helper() }\n",
+		"p/a_" + other + ".go": "package p\n\nfunc platform() int { return helper() }\n",
+		"q/q.go":               "package q\n\nfunc Other() int { return 2 }\n",
+		"q/q_" + other + ".go": "package q\n\nfunc other() int { return 3 }\n",
+	})
+	a := filepath.Join(root, "p", "a.go")
+
+	out := runGoEdit(t, "go_rename_symbol", map[string]any{"path": a, "line": 3, "name": "helper", "new_name": "helper2"})
+	if !strings.Contains(out, "excluded by build constraints") || !strings.Contains(out, "a_"+other+".go") {
+		t.Fatalf("expected refusal naming the %s file, got:\n%s", other, out)
+	}
+	if src := readTestFile(a); !strings.Contains(src, "func helper()") {
+		t.Fatalf("refused rename changed the file:\n%s", src)
+	}
+
+	// Files for other platforms that do not mention the name do not block it.
+	q := filepath.Join(root, "q", "q.go")
+	out = runGoEdit(t, "go_rename_symbol", map[string]any{"path": q, "line": 3, "name": "Other", "new_name": "Another"})
+	if !strings.Contains(out, "1 reference(s) in 1 file(s)") {
+		t.Fatalf("unexpected output:\n%s", out)
+	}
+}
@@ -0,0 +1,808 @@
+package tools
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"go/types"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	maxGoReferences     = 300
+	maxGoSnippetLines   = 80
+	maxGoSymbolsListed  = 500
+	goSignatureMaxChars = 200
+)
+
+// GoSymbol is one top-level declaration in a Go file.
+type GoSymbol struct {
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Receiver  string `json:"receiver,omitempty"`
+	Signature string `json:"signature,omitempty"`
+	File      string `json:"file"`
+	StartLine int    `json:"start_line"`
+	EndLine   int    `json:"end_line"`
+	Exported  bool   `json:"exported"`
+}
+
+// QualifiedName returns "Recv.Name" for methods and Name otherwise.
+func (s GoSymbol) QualifiedName() string {
+	if s.Receiver != "" {
+		return s.Receiver + "." + s.Name
+	}
+	return s.Name
+}
+
+// GoLocation is a position in a Go source file.
+type GoLocation struct {
+	File   string `json:"file"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+	Text   string `json:"text,omitempty"`
+}
+
+// goPackage is one type-checked directory. xtest is the external test
+// package (package foo_test) of the directory, if it has one.
+type goPackage struct {
+	dir   string
+	path  string
+	files []*ast.File
+	types *types.Package
+	info  *types.Info
+	xtest *goPackage
+	// skipped holds files of the package excluded by build constraints on
+	// this host; they are parsed but not type-checked.
+	skipped []*ast.File
+}
+
+// goLoader parses and type-checks packages of the enclosing module from
+// source. Imports outside the module resolve to empty placeholder packages,
+// so type errors are expected and ignored; identifiers declared inside the
+// module still resolve to the same objects across packages.
+type goLoader struct {
+	fset       *token.FileSet
+	modRoot    string
+	modPath    string
+	pkgs       map[string]*goPackage
+	loading    map[string]bool
+	external   map[string]*types.Package
+	sourceByFn map[string][]string
+}
+
+func newGoLoader(start string) *goLoader {
+	l := &goLoader{
+		fset:       token.NewFileSet(),
+		pkgs:       map[string]*goPackage{},
+		loading:    map[string]bool{},
+		external:   map[string]*types.Package{},
+		sourceByFn: map[string][]string{},
+	}
+	l.modRoot, l.modPath = findGoModule(start)
+	return l
+}
+
+// findGoModule returns the directory and module path of the nearest go.mod
+// above start, or empty strings when there is none.
+func findGoModule(start string) (string, string) {
+	dir := start
+	for {
+		data, err := os.ReadFile(filepath.Join(dir, "go.mod"))
+		if err == nil {
+			scanner := bufio.NewScanner(bytes.NewReader(data))
+			for scanner.Scan() {
+				line := strings.TrimSpace(scanner.Text())
+				if strings.HasPrefix(line, "module ") {
+					return dir, strings.Trim(strings.TrimSpace(strings.TrimPrefix(line, "module ")), `"`)
+				}
+			}
+			return dir, ""
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", ""
+		}
+		dir = parent
+	}
+}
+
+func (l *goLoader) importPathFor(dir string) string {
+	if l.modRoot == "" {
+		return filepath.Base(dir)
+	}
+	rel, err := filepath.Rel(l.modRoot, dir)
+	if err != nil || rel == "." {
+		return l.modPath
+	}
+	return path.Join(l.modPath, filepath.ToSlash(rel))
+}
+
+// Import implements types.Importer.
+func (l *goLoader) Import(importPath string) (*types.Package, error) {
+	if l.modPath != "" && (importPath == l.modPath || strings.HasPrefix(importPath, l.modPath+"/")) {
+		rel := strings.TrimPrefix(strings.TrimPrefix(importPath, l.modPath), "/")
+		dir := filepath.Join(l.modRoot, filepath.FromSlash(rel))
+		if !l.loading[dir] {
+			if pkg, err := l.loadDir(dir); err == nil {
+				return pkg.types, nil
+			}
+		}
+	}
+	if pkg, ok := l.external[importPath]; ok {
+		return pkg, nil
+	}
+	pkg := types.NewPackage(importPath, guessPackageName(importPath))
+	pkg.MarkComplete()
+	l.external[importPath] = pkg
+	return pkg, nil
+}
+
+// guessPackageName derives a package name from an import path, skipping
+// major-version suffixes such as "/v2" and "gopkg.in" ".vN" suffixes.
+func guessPackageName(importPath string) string {
+	parts := strings.Split(importPath, "/")
+	name := parts[len(parts)-1]
+	if len(parts) > 1 && len(name) > 1 && name[0] == 'v' {
+		if _, err := strconv.Atoi(name[1:]); err == nil {
+			name = parts[len(parts)-2]
+		}
+	}
+	if idx := strings.Index(name, ".v"); idx > 0 {
+		name = name[:idx]
+	}
+	name = strings.TrimPrefix(name, "go-")
+	return strings.NewReplacer("-", "_", ".", "_").Replace(name)
+}
+
+// loadDir parses the Go files in dir that match the current build context,
+// including in-package tests, and type-checks them. Files for other
+// platforms or build tags are kept unchecked in goPackage.skipped.
+func (l *goLoader) loadDir(dir string) (*goPackage, error) {
+	dir = filepath.Clean(dir)
+	if pkg, ok := l.pkgs[dir]; ok {
+		return pkg, nil
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read package dir: %w", err)
+	}
+
+	byName := map[string][]*ast.File{}
+	skipped := map[string][]*ast.File{}
+	counts := map[string]int{}
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasSuffix(name, ".go") {
+			continue
+		}
+		match, err := build.Default.MatchFile(dir, name)
+		if err != nil {
+			continue
+		}
+		// Files with syntax errors are still useful; the parser returns a
+		// partial AST alongside the error.
+		file, _ := parser.ParseFile(l.fset, filepath.Join(dir, name), nil, parser.ParseComments)
+		if file == nil {
+			continue
+		}
+		pkgName := file.Name.Name
+		if !match {
+			skipped[pkgName] = append(skipped[pkgName], file)
+			continue
+		}
+		byName[pkgName] = append(byName[pkgName], file)
+		if !strings.HasSuffix(name, "_test.go") {
+			counts[pkgName] += 2
+		} else {
+			counts[pkgName]++
+		}
+	}
+	if len(byName) == 0 {
+		return nil, fmt.Errorf("no Go files in %s", dir)
+	}
+
+	// The package with the most non-test files is the directory's package;
+	// its external test package (foo_test) is checked separately below.
+	primary := ""
+	for name, n := range counts {
+		if primary == "" || n > counts[primary] || (n == counts[primary] && name < primary) {
+			primary = name
+		}
+	}
+
+	l.loading[dir] = true
+	pkg := l.checkPackage(dir, l.importPathFor(dir), byName[primary])
+	pkg.skipped = skipped[primary]
+	delete(l.loading, dir)
+	l.pkgs[dir] = pkg
+	if xfiles := byName[primary+"_test"]; len(xfiles) > 0 && !strings.HasSuffix(primary, "_test") {
+		// The external test package imports pkg, which is now cached.
+		pkg.xtest = l.checkPackage(dir, pkg.path+"_test", xfiles)
+		pkg.xtest.skipped = skipped[primary+"_test"]
+	}
+	return pkg, nil
+}
+
+// checkPackage type-checks files as the package importPath in dir.
+func (l *goLoader) checkPackage(dir, importPath string, files []*ast.File) *goPackage {
+	pkg := &goPackage{
+		dir:   dir,
+		path:  importPath,
+		files: files,
+		info: &types.Info{
+			Defs:  map[*ast.Ident]types.Object{},
+			Uses:  map[*ast.Ident]types.Object{},
+			Types: map[ast.Expr]types.TypeAndValue{},
+		},
+	}
+	conf := types.Config{Importer: l, Error: func(error) {}, FakeImportC: true}
+	pkg.types, _ = conf.Check(pkg.path, l.fset, pkg.files, pkg.info)
+	return pkg
+}
+
+// packages returns every loaded package, external test packages included,
+// ordered by directory.
+func (l *goLoader) packages() []*goPackage {
+	dirs := make([]string, 0, len(l.pkgs))
+	for dir := range l.pkgs {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+	var out []*goPackage
+	for _, dir := range dirs {
+		out = append(out, l.pkgs[dir])
+		if x := l.pkgs[dir].xtest; x != nil {
+			out = append(out, x)
+		}
+	}
+	return out
+}
+
+// loadModule type-checks every package under the module root (or under root
+// when there is no module), skipping hidden, vendor and testdata trees.
+func (l *goLoader) loadModule(ctx context.Context, root string) error {
+	if l.modRoot != "" {
+		root = l.modRoot
+	}
+	return filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		name := d.Name()
+		if p != root && (strings.HasPrefix(name, ".") || name == "vendor" || name == "testdata" || name == "node_modules") {
+			return filepath.SkipDir
+		}
+		_, _ = l.loadDir(p)
+		return nil
+	})
+}
+
+func (l *goLoader) lines(file string) []string {
+	if lines, ok := l.sourceByFn[file]; ok {
+		return lines
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil
+	}
+	lines := strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	l.sourceByFn[file] = lines
+	return lines
+}
+
+func (l *goLoader) location(pos token.Pos) GoLocation {
+	p := l.fset.Position(pos)
+	loc := GoLocation{File: p.Filename, Line: p.Line, Column: p.Column}
+	if lines := l.lines(p.Filename); p.Line >= 1 && p.Line <= len(lines) {
+		loc.Text = strings.TrimSpace(lines[p.Line-1])
+	}
+	return loc
+}
+
+// ListGoSymbols returns the top-level declarations of a Go file, or of every
+// file in a package directory.
+func ListGoSymbols(target string, exportedOnly bool) ([]GoSymbol, error) {
+	abs, err := filepath.Abs(strings.TrimSpace(target))
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve path: %w", err)
+	}
+	info, err := os.Stat(abs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat path: %w", err)
+	}
+
+	fset := token.NewFileSet()
+	var files []*ast.File
+	if info.IsDir() {
+		pkgs, err := parseGoDir(fset, abs)
+		if err != nil {
+			return nil, err
+		}
+		files = pkgs
+	} else {
+		file, err := parser.ParseFile(fset, abs, nil, parser.ParseComments)
+		if file == nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", abs, err)
+		}
+		files = []*ast.File{file}
+	}
+
+	var out []GoSymbol
+	for _, file := range files {
+		for _, sym := range fileSymbols(fset, file) {
+			if exportedOnly && !sym.Exported {
+				continue
+			}
+			out = append(out, sym)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].File != out[j].File {
+			return out[i].File < out[j].File
+		}
+		return out[i].StartLine < out[j].StartLine
+	})
+	return out, nil
+}
+
+func parseGoDir(fset *token.FileSet, dir string) ([]*ast.File, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dir: %w", err)
+	}
+	var files []*ast.File
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".go") {
+			continue
+		}
+		file, _ := parser.ParseFile(fset, filepath.Join(dir, e.Name()), nil, parser.ParseComments)
+		if file != nil {
+			files = append(files, file)
+		}
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no Go files in %s", dir)
+	}
+	return files, nil
+}
+
+func fileSymbols(fset *token.FileSet, file *ast.File) []GoSymbol {
+	var out []GoSymbol
+	span := func(node ast.Node) (string, int, int) {
+		start := fset.Position(node.Pos())
+		return start.Filename, start.Line, fset.Position(node.End()).Line
+	}
+
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			sym := GoSymbol{Kind: "func", Name: d.Name.Name, Exported: d.Name.IsExported()}
+			if d.Recv != nil && len(d.Recv.List) > 0 {
+				sym.Kind = "method"
+				sym.Receiver = receiverTypeName(d.Recv.List[0].Type)
+			}
+			sym.File, sym.StartLine, sym.EndLine = span(d)
+			if d.Doc != nil {
+				sym.StartLine = fset.Position(d.Doc.Pos()).Line
+			}
+			sym.Signature = funcSignature(fset, d)
+			out = append(out, sym)
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					sym := GoSymbol{Kind: "type", Name: s.Name.Name, Exported: s.Name.IsExported()}
+					switch s.Type.(type) {
+					case *ast.StructType:
+						sym.Signature = "struct"
+					case *ast.InterfaceType:
+						sym.Signature = "interface"
+					default:
+						sym.Signature = nodeString(fset, s.Type)
+					}
+					node := ast.Node(s)
+					if len(d.Specs) == 1 {
+						node = d
+					}
+					sym.File, sym.StartLine, sym.EndLine = span(node)
+					out = append(out, sym)
+				case *ast.ValueSpec:
+					kind := "var"
+					if d.Tok == token.CONST {
+						kind = "const"
+					}
+					for _, name := range s.Names {
+						if name.Name == "_" {
+							continue
+						}
+						sym := GoSymbol{Kind: kind, Name: name.Name, Exported: name.IsExported()}
+						if s.Type != nil {
+							sym.Signature = nodeString(fset, s.Type)
+						}
+						sym.File, sym.StartLine, sym.EndLine = span(s)
+						out = append(out, sym)
+					}
+				}
+			}
+		}
+	}
+	return out
+}
+
+func receiverTypeName(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.StarExpr:
+		return receiverTypeName(t.X)
+	case *ast.IndexExpr:
+		return receiverTypeName(t.X)
+	case *ast.IndexListExpr:
+		return receiverTypeName(t.X)
+	case *ast.Ident:
+		return t.Name
+	}
+	return ""
+}
+
+func funcSignature(fset *token.FileSet, d *ast.FuncDecl) string {
+	sig := *d
+	sig.Body = nil
+	sig.Doc = nil
+	return truncateRunes(nodeString(fset, &sig), goSignatureMaxChars)
+}
+
+func nodeString(fset *token.FileSet, node any) string {
+	var b bytes.Buffer
+	if err := printer.Fprint(&b, fset, node); err != nil {
+		return ""
+	}
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// FindGoFunction returns the source of the function or method named name
+// ("Func" or "Type.Method") in a file or package directory, with its doc
+// comment, numbered like read_file output.
+func FindGoFunction(target, name string) (GoSymbol, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return GoSymbol{}, "", fmt.Errorf("name is required")
+	}
+	symbols, err := ListGoSymbols(target, false)
+	if err != nil {
+		return GoSymbol{}, "", err
+	}
+	var matches []GoSymbol
+	for _, sym := range symbols {
+		if sym.QualifiedName() == name || (!strings.Contains(name, ".") && sym.Name == name) {
+			matches = append(matches, sym)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return GoSymbol{}, "", fmt.Errorf("no declaration named %s in %s", name, target)
+	case 1:
+	default:
+		var names []string
+		for _, m := range matches {
+			names = append(names, fmt.Sprintf("%s (%s:%d)", m.QualifiedName(), m.File, m.StartLine))
+		}
+		return GoSymbol{}, "", fmt.Errorf("%s is ambiguous; use Type.Method: %s", name, strings.Join(names, ", "))
+	}
+
+	sym := matches[0]
+	out, err := ReadFileWithLines(sym.File, fmt.Sprintf("%d-%d", sym.StartLine, sym.EndLine))
+	if err != nil {
+		return GoSymbol{}, "", err
+	}
+	return sym, out, nil
+}
+
+// resolveGoIdent finds the identifier called name on line (and column, when
+// non-zero) of file and returns the object it refers to.
+func (l *goLoader) resolveGoIdent(file string, line, column int, name string) (types.Object, *goPackage, error) {
+	pkg, err := l.loadDir(filepath.Dir(file))
+	if err != nil {
+		return nil, nil, err
+	}
+	files := pkg.files
+	if pkg.xtest != nil && !slices.ContainsFunc(files, func(f *ast.File) bool { return l.fset.Position(f.Pos()).Filename == file }) {
+		pkg, files = pkg.xtest, pkg.xtest.files
+	}
+	for _, f := range files {
+		if l.fset.Position(f.Pos()).Filename != file {
+			continue
+		}
+		var found *ast.Ident
+		ast.Inspect(f, func(n ast.Node) bool {
+			if found != nil {
+				return false
+			}
+			id, ok := n.(*ast.Ident)
+			if !ok || id.Name != name {
+				return true
+			}
+			p := l.fset.Position(id.Pos())
+			if p.Line == line && (column <= 0 || (column >= p.Column && column < p.Column+len(id.Name))) {
+				found = id
+			}
+			return true
+		})
+		if found == nil {
+			return nil, pkg, fmt.Errorf("identifier %s not found on %s:%d", name, file, line)
+		}
+		if obj := pkg.info.Defs[found]; obj != nil {
+			return obj, pkg, nil
+		}
+		if obj := pkg.info.Uses[found]; obj != nil {
+			return obj, pkg, nil
+		}
+		return nil, pkg, fmt.Errorf("could not resolve %s on %s:%d (declared outside the module or in code with errors)", name, file, line)
+	}
+	return nil, pkg, fmt.Errorf("%s is not part of the package in %s (check build tags or package clause)", file, filepath.Dir(file))
+}
+
+// FindGoDefinition returns where the identifier name at file:line is
+// declared, along with the numbered source of its declaration.
+func FindGoDefinition(file string, line, column int, name string) (GoLocation, string, error) {
+	abs, err := filepath.Abs(strings.TrimSpace(file))
+	if err != nil {
+		return GoLocation{}, "", fmt.Errorf("failed to resolve path: %w", err)
+	}
+	l := newGoLoader(filepath.Dir(abs))
+	obj, _, err := l.resolveGoIdent(abs, line, column, strings.TrimSpace(name))
+	if err != nil {
+		return GoLocation{}, "", err
+	}
+	if !obj.Pos().IsValid() {
+		pkgPath := ""
+		if obj.Pkg() != nil {
+			pkgPath = obj.Pkg().Path()
+		}
+		return GoLocation{}, "", fmt.Errorf("%s is declared outside the module (%s); source is not available", name, strings.TrimSpace(pkgPath+" "+obj.Type().String()))
+	}
+
+	loc := l.location(obj.Pos())
+	start, end := l.declarationSpan(obj)
+	snippet, err := ReadFileWithLines(loc.File, fmt.Sprintf("%d-%d", start, min(end, start+maxGoSnippetLines-1)))
+	if err != nil {
+		return loc, "", err
+	}
+	return loc, snippet, nil
+}
+
+// declarationSpan returns the line range of the declaration that introduces
+// obj, falling back to the object's own line.
+func (l *goLoader) declarationSpan(obj types.Object) (int, int) {
+	pos := l.fset.Position(obj.Pos())
+	start, end := pos.Line, pos.Line
+	for _, pkg := range l.packages() {
+		for _, f := range pkg.files {
+			if l.fset.Position(f.Pos()).Filename != pos.Filename {
+				continue
+			}
+			nodes, _ := pathEnclosing(f, obj.Pos())
+			for _, n := range nodes {
+				switch d := n.(type) {
+				case *ast.FuncDecl:
+					if d.Name.Pos() == obj.Pos() {
+						start = l.fset.Position(d.Pos()).Line
+						if d.Doc != nil {
+							start = l.fset.Position(d.Doc.Pos()).Line
+						}
+						return start, l.fset.Position(d.End()).Line
+					}
+				case *ast.TypeSpec, *ast.ValueSpec, *ast.Field:
+					return l.fset.Position(n.Pos()).Line, l.fset.Position(n.End()).Line
+				case *ast.AssignStmt:
+					return l.fset.Position(n.Pos()).Line, l.fset.Position(n.End()).Line
+				}
+			}
+			return start, end
+		}
+	}
+	return start, end
+}
+
+// pathEnclosing returns the nodes enclosing pos, innermost first.
+func pathEnclosing(file *ast.File, pos token.Pos) ([]ast.Node, bool) {
+	var stack []ast.Node
+	ast.Inspect(file, func(n ast.Node) bool {
+		if n == nil {
+			return false
+		}
+		if pos < n.Pos() || pos >= n.End() {
+			return false
+		}
+		stack = append(stack, n)
+		return true
+	})
+	for i, j := 0, len(stack)-1; i < j; i, j = i+1, j-1 {
+		stack[i], stack[j] = stack[j], stack[i]
+	}
+	return stack, len(stack) > 0
+}
+
+// FindGoReferences returns every use of the identifier name at file:line
+// across the enclosing module, including its declaration.
+func FindGoReferences(ctx context.Context, file string, line, column int, name string) ([]GoLocation, bool, error) {
+	abs, err := filepath.Abs(strings.TrimSpace(file))
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to resolve path: %w", err)
+	}
+	l := newGoLoader(filepath.Dir(abs))
+	obj, _, err := l.resolveGoIdent(abs, line, column, strings.TrimSpace(name))
+	if err != nil {
+		return nil, false, err
+	}
+	// Unexported and local objects can only be referenced from their own
+	// package, which is already loaded.
+	if obj.Exported() {
+		if err := l.loadModule(ctx, filepath.Dir(abs)); err != nil {
+			return nil, false, err
+		}
+	}
+
+	seen := map[token.Pos]bool{}
+	var out []GoLocation
+	for _, pkg := range l.packages() {
+		for _, m := range []map[*ast.Ident]types.Object{pkg.info.Defs, pkg.info.Uses} {
+			for id, o := range m {
+				if o == nil || seen[id.Pos()] || !sameGoObject(o, obj) {
+					continue
+				}
+				seen[id.Pos()] = true
+				out = append(out, l.location(id.Pos()))
+			}
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].File != out[j].File {
+			return out[i].File < out[j].File
+		}
+		if out[i].Line != out[j].Line {
+			return out[i].Line < out[j].Line
+		}
+		return out[i].Column < out[j].Column
+	})
+	truncated := len(out) > maxGoReferences
+	if truncated {
+		out = out[:maxGoReferences]
+	}
+	return out, truncated, nil
+}
+
+// sameGoObject matches objects by identity, and methods or fields of
+// instantiated generic types by their origin.
+func sameGoObject(a, b types.Object) bool {
+	if a == b {
+		return true
+	}
+	switch oa := a.(type) {
+	case *types.Func:
+		if ob, ok := b.(*types.Func); ok {
+			return oa.Origin() == ob.Origin()
+		}
+	case *types.Var:
+		if ob, ok := b.(*types.Var); ok {
+			return oa.Origin() == ob.Origin()
+		}
+	}
+	return false
+}
+
+// ExecuteGoSymbolTool runs the Go navigation tools from raw tool arguments.
+func ExecuteGoSymbolTool(ctx context.Context, name string, rawArgs string) (handled bool, output string) {
+	switch name {
+	case "go_list_symbols", "go_find_definition", "go_find_references", "go_show_function":
+	default:
+		return false, ""
+	}
+	args := map[string]any{}
+	if err := json.Unmarshal([]byte(rawArgs), &args); err != nil {
+		return true, fmt.Sprintf("Error: invalid arguments for %s: %v", name, err)
+	}
+	getStr := func(key string) string {
+		v, _ := args[key].(string)
+		return strings.TrimSpace(v)
+	}
+	getInt := func(key string) int {
+		switch v := args[key].(type) {
+		case float64:
+			return int(v)
+		case string:
+			n, _ := strconv.Atoi(strings.TrimSpace(v))
+			return n
+		}
+		return 0
+	}
+
+	target := getStr("path")
+	if target == "" {
+		return true, fmt.Sprintf("Error: %s requires a non-empty 'path' argument.", name)
+	}
+
+	switch name {
+	case "go_list_symbols":
+		exportedOnly, _ := args["exported_only"].(bool)
+		symbols, err := ListGoSymbols(target, exportedOnly)
+		if err != nil {
+			return true, fmt.Sprintf("Error: %v", err)
+		}
+		return true, formatGoSymbols(symbols)
+	case "go_show_function":
+		_, out, err := FindGoFunction(target, getStr("name"))
+		if err != nil {
+			return true, fmt.Sprintf("Error: %v", err)
+		}
+		return true, out
+	}
+
+	ident := getStr("name")
+	line := getInt("line")
+	if ident == "" || line <= 0 {
+		return true, fmt.Sprintf("Error: %s requires 'name' and a positive 'line'.", name)
+	}
+	if name == "go_find_definition" {
+		loc, snippet, err := FindGoDefinition(target, line, getInt("column"), ident)
+		if err != nil {
+			return true, fmt.Sprintf("Error: %v", err)
+		}
+		return true, fmt.Sprintf("%s is defined at %s:%d:%d\n%s", ident, loc.File, loc.Line, loc.Column, snippet)
+	}
+
+	refs, truncated, err := FindGoReferences(ctx, target, line, getInt("column"), ident)
+	if err != nil {
+		return true, fmt.Sprintf("Error: %v", err)
+	}
+	var b strings.Builder
+	for _, ref := range refs {
+		fmt.Fprintf(&b, "%s:%d:%d: %s\n", ref.File, ref.Line, ref.Column, ref.Text)
+	}
+	fmt.Fprintf(&b, "\n%d references to %s", len(refs), ident)
+	if truncated {
+		fmt.Fprintf(&b, " (truncated at %d)", maxGoReferences)
+	}
+	return true, b.String()
+}
+
+func formatGoSymbols(symbols []GoSymbol) string {
+	if len(symbols) == 0 {
+		return "No symbols found."
+	}
+	var b strings.Builder
+	lastFile := ""
+	for i, sym := range symbols {
+		if i >= maxGoSymbolsListed {
+			fmt.Fprintf(&b, "... %d more symbols; narrow 'path' to a single file\n", len(symbols)-i)
+			break
+		}
+		if sym.File != lastFile {
+			fmt.Fprintf(&b, "%s:\n", sym.File)
+			lastFile = sym.File
+		}
+		// Func and method signatures already start with "func".
+		detail := sym.Signature
+		if sym.Kind != "func" && sym.Kind != "method" {
+			detail = strings.TrimSpace(sym.Kind + " " + sym.Name + " " + sym.Signature)
+		}
+		fmt.Fprintf(&b, "  %d-%d %s\n", sym.StartLine, sym.EndLine, detail)
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
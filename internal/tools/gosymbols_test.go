@@ -0,0 +1,116 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeGoFixture(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	writeSearchFixture(t, root, map[string]string{
+		"go.mod": "module example.com/demo\n\ngo 1.22\n",
+		"store/store.go": `package store
+
+import "fmt"
+
+// Store keeps items.
+type Store struct {
+	items []string
+}
+
+// Add appends an item.
+func (s *Store) Add(item string) {
+	s.items = append(s.items, item)
+	fmt.Println(item)
+}
+
+func helper() int { return 1 }
+`,
+		"main.go": `package main
+
+import "example.com/demo/store"
+
+func main() {
+	s := &store.Store{}
+	s.Add("a")
+	s.Add("b")
+}
+
+type other struct{}
+
+func (other) Add(string) {}
+`,
+	})
+	return root
+}
+
+func TestListGoSymbolsAndShowFunction(t *testing.T) {
+	root := writeGoFixture(t)
+
+	symbols, err := ListGoSymbols(filepath.Join(root, "store"), false)
+	if err != nil {
+		t.Fatalf("ListGoSymbols: %v", err)
+	}
+	var names []string
+	for _, s := range symbols {
+		names = append(names, s.Kind+":"+s.QualifiedName())
+	}
+	if strings.Join(names, ",") != "type:Store,method:Store.Add,func:helper" {
+		t.Fatalf("unexpected symbols %v", names)
+	}
+	if symbols[1].StartLine != 10 || symbols[1].EndLine != 14 {
+		t.Fatalf("unexpected method range %d-%d", symbols[1].StartLine, symbols[1].EndLine)
+	}
+
+	_, out, err := FindGoFunction(filepath.Join(root, "store", "store.go"), "Store.Add")
+	if err != nil {
+		t.Fatalf("FindGoFunction: %v", err)
+	}
+	if !strings.HasPrefix(out, "10 | // Add appends an item.") || !strings.Contains(out, "14 | }") {
+		t.Fatalf("unexpected function source:\n%s", out)
+	}
+	sym, _, err := FindGoFunction(root, "Add")
+	if err != nil || sym.Receiver != "other" {
+		t.Fatalf("expected bare method name to resolve to other.Add, got %+v (%v)", sym, err)
+	}
+	if _, _, err := FindGoFunction(root, "Missing"); err == nil {
+		t.Fatal("expected unknown function to fail")
+	}
+}
+
+func TestFindGoDefinitionAndReferencesAcrossPackages(t *testing.T) {
+	root := writeGoFixture(t)
+	mainFile := filepath.Join(root, "main.go")
+
+	loc, snippet, err := FindGoDefinition(mainFile, 7, 0, "Add")
+	if err != nil {
+		t.Fatalf("FindGoDefinition: %v", err)
+	}
+	if filepath.Base(loc.File) != "store.go" || loc.Line != 11 {
+		t.Fatalf("unexpected definition %+v", loc)
+	}
+	if !strings.Contains(snippet, "func (s *Store) Add(item string)") {
+		t.Fatalf("unexpected snippet:\n%s", snippet)
+	}
+
+	refs, truncated, err := FindGoReferences(context.Background(), mainFile, 7, 0, "Add")
+	if err != nil {
+		t.Fatalf("FindGoReferences: %v", err)
+	}
+	var got []string
+	for _, ref := range refs {
+		got = append(got, fmt.Sprintf("%s:%d", filepath.Base(ref.File), ref.Line))
+	}
+	// other.Add in main.go shares the name but is a different method.
+	if truncated || strings.Join(got, ",") != "main.go:7,main.go:8,store.go:11" {
+		t.Fatalf("unexpected references %v", got)
+	}
+
+	if _, _, err := FindGoDefinition(mainFile, 7, 0, "missing"); err == nil {
+		t.Fatal("expected unknown identifier to fail")
+	}
+}
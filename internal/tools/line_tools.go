@@ -4,7 +4,6 @@ import (
 	"bufio"
 	"encoding/json"
 	"fmt"
-	"os"
 	"regexp"
 	"sort"
 	"strconv"
@@ -47,28 +46,31 @@ func parseRangeSpec(spec string) (LineRange, error) {
 	return LineRange{Start: line, End: line}, nil
 }
 
-func readTextLines(path string) ([]string, bool, error) {
-	content, err := os.ReadFile(path)
+// readTextLines decodes path and splits it into lines without their
+// endings. The returned format writes the lines back the way they were
+// stored.
+func readTextLines(path string) ([]string, textFormat, error) {
+	text, format, err := readTextFile(path)
 	if err != nil {
-		return nil, false, fmt.Errorf("failed to read file: %w", err)
+		return nil, format, err
 	}
-	hadTrailingNewline := strings.HasSuffix(string(content), "\n")
-	lines := strings.Split(string(content), "\n")
-	if len(lines) > 0 && lines[len(lines)-1] == "" {
-		lines = lines[:len(lines)-1]
+	if text == "" {
+		return []string{}, format, nil
 	}
-	return lines, hadTrailingNewline, nil
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n"), format, nil
 }
 
-func writeTextLines(path string, lines []string, hadTrailingNewline bool) error {
-	out := strings.Join(lines, "\n")
-	if hadTrailingNewline || len(lines) > 0 {
-		out += "\n"
-	}
-	if err := os.WriteFile(path, []byte(out), 0644); err != nil {
-		return fmt.Errorf("failed to write file: %w", err)
+func writeTextLines(path string, lines []string, format textFormat) error {
+	return format.writeText(path, format.joinLines(lines))
+}
+
+// withLineEndingNote appends the note about normalized line endings, if
+// any, to a line tool's result.
+func withLineEndingNote(msg, path string, format textFormat) string {
+	if note := format.lineEndingNote(path); note != "" {
+		return msg + "\nNote: " + note
 	}
-	return nil
+	return msg
 }
 
 func normalizeRange(start, end, max int) (int, int, error) {
@@ -91,7 +93,7 @@ func RemoveLineRanges(path string, ranges []LineRange) (string, error) {
 	if len(ranges) == 0 {
 		return "Error: at least one range is required.", nil
 	}
-	lines, hadTrailingNewline, err := readTextLines(path)
+	lines, format, err := readTextLines(path)
 	if err != nil {
 		return "", err
 	}
@@ -118,14 +120,14 @@ func RemoveLineRanges(path string, ranges []LineRange) (string, error) {
 		newLines = append(newLines, line)
 	}
 
-	if err := writeTextLines(path, newLines, hadTrailingNewline); err != nil {
+	if err := writeTextLines(path, newLines, format); err != nil {
 		return "", err
 	}
-	return fmt.Sprintf("Removed %d line(s) in %s.", len(del), path), nil
+	return withLineEndingNote(fmt.Sprintf("Removed %d line(s) in %s.", len(del), path), path, format), nil
 }
 
 func ReplaceLineRange(path string, start, end int, replacement string) (string, error) {
-	lines, hadTrailingNewline, err := readTextLines(path)
+	lines, format, err := readTextLines(path)
 	if err != nil {
 		return "", err
 	}
@@ -147,17 +149,17 @@ func ReplaceLineRange(path string, start, end int, replacement string) (string,
 		newLines = append(newLines, lines[end:]...)
 	}
 
-	if err := writeTextLines(path, newLines, hadTrailingNewline); err != nil {
+	if err := writeTextLines(path, newLines, format); err != nil {
 		return "", err
 	}
-	return fmt.Sprintf("Replaced lines %d-%d in %s.", start, end, path), nil
+	return withLineEndingNote(fmt.Sprintf("Replaced lines %d-%d in %s.", start, end, path), path, format), nil
 }
 
 func ApplyBatchLineOperations(path string, ops []BatchLineOperation) (string, error) {
 	if len(ops) == 0 {
 		return "Error: operations cannot be empty.", nil
 	}
-	lines, hadTrailingNewline, err := readTextLines(path)
+	lines, format, err := readTextLines(path)
 	if err != nil {
 		return "", err
 	}
@@ -230,17 +232,17 @@ func ApplyBatchLineOperations(path string, ops []BatchLineOperation) (string, er
 		}
 	}
 
-	if err := writeTextLines(path, lines, hadTrailingNewline); err != nil {
+	if err := writeTextLines(path, lines, format); err != nil {
 		return "", err
 	}
-	return fmt.Sprintf("Applied %d batch operation(s) to %s.", len(ops), path), nil
+	return withLineEndingNote(fmt.Sprintf("Applied %d batch operation(s) to %s.", len(ops), path), path, format), nil
 }
 
 func DeleteLinesByPattern(path, pattern string, caseSensitive bool) (string, error) {
 	if strings.TrimSpace(pattern) == "" {
 		return "Error: pattern is required.", nil
 	}
-	lines, hadTrailingNewline, err := readTextLines(path)
+	lines, format, err := readTextLines(path)
 	if err != nil {
 		return "", err
 	}
@@ -262,10 +264,10 @@ func DeleteLinesByPattern(path, pattern string, caseSensitive bool) (string, err
 		newLines = append(newLines, line)
 	}
 
-	if err := writeTextLines(path, newLines, hadTrailingNewline); err != nil {
+	if err := writeTextLines(path, newLines, format); err != nil {
 		return "", err
 	}
-	return fmt.Sprintf("Removed %d line(s) matching pattern in %s.", removed, path), nil
+	return withLineEndingNote(fmt.Sprintf("Removed %d line(s) matching pattern in %s.", removed, path), path, format), nil
 }
 
 func ExtractLineRange(path string, start, end int) (string, error) {
@@ -288,7 +290,7 @@ func ExtractLineRange(path string, start, end int) (string, error) {
 }
 
 func ReorderLineRange(path string, start, end, targetLine int) (string, error) {
-	lines, hadTrailingNewline, err := readTextLines(path)
+	lines, format, err := readTextLines(path)
 	if err != nil {
 		return "", err
 	}
@@ -322,14 +324,14 @@ func ReorderLineRange(path string, start, end, targetLine int) (string, error) {
 	reordered = append(reordered, block...)
 	reordered = append(reordered, rest[insertIdx:]...)
 
-	if err := writeTextLines(path, reordered, hadTrailingNewline); err != nil {
+	if err := writeTextLines(path, reordered, format); err != nil {
 		return "", err
 	}
-	return fmt.Sprintf("Moved lines %d-%d to before line %d in %s.", start, end, targetLine, path), nil
+	return withLineEndingNote(fmt.Sprintf("Moved lines %d-%d to before line %d in %s.", start, end, targetLine, path), path, format), nil
 }
 
 func RemoveDuplicateLines(path string, caseSensitive bool, ignoreBlank bool) (string, error) {
-	lines, hadTrailingNewline, err := readTextLines(path)
+	lines, format, err := readTextLines(path)
 	if err != nil {
 		return "", err
 	}
@@ -352,10 +354,10 @@ func RemoveDuplicateLines(path string, caseSensitive bool, ignoreBlank bool) (st
 		seen[key] = struct{}{}
 		result = append(result, line)
 	}
-	if err := writeTextLines(path, result, hadTrailingNewline); err != nil {
+	if err := writeTextLines(path, result, format); err != nil {
 		return "", err
 	}
-	return fmt.Sprintf("Removed %d duplicate line(s) from %s.", removed, path), nil
+	return withLineEndingNote(fmt.Sprintf("Removed %d duplicate line(s) from %s.", removed, path), path, format), nil
 }
 
 func ExecuteLineTool(name string, rawArgs string) (handled bool, output string) {
@@ -3,80 +3,45 @@ package tools
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
-	"flag"
 	"fmt"
-	"io"
 	"io/fs"
 	"os"
-	"os/signal"
 	"path/filepath"
 	"regexp"
 	"runtime"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 )
 
-func SearchFilesWrapper(ctx context.Context, cfg *Config) (string, error) {
-	// Redirect stdout to capture the results
-	old := os.Stdout
-	r, w, _ := os.Pipe()
-	os.Stdout = w
-
-	outC := make(chan string)
-	go func() {
-		var buf bytes.Buffer
-		io.Copy(&buf, r)
-		outC <- buf.String()
-	}()
-
-	// Execute original search
-	RunSearch(ctx, cfg)
-
-	// Restore and close
-	w.Close()
-	os.Stdout = old
-	return <-outC, nil
-}
-
-// ListTreeWrapper adapts the RunTreeGenerator logic for the AI Processor.
-func ListTreeWrapper(cfg *Config) (string, error) {
-	old := os.Stdout
-	r, w, _ := os.Pipe()
-	os.Stdout = w
-
-	outC := make(chan string)
-	go func() {
-		var buf bytes.Buffer
-		io.Copy(&buf, r)
-		outC <- buf.String()
-	}()
-
-	RunTreeGenerator(cfg)
-
-	w.Close()
-	os.Stdout = old
-	return <-outC, nil
-}
-
-// ======================================================================================
-// CONFIGURATION & CONSTANTS
-// ======================================================================================
-
 const (
-	ColorReset  = "\033[0m"
-	ColorRed    = "\033[31m"
-	ColorGreen  = "\033[32m"
-	ColorYellow = "\033[33m"
-	ColorBlue   = "\033[34m"
-	ColorCyan   = "\033[36m"
+	defaultSearchMaxResults  = 200
+	maxSearchResults         = 2000
+	defaultMaxSearchFileSize = 10 * 1024 * 1024
+	maxSearchContextLines    = 10
+	maxMatchLineChars        = 400
+	binarySniffBytes         = 8000
+
+	// defaultSearchTimeout bounds a single tool call so a huge tree cannot hold
+	// the REPL; the search returns what it found so far when it expires.
+	defaultSearchTimeout = 60 * time.Second
 )
 
-// Config holds all CLI arguments and compiled regexes
+var (
+	errContentTooLarge = errors.New("file too large for content search")
+	errBinaryFile      = errors.New("binary file")
+)
+
+// Config describes one search over a directory tree. Zero values mean "no
+// filter"; see normalize for the defaults applied before searching.
 type Config struct {
 	RootPath         string
+	Glob             string
 	Extensions       []string
 	IncludePathRegex []*regexp.Regexp
 	ExcludePathRegex []*regexp.Regexp
@@ -84,440 +49,737 @@ type Config struct {
 	ExcludeNameRegex []*regexp.Regexp
 	ContentRegex     *regexp.Regexp
 	ContentInclude   bool
-	ShowTree         bool
+	ContextLines     int
+	MaxResults       int
+	MaxFileSize      int64
+	RespectGitignore bool
+	IncludeHidden    bool
 	WorkerCount      int
-	Verbose          bool
 }
 
-// Global buffer pool to reduce GC pressure during content reading
-var bufferPool = sync.Pool{
-	New: func() interface{} {
-		// Allocate 32KB buffers
-		return make([]byte, 32*1024)
-	},
+// FileMatch is one file returned by FindFiles.
+type FileMatch struct {
+	Path string `json:"path"`
+	Size int64  `json:"size"`
 }
 
-// ======================================================================================
-// MAIN ENTRY POINT
-// ======================================================================================
-
-// func main() {
-// 	// 1. Parse CLI Flags
-// 	config := parseFlags()
-
-// 	// 2. Setup Signal Handling (Ctrl+C)
-// 	ctx, cancel := context.WithCancel(context.Background())
-// 	defer cancel()
-// 	setupSignalHandler(cancel)
-
-// 	// 3. Mode Selection
-// 	if config.ShowTree {
-// 		runTreeGenerator(config)
-// 	} else {
-// 		runSearch(ctx, config)
-// 	}
-// }
-
-// ======================================================================================
-// FLAG PARSING & SETUP
-// ======================================================================================
-
-func ParseFlags() *Config {
-	rootDir := flag.String("dir", ".", "Directory to search")
-	exts := flag.String("ext", "", "Comma-separated extensions (e.g. .go,.dart,.json)")
-	incPath := flag.String("inc-path", "", "Include path patterns (wildcards allowed: src/*)")
-	excPath := flag.String("exc-path", "", "Exclude path patterns (wildcards allowed: .git/*)")
-	incName := flag.String("inc-name", "", "Include filename patterns (wildcards allowed: *_test.go)")
-	excName := flag.String("exc-name", "", "Exclude filename patterns")
-	content := flag.String("content", "", "Regex content filter")
-	contentExc := flag.Bool("content-exclude", false, "Exclude files matching content regex instead of including")
-	tree := flag.Bool("tree", false, "Generate file tree instead of searching")
-	workers := flag.Int("workers", runtime.NumCPU(), "Number of concurrent workers")
-	verbose := flag.Bool("v", false, "Verbose output")
-
-	flag.Parse()
-
-	absRoot, err := filepath.Abs(*rootDir)
-	if err != nil {
-		fatalf("Failed to resolve absolute path: %v", err)
-	}
-
-	// Parse Extensions
-	var extList []string
-	if *exts != "" {
-		parts := strings.Split(*exts, ",")
-		for _, p := range parts {
-			ext := strings.TrimSpace(p)
-			if !strings.HasPrefix(ext, ".") {
-				ext = "." + ext
-			}
-			extList = append(extList, strings.ToLower(ext))
-		}
-	}
-
-	cfg := &Config{
-		RootPath:       absRoot,
-		Extensions:     extList,
-		ContentInclude: !*contentExc,
-		ShowTree:       *tree,
-		WorkerCount:    *workers,
-		Verbose:        *verbose,
-	}
-
-	// Compile Regex Patterns
-	cfg.IncludePathRegex = compilePatterns(*incPath)
-	cfg.ExcludePathRegex = compilePatterns(*excPath)
-	cfg.IncludeNameRegex = compilePatterns(*incName)
-	cfg.ExcludeNameRegex = compilePatterns(*excName)
-
-	if *content != "" {
-		re, err := regexp.Compile(*content) // Use standard regex for content
-		if err != nil {
-			fatalf("Invalid content regex: %v", err)
-		}
-		cfg.ContentRegex = re
-	}
+// LineMatch is one matching line returned by GrepCode, with optional context.
+type LineMatch struct {
+	Path   string   `json:"path"`
+	Line   int      `json:"line"`
+	Text   string   `json:"text"`
+	Before []string `json:"before,omitempty"`
+	After  []string `json:"after,omitempty"`
+}
 
-	return cfg
+// SearchStats describes how much of the tree a search covered.
+type SearchStats struct {
+	FilesScanned  int    `json:"files_scanned"`
+	SkippedLarge  int    `json:"skipped_large,omitempty"`
+	SkippedBinary int    `json:"skipped_binary,omitempty"`
+	Truncated     bool   `json:"truncated,omitempty"`
+	TimedOut      bool   `json:"timed_out,omitempty"`
+	Elapsed       string `json:"elapsed"`
 }
 
-func SetupSignalHandler(cancel context.CancelFunc) {
-	c := make(chan os.Signal, 1)
-	signal.Notify(c, os.Interrupt)
-	go func() {
-		<-c
-		fmt.Println("\n" + ColorRed + "Cancelling operation..." + ColorReset)
-		cancel()
-	}()
+// FindResult is the result of FindFiles. Paths are relative to Root.
+type FindResult struct {
+	Root  string      `json:"root"`
+	Files []FileMatch `json:"files"`
+	SearchStats
 }
 
-func CompilePatterns(input string) []*regexp.Regexp {
-	return compilePatterns(input)
+// GrepResult is the result of GrepCode. Paths are relative to Root.
+type GrepResult struct {
+	Root         string      `json:"root"`
+	Matches      []LineMatch `json:"matches"`
+	FilesMatched int         `json:"files_matched"`
+	SearchStats
 }
 
-// ======================================================================================
-// PATTERN MATCHING ENGINE
-// ======================================================================================
+type searchJob struct {
+	path string
+	rel  string
+	size int64
+}
 
-// compilePatterns replicates the Dart logic:
-// 1. Escape regex chars
-// 2. Replace '*' with '.*'
-// 3. Handle path prefixes/suffixes
-func compilePatterns(input string) []*regexp.Regexp {
-	if strings.TrimSpace(input) == "" {
-		return nil
+// FindFiles walks cfg.RootPath and returns the files that pass every filter.
+// When cfg.ContentRegex is set, files are kept (or dropped when
+// ContentInclude is false) based on whether their content matches. The walk
+// does not stop at cfg.MaxResults, so a truncated result is always the first
+// MaxResults paths in sorted order, unless the search timed out.
+func FindFiles(ctx context.Context, cfg Config) (*FindResult, error) {
+	if err := cfg.normalize(); err != nil {
+		return nil, err
 	}
-	var results []*regexp.Regexp
-	parts := strings.Split(input, ",")
-	for _, p := range parts {
-		pattern := strings.TrimSpace(p)
-		if pattern == "" {
-			continue
+	res := &FindResult{Root: cfg.RootPath}
+	var mu sync.Mutex
+
+	stats, err := runSearch(ctx, &cfg, func(job searchJob, stats *searchCounters) int {
+		if cfg.ContentRegex != nil {
+			data, err := readSearchFile(job.path, cfg.MaxFileSize)
+			if err != nil {
+				stats.skipped(err)
+				return 0
+			}
+			if cfg.ContentRegex.Match(data) != cfg.ContentInclude {
+				return 0
+			}
 		}
+		mu.Lock()
+		res.Files = append(res.Files, FileMatch{Path: job.rel, Size: job.size})
+		mu.Unlock()
+		// Report no results so runSearch never stops early; which files
+		// the workers reach first varies between runs.
+		return 0
+	})
+	sort.Slice(res.Files, func(i, j int) bool { return res.Files[i].Path < res.Files[j].Path })
+	if len(res.Files) > cfg.MaxResults {
+		res.Files = res.Files[:cfg.MaxResults]
+		stats.Truncated = true
+	}
+	res.SearchStats = stats
+	return res, err
+}
 
-		// Escape special regex characters first
-		regexStr := regexp.QuoteMeta(pattern)
+// GrepCode returns the lines matching cfg.ContentRegex, with up to
+// cfg.ContextLines lines of context on each side.
+func GrepCode(ctx context.Context, cfg Config) (*GrepResult, error) {
+	if cfg.ContentRegex == nil {
+		return nil, fmt.Errorf("a content pattern is required")
+	}
+	if err := cfg.normalize(); err != nil {
+		return nil, err
+	}
+	res := &GrepResult{Root: cfg.RootPath}
+	var mu sync.Mutex
 
-		// Dart logic: If starts with /, anchor start. If ends with /, anchor end.
-		// Note: We use filepath separator handling in Go, so we normalize.
-		if strings.HasPrefix(pattern, "/") || strings.HasPrefix(pattern, "\\") {
-			regexStr = "^" + regexStr
+	stats, err := runSearch(ctx, &cfg, func(job searchJob, stats *searchCounters) int {
+		data, err := readSearchFile(job.path, cfg.MaxFileSize)
+		if err != nil {
+			stats.skipped(err)
+			return 0
 		}
-		if strings.HasSuffix(pattern, "/") || strings.HasSuffix(pattern, "\\") {
-			regexStr = regexStr + "$"
+		matches := grepLines(job.rel, data, cfg.ContentRegex, cfg.ContextLines, cfg.MaxResults)
+		if len(matches) == 0 {
+			return 0
 		}
+		mu.Lock()
+		res.Matches = append(res.Matches, matches...)
+		res.FilesMatched++
+		mu.Unlock()
+		return len(matches)
+	})
+	sort.SliceStable(res.Matches, func(i, j int) bool {
+		if res.Matches[i].Path != res.Matches[j].Path {
+			return res.Matches[i].Path < res.Matches[j].Path
+		}
+		return res.Matches[i].Line < res.Matches[j].Line
+	})
+	if len(res.Matches) > cfg.MaxResults {
+		res.Matches = res.Matches[:cfg.MaxResults]
+		stats.Truncated = true
+	}
+	res.SearchStats = stats
+	return res, err
+}
 
-		// Un-escape the '*' we just escaped, and turn it into '.*'
-		// QuoteMeta turns '*' into '\*'
-		regexStr = strings.ReplaceAll(regexStr, "\\*", ".*")
+func (cfg *Config) normalize() error {
+	root := strings.TrimSpace(cfg.RootPath)
+	if root == "" {
+		root = "."
+	}
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return fmt.Errorf("failed to resolve search path: %w", err)
+	}
+	info, err := os.Stat(abs)
+	if err != nil {
+		return fmt.Errorf("search path not found: %w", err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("search path is not a directory: %s", abs)
+	}
+	cfg.RootPath = abs
 
-		re, err := regexp.Compile("(?i)" + regexStr) // Case insensitive
-		if err != nil {
-			fmt.Printf(ColorYellow+"Warning: Invalid pattern '%s': %v\n"+ColorReset, pattern, err)
+	exts := cfg.Extensions[:0:0]
+	for _, ext := range cfg.Extensions {
+		ext = strings.ToLower(strings.TrimSpace(ext))
+		if ext == "" {
 			continue
 		}
-		results = append(results, re)
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		exts = append(exts, ext)
 	}
-	return results
-}
+	cfg.Extensions = exts
 
-func matchesAny(s string, regexes []*regexp.Regexp) bool {
-	for _, re := range regexes {
-		if re.MatchString(s) {
-			return true
-		}
+	if cfg.MaxResults <= 0 {
+		cfg.MaxResults = defaultSearchMaxResults
 	}
-	return false
+	cfg.MaxResults = min(cfg.MaxResults, maxSearchResults)
+	cfg.ContextLines = max(0, min(cfg.ContextLines, maxSearchContextLines))
+	if cfg.MaxFileSize <= 0 {
+		cfg.MaxFileSize = defaultMaxSearchFileSize
+	}
+	if cfg.WorkerCount <= 0 {
+		cfg.WorkerCount = runtime.NumCPU()
+	}
+	return nil
 }
 
-// ======================================================================================
-// SEARCH LOGIC (CONCURRENT PIPELINE)
-// ======================================================================================
+// searchCounters are shared by the workers of one search.
+type searchCounters struct {
+	scanned       atomic.Int64
+	skippedLarge  atomic.Int64
+	skippedBinary atomic.Int64
+}
 
-func RunSearch(ctx context.Context, cfg *Config) {
-	start := time.Now()
-	fmt.Printf(ColorBlue+"Starting search in: %s\n"+ColorReset, cfg.RootPath)
-	if len(cfg.Extensions) > 0 {
-		fmt.Printf("Extensions: %v\n", cfg.Extensions)
+func (c *searchCounters) skipped(err error) {
+	switch {
+	case errors.Is(err, errContentTooLarge):
+		c.skippedLarge.Add(1)
+	case errors.Is(err, errBinaryFile):
+		c.skippedBinary.Add(1)
 	}
+}
 
-	pathsCh := make(chan string, 1000)
-	resultsCh := make(chan string, 1000)
-	var wg sync.WaitGroup
-	var skippedLargeFiles int64
+// runSearch walks the tree on one goroutine and hands candidate files to a
+// worker pool. work returns how many results it produced; the search stops
+// early once cfg.MaxResults is reached or ctx is done. Cancellation of ctx by
+// the caller is reported as an error; hitting the limit is not.
+func runSearch(ctx context.Context, cfg *Config, work func(searchJob, *searchCounters) int) (SearchStats, error) {
+	start := time.Now()
+	searchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var counters searchCounters
+	var found atomic.Int64
+	var limited atomic.Bool
 
-	// 1. Worker Pool
+	jobs := make(chan searchJob, 256)
+	var wg sync.WaitGroup
 	for i := 0; i < cfg.WorkerCount; i++ {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			worker(ctx, cfg, pathsCh, resultsCh, &skippedLargeFiles)
-		}()
-	}
-
-	// 2. Result Collector (separate goroutine)
-	totalFound := 0
-	doneCh := make(chan struct{})
-	go func() {
-		for res := range resultsCh {
-			fmt.Println(res)
-			totalFound++
-		}
-		close(doneCh)
-	}()
-
-	// 3. File Walker (Producer)
-	go func() {
-		defer close(pathsCh)
-		err := filepath.WalkDir(cfg.RootPath, func(path string, d fs.DirEntry, err error) error {
-			if err != nil {
-				if cfg.Verbose {
-					fmt.Fprintf(os.Stderr, "Access error: %v\n", err)
+			for job := range jobs {
+				if searchCtx.Err() != nil {
+					continue
 				}
-				return nil // Continue walking
-			}
-			if ctx.Err() != nil {
-				return filepath.SkipAll
-			}
-
-			normalizedPath := filepath.ToSlash(path)
-
-			// --- Directory Filtering ---
-			if d.IsDir() {
-				// If we have path exclude patterns, we might want to skip entire dirs
-				// This matches the Flutter logic "if entity is Directory ... continue"
-				if len(cfg.ExcludePathRegex) > 0 && matchesAny(normalizedPath, cfg.ExcludePathRegex) {
-					return filepath.SkipDir
+				counters.scanned.Add(1)
+				if n := work(job, &counters); n > 0 && found.Add(int64(n)) >= int64(cfg.MaxResults) {
+					limited.Store(true)
+					cancel()
 				}
-				return nil
-			}
-
-			// --- Path Filtering (Pre-check) ---
-			// Check excludes first
-			if len(cfg.ExcludePathRegex) > 0 && matchesAny(normalizedPath, cfg.ExcludePathRegex) {
-				return nil
-			}
-			// Check includes (if defined)
-			if len(cfg.IncludePathRegex) > 0 && !matchesAny(normalizedPath, cfg.IncludePathRegex) {
-				return nil
 			}
+		}()
+	}
 
-			pathsCh <- path
-			return nil
-		})
-		if err != nil {
-			fmt.Fprintf(os.Stderr, ColorRed+"Walk error: %v\n"+ColorReset, err)
-		}
-	}()
-
-	wg.Wait()        // Wait for workers
-	close(resultsCh) // Signal collector
-	<-doneCh         // Wait for collector
+	walkErr := walkSearchTree(searchCtx, cfg, jobs)
+	close(jobs)
+	wg.Wait()
 
-	fmt.Printf(ColorGreen+"\nSearch completed in %v. Found %d files.\n"+ColorReset, time.Since(start), totalFound)
-	if skippedLargeFiles > 0 {
-		fmt.Printf(ColorYellow+"Warning: Skipped %d files larger than 10MB during content search.\n"+ColorReset, skippedLargeFiles)
+	stats := SearchStats{
+		FilesScanned:  int(counters.scanned.Load()),
+		SkippedLarge:  int(counters.skippedLarge.Load()),
+		SkippedBinary: int(counters.skippedBinary.Load()),
+		Truncated:     limited.Load(),
+		Elapsed:       time.Since(start).Round(time.Millisecond).String(),
 	}
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		stats.TimedOut = true
+		stats.Truncated = true
+		return stats, nil
+	case ctx.Err() != nil:
+		return stats, ctx.Err()
+	}
+	return stats, walkErr
 }
 
-func worker(ctx context.Context, cfg *Config, jobs <-chan string, results chan<- string, skippedLargeFiles *int64) {
-	for path := range jobs {
-		if ctx.Err() != nil {
-			return
+func walkSearchTree(ctx context.Context, cfg *Config, jobs chan<- searchJob) error {
+	var ignore *ignoreMatcher
+	if cfg.RespectGitignore {
+		ignore = newIgnoreMatcher(cfg.RootPath)
+	}
+	var globRe *regexp.Regexp
+	globOnPath := false
+	if strings.TrimSpace(cfg.Glob) != "" {
+		re, onPath, err := compileGlob(cfg.Glob)
+		if err != nil {
+			return fmt.Errorf("invalid glob %q: %w", cfg.Glob, err)
 		}
+		globRe, globOnPath = re, onPath
+	}
 
-		info, err := os.Stat(path)
+	err := filepath.WalkDir(cfg.RootPath, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
-			continue
+			return nil // unreadable entries are skipped, not fatal
+		}
+		if ctx.Err() != nil {
+			return filepath.SkipAll
+		}
+		if path == cfg.RootPath {
+			if ignore != nil {
+				ignore.loadDir(path)
+			}
+			return nil
 		}
 
-		fileName := info.Name()
-		ext := strings.ToLower(filepath.Ext(fileName))
+		rel, _ := filepath.Rel(cfg.RootPath, path)
+		rel = filepath.ToSlash(rel)
+		name := d.Name()
 
-		// 1. Extension Filter
-		if len(cfg.Extensions) > 0 {
-			found := false
-			for _, allowed := range cfg.Extensions {
-				if allowed == ext {
-					found = true
-					break
-				}
+		if d.IsDir() {
+			if name == ".git" || (!cfg.IncludeHidden && strings.HasPrefix(name, ".")) {
+				return filepath.SkipDir
+			}
+			if ignore.ignored(rel, true) || matchesAny("/"+rel+"/", cfg.ExcludePathRegex) {
+				return filepath.SkipDir
 			}
-			if !found {
-				continue
+			if ignore != nil {
+				ignore.loadDir(path)
 			}
+			return nil
 		}
-
-		// 2. Filename Pattern Filter
-		if len(cfg.ExcludeNameRegex) > 0 && matchesAny(fileName, cfg.ExcludeNameRegex) {
-			continue
+		if !d.Type().IsRegular() {
+			return nil
 		}
-		if len(cfg.IncludeNameRegex) > 0 && !matchesAny(fileName, cfg.IncludeNameRegex) {
-			continue
+		if !cfg.IncludeHidden && strings.HasPrefix(name, ".") {
+			return nil
+		}
+		if ignore.ignored(rel, false) || !cfg.matchesFile(rel, name, globRe, globOnPath) {
+			return nil
 		}
 
-		// 3. Content Filter (Heavy I/O)
-		if cfg.ContentRegex != nil {
-			matched, err := CheckFileContent(path, cfg.ContentRegex)
-			if err != nil {
-				if errors.Is(err, errContentTooLarge) {
-					atomic.AddInt64(skippedLargeFiles, 1)
-					continue
-				}
-				if cfg.Verbose {
-					fmt.Fprintf(os.Stderr, "Read error %s: %v\n", path, err)
-				}
-				continue
-			}
-
-			// Logic:
-			// If IncludeMode: matched=true -> Keep
-			// If ExcludeMode: matched=true -> Drop
-			shouldKeep := false
-			if cfg.ContentInclude {
-				shouldKeep = matched
-			} else {
-				shouldKeep = !matched
-			}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		select {
+		case jobs <- searchJob{path: path, rel: rel, size: info.Size()}:
+		case <-ctx.Done():
+			return filepath.SkipAll
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("walk failed: %w", err)
+	}
+	return nil
+}
 
-			if !shouldKeep {
-				continue
+func (cfg *Config) matchesFile(rel, name string, globRe *regexp.Regexp, globOnPath bool) bool {
+	if len(cfg.Extensions) > 0 {
+		ext := strings.ToLower(filepath.Ext(name))
+		found := false
+		for _, allowed := range cfg.Extensions {
+			if allowed == ext {
+				found = true
+				break
 			}
 		}
-
-		// Format output (relative path for cleaner display)
-		relPath, _ := filepath.Rel(cfg.RootPath, path)
-		results <- fmt.Sprintf("%s%s%s (%s)", ColorCyan, relPath, ColorReset, ReadableSize(info.Size()))
+		if !found {
+			return false
+		}
+	}
+	if globRe != nil {
+		target := name
+		if globOnPath {
+			target = rel
+		}
+		if !globRe.MatchString(target) {
+			return false
+		}
 	}
+	slashed := "/" + rel
+	if matchesAny(slashed, cfg.ExcludePathRegex) {
+		return false
+	}
+	if len(cfg.IncludePathRegex) > 0 && !matchesAny(slashed, cfg.IncludePathRegex) {
+		return false
+	}
+	if matchesAny(name, cfg.ExcludeNameRegex) {
+		return false
+	}
+	if len(cfg.IncludeNameRegex) > 0 && !matchesAny(name, cfg.IncludeNameRegex) {
+		return false
+	}
+	return true
 }
 
-// checkFileContent reads file using buffer pool to minimize allocations.
-// It stops reading as soon as a match is found (if in include mode).
-func CheckFileContent(path string, re *regexp.Regexp) (bool, error) {
-	f, err := os.Open(path)
+// readSearchFile reads a file for content matching, refusing files over
+// maxSize and files that look binary.
+func readSearchFile(path string, maxSize int64) ([]byte, error) {
+	info, err := os.Stat(path)
 	if err != nil {
-		return false, err
+		return nil, err
 	}
-	defer f.Close()
-
-	// Use buffered reader
-	buf := bufferPool.Get().([]byte)
-	defer bufferPool.Put(buf)
-
-	// Since regexp works on streams/bytes, we can scan the file.
-	// However, standard regexp doesn't support io.Reader natively in a streaming fashion nicely for "Any Match".
-	// For large files, reading chunk by chunk might miss matches crossing buffer boundaries.
-	// For "0% bugs" correctness, we should read the whole file or use a sliding window.
-	// Given typical source code files, reading fully is acceptable, but let's be memory safe and cap it.
-	// Limitation: Files > 10MB are skipped for content search to prevent OOM in massive repos.
+	if info.Size() > maxSize {
+		return nil, errContentTooLarge
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.IndexByte(data[:min(len(data), binarySniffBytes)], 0) != -1 {
+		return nil, errBinaryFile
+	}
+	return data, nil
+}
 
-	stat, _ := f.Stat()
-	if stat.Size() > 10*1024*1024 { // 10MB limit
-		return false, errContentTooLarge
+func grepLines(rel string, data []byte, re *regexp.Regexp, contextLines, limit int) []LineMatch {
+	lines := strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	if len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
 	}
 
-	// Read full content (safe for source code)
-	// We use the bufferPool buffer if it fits, otherwise ReadAll
-	var data []byte
-	if stat.Size() <= int64(len(buf)) {
-		n, err := f.Read(buf)
-		if err != nil && err.Error() != "EOF" {
-			return false, err
+	var out []LineMatch
+	for i, line := range lines {
+		if !re.MatchString(line) {
+			continue
 		}
-		data = buf[:n]
-	} else {
-		// Fallback for larger files
-		data, err = os.ReadFile(path)
-		if err != nil {
-			return false, err
+		m := LineMatch{Path: rel, Line: i + 1, Text: clipSearchLine(line)}
+		for j := max(0, i-contextLines); j < i; j++ {
+			m.Before = append(m.Before, clipSearchLine(lines[j]))
+		}
+		for j := i + 1; j <= min(len(lines)-1, i+contextLines); j++ {
+			m.After = append(m.After, clipSearchLine(lines[j]))
+		}
+		out = append(out, m)
+		if len(out) >= limit {
+			break
 		}
 	}
+	return out
+}
 
-	return re.Match(data), nil
+func clipSearchLine(line string) string {
+	return truncateRunes(line, maxMatchLineChars)
 }
 
-var errContentTooLarge = errors.New("file too large for content search")
+// CompilePatterns compiles a comma-separated list of wildcard patterns.
+func CompilePatterns(input string) []*regexp.Regexp {
+	return compilePatterns(input)
+}
+
+// compilePatterns turns comma-separated wildcard patterns into
+// case-insensitive regexes: '*' matches anything, a leading '/' anchors at the
+// start and a trailing '/' anchors at the end.
+func compilePatterns(input string) []*regexp.Regexp {
+	if strings.TrimSpace(input) == "" {
+		return nil
+	}
+	var results []*regexp.Regexp
+	for _, p := range strings.Split(input, ",") {
+		pattern := strings.TrimSpace(p)
+		if pattern == "" {
+			continue
+		}
+		regexStr := regexp.QuoteMeta(filepath.ToSlash(pattern))
+		if strings.HasPrefix(pattern, "/") || strings.HasPrefix(pattern, "\\") {
+			regexStr = "^" + regexStr
+		}
+		if strings.HasSuffix(pattern, "/") || strings.HasSuffix(pattern, "\\") {
+			regexStr = regexStr + "$"
+		}
+		regexStr = strings.ReplaceAll(regexStr, "\\*", ".*")
+		// QuoteMeta output is always a valid expression.
+		results = append(results, regexp.MustCompile("(?i)"+regexStr))
+	}
+	return results
+}
 
-// ======================================================================================
-// TREE GENERATOR
-// ======================================================================================
+func matchesAny(s string, regexes []*regexp.Regexp) bool {
+	for _, re := range regexes {
+		if re.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
 
-func RunTreeGenerator(cfg *Config) {
-	fmt.Printf(ColorBlue+"Generating File Tree for: %s\n"+ColorReset, cfg.RootPath)
-	fmt.Println(".")
-	err := generateTree(cfg.RootPath, "", cfg)
-	if err != nil {
-		fatalf("Tree generation failed: %v", err)
+// BuildTree renders the directory tree under cfg.RootPath, honouring the same
+// hidden-file and .gitignore rules as FindFiles.
+func BuildTree(ctx context.Context, cfg Config) (string, error) {
+	if err := cfg.normalize(); err != nil {
+		return "", err
+	}
+	var ignore *ignoreMatcher
+	if cfg.RespectGitignore {
+		ignore = newIgnoreMatcher(cfg.RootPath)
+	}
+	var b strings.Builder
+	b.WriteString(".\n")
+	if err := generateTree(ctx, &b, cfg.RootPath, "", &cfg, ignore); err != nil {
+		return b.String(), err
 	}
+	return strings.TrimRight(b.String(), "\n"), nil
 }
 
-func generateTree(dir string, prefix string, cfg *Config) error {
+func generateTree(ctx context.Context, b *strings.Builder, dir, prefix string, cfg *Config, ignore *ignoreMatcher) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if ignore != nil {
+		ignore.loadDir(dir)
+	}
 	entries, err := os.ReadDir(dir)
 	if err != nil {
 		return err
 	}
 
-	// Filter entries before processing to know which is "last"
 	var filtered []fs.DirEntry
 	for _, e := range entries {
-		// Basic visibility filter (skip dotfiles unless explicit? Keeping simple for now)
+		name := e.Name()
+		if name == ".git" || (!cfg.IncludeHidden && strings.HasPrefix(name, ".")) {
+			continue
+		}
+		rel, _ := filepath.Rel(cfg.RootPath, filepath.Join(dir, name))
+		if ignore.ignored(filepath.ToSlash(rel), e.IsDir()) {
+			continue
+		}
 		filtered = append(filtered, e)
 	}
 
 	for i, e := range filtered {
 		isLast := i == len(filtered)-1
-
-		connector := "├── "
+		connector, extension := "├── ", "│   "
 		if isLast {
-			connector = "└── "
+			connector, extension = "└── ", "    "
 		}
-
 		name := e.Name()
-		displayName := name
 		if e.IsDir() {
-			displayName = ColorBlue + name + ColorReset
-		} else {
-			displayName = ColorGreen + name + ColorReset
+			name += "/"
 		}
-
-		fmt.Printf("%s%s%s\n", prefix, connector, displayName)
-
+		fmt.Fprintf(b, "%s%s%s\n", prefix, connector, name)
 		if e.IsDir() {
-			extension := "│   "
-			if isLast {
-				extension = "    "
+			if err := generateTree(ctx, b, filepath.Join(dir, e.Name()), prefix+extension, cfg, ignore); err != nil && ctx.Err() != nil {
+				return err
 			}
-			// Recursive call
-			_ = generateTree(filepath.Join(dir, name), prefix+extension, cfg)
 		}
 	}
 	return nil
 }
 
-// ======================================================================================
-// UTILS
-// ======================================================================================
+// FormatFindResult renders a FindResult as plain text for the model.
+func FormatFindResult(res *FindResult) string {
+	var b strings.Builder
+	for _, f := range res.Files {
+		fmt.Fprintf(&b, "%s (%s)\n", f.Path, ReadableSize(f.Size))
+	}
+	if len(res.Files) == 0 {
+		b.WriteString("No files matched.\n")
+	}
+	fmt.Fprintf(&b, "\n%d files under %s (scanned %d in %s)", len(res.Files), res.Root, res.FilesScanned, res.Elapsed)
+	b.WriteString(searchNotes(res.SearchStats))
+	return b.String()
+}
+
+// FormatGrepResult renders a GrepResult in grep style: "path:line:text" for
+// matches and "path-line-text" for context, with "--" between groups.
+func FormatGrepResult(res *GrepResult) string {
+	type outLine struct {
+		text  string
+		match bool
+	}
+	var b strings.Builder
+	flush := func(path string, lines map[int]outLine) {
+		nums := make([]int, 0, len(lines))
+		for n := range lines {
+			nums = append(nums, n)
+		}
+		sort.Ints(nums)
+		for i, n := range nums {
+			if i > 0 && n > nums[i-1]+1 {
+				b.WriteString("--\n")
+			}
+			sep := "-"
+			if lines[n].match {
+				sep = ":"
+			}
+			fmt.Fprintf(&b, "%s%s%d%s%s\n", path, sep, n, sep, lines[n].text)
+		}
+	}
+
+	// Matches are sorted by path and line, so each file is one group and
+	// overlapping context is printed once.
+	var path string
+	lines := map[int]outLine{}
+	for _, m := range res.Matches {
+		if m.Path != path {
+			if path != "" {
+				flush(path, lines)
+				b.WriteString("--\n")
+			}
+			path, lines = m.Path, map[int]outLine{}
+		}
+		for i, text := range m.Before {
+			if n := m.Line - len(m.Before) + i; !lines[n].match {
+				lines[n] = outLine{text: text}
+			}
+		}
+		lines[m.Line] = outLine{text: m.Text, match: true}
+		for i, text := range m.After {
+			if n := m.Line + 1 + i; !lines[n].match {
+				lines[n] = outLine{text: text}
+			}
+		}
+	}
+	if path != "" {
+		flush(path, lines)
+	}
+	if len(res.Matches) == 0 {
+		b.WriteString("No matches.\n")
+	}
+	fmt.Fprintf(&b, "\n%d matches in %d files under %s (scanned %d in %s)", len(res.Matches), res.FilesMatched, res.Root, res.FilesScanned, res.Elapsed)
+	b.WriteString(searchNotes(res.SearchStats))
+	return b.String()
+}
+
+func searchNotes(stats SearchStats) string {
+	var notes []string
+	if stats.TimedOut {
+		notes = append(notes, "search timed out; results are partial")
+	} else if stats.Truncated {
+		notes = append(notes, "results truncated at max_results; narrow the search")
+	}
+	if stats.SkippedLarge > 0 {
+		notes = append(notes, fmt.Sprintf("skipped %d large files", stats.SkippedLarge))
+	}
+	if stats.SkippedBinary > 0 {
+		notes = append(notes, fmt.Sprintf("skipped %d binary files", stats.SkippedBinary))
+	}
+	if len(notes) == 0 {
+		return ""
+	}
+	return "\nNote: " + strings.Join(notes, "; ")
+}
 
+// ExecuteSearchTool runs find_files or grep_code from raw tool arguments.
+func ExecuteSearchTool(ctx context.Context, name string, rawArgs string) (handled bool, output string) {
+	if name != "find_files" && name != "grep_code" {
+		return false, ""
+	}
+	args := map[string]any{}
+	if err := json.Unmarshal([]byte(rawArgs), &args); err != nil {
+		return true, fmt.Sprintf("Error: invalid arguments for %s: %v", name, err)
+	}
+
+	getStr := func(key string) string {
+		v, _ := args[key].(string)
+		return strings.TrimSpace(v)
+	}
+	getBool := func(key string) bool {
+		switch v := args[key].(type) {
+		case bool:
+			return v
+		case string:
+			b, _ := strconv.ParseBool(strings.TrimSpace(v))
+			return b
+		}
+		return false
+	}
+	getInt := func(key string) int {
+		switch v := args[key].(type) {
+		case float64:
+			return int(v)
+		case string:
+			n, _ := strconv.Atoi(strings.TrimSpace(v))
+			return n
+		}
+		return 0
+	}
+	getList := func(key string) []string {
+		switch v := args[key].(type) {
+		case []any:
+			out := make([]string, 0, len(v))
+			for _, item := range v {
+				if s, ok := item.(string); ok {
+					out = append(out, s)
+				}
+			}
+			return out
+		case string:
+			return strings.Split(v, ",")
+		}
+		return nil
+	}
+
+	cfg := Config{
+		RootPath:         getStr("path"),
+		Glob:             getStr("glob"),
+		Extensions:       getList("extensions"),
+		IncludePathRegex: compilePatterns(getStr("include_paths")),
+		ExcludePathRegex: compilePatterns(getStr("exclude_paths")),
+		ContentInclude:   true,
+		ContextLines:     getInt("context_lines"),
+		MaxResults:       getInt("max_results"),
+		RespectGitignore: !getBool("no_ignore"),
+		IncludeHidden:    getBool("include_hidden"),
+	}
+	pattern := getStr("pattern")
+	if name == "find_files" {
+		pattern = getStr("contains")
+	}
+	if pattern != "" {
+		if getBool("ignore_case") {
+			pattern = "(?i)" + pattern
+		}
+		if name == "find_files" {
+			// Whole-file match; let ^ and $ anchor at line boundaries as in grep_code.
+			pattern = "(?m)" + pattern
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return true, fmt.Sprintf("Error: invalid regex %q: %v", pattern, err)
+		}
+		cfg.ContentRegex = re
+	} else if name == "grep_code" {
+		return true, "Error: grep_code requires a non-empty 'pattern' argument."
+	}
+
+	searchCtx, cancel := context.WithTimeout(ctx, defaultSearchTimeout)
+	defer cancel()
+	asJSON := strings.EqualFold(getStr("format"), "json")
+
+	var (
+		result any
+		text   string
+		err    error
+	)
+	if name == "find_files" {
+		var res *FindResult
+		res, err = FindFiles(searchCtx, cfg)
+		if res != nil {
+			result, text = res, FormatFindResult(res)
+		}
+	} else {
+		var res *GrepResult
+		res, err = GrepCode(searchCtx, cfg)
+		if res != nil {
+			result, text = res, FormatGrepResult(res)
+		}
+	}
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			return true, fmt.Sprintf("Error: %s canceled", name)
+		}
+		return true, fmt.Sprintf("Error: %v", err)
+	}
+	if asJSON {
+		blob, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return true, fmt.Sprintf("Error: failed to encode results: %v", err)
+		}
+		return true, string(blob)
+	}
+	return true, text
+}
+
+// ReadableSize formats a byte count using binary units.
 func ReadableSize(b int64) string {
 	const unit = 1024
 	if b < unit {
@@ -530,8 +792,3 @@ func ReadableSize(b int64) string {
 	}
 	return fmt.Sprintf("%.1f %cB", float64(b)/float64(div), "KMGTPE"[exp])
 }
-
-func fatalf(format string, args ...interface{}) {
-	fmt.Printf(ColorRed+"FATAL: "+format+"\n"+ColorReset, args...)
-	os.Exit(1)
-}
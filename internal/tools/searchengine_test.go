@@ -0,0 +1,125 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func writeSearchFixture(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for rel, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("write %s: %v", rel, err)
+		}
+	}
+}
+
+func TestFindFilesRespectsGitignoreAndGlob(t *testing.T) {
+	root := t.TempDir()
+	writeSearchFixture(t, root, map[string]string{
+		".gitignore":            "build/\n*.log\n!keep.log\n",
+		"main.go":               "package main\n",
+		"main_test.go":          "package main\n",
+		"keep.log":              "kept\n",
+		"debug.log":             "ignored\n",
+		"build/out.go":          "package build\n",
+		"internal/a/a.go":       "package a\n",
+		"internal/a/.gitignore": "gen_*.go\n",
+		"internal/a/gen_x.go":   "package a\n",
+		".hidden/secret.go":     "package hidden\n",
+	})
+
+	res, err := FindFiles(context.Background(), Config{RootPath: root, Extensions: []string{"go"}, RespectGitignore: true})
+	if err != nil {
+		t.Fatalf("FindFiles: %v", err)
+	}
+	var got []string
+	for _, f := range res.Files {
+		got = append(got, f.Path)
+	}
+	want := "internal/a/a.go,main.go,main_test.go"
+	if strings.Join(got, ",") != want {
+		t.Fatalf("unexpected files %v, want %s", got, want)
+	}
+
+	res, err = FindFiles(context.Background(), Config{RootPath: root, Glob: "*.log", RespectGitignore: true})
+	if err != nil {
+		t.Fatalf("FindFiles: %v", err)
+	}
+	if len(res.Files) != 1 || res.Files[0].Path != "keep.log" {
+		t.Fatalf("negated ignore rule not applied: %+v", res.Files)
+	}
+
+	res, err = FindFiles(context.Background(), Config{RootPath: root, Glob: "internal/**/*.go", MaxResults: 1})
+	if err != nil {
+		t.Fatalf("FindFiles: %v", err)
+	}
+	if len(res.Files) != 1 || !res.Truncated || res.Files[0].Path != "internal/a/a.go" {
+		t.Fatalf("expected one truncated result without ignore rules, got %+v", res)
+	}
+}
+
+func TestGrepCodeReturnsContextAndJSON(t *testing.T) {
+	root := t.TempDir()
+	writeSearchFixture(t, root, map[string]string{
+		"a.go":   "package a\n\nfunc Foo() {}\nfunc Bar() {}\n\nfunc Baz() {}\n",
+		"b.txt":  "nothing here\n",
+		"bin.go": "func Foo\x00binary",
+	})
+
+	res, err := GrepCode(context.Background(), Config{RootPath: root, ContentRegex: regexp.MustCompile(`^func (Foo|Bar)`), ContextLines: 1})
+	if err != nil {
+		t.Fatalf("GrepCode: %v", err)
+	}
+	if len(res.Matches) != 2 || res.FilesMatched != 1 || res.SkippedBinary != 1 {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+	if res.Matches[0].Line != 3 || len(res.Matches[0].Before) != 1 || res.Matches[1].After[0] != "" {
+		t.Fatalf("unexpected context: %+v", res.Matches)
+	}
+
+	text := FormatGrepResult(res)
+	for _, want := range []string{"a.go-2-\n", "a.go:3:func Foo() {}\n", "a.go:4:func Bar() {}\n", "a.go-5-\n"} {
+		if !strings.Contains(text, want) {
+			t.Fatalf("formatted output missing %q:\n%s", want, text)
+		}
+	}
+	if strings.Contains(text, "a.go-4-") {
+		t.Fatalf("match line printed as context:\n%s", text)
+	}
+
+	handled, out := ExecuteSearchTool(context.Background(), "grep_code", `{"pattern":"baz","ignore_case":true,"path":"`+filepath.ToSlash(root)+`","format":"json"}`)
+	if !handled {
+		t.Fatal("grep_code not handled")
+	}
+	var decoded GrepResult
+	if err := json.Unmarshal([]byte(out), &decoded); err != nil {
+		t.Fatalf("invalid JSON output %q: %v", out, err)
+	}
+	if len(decoded.Matches) != 1 || decoded.Matches[0].Line != 6 {
+		t.Fatalf("unexpected JSON matches: %+v", decoded.Matches)
+	}
+
+	if _, out := ExecuteSearchTool(context.Background(), "grep_code", `{"pattern":""}`); !strings.HasPrefix(out, "Error") {
+		t.Fatalf("expected error for empty pattern, got %q", out)
+	}
+}
+
+func TestGrepCodeStopsOnCancel(t *testing.T) {
+	root := t.TempDir()
+	writeSearchFixture(t, root, map[string]string{"a.go": "x\n"})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := GrepCode(ctx, Config{RootPath: root, ContentRegex: regexp.MustCompile("x")}); err == nil {
+		t.Fatal("expected canceled search to return an error")
+	}
+}
@@ -0,0 +1,216 @@
+package tools
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// Text encodings edit tools read and write back unchanged. Files that are
+// neither valid UTF-8 nor UTF-16 with a byte order mark are treated as
+// Windows-1252, which covers Latin-1.
+const (
+	encodingUTF8    = "utf-8"
+	encodingUTF16LE = "utf-16le"
+	encodingUTF16BE = "utf-16be"
+	encodingCP1252  = "windows-1252"
+)
+
+var (
+	bomUTF8    = []byte{0xEF, 0xBB, 0xBF}
+	bomUTF16LE = []byte{0xFF, 0xFE}
+	bomUTF16BE = []byte{0xFE, 0xFF}
+)
+
+// errBinaryText is returned when a file is not text an edit tool can safely
+// rewrite.
+var errBinaryText = errors.New("looks like a binary file; refusing to rewrite it as text")
+
+// textFormat is how a text file is stored on disk. Edit tools work on the
+// decoded, LF-only text and encode it back with the same format, so
+// untouched lines keep their bytes. The exception is a file with mixed line
+// endings: it is written back with the style most of its lines use, and
+// the tools say so in their output (see lineEndingNote).
+type textFormat struct {
+	encoding string
+	bom      bool
+	newline  string
+	// mixed reports whether the file had both CRLF and bare LF endings.
+	mixed bool
+	// trailing reports whether the last line ends with a newline; line
+	// based tools keep it as they were.
+	trailing bool
+	mode     os.FileMode
+}
+
+// defaultTextFormat is used for files created by edit tools.
+func defaultTextFormat() textFormat {
+	return textFormat{encoding: encodingUTF8, newline: "\n", trailing: true, mode: 0644}
+}
+
+// readTextFile reads and decodes path. Binary files and files whose bytes do
+// not survive a decode/encode round trip are refused.
+func readTextFile(path string) (string, textFormat, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", textFormat{}, fmt.Errorf("failed to read file: %w", err)
+	}
+	if info.IsDir() {
+		return "", textFormat{}, fmt.Errorf("%s is a directory", path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", textFormat{}, fmt.Errorf("failed to read file: %w", err)
+	}
+	text, format, err := decodeText(data)
+	if err != nil {
+		return "", textFormat{}, fmt.Errorf("%s %w", path, err)
+	}
+	format.mode = info.Mode().Perm()
+	return text, format, nil
+}
+
+// decodeText detects the encoding and newline style of data and returns its
+// text with LF line endings.
+func decodeText(data []byte) (string, textFormat, error) {
+	format := defaultTextFormat()
+	body := data
+	switch {
+	case bytes.HasPrefix(data, bomUTF8):
+		format.bom, body = true, data[len(bomUTF8):]
+	case bytes.HasPrefix(data, bomUTF16LE):
+		format.encoding, format.bom, body = encodingUTF16LE, true, data[len(bomUTF16LE):]
+	case bytes.HasPrefix(data, bomUTF16BE):
+		format.encoding, format.bom, body = encodingUTF16BE, true, data[len(bomUTF16BE):]
+	case looksBinary(data):
+		return "", format, errBinaryText
+	case !utf8.Valid(data):
+		format.encoding = encodingCP1252
+	}
+
+	text := string(body)
+	if enc := format.codec(); enc != nil {
+		decoded, err := enc.NewDecoder().Bytes(body)
+		if err != nil {
+			return "", format, fmt.Errorf("is not valid %s: %w", format.encoding, err)
+		}
+		if again, err := enc.NewEncoder().Bytes(decoded); err != nil || !bytes.Equal(again, body) {
+			return "", format, fmt.Errorf("does not round-trip as %s; refusing to rewrite it", format.encoding)
+		}
+		text = string(decoded)
+	}
+	if strings.ContainsRune(text, 0) {
+		return "", format, errBinaryText
+	}
+
+	crlf, lf := strings.Count(text, "\r\n"), strings.Count(text, "\n")
+	if crlf > 0 && crlf*2 >= lf {
+		format.newline = "\r\n"
+	}
+	format.mixed = crlf > 0 && crlf < lf
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	format.trailing = text == "" || strings.HasSuffix(text, "\n")
+	return text, format, nil
+}
+
+func (f textFormat) codec() encoding.Encoding {
+	switch f.encoding {
+	case encodingUTF16LE:
+		return unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM)
+	case encodingUTF16BE:
+		return unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM)
+	case encodingCP1252:
+		return charmap.Windows1252
+	}
+	return nil
+}
+
+// encode converts LF text back to the bytes f describes.
+func (f textFormat) encode(text string) ([]byte, error) {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	if f.newline == "\r\n" {
+		text = strings.ReplaceAll(text, "\n", "\r\n")
+	}
+	body := []byte(text)
+	if enc := f.codec(); enc != nil {
+		var err error
+		if body, err = enc.NewEncoder().Bytes(body); err != nil {
+			return nil, fmt.Errorf("text cannot be encoded as %s: %w", f.encoding, err)
+		}
+	}
+	if !f.bom {
+		return body, nil
+	}
+	var bom []byte
+	switch f.encoding {
+	case encodingUTF16LE:
+		bom = bomUTF16LE
+	case encodingUTF16BE:
+		bom = bomUTF16BE
+	default:
+		bom = bomUTF8
+	}
+	return append(append([]byte{}, bom...), body...), nil
+}
+
+// writeText encodes text with f and writes it to path.
+func (f textFormat) writeText(path, text string) error {
+	data, err := f.encode(text)
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	mode := f.mode
+	if mode == 0 {
+		mode = 0644
+	}
+	if err := os.WriteFile(path, data, mode); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	return nil
+}
+
+// joinLines joins lines and ends the last one with a newline when the file
+// originally did.
+func (f textFormat) joinLines(lines []string) string {
+	if len(lines) == 0 {
+		return ""
+	}
+	text := strings.Join(lines, "\n")
+	if f.trailing {
+		text += "\n"
+	}
+	return text
+}
+
+// lineEndingNote tells the caller that writing path back with f changed the
+// line endings of lines it did not edit. It is empty unless the file had
+// mixed line endings.
+func (f textFormat) lineEndingNote(path string) string {
+	if !f.mixed {
+		return ""
+	}
+	style := "LF"
+	if f.newline == "\r\n" {
+		style = "CRLF"
+	}
+	return fmt.Sprintf("%s had mixed line endings; every line now ends with %s", path, style)
+}
+
+// describe summarizes f for tool output, e.g. "utf-16le with BOM, CRLF".
+func (f textFormat) describe() string {
+	s := f.encoding
+	if f.bom {
+		s += " with BOM"
+	}
+	if f.newline == "\r\n" {
+		return s + ", CRLF"
+	}
+	return s + ", LF"
+}
@@ -0,0 +1,164 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDecodeTextRoundTrip(t *testing.T) {
+	cases := map[string][]byte{
+		"utf8 lf":       []byte("a\nb\n"),
+		"utf8 crlf":     []byte("a\r\nb\r\n"),
+		"utf8 bom":      append([]byte{0xEF, 0xBB, 0xBF}, "héllo\n"...),
+		"no trailing":   []byte("a\nb"),
+		"utf16le bom":   {0xFF, 0xFE, 'h', 0, 'i', 0, '\r', 0, '\n', 0},
+		"utf16be bom":   {0xFE, 0xFF, 0, 'h', 0, 'i', 0, '\n'},
+		"windows-1252":  []byte("caf\xe9\r\n"),
+		"empty":         {},
+		"mostly crlf":   []byte("a\r\nb\r\nc\n"),
+		"mostly lf":     []byte("a\nb\nc\r\n"),
+		"no newline at": []byte("x"),
+	}
+	for name, data := range cases {
+		text, format, err := decodeText(data)
+		if err != nil {
+			t.Fatalf("%s: decodeText: %v", name, err)
+		}
+		if strings.Contains(text, "\r") {
+			t.Fatalf("%s: decoded text still has CR: %q", name, text)
+		}
+		out, err := format.encode(text)
+		if err != nil {
+			t.Fatalf("%s: encode: %v", name, err)
+		}
+		if name == "mostly crlf" || name == "mostly lf" {
+			continue
+		}
+		if !bytes.Equal(out, data) {
+			t.Fatalf("%s: round trip changed bytes:\n got %q\nwant %q", name, out, data)
+		}
+	}
+
+	_, format, _ := decodeText([]byte("a\r\nb\r\nc\n"))
+	if format.newline != "\r\n" {
+		t.Fatalf("expected dominant CRLF, got %q", format.newline)
+	}
+	if _, _, err := decodeText([]byte{0x7f, 'E', 'L', 'F', 0, 0, 1}); !errors.Is(err, errBinaryText) {
+		t.Fatalf("expected binary error, got %v", err)
+	}
+}
+
+func TestLineToolsPreserveFormat(t *testing.T) {
+	dir := t.TempDir()
+
+	crlf := filepath.Join(dir, "crlf.txt")
+	writeTestFile(t, crlf, "one\r\ntwo\r\nthree")
+	if _, err := ReplaceLineRange(crlf, 2, 2, "TWO\nextra"); err != nil {
+		t.Fatalf("ReplaceLineRange: %v", err)
+	}
+	if got := readTestFile(crlf); got != "one\r\nTWO\r\nextra\r\nthree" {
+		t.Fatalf("CRLF or missing trailing newline not kept: %q", got)
+	}
+
+	bom := filepath.Join(dir, "bom.txt")
+	writeTestFile(t, bom, "\xEF\xBB\xBFfirst\nsecond\n")
+	if _, err := ApplyFilePatch(bom, "2++ changed"); err != nil {
+		t.Fatalf("ApplyFilePatch: %v", err)
+	}
+	if got := readTestFile(bom); got != "\xEF\xBB\xBFfirst\nchanged\n" {
+		t.Fatalf("BOM not kept: %q", got)
+	}
+
+	latin := filepath.Join(dir, "latin.txt")
+	writeTestFile(t, latin, "caf\xe9\nbar")
+	if _, err := ApplyFilePatch(latin, "2++ baz"); err != nil {
+		t.Fatalf("ApplyFilePatch: %v", err)
+	}
+	if got := readTestFile(latin); got != "caf\xe9\nbaz" {
+		t.Fatalf("windows-1252 bytes or trailing state changed: %q", got)
+	}
+}
+
+func TestMixedLineEndingsAreReported(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	dir := t.TempDir()
+
+	lines := filepath.Join(dir, "lines.txt")
+	writeTestFile(t, lines, "one\r\ntwo\r\nthree\n")
+	out, err := ReplaceLineRange(lines, 1, 1, "ONE")
+	if err != nil {
+		t.Fatalf("ReplaceLineRange: %v", err)
+	}
+	if got := readTestFile(lines); got != "ONE\r\ntwo\r\nthree\r\n" {
+		t.Fatalf("expected majority CRLF, got %q", got)
+	}
+	if !strings.Contains(out, "mixed line endings; every line now ends with CRLF") {
+		t.Fatalf("normalization not reported: %q", out)
+	}
+
+	edited := filepath.Join(dir, "edited.txt")
+	writeTestFile(t, edited, "a\nb\nc\r\n")
+	out, err = EditFile(context.Background(), edited, []StringEdit{{OldString: "a", NewString: "A"}})
+	if err != nil || !strings.Contains(out, "every line now ends with LF") {
+		t.Fatalf("EditFile: %q %v", out, err)
+	}
+
+	plain := filepath.Join(dir, "plain.txt")
+	writeTestFile(t, plain, "a\r\nb\r\n")
+	if out, err := ReplaceLineRange(plain, 1, 1, "A"); err != nil || strings.Contains(out, "Note") {
+		t.Fatalf("unexpected note for uniform endings: %q %v", out, err)
+	}
+}
+
+func TestEditFilePreservesUTF16(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "utf16.txt")
+	data := []byte{0xFF, 0xFE}
+	for _, r := range "key=old\r\nnext=é\r\n" {
+		data = append(data, byte(r), byte(r>>8))
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := EditFile(context.Background(), path, []StringEdit{{OldString: "key=old\r\n", NewString: "key=new\r\n"}}); err != nil {
+		t.Fatalf("EditFile: %v", err)
+	}
+	text, format, err := readTextFile(path)
+	if err != nil {
+		t.Fatalf("readTextFile: %v", err)
+	}
+	if text != "key=new\nnext=é\n" {
+		t.Fatalf("unexpected text %q", text)
+	}
+	if format.encoding != encodingUTF16LE || !format.bom || format.newline != "\r\n" {
+		t.Fatalf("format not kept: %s", format.describe())
+	}
+	if format.mode != 0600 {
+		t.Fatalf("mode not kept: %v", format.mode)
+	}
+}
+
+func TestEditToolsRefuseBinary(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "blob.bin")
+	data := []byte("text\x00\x01\x02more\n")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ReplaceLineRange(path, 1, 1, "x"); err == nil || !strings.Contains(err.Error(), "binary") {
+		t.Fatalf("ReplaceLineRange should refuse binary, got %v", err)
+	}
+	if _, err := EditFile(context.Background(), path, []StringEdit{{OldString: "text", NewString: "x"}}); err == nil {
+		t.Fatal("EditFile should refuse binary")
+	}
+	if got, _ := os.ReadFile(path); !bytes.Equal(got, data) {
+		t.Fatal("binary file was modified")
+	}
+}
@@ -0,0 +1,541 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// TxOp is one operation of an edit transaction. Which fields apply depends
+// on Op:
+//
+//	replace  path, old_string, new_string, replace_all
+//	insert   path, line (insert before it; 0 appends), text
+//	delete   path, start_line, end_line; without a line range the file is deleted
+//	create   path, content, overwrite
+//	rename   path, to
+//	chmod    path, mode (octal, e.g. "755")
+type TxOp struct {
+	Op         string `json:"op"`
+	Path       string `json:"path"`
+	OldString  string `json:"old_string,omitempty"`
+	NewString  string `json:"new_string,omitempty"`
+	ReplaceAll bool   `json:"replace_all,omitempty"`
+	Line       int    `json:"line,omitempty"`
+	Text       string `json:"text,omitempty"`
+	StartLine  int    `json:"start_line,omitempty"`
+	EndLine    int    `json:"end_line,omitempty"`
+	Content    string `json:"content,omitempty"`
+	Overwrite  bool   `json:"overwrite,omitempty"`
+	To         string `json:"to,omitempty"`
+	Mode       string `json:"mode,omitempty"`
+}
+
+// EditTransactionArgs are the arguments of the edit_transaction tool.
+// Relative paths are resolved against WorkTree, which defaults to
+// DefaultWorkTree.
+type EditTransactionArgs struct {
+	WorkTree   string `json:"work_tree"`
+	Operations []TxOp `json:"operations"`
+	Verify     string `json:"verify"`
+}
+
+// ParseEditTransactionArgs decodes edit_transaction arguments and resolves
+// the work tree.
+func ParseEditTransactionArgs(rawArgs string) (EditTransactionArgs, error) {
+	var args EditTransactionArgs
+	if err := json.Unmarshal([]byte(rawArgs), &args); err != nil {
+		return args, fmt.Errorf("invalid arguments for edit_transaction: %w", err)
+	}
+	if len(args.Operations) == 0 {
+		return args, errors.New("edit_transaction requires a non-empty 'operations' list")
+	}
+	workTree := strings.TrimSpace(args.WorkTree)
+	if workTree == "" {
+		var err error
+		if workTree, err = DefaultWorkTree(); err != nil {
+			return args, err
+		}
+	}
+	abs, err := filepath.Abs(workTree)
+	if err != nil {
+		return args, fmt.Errorf("failed to resolve worktree path: %w", err)
+	}
+	args.WorkTree = abs
+	return args, nil
+}
+
+// Paths returns the absolute paths the transaction may write, create or
+// remove, in operation order.
+func (a EditTransactionArgs) Paths() []string {
+	var paths []string
+	seen := map[string]bool{}
+	for _, op := range a.Operations {
+		for _, p := range []string{op.Path, op.To} {
+			if p = a.absPath(p); p != "" && !seen[p] {
+				seen[p] = true
+				paths = append(paths, p)
+			}
+		}
+	}
+	return paths
+}
+
+// TargetPaths returns the absolute paths of the files the transaction leaves
+// behind, skipping deleted files and rename sources.
+func (a EditTransactionArgs) TargetPaths() []string {
+	removed := map[string]bool{}
+	for _, op := range a.Operations {
+		p := a.absPath(op.Path)
+		switch {
+		case op.Op == "rename":
+			removed[p] = true
+			delete(removed, a.absPath(op.To))
+		case op.Op == "delete" && op.StartLine == 0 && op.EndLine == 0:
+			removed[p] = true
+		case op.Op == "create":
+			delete(removed, p)
+		}
+	}
+	var targets []string
+	for _, p := range a.Paths() {
+		if !removed[p] {
+			targets = append(targets, p)
+		}
+	}
+	return targets
+}
+
+func (a EditTransactionArgs) absPath(p string) string {
+	if p = strings.TrimSpace(p); p == "" {
+		return ""
+	}
+	if !filepath.IsAbs(p) {
+		p = filepath.Join(a.WorkTree, p)
+	}
+	return filepath.Clean(p)
+}
+
+// txFile is the staged state of one file. Binary files can only be renamed,
+// deleted or chmodded.
+type txFile struct {
+	pendingFile
+	origData    []byte
+	origContent string
+	origMode    os.FileMode
+	renamedFrom string
+}
+
+func (f *txFile) changed() bool {
+	return f.exists != f.onDisk || f.content != f.origContent || (f.exists && f.mode != f.origMode)
+}
+
+// EditTransaction validates every operation against an in-memory copy of the
+// files first and writes nothing unless all of them apply. Changed files are
+// then written to temporary files and renamed into place; if any write
+// fails, files already replaced are restored. The written files are then
+// formatted (see FormatEditedFiles). The result is one combined diff, with
+// editor checkpoints recorded before and after.
+func EditTransaction(ctx context.Context, args EditTransactionArgs) (string, error) {
+	root := args.WorkTree
+	files := map[string]*txFile{}
+	var order []string
+	load := func(rel string) (*txFile, error) {
+		if f, ok := files[rel]; ok {
+			return f, nil
+		}
+		f := &txFile{pendingFile: pendingFile{mode: 0644}}
+		abs := filepath.Join(root, rel)
+		info, err := os.Stat(abs)
+		switch {
+		case err == nil && info.IsDir():
+			return nil, fmt.Errorf("%s is a directory", rel)
+		case err == nil:
+			data, err := os.ReadFile(abs)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read %s: %w", rel, err)
+			}
+			f.exists, f.onDisk, f.mode, f.origData = true, true, info.Mode().Perm(), data
+			if f.content, f.format, err = decodeText(data); err != nil {
+				f.content, f.binary = string(data), true
+			}
+		case !errors.Is(err, os.ErrNotExist):
+			return nil, fmt.Errorf("failed to stat %s: %w", rel, err)
+		}
+		if !f.exists {
+			f.format = defaultTextFormat()
+		}
+		f.origContent, f.origMode = f.content, f.mode
+		files[rel] = f
+		order = append(order, rel)
+		return f, nil
+	}
+
+	for i, op := range args.Operations {
+		if err := stageTxOp(root, op, load); err != nil {
+			return "", fmt.Errorf("operation %d (%s %s): %w; nothing was written", i+1, op.Op, op.Path, err)
+		}
+	}
+
+	var changed []string
+	for _, rel := range order {
+		if files[rel].changed() {
+			changed = append(changed, rel)
+		}
+	}
+	if len(changed) == 0 {
+		return "", errors.New("the operations leave every file unchanged")
+	}
+
+	var notes []string
+	if _, err := CreateCheckpoint(root, "", "editor checkpoint: before edit_transaction"); err != nil {
+		notes = append(notes, fmt.Sprintf("pre-edit checkpoint skipped: %v", err))
+	}
+	if err := commitTxFiles(root, changed, files); err != nil {
+		return "", err
+	}
+	var written []string
+	for _, rel := range changed {
+		if f := files[rel]; f.exists && !f.binary {
+			written = append(written, filepath.Join(root, rel))
+		}
+	}
+	formatted := FormatEditedFiles(ctx, written)
+	checkpoint := ""
+	if head, err := CreateCheckpoint(root, "", "editor checkpoint: edit_transaction"); err != nil {
+		notes = append(notes, fmt.Sprintf("checkpoint skipped: %v", err))
+	} else {
+		checkpoint = fmt.Sprintf(" Checkpoint: %s (work_tree %s).", shortHash(head), root)
+	}
+
+	for _, rel := range changed {
+		if f := files[rel]; f.exists && !f.binary && f.content != f.origContent {
+			if note := f.format.lineEndingNote(filepath.ToSlash(rel)); note != "" {
+				notes = append(notes, note)
+			}
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Transaction applied: %d operation(s), %d file(s) changed.%s\n", len(args.Operations), len(changed), checkpoint)
+	for _, n := range notes {
+		fmt.Fprintf(&b, "Note: %s\n", n)
+	}
+	for _, rel := range changed {
+		f := files[rel]
+		if f.renamedFrom != "" && f.exists {
+			fmt.Fprintf(&b, "rename %s -> %s\n", filepath.ToSlash(f.renamedFrom), filepath.ToSlash(rel))
+		}
+		if f.exists && f.onDisk && f.mode != f.origMode {
+			fmt.Fprintf(&b, "mode %s: %o -> %o\n", filepath.ToSlash(rel), f.origMode, f.mode)
+		}
+		if f.content == f.origContent && f.exists == f.onDisk {
+			continue
+		}
+		if f.binary {
+			if !f.exists && !isRenameSource(rel, files) {
+				fmt.Fprintf(&b, "delete binary file %s\n", filepath.ToSlash(rel))
+			}
+			continue
+		}
+		from, to := "a/"+filepath.ToSlash(rel), "b/"+filepath.ToSlash(rel)
+		oldLines, newLines := splitDiffLines(f.origContent), splitDiffLines(f.content)
+		switch {
+		case !f.onDisk && f.renamedFrom != "":
+			src := files[f.renamedFrom]
+			from, oldLines = "a/"+filepath.ToSlash(f.renamedFrom), splitDiffLines(src.origContent)
+			if src.origContent == f.content {
+				continue
+			}
+		case !f.onDisk:
+			from = "/dev/null"
+		case !f.exists:
+			to = "/dev/null"
+			if isRenameSource(rel, files) {
+				continue
+			}
+		}
+		b.WriteString(BuildSimpleUnifiedDiff(from, to, oldLines, newLines))
+		b.WriteByte('\n')
+	}
+	return withFormatReport(strings.TrimRight(b.String(), "\n"), formatted), nil
+}
+
+func isRenameSource(rel string, files map[string]*txFile) bool {
+	for _, f := range files {
+		if f.renamedFrom == rel && f.exists {
+			return true
+		}
+	}
+	return false
+}
+
+// stageTxOp validates op and applies it to the staged files.
+func stageTxOp(root string, op TxOp, load func(rel string) (*txFile, error)) error {
+	rel, err := txRelPath(root, op.Path)
+	if err != nil {
+		return err
+	}
+	f, err := load(rel)
+	if err != nil {
+		return err
+	}
+	requireText := func() error {
+		if !f.exists {
+			return fmt.Errorf("%s does not exist", rel)
+		}
+		if f.binary {
+			return fmt.Errorf("%s %w", rel, errBinaryText)
+		}
+		return nil
+	}
+
+	switch op.Op {
+	case "replace":
+		if err := requireText(); err != nil {
+			return err
+		}
+		if op.OldString == "" {
+			return errors.New("replace requires a non-empty old_string")
+		}
+		content, _, err := replaceString(f.content, rel, StringEdit{OldString: op.OldString, NewString: op.NewString, ReplaceAll: op.ReplaceAll})
+		if err != nil {
+			return err
+		}
+		f.content = content
+	case "insert":
+		if err := requireText(); err != nil {
+			return err
+		}
+		lines, trailing := splitTxLines(f.content)
+		at := op.Line
+		if at == 0 {
+			at = len(lines) + 1
+		}
+		if at < 1 || at > len(lines)+1 {
+			return fmt.Errorf("line %d is out of range (file has %d lines; use %d to append)", op.Line, len(lines), len(lines)+1)
+		}
+		if op.Text == "" {
+			return errors.New("insert requires text")
+		}
+		inserted, _ := splitTxLines(strings.ReplaceAll(op.Text, "\r\n", "\n"))
+		lines = append(lines[:at-1], append(inserted, lines[at-1:]...)...)
+		f.content = joinTxLines(lines, trailing)
+	case "delete":
+		if op.StartLine == 0 && op.EndLine == 0 {
+			if !f.exists {
+				return fmt.Errorf("%s does not exist", rel)
+			}
+			f.exists, f.content = false, ""
+			return nil
+		}
+		if err := requireText(); err != nil {
+			return err
+		}
+		lines, trailing := splitTxLines(f.content)
+		end := op.EndLine
+		if end == 0 {
+			end = op.StartLine
+		}
+		if op.StartLine < 1 || end < op.StartLine || end > len(lines) {
+			return fmt.Errorf("line range %d-%d is out of range (file has %d lines)", op.StartLine, end, len(lines))
+		}
+		lines = append(lines[:op.StartLine-1], lines[end:]...)
+		f.content = joinTxLines(lines, trailing)
+	case "create":
+		if f.exists && !op.Overwrite {
+			return fmt.Errorf("%s already exists (set overwrite to replace it)", rel)
+		}
+		if f.binary || !f.exists {
+			f.format, f.binary = defaultTextFormat(), false
+			if strings.Contains(op.Content, "\r\n") {
+				f.format.newline = "\r\n"
+			}
+		}
+		f.content, f.exists = strings.ReplaceAll(op.Content, "\r\n", "\n"), true
+	case "rename":
+		if !f.exists {
+			return fmt.Errorf("%s does not exist", rel)
+		}
+		to, err := txRelPath(root, op.To)
+		if err != nil {
+			return fmt.Errorf("to: %w", err)
+		}
+		if to == rel {
+			return errors.New("rename source and destination are the same")
+		}
+		dst, err := load(to)
+		if err != nil {
+			return err
+		}
+		if dst.exists {
+			return fmt.Errorf("%s already exists", to)
+		}
+		dst.content, dst.mode, dst.exists, dst.renamedFrom = f.content, f.mode, true, rel
+		dst.format, dst.binary = f.format, f.binary
+		f.exists, f.content = false, ""
+	case "chmod":
+		if !f.exists {
+			return fmt.Errorf("%s does not exist", rel)
+		}
+		mode, err := strconv.ParseUint(strings.TrimSpace(op.Mode), 8, 32)
+		if err != nil || mode > 0o777 {
+			return fmt.Errorf("invalid mode %q; use octal permissions such as \"644\" or \"755\"", op.Mode)
+		}
+		f.mode = os.FileMode(mode)
+	default:
+		return fmt.Errorf("unknown op %q; use replace, insert, delete, create, rename or chmod", op.Op)
+	}
+	return nil
+}
+
+// txRelPath resolves path against root and rejects paths outside it.
+func txRelPath(root, path string) (string, error) {
+	path = strings.TrimSpace(path)
+	if path == "" {
+		return "", errors.New("path is required")
+	}
+	_, rel, err := patchPaths(root, FilePatch{NewPath: filepath.ToSlash(path)})
+	return rel, err
+}
+
+// splitTxLines splits LF text into lines and reports whether the last line
+// was terminated. Empty text counts as terminated so appended lines end
+// with a newline.
+func splitTxLines(content string) (lines []string, trailing bool) {
+	if content == "" {
+		return nil, true
+	}
+	return strings.Split(strings.TrimSuffix(content, "\n"), "\n"), strings.HasSuffix(content, "\n")
+}
+
+func joinTxLines(lines []string, trailing bool) string {
+	if len(lines) == 0 {
+		return ""
+	}
+	s := strings.Join(lines, "\n")
+	if trailing {
+		s += "\n"
+	}
+	return s
+}
+
+// commitTxFiles writes every changed file to a temporary file next to it and
+// then renames them into place. On failure the files already replaced or
+// removed are restored from their staged originals.
+func commitTxFiles(root string, changed []string, files map[string]*txFile) error {
+	temps := map[string]string{}
+	var createdDirs []string
+	cleanup := func() {
+		for _, tmp := range temps {
+			os.Remove(tmp)
+		}
+		for i := len(createdDirs) - 1; i >= 0; i-- {
+			os.Remove(createdDirs[i])
+		}
+	}
+
+	for _, rel := range changed {
+		f := files[rel]
+		if !f.exists {
+			continue
+		}
+		abs := filepath.Join(root, rel)
+		dirs, err := mkdirAllTracked(filepath.Dir(abs))
+		createdDirs = append(createdDirs, dirs...)
+		if err != nil {
+			cleanup()
+			return fmt.Errorf("failed to create directory for %s: %w; nothing was written", rel, err)
+		}
+		data := []byte(f.content)
+		if !f.binary {
+			if data, err = f.format.encode(f.content); err != nil {
+				cleanup()
+				return fmt.Errorf("failed to stage %s: %w; nothing was written", rel, err)
+			}
+		}
+		tmp, err := os.CreateTemp(filepath.Dir(abs), "."+filepath.Base(abs)+".tx-*")
+		if err == nil {
+			temps[rel] = tmp.Name()
+			_, err = tmp.Write(data)
+			if closeErr := tmp.Close(); err == nil {
+				err = closeErr
+			}
+		}
+		if err == nil {
+			err = os.Chmod(temps[rel], f.mode)
+		}
+		if err != nil {
+			cleanup()
+			return fmt.Errorf("failed to stage %s: %w; nothing was written", rel, err)
+		}
+	}
+
+	var done []string
+	for _, rel := range changed {
+		f := files[rel]
+		abs := filepath.Join(root, rel)
+		var err error
+		if f.exists {
+			err = os.Rename(temps[rel], abs)
+			if err == nil {
+				delete(temps, rel)
+			}
+		} else if f.onDisk {
+			err = os.Remove(abs)
+		}
+		if err != nil {
+			restoreErr := rollbackTxFiles(root, done, files)
+			cleanup()
+			if restoreErr != nil {
+				return fmt.Errorf("failed to write %s: %w; rollback also failed: %v", rel, err, restoreErr)
+			}
+			return fmt.Errorf("failed to write %s: %w; earlier files were rolled back", rel, err)
+		}
+		done = append(done, rel)
+	}
+	return nil
+}
+
+func rollbackTxFiles(root string, done []string, files map[string]*txFile) error {
+	var errs []error
+	for i := len(done) - 1; i >= 0; i-- {
+		f := files[done[i]]
+		abs := filepath.Join(root, done[i])
+		if !f.onDisk {
+			if err := os.Remove(abs); err != nil && !errors.Is(err, os.ErrNotExist) {
+				errs = append(errs, err)
+			}
+			continue
+		}
+		if err := writeFileAtomic(abs, f.origData); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if err := os.Chmod(abs, f.origMode); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// mkdirAllTracked creates dir and its missing parents and returns the
+// directories it created, outermost first.
+func mkdirAllTracked(dir string) ([]string, error) {
+	var missing []string
+	for d := dir; ; d = filepath.Dir(d) {
+		if _, err := os.Stat(d); err == nil || filepath.Dir(d) == d {
+			break
+		}
+		missing = append([]string{d}, missing...)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return missing, nil
+}
@@ -0,0 +1,142 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestEditTransactionAppliesAllOperations(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	dir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, "a.go"), "package a\n\nfunc Old() {}\n")
+	writeTestFile(t, filepath.Join(dir, "b.txt"), "one\ntwo\nthree\n")
+	writeTestFile(t, filepath.Join(dir, "old.txt"), "moved\n")
+	writeTestFile(t, filepath.Join(dir, "gone.txt"), "bye\n")
+	writeTestFile(t, filepath.Join(dir, "run.sh"), "#!/bin/sh\n")
+
+	args, err := ParseEditTransactionArgs(mustJSON(t, map[string]any{
+		"work_tree": dir,
+		"operations": []map[string]any{
+			{"op": "replace", "path": "a.go", "old_string": "func Old()", "new_string": "func New()"},
+			{"op": "insert", "path": "b.txt", "line": 2, "text": "one and a half\n"},
+			{"op": "delete", "path": "b.txt", "start_line": 4},
+			{"op": "create", "path": "pkg/new.txt", "content": "fresh\n"},
+			{"op": "rename", "path": "old.txt", "to": "pkg/moved.txt"},
+			{"op": "replace", "path": "pkg/moved.txt", "old_string": "moved", "new_string": "moved and edited"},
+			{"op": "delete", "path": "gone.txt"},
+			{"op": "chmod", "path": "run.sh", "mode": "755"},
+		},
+	}))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	out, err := EditTransaction(context.Background(), args)
+	if err != nil {
+		t.Fatalf("EditTransaction: %v", err)
+	}
+
+	want := map[string]string{
+		"a.go":          "package a\n\nfunc New() {}\n",
+		"b.txt":         "one\none and a half\ntwo\n",
+		"pkg/new.txt":   "fresh\n",
+		"pkg/moved.txt": "moved and edited\n",
+		"old.txt":       "<missing>",
+		"gone.txt":      "<missing>",
+	}
+	for rel, content := range want {
+		if got := readTestFile(filepath.Join(dir, rel)); got != content {
+			t.Fatalf("%s: got %q, want %q", rel, got, content)
+		}
+	}
+	if info, err := os.Stat(filepath.Join(dir, "run.sh")); runtime.GOOS != "windows" && (err != nil || info.Mode().Perm() != 0755) {
+		t.Fatalf("chmod not applied: %v %v", info, err)
+	}
+	for _, s := range []string{"7 file(s) changed", "Checkpoint:", "-func Old() {}", "+func New() {}", "rename old.txt -> pkg/moved.txt",
+		"--- a/old.txt\n+++ b/pkg/moved.txt", "--- /dev/null\n+++ b/pkg/new.txt", "--- a/gone.txt\n+++ /dev/null", "mode run.sh: 644 -> 755"} {
+		if runtime.GOOS == "windows" && strings.HasPrefix(s, "mode") {
+			continue
+		}
+		if !strings.Contains(out, s) {
+			t.Fatalf("output missing %q:\n%s", s, out)
+		}
+	}
+
+	targets := args.TargetPaths()
+	for _, p := range targets {
+		if strings.HasSuffix(p, "old.txt") || strings.HasSuffix(p, "gone.txt") {
+			t.Fatalf("removed file %s listed as a target: %v", p, targets)
+		}
+	}
+	if len(targets) != 5 {
+		t.Fatalf("unexpected targets %v", targets)
+	}
+}
+
+func TestEditTransactionWritesNothingOnFailure(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	dir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, "a.txt"), "alpha\n")
+	writeTestFile(t, filepath.Join(dir, "b.txt"), "beta\n")
+
+	cases := []struct {
+		ops  []map[string]any
+		want string
+	}{
+		{[]map[string]any{
+			{"op": "replace", "path": "a.txt", "old_string": "alpha", "new_string": "ALPHA"},
+			{"op": "create", "path": "c/new.txt", "content": "x\n"},
+			{"op": "replace", "path": "b.txt", "old_string": "missing", "new_string": "x"},
+		}, "operation 3 (replace b.txt): old_string not found"},
+		{[]map[string]any{{"op": "rename", "path": "a.txt", "to": "b.txt"}}, "b.txt already exists"},
+		{[]map[string]any{{"op": "create", "path": "a.txt", "content": "x"}}, "set overwrite"},
+		{[]map[string]any{{"op": "delete", "path": "a.txt", "start_line": 2}}, "out of range"},
+		{[]map[string]any{{"op": "chmod", "path": "a.txt", "mode": "rwx"}}, "invalid mode"},
+		{[]map[string]any{{"op": "replace", "path": "../outside.txt", "old_string": "a", "new_string": "b"}}, "outside the work tree"},
+		{[]map[string]any{{"op": "truncate", "path": "a.txt"}}, "unknown op"},
+		{[]map[string]any{{"op": "replace", "path": "a.txt", "old_string": "alpha", "new_string": "x"}, {"op": "replace", "path": "a.txt", "old_string": "x", "new_string": "alpha"}}, "unchanged"},
+	}
+	for _, c := range cases {
+		args, err := ParseEditTransactionArgs(mustJSON(t, map[string]any{"work_tree": dir, "operations": c.ops}))
+		if err != nil {
+			t.Fatalf("parse: %v", err)
+		}
+		if _, err := EditTransaction(context.Background(), args); err == nil || !strings.Contains(err.Error(), c.want) {
+			t.Fatalf("ops %v: expected error containing %q, got %v", c.ops, c.want, err)
+		}
+	}
+	if readTestFile(filepath.Join(dir, "a.txt")) != "alpha\n" || readTestFile(filepath.Join(dir, "b.txt")) != "beta\n" {
+		t.Fatal("failed transactions must not write")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "c")); !os.IsNotExist(err) {
+		t.Fatal("failed transactions must not create directories")
+	}
+}
+
+func TestCommitTxFilesRollsBack(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, "a.txt"), "old a\n")
+	files := map[string]*txFile{
+		"a.txt":   {pendingFile: pendingFile{content: "new a\n", exists: true, onDisk: true, mode: 0644}, origContent: "old a\n", origData: []byte("old a\n"), origMode: 0644},
+		"new.txt": {pendingFile: pendingFile{content: "new\n", exists: true, mode: 0644}, origMode: 0644},
+		"blocked": {pendingFile: pendingFile{content: "x\n", exists: true, mode: 0644}, origMode: 0644},
+	}
+	// A directory in the way makes the final rename fail after the first
+	// two files were already replaced.
+	if err := os.MkdirAll(filepath.Join(dir, "blocked", "child"), 0755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	err := commitTxFiles(dir, []string{"a.txt", "new.txt", "blocked"}, files)
+	if err == nil || !strings.Contains(err.Error(), "rolled back") {
+		t.Fatalf("expected rollback error, got %v", err)
+	}
+	if readTestFile(filepath.Join(dir, "a.txt")) != "old a\n" || readTestFile(filepath.Join(dir, "new.txt")) != "<missing>" {
+		t.Fatalf("rollback incomplete: a=%q new=%q", readTestFile(filepath.Join(dir, "a.txt")), readTestFile(filepath.Join(dir, "new.txt")))
+	}
+	if leftovers, _ := filepath.Glob(filepath.Join(dir, ".*.tx-*")); len(leftovers) != 0 {
+		t.Fatalf("temp files left behind: %v", leftovers)
+	}
+}
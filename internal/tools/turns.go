@@ -0,0 +1,424 @@
+package tools
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bilbilaki/ai2go/internal/diff"
+	"github.com/bilbilaki/ai2go/internal/project"
+)
+
+const (
+	turnJournalFile = "turns.json"
+
+	// turnRefPrefix keeps every recorded turn snapshot reachable in the
+	// shadow repo, so undo_checkpoints moving HEAD back cannot orphan them.
+	turnRefPrefix = "refs/ai2go/turns/"
+)
+
+// Turn is one agent turn that changed files: the working tree snapshots
+// taken before and after it in the shadow editor repo.
+type Turn struct {
+	ID      int       `json:"id"`
+	Prompt  string    `json:"prompt"`
+	Before  string    `json:"before"`
+	After   string    `json:"after"`
+	Started time.Time `json:"started"`
+	Files   []string  `json:"files"`
+	Undone  bool      `json:"undone,omitempty"`
+}
+
+// turnJournal lists recorded turns, oldest first. Redo holds the IDs of
+// undone turns, most recent last; a new turn clears it.
+type turnJournal struct {
+	Turns []Turn `json:"turns"`
+	Redo  []int  `json:"redo,omitempty"`
+}
+
+// TurnRecorder snapshots the working tree around one agent turn.
+type TurnRecorder struct {
+	workTree string
+	prompt   string
+	before   string
+	started  time.Time
+}
+
+// DefaultWorkTree is the tree automatic checkpoints cover: the git root of
+// the current directory, or the directory itself outside a repository.
+func DefaultWorkTree() (string, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", fmt.Errorf("failed to get working directory: %w", err)
+	}
+	if root := project.FindGitRoot(cwd); root != "" {
+		return root, nil
+	}
+	return cwd, nil
+}
+
+// CheckpointRoot returns the work tree whose shadow repo records changes
+// under dir. Turn snapshots, the edit tools and the checkpoint tools all go
+// through it, so edits made with any of them land in one shadow repo: the
+// session's DefaultWorkTree when dir lies inside it, else dir's git root,
+// else dir itself.
+func CheckpointRoot(dir string) string {
+	abs := absPath(dir)
+	if session, err := DefaultWorkTree(); err == nil && checkpointable(session) && pathWithin(abs, session) {
+		return session
+	}
+	if root := project.FindGitRoot(abs); root != "" {
+		return root
+	}
+	return abs
+}
+
+// checkpointable reports whether dir may be snapshotted as a whole; the
+// home directory and filesystem roots would copy far too much.
+func checkpointable(dir string) bool {
+	home, _ := os.UserHomeDir()
+	return dir != home && dir != filepath.Dir(dir)
+}
+
+// pathWithin reports whether path is dir or lies below it.
+func pathWithin(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// checkpointPath moves a work tree and an optional file path (relative to
+// that work tree, or absolute) onto CheckpointRoot.
+func checkpointPath(workTree, filePath string) (string, string) {
+	root := CheckpointRoot(workTree)
+	if filePath == "" {
+		return root, ""
+	}
+	if !filepath.IsAbs(filePath) {
+		filePath = filepath.Join(absPath(workTree), filePath)
+	}
+	if rel, err := filepath.Rel(root, filePath); err == nil {
+		return root, rel
+	}
+	return root, filePath
+}
+
+// BeginTurn snapshots workTree before an agent turn. Home and filesystem
+// roots are refused; snapshotting them would copy far too much.
+func BeginTurn(workTree, prompt string) (*TurnRecorder, error) {
+	abs, err := filepath.Abs(workTree)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve worktree path: %w", err)
+	}
+	if !checkpointable(abs) {
+		return nil, fmt.Errorf("not checkpointing %s; start ai2go inside a project directory", abs)
+	}
+	abs = CheckpointRoot(abs)
+	// Commit messages stay in the shadow repo's history even after the
+	// journal drops the turn, so they carry only the time, never the prompt.
+	started := time.Now()
+	before, err := SnapshotWorkTree(abs, "turn start: "+started.Format(time.RFC3339))
+	if err != nil {
+		return nil, err
+	}
+	return &TurnRecorder{workTree: abs, prompt: turnSummary(prompt), before: before, started: started}, nil
+}
+
+// End snapshots the working tree after the turn and records the turn when
+// files changed. It returns nil when the turn changed nothing.
+func (r *TurnRecorder) End() (*Turn, error) {
+	after, err := SnapshotWorkTree(r.workTree, "turn end: "+r.started.Format(time.RFC3339))
+	if err != nil {
+		return nil, err
+	}
+	if after == r.before {
+		return nil, nil
+	}
+	files, err := changedPaths(r.workTree, r.before, after)
+	if err != nil {
+		return nil, err
+	}
+
+	journal, err := loadTurnJournal(r.workTree)
+	if err != nil {
+		return nil, err
+	}
+	id := 1
+	if n := len(journal.Turns); n > 0 {
+		id = journal.Turns[n-1].ID + 1
+	}
+	journal.Turns = append(journal.Turns, Turn{
+		ID:      id,
+		Prompt:  r.prompt,
+		Before:  r.before,
+		After:   after,
+		Started: r.started,
+		Files:   files,
+	})
+	journal.Redo = nil
+	if err := pinTurn(r.workTree, journal.Turns[len(journal.Turns)-1]); err != nil {
+		return nil, err
+	}
+	if err := saveTurnJournal(r.workTree, journal); err != nil {
+		return nil, err
+	}
+	return &journal.Turns[len(journal.Turns)-1], nil
+}
+
+// pinTurn points the turn's refs at its snapshots.
+func pinTurn(workTree string, t Turn) error {
+	for name, rev := range map[string]string{"before": t.Before, "after": t.After} {
+		if _, err := runGit(workTree, "update-ref", fmt.Sprintf("%s%d/%s", turnRefPrefix, t.ID, name), rev); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// unpinTurn deletes the refs of turn id so its snapshots can be pruned.
+func unpinTurn(workTree string, id int) error {
+	for _, name := range []string{"before", "after"} {
+		if _, err := runGit(workTree, "update-ref", "-d", fmt.Sprintf("%s%d/%s", turnRefPrefix, id, name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SnapshotWorkTree commits the whole working tree to the shadow repo when it
+// differs from the last checkpoint and returns the resulting HEAD.
+func SnapshotWorkTree(workTree, message string) (string, error) {
+	if err := EnsureEditorGitRepo(workTree); err != nil {
+		return "", err
+	}
+	if err := stageCheckpoint(workTree, ""); err != nil {
+		return "", err
+	}
+	if head, err := runGit(workTree, "rev-parse", "--verify", "--quiet", "HEAD"); err == nil {
+		staged, err := runGit(workTree, "diff", "--cached", "--name-only", "HEAD")
+		if err != nil {
+			return "", err
+		}
+		if staged == "" {
+			return head, nil
+		}
+	}
+	if _, err := runGit(workTree, "commit", "--quiet", "--allow-empty", "-m", message); err != nil {
+		return "", err
+	}
+	return runGit(workTree, "rev-parse", "HEAD")
+}
+
+// UndoTurn restores the files changed by the most recent turn that is not
+// already undone. Unless force is set it refuses when those files changed
+// again since the turn.
+func UndoTurn(workTree string, force bool) (*Turn, error) {
+	workTree = CheckpointRoot(workTree)
+	journal, err := loadTurnJournal(workTree)
+	if err != nil {
+		return nil, err
+	}
+	idx := -1
+	for i := len(journal.Turns) - 1; i >= 0; i-- {
+		if !journal.Turns[i].Undone {
+			idx = i
+			break
+		}
+	}
+	if idx < 0 {
+		return nil, errors.New("no turn to undo")
+	}
+	t := &journal.Turns[idx]
+	if err := restoreTurnFiles(workTree, t.After, t.Before, t.Files, force, fmt.Sprintf("undo turn %d", t.ID)); err != nil {
+		return nil, err
+	}
+	t.Undone = true
+	journal.Redo = append(journal.Redo, t.ID)
+	if err := saveTurnJournal(workTree, journal); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// RedoTurn reapplies the most recently undone turn.
+func RedoTurn(workTree string, force bool) (*Turn, error) {
+	workTree = CheckpointRoot(workTree)
+	journal, err := loadTurnJournal(workTree)
+	if err != nil {
+		return nil, err
+	}
+	if len(journal.Redo) == 0 {
+		return nil, errors.New("nothing to redo")
+	}
+	id := journal.Redo[len(journal.Redo)-1]
+	t := journal.find(id)
+	if t == nil {
+		return nil, fmt.Errorf("turn %d is missing from the journal", id)
+	}
+	if err := restoreTurnFiles(workTree, t.Before, t.After, t.Files, force, fmt.Sprintf("redo turn %d", t.ID)); err != nil {
+		return nil, err
+	}
+	t.Undone = false
+	journal.Redo = journal.Redo[:len(journal.Redo)-1]
+	if err := saveTurnJournal(workTree, journal); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// ListTurns returns recorded turns, oldest first.
+func ListTurns(workTree string) ([]Turn, error) {
+	workTree = CheckpointRoot(workTree)
+	journal, err := loadTurnJournal(workTree)
+	if err != nil {
+		return nil, err
+	}
+	return journal.Turns, nil
+}
+
+// TurnDiff renders the changes of turn id, or of the latest turn when id is
+// zero.
+func TurnDiff(workTree string, id int, opts diff.Options) (string, error) {
+	workTree = CheckpointRoot(workTree)
+	journal, err := loadTurnJournal(workTree)
+	if err != nil {
+		return "", err
+	}
+	if len(journal.Turns) == 0 {
+		return "", errors.New("no turns recorded yet")
+	}
+	t := &journal.Turns[len(journal.Turns)-1]
+	if id != 0 {
+		if t = journal.find(id); t == nil {
+			return "", fmt.Errorf("turn %d not found", id)
+		}
+	}
+	body, err := CheckpointDiff(workTree, t.Before, t.After, opts)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s\n%s", t.Describe(), body), nil
+}
+
+// Describe returns a one-line summary of t.
+func (t Turn) Describe() string {
+	state := ""
+	if t.Undone {
+		state = " [undone]"
+	}
+	return fmt.Sprintf("Turn %d (%s, %d file(s))%s: %s", t.ID, t.Started.Format("2006-01-02 15:04"), len(t.Files), state, t.Prompt)
+}
+
+func (j *turnJournal) find(id int) *Turn {
+	for i := range j.Turns {
+		if j.Turns[i].ID == id {
+			return &j.Turns[i]
+		}
+	}
+	return nil
+}
+
+// restoreTurnFiles moves files from snapshot expect to snapshot target. The
+// working tree is snapshotted first so the restore itself can be undone.
+func restoreTurnFiles(workTree, expect, target string, files []string, force bool, message string) error {
+	current, err := SnapshotWorkTree(workTree, "before "+message)
+	if err != nil {
+		return err
+	}
+	if !force {
+		drifted, err := changedPaths(workTree, expect, current, files...)
+		if err != nil {
+			return err
+		}
+		if len(drifted) > 0 {
+			return fmt.Errorf("files changed since the turn: %s (use --force to overwrite)", strings.Join(drifted, ", "))
+		}
+	}
+	if err := restorePaths(workTree, target, files); err != nil {
+		return err
+	}
+	_, err = SnapshotWorkTree(workTree, message)
+	return err
+}
+
+// changedPaths lists files that differ between two snapshots, optionally
+// limited to paths. Files left out of checkpoints (see excludedPaths) are
+// not listed: the snapshots cannot tell how they changed.
+func changedPaths(workTree, from, to string, paths ...string) ([]string, error) {
+	args := append([]string{"diff", "--name-only", "-z", from, to, "--"}, paths...)
+	out, err := runGitRaw(workTree, args...)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, p := range strings.Split(string(out), "\x00") {
+		if p != "" {
+			files = append(files, p)
+		}
+	}
+	excluded, err := excludedPaths(workTree, files)
+	if err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(files, func(f string) bool { return excluded[f] }), nil
+}
+
+func turnJournalPath(workTree string) (string, error) {
+	abs, err := filepath.Abs(workTree)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve worktree path: %w", err)
+	}
+	gitDir, err := editorGitDir(abs)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(gitDir), turnJournalFile), nil
+}
+
+func loadTurnJournal(workTree string) (*turnJournal, error) {
+	path, err := turnJournalPath(workTree)
+	if err != nil {
+		return nil, err
+	}
+	journal := &turnJournal{}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return journal, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read turn journal: %w", err)
+	}
+	if err := json.Unmarshal(data, journal); err != nil {
+		return nil, fmt.Errorf("failed to parse turn journal %s: %w", path, err)
+	}
+	return journal, nil
+}
+
+func saveTurnJournal(workTree string, journal *turnJournal) error {
+	path, err := turnJournalPath(workTree)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(journal, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode turn journal: %w", err)
+	}
+	if err := writeFileAtomic(path, data); err != nil {
+		return fmt.Errorf("failed to write turn journal: %w", err)
+	}
+	return nil
+}
+
+// turnSummary shortens a prompt to its first line for commit messages and
+// the journal.
+func turnSummary(prompt string) string {
+	line := strings.TrimSpace(prompt)
+	if i := strings.IndexByte(line, '\n'); i >= 0 {
+		line = strings.TrimSpace(line[:i]) + " ..."
+	}
+	return truncateRunes(line, 80)
+}
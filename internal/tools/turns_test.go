@@ -0,0 +1,145 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bilbilaki/ai2go/internal/diff"
+)
+
+func TestTurnUndoRedo(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	workTree := t.TempDir()
+	edited := filepath.Join(workTree, "edited.txt")
+	created := filepath.Join(workTree, "sub", "created.txt")
+	write := func(path, content string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("write %s: %v", path, err)
+		}
+	}
+	read := func(path string) string {
+		t.Helper()
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "<missing>"
+		}
+		return string(data)
+	}
+	write(edited, "v1\n")
+
+	// A turn that changes nothing is not recorded.
+	rec, err := BeginTurn(workTree, "look around")
+	if err != nil {
+		t.Fatalf("BeginTurn: %v", err)
+	}
+	if turn, err := rec.End(); err != nil || turn != nil {
+		t.Fatalf("expected no turn, got %+v %v", turn, err)
+	}
+
+	rec, err = BeginTurn(workTree, "change things\nwith detail")
+	if err != nil {
+		t.Fatalf("BeginTurn: %v", err)
+	}
+	write(edited, "v2\n")
+	write(created, "new\n")
+	turn, err := rec.End()
+	if err != nil || turn == nil {
+		t.Fatalf("End: %+v %v", turn, err)
+	}
+	if turn.ID != 1 || turn.Prompt != "change things ..." || strings.Join(turn.Files, ",") != "edited.txt,sub/created.txt" {
+		t.Fatalf("unexpected turn %+v", turn)
+	}
+	if log, err := runGit(workTree, "log", "--format=%s"); err != nil || strings.Contains(log, "change things") {
+		t.Fatalf("prompt leaked into checkpoint messages: %q %v", log, err)
+	}
+	if path, err := turnJournalPath(workTree); err != nil {
+		t.Fatalf("turnJournalPath: %v", err)
+	} else if info, err := os.Stat(path); err != nil {
+		t.Fatalf("stat turn journal: %v", err)
+	} else if info.Mode().Perm()&0077 != 0 {
+		t.Fatalf("turn journal should be private, got %v", info.Mode())
+	}
+
+	out, err := TurnDiff(workTree, 0, diff.Options{})
+	if err != nil || !strings.Contains(out, "-v1\n+v2") || !strings.Contains(out, "+++ b/sub/created.txt") {
+		t.Fatalf("TurnDiff: %q %v", out, err)
+	}
+
+	if _, err := UndoTurn(workTree, false); err != nil {
+		t.Fatalf("UndoTurn: %v", err)
+	}
+	if read(edited) != "v1\n" || read(created) != "<missing>" {
+		t.Fatalf("undo left %q / %q", read(edited), read(created))
+	}
+	if _, err := UndoTurn(workTree, false); err == nil {
+		t.Fatal("expected nothing left to undo")
+	}
+
+	// Redo refuses to clobber edits made after the undo unless forced.
+	write(edited, "user edit\n")
+	if _, err := RedoTurn(workTree, false); err == nil || !strings.Contains(err.Error(), "edited.txt") {
+		t.Fatalf("expected drift error, got %v", err)
+	}
+	if _, err := RedoTurn(workTree, true); err != nil {
+		t.Fatalf("RedoTurn(force): %v", err)
+	}
+	if read(edited) != "v2\n" || read(created) != "new\n" {
+		t.Fatalf("redo left %q / %q", read(edited), read(created))
+	}
+	if _, err := RedoTurn(workTree, false); err == nil {
+		t.Fatal("expected nothing left to redo")
+	}
+
+	turns, err := ListTurns(workTree)
+	if err != nil || len(turns) != 1 || turns[0].Undone {
+		t.Fatalf("ListTurns: %+v %v", turns, err)
+	}
+}
+
+func TestTurnSurvivesCheckpointUndoAndGC(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	workTree := t.TempDir()
+	path := filepath.Join(workTree, "a.txt")
+	if err := os.WriteFile(path, []byte("v1\n"), 0644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	rec, err := BeginTurn(workTree, "edit a")
+	if err != nil {
+		t.Fatalf("BeginTurn: %v", err)
+	}
+	if _, err := EditFile(context.Background(), path, []StringEdit{{OldString: "v1", NewString: "v2"}}); err != nil {
+		t.Fatalf("EditFile: %v", err)
+	}
+	if turn, err := rec.End(); err != nil || turn == nil {
+		t.Fatalf("End: %+v %v", turn, err)
+	}
+
+	// undo_checkpoints resets HEAD below the turn snapshots; the turn refs
+	// must keep them alive through gc.
+	if _, err := UndoLastCheckpoints(workTree, 2); err != nil {
+		t.Fatalf("UndoLastCheckpoints: %v", err)
+	}
+	if _, err := GCCheckpoints(workTree, CheckpointRetention{KeepCheckpoints: 1}); err != nil {
+		t.Fatalf("GCCheckpoints: %v", err)
+	}
+	if _, err := RedoTurn(workTree, true); err == nil {
+		t.Fatal("expected nothing to redo")
+	}
+	if _, err := UndoTurn(workTree, true); err != nil {
+		t.Fatalf("UndoTurn: %v", err)
+	}
+	if _, err := RedoTurn(workTree, true); err != nil {
+		t.Fatalf("RedoTurn: %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "v2\n" {
+		t.Fatalf("redo left %q", data)
+	}
+}
@@ -0,0 +1,755 @@
+package tools
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	// maxHunkFuzz is how many leading/trailing context lines a hunk may drop
+	// when its full context is not found (GNU patch's default fuzz factor).
+	maxHunkFuzz = 2
+)
+
+// HunkLine is one body line of a hunk: Op is ' ' (context), '-' or '+'.
+type HunkLine struct {
+	Op   byte
+	Text string
+}
+
+// Hunk is one "@@ -a,b +c,d @@" section. OldStart is 0 when the header
+// carried no line numbers; the hunk is then located by content alone.
+type Hunk struct {
+	Header       string
+	OldStart     int
+	OldLines     int
+	NewStart     int
+	NewLines     int
+	Lines        []HunkLine
+	OldNoNewline bool // "\ No newline at end of file" after an old line
+	NewNoNewline bool // ... after a new line
+}
+
+// FilePatch is the diff for one file. OldPath is empty for created files and
+// NewPath is empty for deleted ones.
+type FilePatch struct {
+	OldPath string
+	NewPath string
+	NewMode os.FileMode
+	Hunks   []Hunk
+}
+
+// Action describes what the patch does to the file.
+func (fp FilePatch) Action() string {
+	switch {
+	case fp.OldPath == "":
+		return "create"
+	case fp.NewPath == "":
+		return "delete"
+	case fp.OldPath != fp.NewPath:
+		return "rename"
+	}
+	return "modify"
+}
+
+// Path is the file the patch leaves behind (or removes).
+func (fp FilePatch) Path() string {
+	if fp.NewPath != "" {
+		return fp.NewPath
+	}
+	return fp.OldPath
+}
+
+// HunkResult reports where a hunk applied or why it was rejected.
+type HunkResult struct {
+	Index             int // 1-based
+	Header            string
+	Applied           bool
+	Line              int // 1-based line in the original file
+	Offset            int // lines the hunk moved from its header position
+	Fuzz              int // context lines ignored at each end
+	IgnoredWhitespace bool
+	Reason            string
+}
+
+// FilePatchResult is the outcome for one file of a patch.
+type FilePatchResult struct {
+	Path    string
+	OldPath string
+	Action  string
+	Hunks   []HunkResult
+	Err     string // file-level rejection (missing file, target exists, ...)
+	Note    string // set when writing the file normalized its line endings
+}
+
+// Failed reports whether the file or any of its hunks was rejected.
+func (r FilePatchResult) Failed() bool {
+	if r.Err != "" {
+		return true
+	}
+	for _, h := range r.Hunks {
+		if !h.Applied {
+			return true
+		}
+	}
+	return false
+}
+
+var hunkHeaderPattern = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@ ?(.*)$`)
+
+// ParseUnifiedDiff parses git-style and plain unified diffs, including file
+// creation, deletion and rename headers. Hunk line counts are not trusted:
+// a hunk runs until the next hunk or file header.
+func ParseUnifiedDiff(patch string) ([]FilePatch, error) {
+	lines := strings.Split(strings.ReplaceAll(patch, "\r\n", "\n"), "\n")
+
+	var (
+		files              []FilePatch
+		cur                *FilePatch
+		sawHeader          bool
+		created, deleted   bool
+		renameFrom, rename string
+	)
+	flush := func() {
+		if cur == nil {
+			return
+		}
+		if renameFrom != "" {
+			cur.OldPath = renameFrom
+		}
+		if rename != "" {
+			cur.NewPath = rename
+		}
+		if created {
+			cur.OldPath = ""
+		}
+		if deleted {
+			cur.NewPath = ""
+		}
+		if cur.OldPath != "" || cur.NewPath != "" {
+			files = append(files, *cur)
+		}
+		cur, sawHeader, created, deleted, renameFrom, rename = nil, false, false, false, "", ""
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			flush()
+			oldPath, newPath := parseGitDiffPaths(strings.TrimPrefix(line, "diff --git "))
+			cur = &FilePatch{OldPath: oldPath, NewPath: newPath}
+		case isFileHeader(lines, i):
+			if cur == nil || sawHeader || len(cur.Hunks) > 0 {
+				flush()
+				cur = &FilePatch{}
+			}
+			cur.OldPath = parseHeaderPath(strings.TrimPrefix(line, "--- "))
+			cur.NewPath = parseHeaderPath(strings.TrimPrefix(lines[i+1], "+++ "))
+			sawHeader = true
+			i++
+		case cur != nil && strings.HasPrefix(line, "new file mode "):
+			created = true
+			if strings.HasSuffix(line, "755") {
+				cur.NewMode = 0755
+			}
+		case cur != nil && strings.HasPrefix(line, "deleted file mode "):
+			deleted = true
+		case cur != nil && strings.HasPrefix(line, "rename from "):
+			renameFrom = unquotePath(strings.TrimPrefix(line, "rename from "))
+		case cur != nil && strings.HasPrefix(line, "rename to "):
+			rename = unquotePath(strings.TrimPrefix(line, "rename to "))
+		case strings.HasPrefix(line, "@@"):
+			if cur == nil {
+				return nil, fmt.Errorf("patch fragment without header at line %d: %q", i+1, line)
+			}
+			hunk, counted := parseHunkHeader(line)
+			next := readHunkBody(lines, i+1, &hunk, counted)
+			if len(hunk.Lines) == 0 {
+				return nil, fmt.Errorf("empty hunk at line %d: %q", i+1, line)
+			}
+			cur.Hunks = append(cur.Hunks, hunk)
+			i = next - 1
+		}
+	}
+	flush()
+
+	if len(files) == 0 {
+		return nil, errors.New("no file headers (---/+++ or diff --git) found in patch")
+	}
+	for _, fp := range files {
+		if fp.Action() == "modify" && len(fp.Hunks) == 0 {
+			return nil, fmt.Errorf("patch for %s has no hunks", fp.Path())
+		}
+	}
+	return files, nil
+}
+
+func isFileHeader(lines []string, i int) bool {
+	return strings.HasPrefix(lines[i], "--- ") && i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ ")
+}
+
+// parseHunkHeader parses "@@ -a,b +c,d @@ section". Headers without line
+// numbers (a bare "@@") are accepted with OldStart 0.
+func parseHunkHeader(line string) (Hunk, bool) {
+	h := Hunk{Header: strings.TrimSpace(line)}
+	m := hunkHeaderPattern.FindStringSubmatch(line)
+	if m == nil {
+		return h, false
+	}
+	atoi := func(s string, def int) int {
+		if s == "" {
+			return def
+		}
+		n, _ := strconv.Atoi(s)
+		return n
+	}
+	h.OldStart, h.OldLines = atoi(m[1], 0), atoi(m[2], 1)
+	h.NewStart, h.NewLines = atoi(m[3], 0), atoi(m[4], 1)
+	h.Header = strings.TrimSpace(strings.TrimSuffix(line, m[5]))
+	return h, true
+}
+
+// readHunkBody reads hunk lines starting at lines[start] and returns the
+// index of the first line after the hunk.
+func readHunkBody(lines []string, start int, h *Hunk, counted bool) int {
+	j := start
+	trailingBlank := 0
+	for ; j < len(lines); j++ {
+		l := lines[j]
+		if strings.HasPrefix(l, "@@") || strings.HasPrefix(l, "diff --git ") || isFileHeader(lines, j) {
+			break
+		}
+		if l == "" {
+			// Editors and models often strip the space of blank context lines.
+			h.Lines = append(h.Lines, HunkLine{Op: ' '})
+			trailingBlank++
+			continue
+		}
+		op := l[0]
+		if op == '\\' {
+			if n := len(h.Lines); n > 0 {
+				switch h.Lines[n-1].Op {
+				case '+':
+					h.NewNoNewline = true
+				case '-':
+					h.OldNoNewline = true
+				default:
+					h.OldNoNewline, h.NewNoNewline = true, true
+				}
+			}
+			continue
+		}
+		if op != ' ' && op != '-' && op != '+' {
+			break
+		}
+		h.Lines = append(h.Lines, HunkLine{Op: op, Text: l[1:]})
+		trailingBlank = 0
+	}
+
+	// Blank lines after the last hunk are usually patch padding, not context.
+	drop := trailingBlank
+	if counted {
+		old, _ := hunkLineCounts(h.Lines)
+		drop = min(trailingBlank, max(old-h.OldLines, 0))
+	}
+	h.Lines = h.Lines[:len(h.Lines)-drop]
+	return j
+}
+
+func hunkLineCounts(lines []HunkLine) (oldCount, newCount int) {
+	for _, l := range lines {
+		if l.Op != '+' {
+			oldCount++
+		}
+		if l.Op != '-' {
+			newCount++
+		}
+	}
+	return oldCount, newCount
+}
+
+func parseGitDiffPaths(s string) (string, string) {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, `"`) {
+		if end := strings.Index(s[1:], `" `); end >= 0 {
+			return parseHeaderPath(s[:end+2]), parseHeaderPath(s[end+3:])
+		}
+	}
+	if idx := strings.LastIndex(s, " b/"); idx >= 0 {
+		return parseHeaderPath(s[:idx]), parseHeaderPath(s[idx+1:])
+	}
+	fields := strings.Fields(s)
+	if len(fields) == 2 {
+		return parseHeaderPath(fields[0]), parseHeaderPath(fields[1])
+	}
+	return "", ""
+}
+
+// parseHeaderPath strips timestamps and the a/ b/ prefixes from a ---/+++
+// path; /dev/null becomes "".
+func parseHeaderPath(s string) string {
+	if tab := strings.IndexByte(s, '\t'); tab >= 0 {
+		s = s[:tab]
+	}
+	s = unquotePath(strings.TrimSpace(s))
+	if s == "/dev/null" {
+		return ""
+	}
+	if strings.HasPrefix(s, "a/") || strings.HasPrefix(s, "b/") {
+		s = s[2:]
+	}
+	return s
+}
+
+func unquotePath(s string) string {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, `"`) {
+		if u, err := strconv.Unquote(s); err == nil {
+			return u
+		}
+	}
+	return s
+}
+
+// ApplyUnifiedDiff applies a unified diff to the files under workTree. Every
+// file is patched in memory first; nothing is written unless all hunks
+// apply. Hunks are located with offset search, fuzz and, as a last resort,
+// whitespace-insensitive context matching.
+func ApplyUnifiedDiff(workTree, patch string) ([]FilePatchResult, error) {
+	files, err := ParseUnifiedDiff(patch)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse unified diff: %w", err)
+	}
+	return applyFilePatches(workTree, files)
+}
+
+// pendingFile is a file staged in memory by a multi-file edit. Text files are
+// staged decoded (see decodeText) and encoded with their original format
+// when written; binary files keep their raw bytes in content.
+type pendingFile struct {
+	content string
+	exists  bool
+	onDisk  bool
+	mode    os.FileMode
+	format  textFormat
+	binary  bool
+}
+
+func applyFilePatches(workTree string, files []FilePatch) ([]FilePatchResult, error) {
+	root, err := filepath.Abs(workTree)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve worktree path: %w", err)
+	}
+
+	pending := map[string]*txFile{}
+	var order []string
+	load := func(rel string) (*txFile, error) {
+		if pf, ok := pending[rel]; ok {
+			return pf, nil
+		}
+		pf := &txFile{pendingFile: pendingFile{mode: 0644, format: defaultTextFormat()}}
+		data, err := os.ReadFile(filepath.Join(root, rel))
+		switch {
+		case err == nil:
+			info, _ := os.Stat(filepath.Join(root, rel))
+			pf.exists, pf.onDisk = true, true
+			if pf.content, pf.format, err = decodeText(data); err != nil {
+				pf.content, pf.binary = string(data), true
+			}
+			if info != nil {
+				pf.mode = info.Mode().Perm()
+			}
+			pf.origData, pf.origContent, pf.origMode = data, pf.content, pf.mode
+		case !errors.Is(err, os.ErrNotExist):
+			return nil, fmt.Errorf("failed to read %s: %w", rel, err)
+		}
+		pending[rel] = pf
+		order = append(order, rel)
+		return pf, nil
+	}
+
+	results := make([]FilePatchResult, 0, len(files))
+	failed := false
+	for _, fp := range files {
+		res := FilePatchResult{Path: fp.Path(), OldPath: fp.OldPath, Action: fp.Action()}
+		results = append(results, res)
+		r := &results[len(results)-1]
+
+		src, dst, err := patchPaths(root, fp)
+		if err != nil {
+			r.Err, failed = err.Error(), true
+			continue
+		}
+
+		var content string
+		var mode os.FileMode = 0644
+		format, binary := defaultTextFormat(), false
+		if src != "" {
+			pf, err := load(src)
+			if err != nil {
+				return nil, err
+			}
+			if !pf.exists {
+				r.Err, failed = "file does not exist (use /dev/null as the old path to create it)", true
+				continue
+			}
+			if pf.binary && len(fp.Hunks) > 0 {
+				r.Err, failed = errBinaryText.Error(), true
+				continue
+			}
+			content, mode, format, binary = pf.content, pf.mode, pf.format, pf.binary
+		}
+		if dst != "" && dst != src {
+			pf, err := load(dst)
+			if err != nil {
+				return nil, err
+			}
+			if pf.exists && (src != "" || pf.content != "") {
+				r.Err, failed = fmt.Sprintf("%s already exists", dst), true
+				continue
+			}
+		}
+		if fp.NewMode != 0 {
+			mode = fp.NewMode
+		}
+
+		newContent, hunks, ok := applyHunks(content, fp.Hunks)
+		r.Hunks = hunks
+		if !ok {
+			failed = true
+			continue
+		}
+		if dst == "" && strings.TrimSpace(newContent) != "" {
+			r.Err, failed = "file still has content after removing the patch lines; refusing to delete it", true
+			continue
+		}
+
+		if src != "" && src != dst {
+			pending[src].exists, pending[src].content = false, ""
+		}
+		if dst != "" {
+			pending[dst].content, pending[dst].exists, pending[dst].mode = newContent, true, mode
+			pending[dst].format, pending[dst].binary = format, binary
+			if newContent != content {
+				r.Note = format.lineEndingNote(fp.NewPath)
+			}
+		}
+	}
+
+	if failed {
+		return results, fmt.Errorf("hunks rejected, nothing was written:\n%s", FormatPatchResults(results))
+	}
+
+	// Staged files are written through temporary files and renamed into
+	// place, so a failed write rolls back the files already replaced.
+	var changed []string
+	for _, rel := range order {
+		if pending[rel].changed() {
+			changed = append(changed, rel)
+		}
+	}
+	if err := commitTxFiles(root, changed, pending); err != nil {
+		return results, err
+	}
+	return results, nil
+}
+
+// patchPaths resolves the source and destination of fp relative to root,
+// rejecting paths that escape it.
+func patchPaths(root string, fp FilePatch) (src, dst string, err error) {
+	clean := func(p string) (string, error) {
+		if p == "" {
+			return "", nil
+		}
+		rel := filepath.Clean(filepath.FromSlash(p))
+		if filepath.IsAbs(rel) {
+			if r, relErr := filepath.Rel(root, rel); relErr == nil {
+				rel = r
+			}
+		}
+		if filepath.IsAbs(rel) || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return "", fmt.Errorf("path %q is outside the work tree", p)
+		}
+		return rel, nil
+	}
+	if src, err = clean(fp.OldPath); err != nil {
+		return "", "", err
+	}
+	if dst, err = clean(fp.NewPath); err != nil {
+		return "", "", err
+	}
+	return src, dst, nil
+}
+
+// applyHunks applies hunks in order to content, preserving its line endings.
+// ok is false when any hunk was rejected.
+func applyHunks(content string, hunks []Hunk) (string, []HunkResult, bool) {
+	eol := "\n"
+	if strings.Contains(content, "\r\n") {
+		eol = "\r\n"
+	}
+	finalNewline := content == "" || strings.HasSuffix(content, "\n")
+	var lines []string
+	if content != "" {
+		lines = strings.Split(strings.TrimSuffix(strings.ReplaceAll(content, "\r\n", "\n"), "\n"), "\n")
+	}
+
+	out := make([]string, 0, len(lines))
+	results := make([]HunkResult, 0, len(hunks))
+	pos, lastOffset, ok := 0, 0, true
+	for i, h := range hunks {
+		res := HunkResult{Index: i + 1, Header: h.Header}
+		expected := pos
+		if h.OldStart > 0 {
+			expected = h.OldStart - 1 + lastOffset
+			if h.OldLines == 0 {
+				expected++ // "-5,0" inserts after line 5
+			}
+		}
+
+		m, found := locateHunk(lines, pos, expected, h.Lines)
+		if !found {
+			res.Reason = explainRejectedHunk(lines, pos, expected, h)
+			results = append(results, res)
+			ok = false
+			continue
+		}
+
+		body := h.Lines[m.top : len(h.Lines)-m.bottom]
+		out = append(out, lines[pos:m.at]...)
+		k := m.at
+		for _, l := range body {
+			switch l.Op {
+			case ' ':
+				out = append(out, lines[k])
+				k++
+			case '-':
+				k++
+			case '+':
+				out = append(out, l.Text)
+			}
+		}
+		pos = k
+
+		res.Applied = true
+		res.Line = m.at + 1
+		res.Fuzz = max(m.top, m.bottom)
+		res.IgnoredWhitespace = m.whitespace
+		if h.OldStart > 0 {
+			res.Offset = m.at - m.top - (expected - lastOffset)
+			lastOffset = res.Offset
+		}
+		if h.NewNoNewline && pos == len(lines) {
+			finalNewline = false
+		} else if h.OldNoNewline && pos == len(lines) {
+			finalNewline = true
+		}
+		results = append(results, res)
+	}
+	if !ok {
+		return "", results, false
+	}
+
+	out = append(out, lines[pos:]...)
+	if len(out) == 0 {
+		return "", results, true
+	}
+	result := strings.Join(out, eol)
+	if finalNewline {
+		result += eol
+	}
+	return result, results, true
+}
+
+type hunkMatch struct {
+	at          int // index in lines where the (trimmed) old block starts
+	top, bottom int // context lines dropped by fuzz
+	whitespace  bool
+}
+
+// locateHunk finds the old side of a hunk in lines[from:], trying the
+// position closest to expected first, then dropping up to maxHunkFuzz context
+// lines at each end, then ignoring whitespace differences.
+func locateHunk(lines []string, from, expected int, body []HunkLine) (hunkMatch, bool) {
+	leading, trailing := 0, 0
+	for leading < len(body) && body[leading].Op == ' ' {
+		leading++
+	}
+	for trailing < len(body)-leading && body[len(body)-1-trailing].Op == ' ' {
+		trailing++
+	}
+
+	for fuzz := 0; fuzz <= maxHunkFuzz; fuzz++ {
+		top, bottom := min(fuzz, leading), min(fuzz, trailing)
+		if fuzz > 0 && top < fuzz && bottom < fuzz {
+			break // no more context to drop
+		}
+		var old []string
+		for _, l := range body[top : len(body)-bottom] {
+			if l.Op != '+' {
+				old = append(old, l.Text)
+			}
+		}
+		if len(old) == 0 {
+			if fuzz > 0 {
+				break
+			}
+			return hunkMatch{at: min(max(expected, from), len(lines))}, true
+		}
+		for _, ws := range []bool{false, true} {
+			if at := searchBlock(lines, from, expected+top, old, ws); at >= 0 {
+				return hunkMatch{at: at, top: top, bottom: bottom, whitespace: ws}, true
+			}
+		}
+	}
+	return hunkMatch{}, false
+}
+
+// searchBlock returns the start index of block in lines[from:] closest to
+// expected, or -1.
+func searchBlock(lines []string, from, expected int, block []string, ignoreWS bool) int {
+	last := len(lines) - len(block)
+	if last < from {
+		return -1
+	}
+	expected = min(max(expected, from), last)
+	for d := 0; ; d++ {
+		below, above := expected+d, expected-d
+		if below > last && above < from {
+			return -1
+		}
+		if below <= last && blockMatches(lines, below, block, ignoreWS) {
+			return below
+		}
+		if d > 0 && above >= from && blockMatches(lines, above, block, ignoreWS) {
+			return above
+		}
+	}
+}
+
+func blockMatches(lines []string, at int, block []string, ignoreWS bool) bool {
+	for i, want := range block {
+		if !linesEqual(lines[at+i], want, ignoreWS) {
+			return false
+		}
+	}
+	return true
+}
+
+func linesEqual(a, b string, ignoreWS bool) bool {
+	if a == b {
+		return true
+	}
+	if !ignoreWS {
+		return false
+	}
+	return strings.Join(strings.Fields(a), " ") == strings.Join(strings.Fields(b), " ")
+}
+
+// explainRejectedHunk says why a hunk did not apply and where the closest
+// candidate was, so the caller can re-read those lines and retry.
+func explainRejectedHunk(lines []string, from, expected int, h Hunk) string {
+	var old, updated []string
+	for _, l := range h.Lines {
+		if l.Op != '+' {
+			old = append(old, l.Text)
+		}
+		if l.Op != '-' {
+			updated = append(updated, l.Text)
+		}
+	}
+	if len(updated) > 0 && searchBlock(lines, 0, expected, updated, true) >= 0 && searchBlock(lines, 0, expected, old, true) < 0 {
+		return "the hunk's changes already appear in the file"
+	}
+	if len(lines) == 0 {
+		return fmt.Sprintf("file is empty but the hunk expects %d existing lines", len(old))
+	}
+
+	best, bestScore := -1, 0
+	for at := 0; at+len(old) <= len(lines); at++ {
+		score := 0
+		for i, want := range old {
+			if linesEqual(lines[at+i], want, true) {
+				score++
+			}
+		}
+		if score > bestScore || (score == bestScore && best >= 0 && absInt(at-expected) < absInt(best-expected)) {
+			best, bestScore = at, score
+		}
+	}
+	if best < 0 || bestScore == 0 {
+		return fmt.Sprintf("none of the hunk's %d context/removed lines were found (file has %d lines)", len(old), len(lines))
+	}
+	for i, want := range old {
+		if got := lines[best+i]; !linesEqual(got, want, true) {
+			return fmt.Sprintf("closest match at line %d has %d/%d matching lines; line %d differs: expected %q, found %q",
+				best+1, bestScore, len(old), best+i+1, truncateForReason(want), truncateForReason(got))
+		}
+	}
+	if best < from {
+		return fmt.Sprintf("context matches at line %d, which an earlier hunk already consumed; order hunks by line number and do not overlap them", best+1)
+	}
+	return fmt.Sprintf("context matches at line %d but could not be applied", best+1)
+}
+
+func truncateForReason(s string) string {
+	if len(s) > 120 {
+		return s[:120] + "..."
+	}
+	return s
+}
+
+func absInt(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// FormatPatchResults summarizes per-file and per-hunk outcomes. Hunks that
+// applied exactly where their header said are not listed.
+func FormatPatchResults(results []FilePatchResult) string {
+	var b strings.Builder
+	for _, r := range results {
+		label := map[string]string{"create": "created", "delete": "deleted", "modify": "modified", "rename": "renamed from " + r.OldPath}[r.Action]
+		if r.Failed() {
+			label = r.Action + " REJECTED"
+		}
+		fmt.Fprintf(&b, "%s: %s", r.Path, label)
+		if r.Err != "" {
+			fmt.Fprintf(&b, " - %s", r.Err)
+		}
+		if len(r.Hunks) > 0 {
+			fmt.Fprintf(&b, " (%d hunks)", len(r.Hunks))
+		}
+		b.WriteString("\n")
+		if r.Note != "" {
+			fmt.Fprintf(&b, "  note: %s\n", r.Note)
+		}
+		for _, h := range r.Hunks {
+			switch {
+			case !h.Applied:
+				fmt.Fprintf(&b, "  hunk %d %s: REJECTED - %s\n", h.Index, h.Header, h.Reason)
+			case h.Offset != 0 || h.Fuzz > 0 || h.IgnoredWhitespace:
+				var notes []string
+				if h.Offset != 0 {
+					notes = append(notes, fmt.Sprintf("offset %+d", h.Offset))
+				}
+				if h.Fuzz > 0 {
+					notes = append(notes, fmt.Sprintf("fuzz %d", h.Fuzz))
+				}
+				if h.IgnoredWhitespace {
+					notes = append(notes, "whitespace ignored")
+				}
+				fmt.Fprintf(&b, "  hunk %d applied at line %d (%s)\n", h.Index, h.Line, strings.Join(notes, ", "))
+			}
+		}
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
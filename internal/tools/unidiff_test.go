@@ -0,0 +1,221 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeTree(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+}
+
+func readTreeFile(t *testing.T, root, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(root, name))
+	if err != nil {
+		t.Fatalf("read %s: %v", name, err)
+	}
+	return string(data)
+}
+
+func TestApplyUnifiedDiffOffsetFuzzAndWhitespace(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		"main.go": "package main\n\n// added later\n// more\n\nfunc a() {\n\treturn\n}\n\nfunc b() {\n    x := 1\n}\n",
+	})
+
+	// Line numbers are two or three lines off, the first context line of hunk 1 is
+	// wrong (fuzz) and hunk 2 uses tabs where the file has spaces.
+	patch := strings.Join([]string{
+		"--- a/main.go",
+		"+++ b/main.go",
+		"@@ -3,4 +3,4 @@",
+		" WRONG CONTEXT",
+		" func a() {",
+		"-\treturn",
+		"+\treturn // done",
+		" }",
+		"@@ -7,3 +7,3 @@",
+		" func b() {",
+		"-\tx := 1",
+		"+\tx := 2",
+		" }",
+		"",
+	}, "\n")
+
+	results, err := ApplyUnifiedDiff(root, patch)
+	if err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+	want := "package main\n\n// added later\n// more\n\nfunc a() {\n\treturn // done\n}\n\nfunc b() {\n\tx := 2\n}\n"
+	if got := readTreeFile(t, root, "main.go"); got != want {
+		t.Fatalf("unexpected result:\n%q\nwant\n%q", got, want)
+	}
+	h1, h2 := results[0].Hunks[0], results[0].Hunks[1]
+	if h1.Fuzz != 1 || h1.Offset != 2 || h1.IgnoredWhitespace {
+		t.Fatalf("unexpected hunk 1 result: %+v", h1)
+	}
+	if !h2.IgnoredWhitespace || h2.Offset != 3 {
+		t.Fatalf("unexpected hunk 2 result: %+v", h2)
+	}
+	if report := FormatPatchResults(results); !strings.Contains(report, "hunk 1 applied at line 6 (offset +2, fuzz 1)") {
+		t.Fatalf("unexpected report:\n%s", report)
+	}
+}
+
+func TestApplyUnifiedDiffRejectsWithoutWriting(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		"a.txt": "one\ntwo\nthree\n",
+		"b.txt": "alpha\nbeta\ngamma\n",
+	})
+	patch := strings.Join([]string{
+		"--- a/a.txt",
+		"+++ b/a.txt",
+		"@@ -1,3 +1,3 @@",
+		" one",
+		"-two",
+		"+TWO",
+		" three",
+		"--- a/b.txt",
+		"+++ b/b.txt",
+		"@@ -1,3 +1,3 @@",
+		" alpha",
+		"-delta",
+		"+DELTA",
+		" gamma",
+		"",
+	}, "\n")
+
+	results, err := ApplyUnifiedDiff(root, patch)
+	if err == nil || !strings.Contains(err.Error(), "hunks rejected, nothing was written") {
+		t.Fatalf("expected rejection, got %v", err)
+	}
+	if !strings.Contains(err.Error(), `line 2 differs: expected "delta", found "beta"`) {
+		t.Fatalf("expected actionable reason, got %v", err)
+	}
+	if results[0].Failed() || !results[1].Failed() {
+		t.Fatalf("unexpected per-file results: %+v", results)
+	}
+	if got := readTreeFile(t, root, "a.txt"); got != "one\ntwo\nthree\n" {
+		t.Fatalf("a.txt must be untouched, got %q", got)
+	}
+
+	// Re-sending an applied hunk is reported as such.
+	writeTree(t, root, map[string]string{"b.txt": "alpha\nDELTA\ngamma\n"})
+	_, err = ApplyUnifiedDiff(root, patch[strings.Index(patch, "--- a/b.txt"):])
+	if err == nil || !strings.Contains(err.Error(), "already appear in the file") {
+		t.Fatalf("expected already-applied reason, got %v", err)
+	}
+}
+
+func TestApplyUnifiedDiffCreateDeleteRename(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		"old.txt":  "keep\nme\n",
+		"gone.txt": "bye\n",
+	})
+	patch := strings.Join([]string{
+		"diff --git a/old.txt b/new/name.txt",
+		"similarity index 80%",
+		"rename from old.txt",
+		"rename to new/name.txt",
+		"--- a/old.txt",
+		"+++ b/new/name.txt",
+		"@@ -1,2 +1,2 @@",
+		" keep",
+		"-me",
+		"+you",
+		"diff --git a/gone.txt b/gone.txt",
+		"deleted file mode 100644",
+		"--- a/gone.txt",
+		"+++ /dev/null",
+		"@@ -1 +0,0 @@",
+		"-bye",
+		"--- /dev/null",
+		"+++ b/pkg/fresh.go",
+		"@@ -0,0 +1,2 @@",
+		"+package pkg",
+		"+// no newline",
+		`\ No newline at end of file`,
+		"",
+	}, "\n")
+
+	results, err := ApplyUnifiedDiff(root, patch)
+	if err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+	if got := []string{results[0].Action, results[1].Action, results[2].Action}; strings.Join(got, ",") != "rename,delete,create" {
+		t.Fatalf("unexpected actions %v", got)
+	}
+	if got := readTreeFile(t, root, "new/name.txt"); got != "keep\nyou\n" {
+		t.Fatalf("renamed file = %q", got)
+	}
+	for _, name := range []string{"old.txt", "gone.txt"} {
+		if _, err := os.Stat(filepath.Join(root, name)); !os.IsNotExist(err) {
+			t.Fatalf("%s should be gone: %v", name, err)
+		}
+	}
+	if got := readTreeFile(t, root, "pkg/fresh.go"); got != "package pkg\n// no newline" {
+		t.Fatalf("created file = %q", got)
+	}
+
+	if _, err := ApplyUnifiedDiff(root, "--- a/../escape.txt\n+++ b/../escape.txt\n@@ -1 +1 @@\n-a\n+b\n"); err == nil || !strings.Contains(err.Error(), "outside the work tree") {
+		t.Fatalf("expected path escape rejection, got %v", err)
+	}
+}
+
+func TestApplyUnifiedDiffPreservesCRLFAndAcceptsBareHunkHeaders(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{"win.txt": "a\r\nb\r\nc\r\n"})
+	patch := "--- win.txt\n+++ win.txt\n@@\n a\n-b\n+B\n c\n"
+	if _, err := ApplyUnifiedDiff(root, patch); err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+	if got := readTreeFile(t, root, "win.txt"); got != "a\r\nB\r\nc\r\n" {
+		t.Fatalf("unexpected content %q", got)
+	}
+}
+
+func TestParseUnifiedDiffErrors(t *testing.T) {
+	if _, err := ParseUnifiedDiff("@@ -1 +1 @@\n-a\n+b\n"); err == nil || !strings.Contains(err.Error(), "patch fragment without header") {
+		t.Fatalf("expected missing header error, got %v", err)
+	}
+	if _, err := ParseUnifiedDiff("just some text\n"); err == nil {
+		t.Fatal("expected error for patch without files")
+	}
+}
+
+func FuzzApplyHunks(f *testing.F) {
+	f.Add("a\nb\nc\n", "--- a/x\n+++ b/x\n@@ -2 +2 @@\n-b\n+B\n")
+	f.Add("", "--- /dev/null\n+++ b/x\n@@ -0,0 +1 @@\n+new\n\\ No newline at end of file\n")
+	f.Add("a\r\nb\r\n", "--- x\n+++ x\n@@\n a\n-b\n")
+	f.Fuzz(func(t *testing.T, content, patch string) {
+		files, err := ParseUnifiedDiff(patch)
+		if err != nil {
+			return
+		}
+		for _, fp := range files {
+			_, results, ok := applyHunks(content, fp.Hunks)
+			if len(results) > len(fp.Hunks) {
+				t.Fatalf("more results than hunks: %d > %d", len(results), len(fp.Hunks))
+			}
+			for _, r := range results {
+				if ok && !r.Applied {
+					t.Fatalf("ok with rejected hunk: %+v", r)
+				}
+			}
+		}
+	})
+}
@@ -0,0 +1,492 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bilbilaki/ai2go/internal/project"
+)
+
+const (
+	// VerifyConfigFile holds per-project verify profiles
+	// (.ai2go/verify.json under the work tree).
+	VerifyConfigFile = "verify.json"
+
+	// VerifyProfileDefault resolves to the "default" named in verify.json,
+	// falling back to the built-in syntax profile.
+	VerifyProfileDefault = "default"
+
+	defaultVerifyStepTimeout = 2 * time.Minute
+	maxVerifyOutputLines     = 25
+	maxVerifyTailLines       = 15
+	maxVerifyLineLength      = 200
+)
+
+// VerifyStep is one command of a verify profile. The command runs through the
+// platform shell in the work tree. {packages} expands to the Go packages of
+// the changed files (./... when none) and {files} to the changed files; a
+// step whose {files} expands to nothing is skipped.
+type VerifyStep struct {
+	Name           string `json:"name"`
+	Command        string `json:"command"`
+	TimeoutSeconds int    `json:"timeout_seconds,omitempty"`
+}
+
+// VerifyProfile is an ordered list of verification steps. Steps stop at the
+// first failure.
+type VerifyProfile struct {
+	Name              string       `json:"-"`
+	Steps             []VerifyStep `json:"steps"`
+	RollbackOnFailure *bool        `json:"rollback_on_failure,omitempty"`
+}
+
+// Rollback reports whether a failed run should undo the edit (default true).
+func (p VerifyProfile) Rollback() bool {
+	return p.RollbackOnFailure == nil || *p.RollbackOnFailure
+}
+
+type verifyConfig struct {
+	Default  string                   `json:"default"`
+	Profiles map[string]VerifyProfile `json:"profiles"`
+}
+
+// VerifyStepResult is the outcome of one step.
+type VerifyStepResult struct {
+	Name     string
+	Command  string
+	Passed   bool
+	Skipped  bool
+	TimedOut bool
+	Elapsed  time.Duration
+	Err      string
+	Output   string // compacted
+}
+
+// VerifyResult is the outcome of running a profile.
+type VerifyResult struct {
+	Profile string
+	Steps   []VerifyStepResult
+	Passed  bool
+}
+
+// LoadVerifyProfiles returns the built-in profiles for the project type of
+// workTree (detected from marker files, then from changed file extensions)
+// overlaid with the profiles from .ai2go/verify.json, plus that file's
+// default profile name.
+func LoadVerifyProfiles(workTree string, changed []string) (map[string]VerifyProfile, string, error) {
+	profiles := builtinVerifyProfiles(workTree, changed)
+	path := filepath.Join(workTree, project.InstructionsDir, VerifyConfigFile)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return profiles, "", nil
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	var cfg verifyConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, "", fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	for name, p := range cfg.Profiles {
+		if len(p.Steps) == 0 {
+			return nil, "", fmt.Errorf("%s: profile %q has no steps", path, name)
+		}
+		for i, step := range p.Steps {
+			if strings.TrimSpace(step.Command) == "" {
+				return nil, "", fmt.Errorf("%s: profile %q step %d has no command", path, name, i+1)
+			}
+		}
+		profiles[name] = p
+	}
+	return profiles, strings.TrimSpace(cfg.Default), nil
+}
+
+// ResolveVerifyProfile looks up a profile by name for workTree.
+func ResolveVerifyProfile(workTree, name string, changed []string) (VerifyProfile, error) {
+	profiles, def, err := LoadVerifyProfiles(workTree, changed)
+	if err != nil {
+		return VerifyProfile{}, err
+	}
+	name = strings.TrimSpace(name)
+	if name == VerifyProfileDefault {
+		name = def
+		if name == "" {
+			name = string(VerifyModeSyntax)
+		}
+	}
+	p, ok := profiles[name]
+	if !ok {
+		names := make([]string, 0, len(profiles))
+		for n := range profiles {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		available := "none"
+		if len(names) > 0 {
+			available = strings.Join(names, ", ")
+		}
+		return VerifyProfile{}, fmt.Errorf("unknown verify profile %q for %s (available: %s; define profiles in %s)",
+			name, workTree, available, filepath.Join(project.InstructionsDir, VerifyConfigFile))
+	}
+	p.Name = name
+	return p, nil
+}
+
+// builtinVerifyProfiles keeps the historical "syntax" and "tests" modes and
+// maps them onto the toolchain of the detected project type.
+func builtinVerifyProfiles(workTree string, changed []string) map[string]VerifyProfile {
+	has := func(marker string) bool {
+		_, err := os.Stat(filepath.Join(workTree, marker))
+		return err == nil
+	}
+	changedExt := func(exts ...string) bool {
+		for _, f := range changed {
+			for _, ext := range exts {
+				if strings.EqualFold(filepath.Ext(f), ext) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	profile := func(steps ...VerifyStep) VerifyProfile {
+		return VerifyProfile{Steps: steps}
+	}
+
+	switch {
+	case has("go.mod") || has("go.work") || changedExt(".go"):
+		build := VerifyStep{Name: "build", Command: "go build ./..."}
+		return map[string]VerifyProfile{
+			"syntax": profile(build, VerifyStep{Name: "vet", Command: "go vet {packages}"}),
+			"tests":  profile(build, VerifyStep{Name: "test", Command: "go test {packages}"}),
+		}
+	case has("Cargo.toml") || changedExt(".rs"):
+		return map[string]VerifyProfile{
+			"syntax": profile(VerifyStep{Name: "check", Command: "cargo check --quiet"}),
+			"tests":  profile(VerifyStep{Name: "test", Command: "cargo test --quiet"}),
+		}
+	case has("package.json"):
+		syntax := profile(VerifyStep{Name: "node-check", Command: "node --check {files}"})
+		if has("tsconfig.json") {
+			syntax = profile(VerifyStep{Name: "typecheck", Command: "npx --no-install tsc --noEmit"})
+		}
+		return map[string]VerifyProfile{
+			"syntax": syntax,
+			"tests":  profile(VerifyStep{Name: "test", Command: "npm test --silent"}),
+		}
+	case has("pyproject.toml") || has("setup.py") || has("requirements.txt") || changedExt(".py"):
+		return map[string]VerifyProfile{
+			"syntax": profile(VerifyStep{Name: "compile", Command: "python3 -m compileall -q {files}"}),
+			"tests":  profile(VerifyStep{Name: "test", Command: "python3 -m pytest -q"}),
+		}
+	}
+	return map[string]VerifyProfile{}
+}
+
+// RunVerifyProfile runs the steps of p in workTree, stopping at the first
+// failure. changed lists the edited files (absolute or relative to workTree).
+func RunVerifyProfile(ctx context.Context, workTree string, p VerifyProfile, changed []string) VerifyResult {
+	result := VerifyResult{Profile: p.Name, Passed: true}
+	files, packages := verifyTargets(workTree, changed)
+	for _, step := range p.Steps {
+		name := step.Name
+		if name == "" {
+			name = strings.Fields(step.Command)[0]
+		}
+		sr := VerifyStepResult{Name: name}
+		command, ok := expandVerifyCommand(step.Command, files, packages)
+		sr.Command = command
+		if !ok {
+			sr.Passed, sr.Skipped = true, true
+			result.Steps = append(result.Steps, sr)
+			continue
+		}
+
+		timeout := defaultVerifyStepTimeout
+		if step.TimeoutSeconds > 0 {
+			timeout = time.Duration(step.TimeoutSeconds) * time.Second
+		}
+		stepCtx, cancel := context.WithTimeout(ctx, timeout)
+		cmd := prepareCommand(stepCtx, command)
+		cmd.Dir = workTree
+		start := time.Now()
+		output, err := cmd.CombinedOutput()
+		sr.Elapsed = time.Since(start)
+		sr.TimedOut = errors.Is(stepCtx.Err(), context.DeadlineExceeded)
+		cancel()
+
+		sr.Passed = err == nil
+		if !sr.Passed {
+			sr.Err = err.Error()
+			if sr.TimedOut {
+				sr.Err = fmt.Sprintf("timed out after %s", timeout)
+			}
+			sr.Output = compactVerifyOutput(sanitizeText(string(output)))
+		}
+		result.Steps = append(result.Steps, sr)
+		if !sr.Passed {
+			result.Passed = false
+			break
+		}
+	}
+	return result
+}
+
+// Format renders the result compactly for a tool response: one line per
+// step plus the relevant output of the failing step.
+func (r VerifyResult) Format() string {
+	var b strings.Builder
+	status := "passed"
+	if !r.Passed {
+		status = "FAILED"
+	}
+	fmt.Fprintf(&b, "[Verify] profile %q %s", r.Profile, status)
+	for _, s := range r.Steps {
+		switch {
+		case s.Skipped:
+			fmt.Fprintf(&b, "\n  skip %s (no matching files)", s.Name)
+		case s.Passed:
+			fmt.Fprintf(&b, "\n  ok   %s (%s)", s.Name, s.Elapsed.Round(100*time.Millisecond))
+		default:
+			fmt.Fprintf(&b, "\n  FAIL %s (%s): %s\n  $ %s", s.Name, s.Elapsed.Round(100*time.Millisecond), s.Err, s.Command)
+			for _, line := range strings.Split(s.Output, "\n") {
+				if line != "" {
+					b.WriteString("\n    " + line)
+				}
+			}
+		}
+	}
+	return b.String()
+}
+
+// verifyTargets returns the changed files relative to workTree and the Go
+// package patterns (./dir) that contain changed .go files.
+func verifyTargets(workTree string, changed []string) (files, packages []string) {
+	seenPkg := map[string]bool{}
+	for _, f := range changed {
+		rel := f
+		if filepath.IsAbs(f) {
+			r, err := filepath.Rel(workTree, f)
+			if err != nil || strings.HasPrefix(r, "..") {
+				continue
+			}
+			rel = r
+		}
+		if _, err := os.Stat(filepath.Join(workTree, rel)); err != nil {
+			continue
+		}
+		files = append(files, filepath.ToSlash(rel))
+		if filepath.Ext(rel) != ".go" {
+			continue
+		}
+		pkg := "./" + filepath.ToSlash(filepath.Dir(rel))
+		if pkg == "./." {
+			pkg = "."
+		}
+		if !seenPkg[pkg] {
+			seenPkg[pkg] = true
+			packages = append(packages, pkg)
+		}
+	}
+	if len(packages) == 0 {
+		packages = []string{"./..."}
+	}
+	return files, packages
+}
+
+// expandVerifyCommand fills the {files} and {packages} placeholders. It
+// reports false when the command needs files and there are none.
+func expandVerifyCommand(command string, files, packages []string) (string, bool) {
+	if strings.Contains(command, "{files}") {
+		if len(files) == 0 {
+			return command, false
+		}
+		command = strings.ReplaceAll(command, "{files}", shellQuoteAll(files))
+	}
+	return strings.ReplaceAll(command, "{packages}", shellQuoteAll(packages)), true
+}
+
+func shellQuoteAll(args []string) string {
+	quoted := make([]string, len(args))
+	for i, a := range args {
+		if a != "" && strings.Trim(a, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789._/-") == "" {
+			quoted[i] = a
+			continue
+		}
+		quoted[i] = "'" + strings.ReplaceAll(a, "'", `'\''`) + "'"
+	}
+	return strings.Join(quoted, " ")
+}
+
+var verifyErrorLine = regexp.MustCompile(`(?i)(^\S+:\d+(:\d+)?:|\berror\b|^\s*(--- )?FAIL|^panic:|^\s*\^|^E\s|Traceback|assert)`)
+
+// compactVerifyOutput keeps the lines that look like errors (file:line:,
+// FAIL, panic, ...) or, when none do, the tail of the output.
+func compactVerifyOutput(output string) string {
+	lines := strings.Split(strings.TrimRight(output, "\n"), "\n")
+	var picked []string
+	for _, line := range lines {
+		if verifyErrorLine.MatchString(line) {
+			picked = append(picked, line)
+		}
+	}
+	omitted := 0
+	if len(picked) == 0 {
+		picked = lines
+		if len(picked) > maxVerifyTailLines {
+			omitted = len(picked) - maxVerifyTailLines
+			picked = picked[omitted:]
+		}
+	} else if len(picked) > maxVerifyOutputLines {
+		omitted = len(picked) - maxVerifyOutputLines
+		picked = picked[:maxVerifyOutputLines]
+	}
+	for i, line := range picked {
+		if len(line) > maxVerifyLineLength {
+			picked[i] = line[:maxVerifyLineLength] + "..."
+		}
+	}
+	out := strings.Join(picked, "\n")
+	if omitted > 0 {
+		out += fmt.Sprintf("\n... %d more lines", omitted)
+	}
+	return out
+}
+
+// FindVerifyRoot walks up from path to the nearest directory that holds a
+// verify config or a project marker, falling back to the file's directory.
+func FindVerifyRoot(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		abs = path
+	}
+	start := filepath.Dir(abs)
+	markers := []string{
+		filepath.Join(project.InstructionsDir, VerifyConfigFile),
+		"go.mod", "go.work", "Cargo.toml", "package.json", "pyproject.toml", "setup.py", ".git",
+	}
+	for _, marker := range markers {
+		for d := start; ; {
+			if _, err := os.Stat(filepath.Join(d, marker)); err == nil {
+				return d
+			}
+			parent := filepath.Dir(d)
+			if parent == d {
+				break
+			}
+			d = parent
+		}
+	}
+	return start
+}
+
+// VerifyFileEdit runs edit and then the named verify profile for the project
+// containing paths. edit is expected to have formatted what it wrote (see
+// FormatEditedFiles and AppendFormatReport). When verification fails and the
+// profile rolls back, the files are restored to their content and mode from
+// before the edit, and files and directories the edit created are removed.
+// An empty profile (or "none") skips verification.
+func VerifyFileEdit(ctx context.Context, paths []string, profileName string, edit func() (string, error)) (string, error) {
+	profileName = strings.TrimSpace(profileName)
+	if profileName == "" || profileName == string(VerifyModeNone) || len(paths) == 0 {
+		return edit()
+	}
+
+	workTree := FindVerifyRoot(paths[0])
+	profile, err := ResolveVerifyProfile(workTree, profileName, paths)
+	if err != nil {
+		return "", err
+	}
+
+	type snapshot struct {
+		data    []byte
+		mode    os.FileMode
+		exists  bool
+		missing []string // parent directories that did not exist, outermost first
+	}
+	snapshots := map[string]snapshot{}
+	for _, p := range paths {
+		info, statErr := os.Stat(p)
+		if statErr != nil {
+			var missing []string
+			for d := filepath.Dir(p); filepath.Dir(d) != d; d = filepath.Dir(d) {
+				if _, err := os.Stat(d); err == nil {
+					break
+				}
+				missing = append([]string{d}, missing...)
+			}
+			snapshots[p] = snapshot{missing: missing}
+			continue
+		}
+		data, readErr := os.ReadFile(p)
+		if readErr != nil {
+			return "", fmt.Errorf("failed to snapshot %s before verification: %w", p, readErr)
+		}
+		snapshots[p] = snapshot{data: data, mode: info.Mode().Perm(), exists: true}
+	}
+
+	output, err := edit()
+	if err != nil {
+		return output, err
+	}
+
+	result := RunVerifyProfile(ctx, workTree, profile, paths)
+	if result.Passed {
+		return output + "\n\n" + result.Format(), nil
+	}
+	if !profile.Rollback() {
+		return output + "\n\n" + result.Format() + "\n(changes kept; profile does not roll back)", nil
+	}
+	for p, s := range snapshots {
+		if !s.exists {
+			_ = os.Remove(p)
+			for i := len(s.missing) - 1; i >= 0; i-- {
+				_ = os.Remove(s.missing[i]) // fails, as intended, when something else now lives there
+			}
+			continue
+		}
+		writeErr := writeFileAtomic(p, s.data)
+		if writeErr == nil {
+			writeErr = os.Chmod(p, s.mode)
+		}
+		if writeErr != nil {
+			return "", fmt.Errorf("verification failed and restoring %s failed: %v\n%s", p, writeErr, result.Format())
+		}
+	}
+	return "", fmt.Errorf("verification failed and the edit was rolled back:\n%s", result.Format())
+}
+
+// ExecuteVerifiedLineTool is ExecuteLineTool plus post-edit formatting and
+// the verify profile named by the tool's optional "verify" argument.
+func ExecuteVerifiedLineTool(ctx context.Context, name, rawArgs string) (handled bool, output string) {
+	var args struct {
+		Path   string `json:"path"`
+		Verify string `json:"verify"`
+	}
+	_ = json.Unmarshal([]byte(rawArgs), &args)
+	if strings.TrimSpace(args.Path) == "" || name == "extract_line_range" {
+		return ExecuteLineTool(name, rawArgs)
+	}
+
+	handled = true
+	out, err := VerifyFileEdit(ctx, []string{strings.TrimSpace(args.Path)}, args.Verify, func() (string, error) {
+		var out string
+		handled, out = ExecuteLineTool(name, rawArgs)
+		if trimmed := strings.TrimSpace(out); strings.HasPrefix(trimmed, "Error") {
+			return "", errors.New(strings.TrimPrefix(trimmed, "Error: "))
+		}
+		return AppendFormatReport(ctx, []string{strings.TrimSpace(args.Path)}, out), nil
+	})
+	if err != nil {
+		return handled, fmt.Sprintf("Error: %v", err)
+	}
+	return handled, out
+}
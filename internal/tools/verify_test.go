@@ -0,0 +1,146 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeVerifyConfig(t *testing.T, root, content string) {
+	t.Helper()
+	dir := filepath.Join(root, ".ai2go")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, VerifyConfigFile), []byte(content), 0644); err != nil {
+		t.Fatalf("write verify.json: %v", err)
+	}
+}
+
+func TestVerifyFileEditRollsBackOnFailure(t *testing.T) {
+	root := t.TempDir()
+	writeVerifyConfig(t, root, `{
+		"default": "check",
+		"profiles": {
+			"check": {"steps": [{"name": "marker", "command": "grep -q GOOD {files}"}]},
+			"lenient": {"rollback_on_failure": false, "steps": [{"command": "grep -q GOOD {files}"}]}
+		}
+	}`)
+	path := filepath.Join(root, "notes.txt")
+	if err := os.WriteFile(path, []byte("GOOD\n"), 0644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	write := func(content string) func() (string, error) {
+		return func() (string, error) {
+			return "edited", os.WriteFile(path, []byte(content), 0644)
+		}
+	}
+
+	_, err := VerifyFileEdit(context.Background(), []string{path}, "default", write("BAD\n"))
+	if err == nil || !strings.Contains(err.Error(), `profile "check" FAILED`) || !strings.Contains(err.Error(), "FAIL marker") {
+		t.Fatalf("expected verification failure, got %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "GOOD\n" {
+		t.Fatalf("expected rollback, file is %q", data)
+	}
+
+	out, err := VerifyFileEdit(context.Background(), []string{path}, "lenient", write("BAD\n"))
+	if err != nil || !strings.Contains(out, "changes kept") {
+		t.Fatalf("expected kept changes, got %q, %v", out, err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "BAD\n" {
+		t.Fatalf("expected edit to be kept, file is %q", data)
+	}
+
+	out, err = VerifyFileEdit(context.Background(), []string{path}, "check", write("GOOD again\n"))
+	if err != nil || !strings.HasPrefix(out, "edited\n\n[Verify] profile \"check\" passed") {
+		t.Fatalf("expected passing verification, got %q, %v", out, err)
+	}
+
+	if _, err := VerifyFileEdit(context.Background(), []string{path}, "missing", write("x")); err == nil || !strings.Contains(err.Error(), "available: check, lenient") {
+		t.Fatalf("expected unknown profile error, got %v", err)
+	}
+}
+
+func TestVerifyFileEditRollbackRestoresModeAndRemovesCreatedDirs(t *testing.T) {
+	root := t.TempDir()
+	writeVerifyConfig(t, root, `{"profiles": {"check": {"steps": [{"command": "grep -q GOOD {files}"}]}}}`)
+	script := filepath.Join(root, "run.sh")
+	if err := os.WriteFile(script, []byte("GOOD\n"), 0755); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	_, err := VerifyFileEdit(context.Background(), []string{script}, "check", func() (string, error) {
+		// Replace the file the way editors that write a new file do.
+		if err := os.Remove(script); err != nil {
+			return "", err
+		}
+		return "edited", os.WriteFile(script, []byte("BAD\n"), 0644)
+	})
+	if err == nil {
+		t.Fatal("expected verification failure")
+	}
+	if info, err := os.Stat(script); err != nil || info.Mode().Perm() != 0755 || readTestFile(script) != "GOOD\n" {
+		t.Fatalf("rollback did not restore content and mode: %v %v", info, err)
+	}
+
+	created := filepath.Join(root, "new", "pkg", "file.txt")
+	_, err = VerifyFileEdit(context.Background(), []string{created}, "check", func() (string, error) {
+		if err := os.MkdirAll(filepath.Dir(created), 0755); err != nil {
+			return "", err
+		}
+		return "edited", os.WriteFile(created, []byte("BAD\n"), 0644)
+	})
+	if err == nil {
+		t.Fatal("expected verification failure")
+	}
+	if _, err := os.Stat(filepath.Join(root, "new")); !os.IsNotExist(err) {
+		t.Fatalf("rollback left the created directories: %v", err)
+	}
+}
+
+func TestBuiltinVerifyProfilesFollowProjectType(t *testing.T) {
+	root := t.TempDir()
+	if p, err := ResolveVerifyProfile(root, "syntax", []string{"app.py"}); err != nil || p.Steps[0].Name != "compile" {
+		t.Fatalf("expected python syntax profile, got %+v, %v", p, err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "go.mod"), []byte("module example.com/x\n"), 0644); err != nil {
+		t.Fatalf("write go.mod: %v", err)
+	}
+	p, err := ResolveVerifyProfile(root, "tests", nil)
+	if err != nil || len(p.Steps) != 2 || p.Steps[1].Command != "go test {packages}" {
+		t.Fatalf("expected go tests profile, got %+v, %v", p, err)
+	}
+}
+
+func TestVerifyTargetsAndCompactOutput(t *testing.T) {
+	root := t.TempDir()
+	for _, f := range []string{"main.go", "internal/a/a.go", "internal/a/b.go", "README.md"} {
+		path := filepath.Join(root, f)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+	files, packages := verifyTargets(root, []string{filepath.Join(root, "main.go"), "internal/a/a.go", "internal/a/b.go", "README.md", "gone.go"})
+	if !reflect.DeepEqual(packages, []string{".", "./internal/a"}) || len(files) != 4 {
+		t.Fatalf("unexpected targets: files=%v packages=%v", files, packages)
+	}
+	if cmd, ok := expandVerifyCommand("go test {packages}", nil, []string{"./x y"}); !ok || cmd != "go test './x y'" {
+		t.Fatalf("unexpected expansion %q %v", cmd, ok)
+	}
+	if _, ok := expandVerifyCommand("lint {files}", nil, nil); ok {
+		t.Fatal("expected step with no files to be skipped")
+	}
+
+	output := "# example.com/x\nsome noise\ninternal/a/a.go:3:2: undefined: foo\nmore noise\nFAIL\texample.com/x [build failed]\n"
+	got := compactVerifyOutput(output)
+	want := "internal/a/a.go:3:2: undefined: foo\nFAIL\texample.com/x [build failed]"
+	if got != want {
+		t.Fatalf("compactVerifyOutput = %q, want %q", got, want)
+	}
+}
@@ -8,6 +8,8 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+
+	"github.com/bilbilaki/ai2go/internal/redact"
 )
 
 const maxAttachmentBytes = 100 * 1024
@@ -15,8 +17,9 @@ const maxAttachmentBytes = 100 * 1024
 var cachedFileList []string
 
 // ResolveFileTokens scans the input for "/file", prompts the user to select files,
-// and replaces the token with the file content.
-func ResolveFileTokens(input string) string {
+// and replaces the token with the file content. Secrets in attached content are
+// replaced with placeholders when redactor is non-nil.
+func ResolveFileTokens(input string, redactor *redact.Redactor) string {
 	for strings.Contains(input, "/file") {
 		fmt.Println("\n\033[33m[Attachment System] Found '/file' marker.\033[0m")
 
@@ -67,6 +70,11 @@ func ResolveFileTokens(input string) string {
 			fmt.Printf("Error reading file: %v\n", err)
 			continue
 		}
+		if redacted, n := redactor.Redact(content); n > 0 {
+			content = redacted
+			notice += fmt.Sprintf("\n[%d secret value(s) redacted]", n)
+			fmt.Printf("\033[33mRedacted %d secret value(s) from the attachment.\033[0m\n", n)
+		}
 
 		// Format: text before \n user attached this file from this path "$content" \n text after
 		// We use a distinct format to ensure the AI sees it clearly.